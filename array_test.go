@@ -0,0 +1,76 @@
+package jsongroup
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+type arrayHolder struct {
+	Empty     [0]int        `json:"empty" groups:"public"`
+	OmitEmpty [0]int        `json:"omit_empty,omitempty" groups:"public"`
+	Zeros     [2]int        `json:"zeros,omitempty" groups:"public"`
+	Addresses [3]Address    `json:"addresses" groups:"public"`
+	Grid      [2][2]Address `json:"grid" groups:"public"`
+	Matrix    [2][0]int     `json:"matrix" groups:"public"`
+	Hidden    [1]int        `json:"hidden" groups:"admin"`
+}
+
+func newArrayHolder() arrayHolder {
+	return arrayHolder{
+		Addresses: [3]Address{{Street: "1 Main St", City: "A", Zip: "100"}, {}, {City: "C", Zip: "300"}},
+		Grid:      [2][2]Address{{{City: "x", Zip: "1"}}, {{}, {City: "y", Zip: "2"}}},
+		Hidden:    [1]int{9},
+	}
+}
+
+func TestArrays(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		v      any
+		opts   *Options
+		groups []string
+		want   string
+	}{
+		{"zero length root", [0]int{}, New(), nil, `[]`},
+		{"zero length root NullIfEmpty", [0]int{}, New().WithNullIfEmpty(true), nil, `[]`},
+		{"zero length elements", [][0]int{{}, {}}, New().WithNullIfEmpty(true), nil, `[[],[]]`},
+		{"zero length in map", map[string][0]int{"a": {}}, New().WithNullIfEmpty(true), nil, `{"a":[]}`},
+		{"struct elements", [3]Address{{Street: "s", City: "c", Zip: "z"}}, New(), []string{"public"},
+			`[{"street":"s","city":"c"},{"street":"","city":""},{"street":"","city":""}]`},
+		// 零值元素照常输出，[0]int作为字段时与空切片相同
+		{"fields", newArrayHolder(), New(), []string{"public"},
+			`{"empty":[],"zeros":[0,0],` +
+				`"addresses":[{"street":"1 Main St","city":"A"},{"street":"","city":""},{"street":"","city":"C"}],` +
+				`"grid":[[{"street":"","city":"x"},{"street":"","city":""}],[{"street":"","city":""},{"street":"","city":"y"}]],` +
+				`"matrix":[[],[]]}`},
+		// NullIfEmpty对数组元素中的空字段同样生效，与切片元素一致
+		{"fields NullIfEmpty", newArrayHolder(), New().WithNullIfEmpty(true), []string{"public"},
+			`{"empty":null,"omit_empty":null,"zeros":[0,0],` +
+				`"addresses":[{"street":"1 Main St","city":"A"},{"street":null,"city":null},{"street":null,"city":"C"}],` +
+				`"grid":[[{"street":null,"city":"x"},{"street":null,"city":null}],[{"street":null,"city":null},{"street":null,"city":"y"}]],` +
+				`"matrix":[[],[]]}`},
+		{"fields admin", newArrayHolder(), New(), []string{"admin"}, `{"hidden":[9]}`},
+		{"struct elements admin", newArrayHolder().Addresses, New(), []string{"admin"},
+			`[{"street":"1 Main St","city":"A","zip":"100"},{"street":"","city":""},{"street":"","city":"C","zip":"300"}]`},
+		{"nested struct elements admin", newArrayHolder().Grid, New(), []string{"admin"},
+			`[[{"street":"","city":"x","zip":"1"},{"street":"","city":""}],[{"street":"","city":""},{"street":"","city":"y","zip":"2"}]]`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := marshalString(t, tc.v, tc.opts, tc.groups...)
+			if got != tc.want {
+				t.Errorf("direct encoder:\ngot  %s\nwant %s", got, tc.want)
+			}
+
+			value, err := MarshalToValue(tc.v, tc.opts, tc.groups...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, _ := json.Marshal(value)
+			assertJSONEqual(t, string(data), tc.want)
+
+			if n, err := EstimateSize(tc.v, tc.opts, tc.groups...); err != nil || n != len(tc.want) {
+				t.Errorf("EstimateSize = %d, %v; want %d", n, err, len(tc.want))
+			}
+		})
+	}
+}
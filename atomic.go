@@ -0,0 +1,32 @@
+package jsongroup
+
+import "reflect"
+
+// atomicLoad 判断v是否为sync/atomic中带Load方法的类型（atomic.Int64、atomic.Bool、atomic.Pointer[T]、atomic.Value等），
+// 是时调用Load原子地读取当前值并返回，不直接访问其内部字段；返回值按Load声明的类型处理，
+// 如atomic.Pointer[T]得到*T，atomic.Value得到any，之后照常递归、过滤。
+// 只有可寻址的值（通过指针可达的结构体字段、切片和数组元素）才能原子地读取；
+// map的值、接口中按值存放的原子类型等不可寻址的值已经是非原子复制得到的副本，返回ErrTypeUnsupportedValue错误
+func (ctx *serializeContext) atomicLoad(v reflect.Value) (reflect.Value, bool, error) {
+	t := v.Type()
+	if t.PkgPath() != "sync/atomic" {
+		return reflect.Value{}, false, nil
+	}
+	load, ok := atomicLoadMethod(t)
+	if !ok {
+		return reflect.Value{}, false, nil
+	}
+	if !v.CanAddr() {
+		return reflect.Value{}, true, UnsupportedValueError(ctx.path(), v, "原子类型的值不可寻址，无法原子地读取，需通过指针传入")
+	}
+	return v.Addr().Method(load.Index).Call(nil)[0], true, nil
+}
+
+// atomicLoadMethod 返回t的指针类型上无参数、单返回值的Load方法
+func atomicLoadMethod(t reflect.Type) (reflect.Method, bool) {
+	m, ok := reflect.PointerTo(t).MethodByName("Load")
+	if !ok || m.Type.NumIn() != 1 || m.Type.NumOut() != 1 {
+		return reflect.Method{}, false
+	}
+	return m, true
+}
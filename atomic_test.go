@@ -0,0 +1,128 @@
+package jsongroup
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+type atomicCounters struct {
+	Hits    atomic.Int64            `json:"hits" groups:"public"`
+	Misses  atomic.Uint32           `json:"misses" groups:"public"`
+	Ready   atomic.Bool             `json:"ready" groups:"public"`
+	Owner   atomic.Pointer[User]    `json:"owner" groups:"public"`
+	Home    atomic.Pointer[Address] `json:"home,omitempty" groups:"public"`
+	Label   atomic.Value            `json:"label" groups:"public"`
+	Private atomic.Int64            `json:"private" groups:"admin"`
+}
+
+func newAtomicCounters() *atomicCounters {
+	c := &atomicCounters{}
+	c.Hits.Store(42)
+	c.Misses.Store(7)
+	c.Ready.Store(true)
+	c.Owner.Store(&User{ID: 1, Name: "Alice", Email: "alice@example.com"})
+	c.Label.Store("cache")
+	c.Private.Store(-1)
+	return c
+}
+
+func TestAtomicFieldsLoaded(t *testing.T) {
+	c := newAtomicCounters()
+
+	// atomic.Pointer指向的值照常按分组过滤，nil指针与普通的nil指针字段处理相同
+	assertJSONEqual(t, marshalString(t, c, New(), "public"),
+		`{"hits":42,"misses":7,"ready":true,"owner":{"id":1,"name":"Alice"},"label":"cache"}`)
+	assertJSONEqual(t, marshalString(t, c, New(), "admin"), `{"private":-1}`)
+	assertJSONEqual(t, marshalString(t, c, New().WithNullIfEmpty(true), "admin"), `{"private":-1}`)
+
+	m, err := MarshalToMap(c, "public")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m["hits"] != int64(42) || m["misses"] != uint64(7) || m["ready"] != true || m["label"] != "cache" {
+		t.Errorf("MarshalToMap = %#v", m)
+	}
+	if owner, ok := m["owner"].(map[string]any); !ok || !reflect.DeepEqual(owner, map[string]any{"id": int64(1), "name": "Alice"}) {
+		t.Errorf("owner = %#v", m["owner"])
+	}
+
+	data := marshalString(t, c, New(), "public")
+	if n, err := EstimateSize(c, nil, "public"); err != nil || n != len(data) {
+		t.Errorf("EstimateSize = %d, %v; actual %d bytes", n, err, len(data))
+	}
+}
+
+func TestAtomicZeroValues(t *testing.T) {
+	var c atomicCounters
+	// 未存储值的atomic.Value和nil的atomic.Pointer按nil处理：默认省略，NullIfEmpty时输出null
+	assertJSONEqual(t, marshalString(t, &c, New(), "public"), `{"hits":0,"misses":0,"ready":false}`)
+	assertJSONEqual(t, marshalString(t, &c, New().WithNullIfEmpty(true), "public"),
+		`{"hits":0,"misses":0,"ready":false,"owner":null,"home":null,"label":null}`)
+	// 按值传入时根值不可寻址，读取副本
+	assertJSONEqual(t, marshalString(t, []*atomicCounters{&c}, New(), "admin"), `[{"private":0}]`)
+}
+
+// TestAtomicFieldsReadWhileWritten 序列化与写入同时进行，配合-race检查字段是通过Load读取的
+func TestAtomicFieldsReadWhileWritten(t *testing.T) {
+	c := newAtomicCounters()
+	users := []*User{{ID: 1, Name: "Alice"}, {ID: 2, Name: "Bob"}}
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; ; i++ {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			c.Hits.Add(1)
+			c.Ready.Store(i%2 == 0)
+			c.Owner.Store(users[i%2])
+			c.Label.Store("v")
+		}
+	}()
+
+	var last int64
+	for range 200 {
+		m, err := MarshalToMap(c, "public")
+		if err != nil {
+			t.Fatal(err)
+		}
+		hits := m["hits"].(int64)
+		if hits < last {
+			t.Fatalf("hits went backwards from %d to %d", last, hits)
+		}
+		last = hits
+		if owner := m["owner"].(map[string]any); owner["id"] != int64(1) && owner["id"] != int64(2) {
+			t.Fatalf("owner = %#v", owner)
+		}
+	}
+	close(done)
+	wg.Wait()
+}
+
+func TestAtomicNotAddressable(t *testing.T) {
+	// map的值不可寻址，读取它已经是非原子的复制，不调用Load而是返回错误
+	counts := map[string]atomic.Int64{"hits": {}}
+	for name, marshal := range map[string]func() error{
+		"direct":   func() error { _, err := MarshalByGroups(counts); return err },
+		"map":      func() error { _, err := MarshalToValue(counts, nil); return err },
+		"estimate": func() error { _, err := EstimateSize(counts, nil); return err },
+	} {
+		var jerr *Error
+		if err := marshal(); !errors.As(err, &jerr) || jerr.Type != ErrTypeUnsupportedValue || jerr.Path != "hits" {
+			t.Errorf("%s: err = %v, want unsupported value at hits", name, err)
+		}
+	}
+
+	// 通过指针可达的值照常原子地读取
+	ptrs := map[string]*atomic.Int64{"hits": new(atomic.Int64)}
+	ptrs["hits"].Store(3)
+	assertJSONEqual(t, marshalString(t, ptrs, New()), `{"hits":3}`)
+}
@@ -0,0 +1,102 @@
+package jsongroup
+
+import (
+	"cmp"
+	"maps"
+	"reflect"
+	"slices"
+)
+
+// AuditReport AuditCachedTypes生成的分组审计报告，可以直接序列化为JSON
+// 字段路径形如"pkg.Type.Field"，内嵌结构体展开的字段为"pkg.Type.Embedded.Field"；所有列表均按字典序排列
+type AuditReport struct {
+	// Types 参与统计的类型名称
+	Types []string `json:"types"`
+	// Groups 出现过的所有分组名称
+	Groups []string `json:"groups"`
+	// FieldsByGroup 每个分组包含的字段路径
+	FieldsByGroup map[string][]string `json:"fields_by_group"`
+	// Ungrouped 不属于任何分组的字段路径，包括未声明分组标签和标签值为空的字段
+	Ungrouped []string `json:"ungrouped"`
+	// Collisions 内嵌结构体展开后JSON名称冲突的字段
+	Collisions []AuditCollision `json:"collisions"`
+}
+
+// AuditCollision 同一类型中JSON名称相同的一组字段，序列化时后声明的字段覆盖先声明的字段
+type AuditCollision struct {
+	// Type 类型名称
+	Type string `json:"type"`
+	// JSONName 冲突的JSON名称
+	JSONName string `json:"json_name"`
+	// Fields 使用该名称的字段，按声明顺序排列
+	Fields []string `json:"fields"`
+}
+
+// AuditCachedTypes 汇总字段缓存中当前所有类型的分组信息，用于审计各分组实际暴露了哪些字段
+// 只包含已被解析并仍在缓存中的类型：服务启动时先用WarmCache预热所有DTO，再调用本函数即可得到完整的清单。
+// 报告反映的是缓存中的字段信息，被淘汰的类型不会出现；json:"-"和非导出字段不会被序列化，不出现在报告中
+func AuditCachedTypes() AuditReport {
+	return globalCache.audit()
+}
+
+// audit 遍历所有分片中的缓存条目生成审计报告，每个分片只在复制条目时持有锁
+func (c *fieldCache) audit() AuditReport {
+	infos := make(map[reflect.Type]*typeFields)
+	for _, shard := range c.shards.Load().shards {
+		shard.mu.Lock()
+		for key, element := range shard.cache {
+			entry, ok := element.Value.(*cacheEntry)
+			if !ok || entry == nil {
+				continue
+			}
+			// 同一类型按多个标签键缓存时，优先使用默认标签键的解析结果
+			if _, seen := infos[key.typ]; !seen || key.tagKey == DefaultTagKey {
+				infos[key.typ] = entry.value
+			}
+		}
+		shard.mu.Unlock()
+	}
+
+	report := AuditReport{
+		Types:         make([]string, 0, len(infos)),
+		Groups:        []string{},
+		FieldsByGroup: make(map[string][]string),
+		Ungrouped:     []string{},
+		Collisions:    []AuditCollision{},
+	}
+	for typ, info := range infos {
+		name := typeName(typ)
+		report.Types = append(report.Types, name)
+
+		byJSONName := make(map[string][]string)
+		for _, field := range info.fields {
+			path := name + "." + field.Name
+			if len(field.Groups) == 0 {
+				report.Ungrouped = append(report.Ungrouped, path)
+			}
+			for _, g := range field.Groups {
+				// 同一标签中重复的分组只记录一次
+				if paths := report.FieldsByGroup[g]; len(paths) == 0 || paths[len(paths)-1] != path {
+					report.FieldsByGroup[g] = append(paths, path)
+				}
+			}
+			byJSONName[field.JSONName] = append(byJSONName[field.JSONName], field.Name)
+		}
+		for jsonName, fields := range byJSONName {
+			if len(fields) > 1 {
+				report.Collisions = append(report.Collisions, AuditCollision{Type: name, JSONName: jsonName, Fields: fields})
+			}
+		}
+	}
+
+	report.Groups = append(report.Groups, slices.Sorted(maps.Keys(report.FieldsByGroup))...)
+	for _, paths := range report.FieldsByGroup {
+		slices.Sort(paths)
+	}
+	slices.Sort(report.Types)
+	slices.Sort(report.Ungrouped)
+	slices.SortFunc(report.Collisions, func(a, b AuditCollision) int {
+		return cmp.Or(cmp.Compare(a.Type, b.Type), cmp.Compare(a.JSONName, b.JSONName))
+	})
+	return report
+}
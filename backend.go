@@ -0,0 +1,126 @@
+package jsongroup
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// Backend 编码后端，负责直接编码路径无法处理时的最终JSON编码，并声明后端的输出特性
+// 直接编码路径根据Capabilities调整字符串转义，使整体输出与后端保持一致
+type Backend interface {
+	// MarshalFinal 编码由map[string]any、[]any和基本类型组成的中间表示
+	MarshalFinal(v any) ([]byte, error)
+	// Capabilities 返回后端的输出特性
+	Capabilities() BackendCapabilities
+}
+
+// BackendCapabilities 描述编码后端的输出特性
+type BackendCapabilities struct {
+	// EscapeForHTML 字符串中的<、>、&是否转义为\u003c、\u003e、\u0026
+	EscapeForHTML bool
+	// EscapeForJS 字符串中的U+2028和U+2029是否转义为\u2028、\u2029
+	EscapeForJS bool
+}
+
+// stdlibBackend 基于encoding/json的默认后端
+type stdlibBackend struct{}
+
+// MarshalFinal 使用json.Marshal编码
+func (stdlibBackend) MarshalFinal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// Capabilities encoding/json同时转义HTML字符和JS行终止符
+func (stdlibBackend) Capabilities() BackendCapabilities {
+	return BackendCapabilities{EscapeForHTML: true, EscapeForJS: true}
+}
+
+// StdlibBackend 基于encoding/json的默认编码后端
+var StdlibBackend Backend = stdlibBackend{}
+
+// funcBackend 将编码函数包装为后端，假定其转义行为与encoding/json一致
+type funcBackend func(any) ([]byte, error)
+
+// MarshalFinal 调用编码函数
+func (f funcBackend) MarshalFinal(v any) ([]byte, error) {
+	return f(v)
+}
+
+// Capabilities 与encoding/json相同
+func (funcBackend) Capabilities() BackendCapabilities {
+	return StdlibBackend.Capabilities()
+}
+
+// backend 返回选项指定的编码后端，未指定时使用StdlibBackend
+func (o *Options) backend() Backend {
+	if o.Backend == nil {
+		return StdlibBackend
+	}
+	return o.Backend
+}
+
+// applyCapabilities 按后端的输出特性调整直接编码产生的JSON
+// 直接编码总是按encoding/json的规则转义，后端不转义某类字符时在此将对应的转义序列还原为原字符
+func applyCapabilities(b []byte, caps BackendCapabilities) []byte {
+	if (caps.EscapeForHTML && caps.EscapeForJS) || !bytes.Contains(b, []byte(`\u`)) {
+		return b
+	}
+
+	// 合法的JSON输出中反斜杠只出现在字符串的转义序列里，逐个跳过转义序列即可避免误判"\\u003c"这样的原始文本
+	w := 0
+	for r := 0; r < len(b); {
+		if b[r] != '\\' {
+			b[w] = b[r]
+			w++
+			r++
+			continue
+		}
+		if b[r+1] == 'u' {
+			switch seq := b[r+2 : r+6]; {
+			case !caps.EscapeForHTML && string(seq) == "003c":
+				b[w] = '<'
+				w, r = w+1, r+6
+				continue
+			case !caps.EscapeForHTML && string(seq) == "003e":
+				b[w] = '>'
+				w, r = w+1, r+6
+				continue
+			case !caps.EscapeForHTML && string(seq) == "0026":
+				b[w] = '&'
+				w, r = w+1, r+6
+				continue
+			case !caps.EscapeForJS && (string(seq) == "2028" || string(seq) == "2029"):
+				// U+2028和U+2029的UTF-8编码为E2 80 A8和E2 80 A9
+				b[w], b[w+1], b[w+2] = 0xE2, 0x80, 0xA8+seq[3]-'8'
+				w, r = w+3, r+6
+				continue
+			}
+		}
+		b[w], b[w+1] = b[r], b[r+1]
+		w, r = w+2, r+2
+	}
+	return b[:w]
+}
+
+// escapeLineTerminators 将JSON中原始的U+2028和U+2029转义为\u2028、\u2029，不包含时原样返回b
+// 两者只能出现在字符串内部，且转义序列本身只由ASCII字符组成，按字节替换不会影响已转义的内容
+func escapeLineTerminators(b []byte) []byte {
+	if !bytes.Contains(b, []byte("\u2028")) && !bytes.Contains(b, []byte("\u2029")) {
+		return b
+	}
+	out := make([]byte, 0, len(b)+8)
+	for {
+		i := bytes.Index(b, []byte{0xE2, 0x80})
+		if i < 0 || i+2 >= len(b) {
+			break
+		}
+		if c := b[i+2]; c == 0xA8 || c == 0xA9 {
+			out = append(out, b[:i]...)
+			out = append(out, '\\', 'u', '2', '0', '2', '8'+c-0xA8)
+		} else {
+			out = append(out, b[:i+3]...)
+		}
+		b = b[i+3:]
+	}
+	return append(out, b...)
+}
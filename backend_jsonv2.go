@@ -0,0 +1,24 @@
+//go:build go1.27
+
+package jsongroup
+
+import (
+	"encoding/json/jsontext"
+	jsonv2 "encoding/json/v2"
+)
+
+// jsonv2Backend 基于encoding/json/v2的编码后端
+type jsonv2Backend struct{}
+
+// MarshalFinal 使用json/v2编码，map键排序并将无效的UTF-8替换为U+FFFD，与直接编码路径保持一致
+func (jsonv2Backend) MarshalFinal(v any) ([]byte, error) {
+	return jsonv2.Marshal(v, jsonv2.Deterministic(true), jsontext.AllowInvalidUTF8(true))
+}
+
+// Capabilities json/v2默认不转义HTML字符和JS行终止符
+func (jsonv2Backend) Capabilities() BackendCapabilities {
+	return BackendCapabilities{}
+}
+
+// JSONv2Backend 基于encoding/json/v2的编码后端，仅在使用Go 1.27及以上版本构建时可用
+var JSONv2Backend Backend = jsonv2Backend{}
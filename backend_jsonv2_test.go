@@ -0,0 +1,47 @@
+//go:build go1.27
+
+package jsongroup
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestJSONv2BackendMatchesStdlib(t *testing.T) {
+	users := make([]ComplexUser, 5)
+	for i := range users {
+		users[i] = newComplexUser(i)
+	}
+	postProcess := func(root any) (any, error) { return root, nil }
+
+	for _, tc := range []struct {
+		name string
+		v    any
+		opts *Options
+	}{
+		{"complex users", users, New()},
+		{"map root", map[string]any{"b": []any{1, "x", nil}, "a": 1.5, "c": map[string]int{"z": 1, "y": 2}}, New()},
+		{"null if empty", newEstimatePayloads(5), New().WithNullIfEmpty(true)},
+		{"top level key", users[:2], New().WithTopLevelKey("data")},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, opts := range []*Options{tc.opts, tc.opts.WithPostProcess(postProcess)} {
+				std := marshalString(t, tc.v, opts, "public")
+				v2 := marshalString(t, tc.v, opts.WithBackend(JSONv2Backend), "public")
+				// json/v2不转义HTML字符，还原后其余内容应完全相同
+				if want := string(applyCapabilities([]byte(std), JSONv2Backend.Capabilities())); v2 != want {
+					t.Errorf("PostProcess=%v:\nv2     %s\nstdlib %s", opts.PostProcess != nil, v2, want)
+				}
+			}
+		})
+	}
+
+	// 直接编码与经过后端编码的输出都不转义HTML字符
+	u := User{ID: 1, Name: "<a & b>"}
+	for _, opts := range []*Options{New(), New().WithPostProcess(postProcess)} {
+		got := marshalString(t, u, opts.WithBackend(JSONv2Backend), "public")
+		if !strings.Contains(got, `"<a & b>"`) {
+			t.Errorf("PostProcess=%v: got %s", opts.PostProcess != nil, got)
+		}
+	}
+}
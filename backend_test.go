@@ -0,0 +1,109 @@
+package jsongroup
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestApplyCapabilities(t *testing.T) {
+	const escaped = `{"a":"\u003cb\u003e \u0026 \u2028\u2029","b":"\\u003c","c":"\"\n"}`
+	for _, tc := range []struct {
+		name string
+		caps BackendCapabilities
+		want string
+	}{
+		{"stdlib", BackendCapabilities{EscapeForHTML: true, EscapeForJS: true}, escaped},
+		// 原始文本"\u003c"中的反斜杠已被转义，不能被当作转义序列还原
+		{"no escaping", BackendCapabilities{}, "{\"a\":\"<b> & \u2028\u2029\",\"b\":\"\\\\u003c\",\"c\":\"\\\"\\n\"}"},
+		{"html only", BackendCapabilities{EscapeForHTML: true}, "{\"a\":\"\\u003cb\\u003e \\u0026 \u2028\u2029\",\"b\":\"\\\\u003c\",\"c\":\"\\\"\\n\"}"},
+		{"js only", BackendCapabilities{EscapeForJS: true}, `{"a":"<b> & \u2028\u2029","b":"\\u003c","c":"\"\n"}`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := string(applyCapabilities([]byte(escaped), tc.caps))
+			if got != tc.want {
+				t.Errorf("got  %s\nwant %s", got, tc.want)
+			}
+			var a, b any
+			if json.Unmarshal([]byte(got), &a) != nil || json.Unmarshal([]byte(escaped), &b) != nil || a.(map[string]any)["a"] != b.(map[string]any)["a"] {
+				t.Error("adjusted output decodes to a different value")
+			}
+		})
+	}
+
+	// 不含转义序列时原样返回
+	plain := []byte(`{"a":"b"}`)
+	if got := applyCapabilities(plain, BackendCapabilities{}); &got[0] != &plain[0] || string(got) != `{"a":"b"}` {
+		t.Errorf("got %s", got)
+	}
+}
+
+// recordingBackend 记录调用次数，按caps声明输出特性
+type recordingBackend struct {
+	caps  BackendCapabilities
+	calls int
+	err   error
+	empty bool
+}
+
+func (b *recordingBackend) MarshalFinal(v any) ([]byte, error) {
+	b.calls++
+	if b.err != nil || b.empty {
+		return nil, b.err
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	// 按声明的特性还原encoding/json的转义
+	return applyCapabilities(data, b.caps), nil
+}
+
+func (b *recordingBackend) Capabilities() BackendCapabilities { return b.caps }
+
+func TestWithBackend(t *testing.T) {
+	u := User{ID: 1, Name: "<Tom & Jerry>", Address: &Address{City: "a\u2028b"}}
+	postProcess := func(root any) (any, error) { return root, nil }
+	want := "{\"id\":1,\"name\":\"<Tom & Jerry>\",\"address\":{\"street\":\"\",\"city\":\"a\u2028b\"}}"
+
+	// 直接编码路径不调用后端，但按其特性调整转义
+	b := &recordingBackend{}
+	if got := marshalString(t, u, New().WithBackend(b), "public"); got != want {
+		t.Errorf("direct encoder:\ngot  %s\nwant %s", got, want)
+	}
+	if b.calls != 0 {
+		t.Errorf("direct encoder called the backend %d times", b.calls)
+	}
+
+	// 需要中间表示时由后端完成最终编码，两条路径的输出一致
+	got := marshalString(t, u, New().WithBackend(b).WithPostProcess(postProcess), "public")
+	assertJSONEqual(t, got, want)
+	if b.calls != 1 {
+		t.Errorf("PostProcess path called the backend %d times, want 1", b.calls)
+	}
+
+	// JSSafeEscaping对后端的输出同样生效
+	got = marshalString(t, u, New().WithBackend(b).WithPostProcess(postProcess).WithJSSafeEscaping(true), "public")
+	assertJSONEqual(t, got, want)
+	if want := `"city":"a\u2028b"`; !json.Valid([]byte(got)) || !strings.Contains(got, want) {
+		t.Errorf("got %s, want %s", got, want)
+	}
+
+	// 默认后端与encoding/json一致；WithBackend(nil)恢复默认后端
+	std, _ := json.Marshal(map[string]any{"name": u.Name})
+	if got := marshalString(t, map[string]any{"name": u.Name}, New().WithBackend(b).WithBackend(nil)); got != string(std) {
+		t.Errorf("default backend: got %s, want %s", got, std)
+	}
+
+	// 后端的错误附带路径返回，返回空结果时报告错误而不是输出非法的JSON
+	_, err := MarshalByGroupsWithOptions(u, New().WithBackend(&recordingBackend{err: errors.New("boom")}).WithPostProcess(postProcess), "public")
+	var jerr *Error
+	if !errors.As(err, &jerr) {
+		t.Errorf("backend error: got %v, want *Error", err)
+	}
+	_, err = MarshalByGroupsWithOptions(u, New().WithBackend(&recordingBackend{empty: true}).WithPostProcess(postProcess), "public")
+	if !errors.As(err, &jerr) {
+		t.Errorf("empty backend result: got %v, want *Error", err)
+	}
+}
@@ -0,0 +1,77 @@
+package jsongroup
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+type timestamps struct {
+	T01 time.Time `json:"t01" groups:"public"`
+	T02 time.Time `json:"t02" groups:"public"`
+	T03 time.Time `json:"t03" groups:"public"`
+	T04 time.Time `json:"t04" groups:"public"`
+	T05 time.Time `json:"t05" groups:"public"`
+	T06 time.Time `json:"t06" groups:"public"`
+	T07 time.Time `json:"t07" groups:"public"`
+	T08 time.Time `json:"t08" groups:"public"`
+	T09 time.Time `json:"t09" groups:"public"`
+	T10 time.Time `json:"t10" groups:"public"`
+	T11 time.Time `json:"t11" groups:"public"`
+	T12 time.Time `json:"t12" groups:"public"`
+	T13 time.Time `json:"t13" groups:"public"`
+	T14 time.Time `json:"t14" groups:"public"`
+	T15 time.Time `json:"t15" groups:"public"`
+	T16 time.Time `json:"t16" groups:"public"`
+	T17 time.Time `json:"t17" groups:"public"`
+	T18 time.Time `json:"t18" groups:"public"`
+	T19 time.Time `json:"t19" groups:"public"`
+	T20 time.Time `json:"t20,omitempty" groups:"public"`
+}
+
+func newTimestamps() timestamps {
+	base := time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC)
+	var ts timestamps
+	for i, p := range []*time.Time{&ts.T01, &ts.T02, &ts.T03, &ts.T04, &ts.T05, &ts.T06, &ts.T07, &ts.T08, &ts.T09, &ts.T10,
+		&ts.T11, &ts.T12, &ts.T13, &ts.T14, &ts.T15, &ts.T16, &ts.T17, &ts.T18, &ts.T19} {
+		*p = base.Add(time.Duration(i) * time.Hour)
+	}
+	return ts
+}
+
+func TestTimeFieldsMatchEncodingJSON(t *testing.T) {
+	ts := newTimestamps()
+	want, _ := json.Marshal(ts)
+	if got := marshalString(t, ts, nil, "public"); got != string(want) {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+
+	m, err := MarshalToMap(ts, "public")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := m["t01"].(time.Time); !ok || !got.Equal(ts.T01) {
+		t.Errorf("MarshalToMap t01 = %#v, want time.Time %v", m["t01"], ts.T01)
+	}
+	// 与encoding/json相同，omitempty不省略零值的time.Time
+	if got, ok := m["t20"].(time.Time); !ok || !got.IsZero() {
+		t.Errorf("MarshalToMap t20 = %#v, want zero time.Time", m["t20"])
+	}
+}
+
+// TestTimeFieldAllocations 读取time.Time字段不经过Interface()装箱
+// 直接编码时每个字段只有字段上下文的一次分配，MarshalToMap再加上放入结果时的一次装箱
+func TestTimeFieldAllocations(t *testing.T) {
+	if raceEnabled {
+		t.Skip("allocation counts are not meaningful with the race detector")
+	}
+	ts := newTimestamps()
+	MarshalByGroups(ts, "public")
+	if got := testing.AllocsPerRun(100, func() { MarshalByGroups(ts, "public") }); got > 20+8 {
+		t.Errorf("MarshalByGroups: %v allocs/op for 20 time fields", got)
+	}
+	MarshalToMap(ts, "public")
+	if got := testing.AllocsPerRun(100, func() { MarshalToMap(ts, "public") }); got > 2*20+10 {
+		t.Errorf("MarshalToMap: %v allocs/op for 20 time fields", got)
+	}
+}
@@ -0,0 +1,120 @@
+package jsongroup
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// BenchmarkConcurrentCache 不同并发度下缓存命中的吞吐量
+func BenchmarkConcurrentCache(b *testing.B) {
+	types := []reflect.Type{
+		reflect.TypeFor[User](), reflect.TypeFor[Address](), reflect.TypeFor[ComplexUser](),
+		reflect.TypeFor[Profile](), reflect.TypeFor[Stats](), reflect.TypeFor[Social](),
+	}
+	for _, goroutines := range []int{1, 8, 32, 64} {
+		b.Run(fmt.Sprintf("goroutines=%d", goroutines), func(b *testing.B) {
+			c := newFieldCache()
+			for _, typ := range types {
+				c.getFieldsInfo(typ, DefaultTagKey)
+			}
+			b.SetParallelism(goroutines)
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				i := 0
+				for pb.Next() {
+					c.getFieldsInfo(types[i%len(types)], DefaultTagKey)
+					i++
+				}
+			})
+		})
+	}
+}
+
+func BenchmarkMarshalComplexUserPublic(b *testing.B) {
+	u := newComplexUser(1)
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := MarshalByGroups(u, "public"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkMarshalComplexUserMultipleGroups(b *testing.B) {
+	u := newComplexUser(1)
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := MarshalByGroups(u, "public", "admin", "internal"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkBatchEviction 容量远小于类型数时持续淘汰的开销
+func BenchmarkBatchEviction(b *testing.B) {
+	types := distinctStructTypes(10000)
+	c := newFieldCache()
+	c.SetShards(1)
+	c.SetMaxSize(1000)
+	b.ReportAllocs()
+	i := 0
+	for b.Loop() {
+		c.getFieldsInfo(types[i%len(types)], DefaultTagKey)
+		i++
+	}
+}
+
+func BenchmarkMarshalLargeSlice(b *testing.B) {
+	users := make([]ComplexUser, 1000)
+	for i := range users {
+		users[i] = newComplexUser(i)
+	}
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := MarshalByGroups(users, "public", "admin"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkMarshalTimeFields(b *testing.B) {
+	ts := newTimestamps()
+	b.Run("bytes", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			MarshalByGroups(ts, "public")
+		}
+	})
+	b.Run("map", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			MarshalToMap(ts, "public")
+		}
+	})
+}
+
+func BenchmarkMarshalIntSlice(b *testing.B) {
+	ints := make([]int, 1_000_000)
+	for i := range ints {
+		ints[i] = i
+	}
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := MarshalByGroups(ints); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkAnonymousTypeChurn 每次序列化具名类型之间夹杂一个新的匿名类型时具名类型的缓存命中率，
+// skip=true时匿名类型绕过缓存，命中率保持稳定
+func BenchmarkAnonymousTypeChurn(b *testing.B) {
+	for _, skip := range []bool{false, true} {
+		b.Run(fmt.Sprintf("skip=%v", skip), func(b *testing.B) {
+			b.ReportAllocs()
+			ratio := churnNamedHitRatio(b, skip, b.Loop)
+			b.ReportMetric(ratio, "named-hit-ratio")
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package jsongroup
+
+import (
+	"encoding"
+	"encoding/base64"
+	"encoding/json"
+	"reflect"
+	"sync"
+)
+
+var (
+	// binaryMarshalerType encoding.BinaryMarshaler接口的反射类型
+	binaryMarshalerType = reflect.TypeFor[encoding.BinaryMarshaler]()
+	// textMarshalerType encoding.TextMarshaler接口的反射类型
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+	// jsonMarshalerType json.Marshaler接口的反射类型
+	jsonMarshalerType = reflect.TypeFor[json.Marshaler]()
+)
+
+// binaryTypes 缓存各类型实现encoding.BinaryMarshaler的方式
+var binaryTypes sync.Map
+
+// binaryImplOf 判断类型实现encoding.BinaryMarshaler的方式，结果按类型缓存
+// 值或指针同时实现了json.Marshaler或encoding.TextMarshaler的类型返回implNone，不按二进制输出；
+// 本包不调用MarshalJSON和MarshalText，这类类型仍按原有规则处理：time.Time按时间格式输出，其他类型反射其字段
+func binaryImplOf(t reflect.Type) methodImpl {
+	if impl, ok := binaryTypes.Load(t); ok {
+		return impl.(methodImpl)
+	}
+	impl := implNone
+	pt := reflect.PointerTo(t)
+	switch {
+	case t.Implements(jsonMarshalerType) || pt.Implements(jsonMarshalerType) ||
+		t.Implements(textMarshalerType) || pt.Implements(textMarshalerType):
+	case t.Implements(binaryMarshalerType):
+		impl = implValue
+	case pt.Implements(binaryMarshalerType):
+		impl = implPointer
+	}
+	binaryTypes.Store(t, impl)
+	return impl
+}
+
+// binaryValue 将实现了encoding.BinaryMarshaler的值转换为MarshalBinary输出的base64字符串（标准编码，带填充），与[]byte在encoding/json中的输出形式相同
+// 非nil的接口值按其动态值判断；指针接收者的实现只在值可寻址时调用，nil指针不调用MarshalBinary，按普通nil指针处理。
+// 与error相同，以基本类型为底层类型的类型按其值输出，不调用MarshalBinary；
+// v不是BinaryMarshaler时返回false，MarshalBinary返回的错误附带当前路径
+func (ctx *serializeContext) binaryValue(v reflect.Value) (reflect.Value, bool, error) {
+	if v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+	if !v.IsValid() || (v.Kind() == reflect.Pointer && v.IsNil()) {
+		return reflect.Value{}, false, nil
+	}
+
+	var m encoding.BinaryMarshaler
+	switch binaryImplOf(v.Type()) {
+	case implValue:
+		if !v.CanInterface() {
+			return reflect.Value{}, false, nil
+		}
+		m = v.Interface().(encoding.BinaryMarshaler)
+	case implPointer:
+		if !v.CanAddr() || !v.Addr().CanInterface() {
+			return reflect.Value{}, false, nil
+		}
+		m = v.Addr().Interface().(encoding.BinaryMarshaler)
+	default:
+		return reflect.Value{}, false, nil
+	}
+
+	b, err := m.MarshalBinary()
+	if err != nil {
+		return reflect.Value{}, true, ReflectionError(ctx.path(), err)
+	}
+	return reflect.ValueOf(base64.StdEncoding.EncodeToString(b)), true, nil
+}
+
+// binarySchema 返回实现了encoding.BinaryMarshaler的类型的schema
+func binarySchema(t reflect.Type) (map[string]any, bool) {
+	if binaryImplOf(t) == implNone {
+		return nil, false
+	}
+	if _, scalar := mapperFor(t); scalar {
+		return nil, false
+	}
+	return map[string]any{"type": "string", "contentEncoding": "base64"}, true
+}
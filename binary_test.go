@@ -0,0 +1,77 @@
+package jsongroup
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// rawBinary 只实现了encoding.BinaryMarshaler
+type rawBinary struct{ b []byte }
+
+func (r rawBinary) MarshalBinary() ([]byte, error) { return r.b, nil }
+
+// ptrBinary 指针接收者实现encoding.BinaryMarshaler
+type ptrBinary struct{ b []byte }
+
+func (r *ptrBinary) MarshalBinary() ([]byte, error) { return r.b, nil }
+
+// jsonAndBinary 同时实现了json.Marshaler和encoding.BinaryMarshaler
+type jsonAndBinary struct {
+	ID int `json:"id" groups:"public"`
+}
+
+func (jsonAndBinary) MarshalJSON() ([]byte, error)   { return []byte(`"custom"`), nil }
+func (jsonAndBinary) MarshalBinary() ([]byte, error) { return []byte{1}, nil }
+
+// textAndBinary 指针接收者实现encoding.TextMarshaler，值接收者实现encoding.BinaryMarshaler
+type textAndBinary struct {
+	ID int `json:"id" groups:"public"`
+}
+
+func (*textAndBinary) MarshalText() ([]byte, error)  { return []byte("custom"), nil }
+func (textAndBinary) MarshalBinary() ([]byte, error) { return []byte{1}, nil }
+
+type binaryHolder struct {
+	Raw  rawBinary     `json:"raw" groups:"public"`
+	Ptr  ptrBinary     `json:"ptr" groups:"public"`
+	JSON jsonAndBinary `json:"json" groups:"public"`
+	Text textAndBinary `json:"text" groups:"public"`
+	At   time.Time     `json:"at" groups:"public"`
+}
+
+func TestBinaryMarshalerPrecedence(t *testing.T) {
+	at := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	h := &binaryHolder{
+		Raw:  rawBinary{[]byte("hi")},
+		Ptr:  ptrBinary{[]byte{0xff}},
+		JSON: jsonAndBinary{ID: 1},
+		Text: textAndBinary{ID: 2},
+		At:   at,
+	}
+	atJSON, _ := json.Marshal(at)
+
+	// 只实现BinaryMarshaler的类型输出base64；同时实现json.Marshaler或encoding.TextMarshaler的类型
+	// 不按二进制输出，也不调用MarshalJSON和MarshalText，而是反射其字段；time.Time按时间格式输出
+	want := `{"raw":"aGk=","ptr":"/w==","json":{"id":1},"text":{"id":2},"at":` + string(atJSON) + `}`
+	assertJSONEqual(t, marshalString(t, h, New(), "public"), want)
+
+	m, err := MarshalToMap(h, "public")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m["raw"] != "aGk=" || m["ptr"] != "/w==" {
+		t.Errorf("MarshalToMap = %#v", m)
+	}
+	if nested, ok := m["json"].(map[string]any); !ok || nested["id"] != int64(1) {
+		t.Errorf("json = %#v", m["json"])
+	}
+	data := marshalString(t, h, New(), "public")
+	if n, err := EstimateSize(h, nil, "public"); err != nil || n != len(data) {
+		t.Errorf("EstimateSize = %d, %v; actual %d bytes", n, err, len(data))
+	}
+
+	// 指针接收者的实现只在值可寻址时调用，否则照常反射
+	vals := map[string]ptrBinary{"p": {[]byte{0xff}}}
+	assertJSONEqual(t, marshalString(t, vals, New()), `{"p":{}}`)
+}
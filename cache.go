@@ -1,23 +1,62 @@
 package jsongroup
 
 import (
+	"cmp"
 	"container/list"
+	"errors"
+	"math/bits"
 	"reflect"
+	"runtime"
+	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+	"unsafe"
 )
 
 // 全局字段信息缓存实例
 var globalCache = newFieldCache()
 
+// 全局分组过滤结果缓存实例
+var globalFilterCache = newFilterCache()
+
 // CacheStats 提供缓存使用统计信息
 type CacheStats struct {
-	CurrentSize int     // 当前缓存条目数
-	MaxSize     int     // 最大缓存容量
-	Hits        int64   // 缓存命中次数
-	Misses      int64   // 缓存未命中次数
-	HitRatio    float64 // 命中率（0-1之间）
+	CurrentSize  int     // 当前缓存条目数
+	MaxSize      int     // 最大缓存容量
+	Shards       int     // 缓存分片数
+	CurrentBytes int64   // 当前缓存条目估算占用的字节数
+	MaxBytes     int64   // 最大缓存字节数，0表示不限制
+	Hits         int64   // 缓存命中次数
+	Misses       int64   // 缓存未命中次数
+	Evictions    int64   // 缓存淘汰次数
+	HitRatio     float64 // 命中率（0-1之间）
+	// TopMisses 未命中次数最多的类型，按次数降序排列，最多topMissesLimit个
+	// 仅在通过EnableDetailedCacheStats开启详细统计后才有数据
+	TopMisses []TypeMisses
+
+	// 片段缓存（CacheableFragment）的统计信息
+	FragmentEntries   int   // 当前缓存的片段数
+	FragmentBytes     int64 // 当前缓存的片段字节数
+	MaxFragmentBytes  int64 // 片段缓存的字节容量，0表示已关闭
+	FragmentHits      int64 // 片段缓存命中次数
+	FragmentMisses    int64 // 片段缓存未命中次数
+	FragmentEvictions int64 // 片段缓存淘汰次数
+}
+
+// TypeMisses 单个类型的缓存未命中次数
+type TypeMisses struct {
+	Type   string // 类型名称
+	Misses int64  // 缓存未命中次数
+}
+
+// TypeCacheStats 单个类型的缓存统计信息
+type TypeCacheStats struct {
+	Hits     int64   // 缓存命中次数
+	Misses   int64   // 缓存未命中次数
+	HitRatio float64 // 命中率（0-1之间）
+	Cached   bool    // 当前是否在缓存中
 }
 
 // fieldInfo 表示结构体字段的元数据
@@ -28,76 +67,259 @@ type fieldInfo struct {
 	Name string
 	// JSON序列化名称
 	JSONName string
+	// 预先编码的键名，形如 "jsonName": ，直接编码时原样写入
+	EncodedKey []byte
 	// 字段所属分组列表
 	Groups []string
+	// 字段所属分组的位图，用于快速判断是否包含在指定分组中
+	GroupMask groupMask
+	// 是否声明了分组标签，标签值为空也视为已声明
+	Tagged bool
+	// deprecated标签的说明和上报状态，未声明时为nil
+	Deprecation *deprecation
+	// required标签声明的分组，请求其中任一分组时ValidateRequired要求文档包含该字段；未声明时为nil
+	Required []string
 	// 是否忽略空值
 	OmitEmpty bool
 	// 是否忽略零值（Go 1.24新特性）
 	OmitZero bool
-	// 是否为匿名字段
+	// 是否为匿名字段，指定了json名称的内嵌结构体不算在内
 	Anonymous bool
+	// 提升该字段的内嵌结构体中声明了omitempty或omitzero的层级，外层在前；没有时为nil
+	Embeds []*embedding
+	// 是否为基本类型字段（字符串、布尔、数值），转换时不会出错
+	Scalar bool
+	// 预编译的字段值转换函数
+	Mapper valueMapper
+	// 预编译的JSON编码函数，仅基本类型字段非nil
+	Encoder scalarEncoder
+}
+
+// fieldsKey 字段信息缓存的键，同一类型按不同标签键解析出的分组不同，分别缓存
+type fieldsKey struct {
+	typ    reflect.Type
+	tagKey string
 }
 
 // cacheEntry 缓存条目，包含值和创建时间
 type cacheEntry struct {
+	// 条目对应的键，淘汰时用于从映射中删除
+	key fieldsKey
 	// 创建时间，用于统计和清理策略
 	createdAt time.Time
-	// 缓存的字段信息列表
-	value []fieldInfo
+	// 缓存的字段信息
+	value *typeFields
+	// 条目估算占用的字节数
+	size int64
+}
+
+// typeFields 单个结构体类型解析后的字段信息
+type typeFields struct {
+	// 所有可序列化字段，按声明顺序排列
+	fields []fieldInfo
+	// 未指定分组时使用的完整字段集合，无需查找分组过滤结果缓存
+	all *fieldSet
+	// 类型没有分组标签时，指定分组后使用的空字段集合，所有字段均被排除
+	none *fieldSet
+	// 是否所有字段都没有分组标签，此时指定任何分组都不会包含字段
+	noGroupTags bool
+	// 未声明分组标签的字段名，供RequireGroupTags检查
+	untagged []string
+	// 字段标签中相互矛盾的声明，供StrictTags和ValidateType检查
+	contradictions []string
+	// 解析时RegisterFieldGroups的注册代数，与当前代数不同的缓存条目已过期
+	generation uint64
+}
+
+// emptyTypeFields 非结构体类型对应的空字段信息
+var emptyTypeFields = newTypeFields(nil)
+
+// newTypeFields 根据解析结果创建字段信息，并记录是否存在分组标签
+func newTypeFields(fields []fieldInfo) *typeFields {
+	info := &typeFields{
+		fields:      fields,
+		all:         newFieldSet(fields),
+		none:        &fieldSet{excluded: fields},
+		noGroupTags: true,
+	}
+	for _, f := range fields {
+		if len(f.Groups) > 0 {
+			info.noGroupTags = false
+		}
+		if !f.Tagged {
+			info.untagged = append(info.untagged, f.Name)
+		}
+	}
+	return info
 }
 
 // fieldCache 结构体字段信息缓存
+// 按类型指针哈希分散到多个独立加锁的分片，降低高并发下的锁竞争
 type fieldCache struct {
-	// 保护缓存的互斥锁
-	mu sync.RWMutex
-	// 缓存映射：类型 -> 字段信息列表
-	cache map[reflect.Type]*list.Element
+	// 保护配置变更（分片数、容量）的互斥锁，读写缓存时不持有
+	mu sync.Mutex
+	// 当前分片集合，重新配置时整体替换
+	shards atomic.Pointer[shardSet]
+	// 最大缓存条目数（所有分片合计），在mu保护下写入，读取统计时无需加锁
+	maxSize atomic.Int64
+	// 最大缓存字节数（所有分片合计），0表示不限制
+	maxBytes atomic.Int64
+	// 是否按类型记录统计信息
+	detailed atomic.Bool
+}
+
+// shardSet 一组缓存分片，分片数为2的幂
+type shardSet struct {
+	// 分片列表
+	shards []*cacheShard
+	// 哈希右移位数，用于从哈希值高位选取分片
+	shift uint
+}
+
+// cacheShard 单个缓存分片，拥有独立的锁、LRU列表和统计信息
+type cacheShard struct {
+	// 保护分片的互斥锁
+	// 命中时也需要调整LRU顺序，因此使用互斥锁而非读写锁
+	mu sync.Mutex
+	// 缓存映射：(类型, 标签键) -> 字段信息列表
+	cache map[fieldsKey]*list.Element
 	// 访问顺序列表，用于LRU淘汰
 	evictList *list.List
-	// 最大缓存条目数
+	// 分片最大缓存条目数
 	maxSize int
+	// 分片最大缓存字节数，0表示不限制
+	maxBytes int64
+	// 当前缓存条目数和估算占用的字节数，在分片锁保护下修改，读取统计时无需加锁
+	entries atomic.Int64
+	bytes   atomic.Int64
 	// 缓存统计信息
 	stats cacheStat
+	// 未命中次数最多的类型，仅在启用详细统计时维护；每次变化时整体替换，读取时无需加锁
+	topMisses atomic.Pointer[[]typeMissStat]
+	// 按类型的统计信息，仅在启用详细统计时记录
+	// 类型被淘汰后统计信息仍然保留，便于发现频繁淘汰的类型
+	typeStats map[reflect.Type]*typeCacheStat
+}
+
+// typeCacheStat 单个类型的命中统计
+type typeCacheStat struct {
+	hits   int64
+	misses int64
+}
+
+// typeMissStat 分片内单个类型的未命中次数
+type typeMissStat struct {
+	typ    reflect.Type
+	misses int64
 }
 
-// cacheStat 缓存统计信息
+// topMissesLimit 统计信息中保留的未命中次数最多的类型数
+const topMissesLimit = 10
+
+// cacheStat 缓存统计信息，在分片锁保护下修改，读取时无需加锁
 type cacheStat struct {
-	// 总访问次数
-	hits int64
 	// 缓存命中次数
-	misses int64
+	hits atomic.Int64
+	// 缓存未命中次数
+	misses atomic.Int64
 	// 缓存淘汰次数
-	evictions int64
+	evictions atomic.Int64
+}
+
+// reset 将统计信息清零
+func (s *cacheStat) reset() {
+	s.hits.Store(0)
+	s.misses.Store(0)
+	s.evictions.Store(0)
 }
 
-// newFieldCache 创建字段缓存
+// newFieldCache 创建字段缓存，分片数根据GOMAXPROCS推导
 func newFieldCache() *fieldCache {
-	return &fieldCache{
-		cache:     make(map[reflect.Type]*list.Element),
-		evictList: list.New(),
-		maxSize:   DefaultMaxCacheSize,
-		stats:     cacheStat{},
+	c := &fieldCache{}
+	c.maxSize.Store(DefaultMaxCacheSize)
+	c.shards.Store(newShardSet(defaultShardCount(), DefaultMaxCacheSize, 0))
+	return c
+}
+
+// defaultShardCount 返回默认分片数GOMAXPROCS，由newShardSet向上取整为2的幂
+func defaultShardCount() int {
+	return runtime.GOMAXPROCS(0)
+}
+
+// newShardSet 创建指定数量的分片，n会向上取整为2的幂
+// 总容量平均分配到各分片，每个分片至少容纳一个条目
+func newShardSet(n, maxSize int, maxBytes int64) *shardSet {
+	if n < 1 {
+		n = 1
 	}
+	shardBits := uint(bits.Len(uint(n - 1)))
+	n = 1 << shardBits
+
+	set := &shardSet{
+		shards: make([]*cacheShard, n),
+		shift:  64 - shardBits,
+	}
+	perShard := shardCapacity(maxSize, n)
+	perShardBytes := shardByteCapacity(maxBytes, n)
+	for i := range set.shards {
+		set.shards[i] = &cacheShard{
+			cache:     make(map[fieldsKey]*list.Element),
+			evictList: list.New(),
+			maxSize:   perShard,
+			maxBytes:  perShardBytes,
+		}
+	}
+	return set
 }
 
-// GetCacheStats 返回当前缓存使用统计信息
-func GetCacheStats() CacheStats {
-	globalCache.mu.RLock()
-	defer globalCache.mu.RUnlock()
+// shardCapacity 计算单个分片的容量，0表示不限制
+func shardCapacity(maxSize, n int) int {
+	if maxSize <= 0 {
+		return 0
+	}
+	return (maxSize + n - 1) / n
+}
 
-	total := float64(globalCache.stats.hits + globalCache.stats.misses)
-	hitRatio := 0.0
-	if total > 0 {
-		hitRatio = float64(globalCache.stats.hits) / total
+// shardByteCapacity 计算单个分片的字节容量，0表示不限制
+func shardByteCapacity(maxBytes int64, n int) int64 {
+	if maxBytes <= 0 {
+		return 0
 	}
+	return (maxBytes + int64(n) - 1) / int64(n)
+}
 
-	return CacheStats{
-		CurrentSize: globalCache.evictList.Len(),
-		MaxSize:     globalCache.maxSize,
-		Hits:        globalCache.stats.hits,
-		Misses:      globalCache.stats.misses,
-		HitRatio:    hitRatio,
+// shardFor 根据类型指针哈希选取分片
+func (s *shardSet) shardFor(t reflect.Type) *cacheShard {
+	if len(s.shards) == 1 {
+		return s.shards[0]
 	}
+	// reflect.Type的动态值为指向类型描述符的指针，同一类型地址唯一
+	h := uint64(reflect.ValueOf(t).Pointer()) * 0x9E3779B97F4A7C15
+	return s.shards[h>>s.shift]
+}
+
+// GetCacheStats 返回当前缓存使用统计信息
+func GetCacheStats() CacheStats {
+	return globalCache.GetStats()
+}
+
+// CacheStatsSnapshot 返回当前缓存使用统计信息，只读取原子计数器，不持有任何锁
+// 开销固定且很小，适合监控系统每次采集时调用；不同计数器之间可能有微小的偏差
+func CacheStatsSnapshot() CacheStats {
+	return globalCache.Snapshot()
+}
+
+// EnableDetailedCacheStats 设置是否按类型记录全局缓存的命中统计
+// 默认关闭以避免额外开销；关闭时会丢弃已记录的按类型统计
+// 注意：开启后每个出现过的类型都会保留一条统计记录，包括已被淘汰的类型
+func EnableDetailedCacheStats(enable bool) {
+	globalCache.SetDetailedStats(enable)
+}
+
+// CacheStatsByType 返回按类型汇总的缓存统计信息，键为类型名称
+// 仅在通过EnableDetailedCacheStats开启详细统计后才有数据
+func CacheStatsByType() map[string]TypeCacheStats {
+	return globalCache.GetStatsByType()
 }
 
 // SetMaxCacheSize 设置全局缓存的最大容量
@@ -105,190 +327,715 @@ func SetMaxCacheSize(size int) {
 	globalCache.SetMaxSize(size)
 }
 
+// SetMaxCacheBytes 设置全局缓存估算占用字节数的上限，与条目数上限同时生效
+// 超出时按LRU顺序淘汰，直到低于上限；设置为0表示不限制（默认）
+// 字节数为估算值，包含字段元数据结构体及其字符串、切片的长度
+func SetMaxCacheBytes(n int64) {
+	globalCache.SetMaxBytes(n)
+}
+
+// SetCacheShards 设置全局缓存的分片数
+// n会向上取整为2的幂，n<=0时根据GOMAXPROCS推导
+// 重新分片会清空现有缓存条目和统计信息
+func SetCacheShards(n int) {
+	globalCache.SetShards(n)
+}
+
 // SetMaxSize 设置缓存的最大容量
+// 容量平均分配到各分片，总条目数最多可能超出size（分片数-1）个
 func (c *fieldCache) SetMaxSize(size int) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	c.maxSize = size
-	// 如果新的大小小于当前缓存条目数，需要进行淘汰
-	for c.evictList.Len() > c.maxSize && c.maxSize > 0 {
-		if err := c.evict(); err != nil {
-			// 淘汰失败时停止，避免死循环
-			break
-		}
+	c.maxSize.Store(int64(size))
+	set := c.shards.Load()
+	perShard := shardCapacity(size, len(set.shards))
+	for _, shard := range set.shards {
+		shard.setMaxSize(perShard)
 	}
 }
 
-// GetStats 获取缓存统计信息
+// SetMaxBytes 设置缓存的最大字节数
+// 字节容量平均分配到各分片，单个条目超过分片容量时不会被缓存
+func (c *fieldCache) SetMaxBytes(n int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.maxBytes.Store(n)
+	set := c.shards.Load()
+	perShard := shardByteCapacity(n, len(set.shards))
+	for _, shard := range set.shards {
+		shard.setMaxBytes(perShard)
+	}
+}
+
+// SetShards 重新设置缓存分片数，现有条目和统计信息会被清空
+func (c *fieldCache) SetShards(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if n <= 0 {
+		n = defaultShardCount()
+	}
+	c.shards.Store(newShardSet(n, int(c.maxSize.Load()), c.maxBytes.Load()))
+}
+
+// GetStats 获取缓存统计信息，逐个锁定分片汇总，同一分片的各项数据相互一致
 func (c *fieldCache) GetStats() CacheStats {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
+	return c.collectStats(true)
+}
 
-	total := float64(c.stats.hits + c.stats.misses)
-	hitRatio := 0.0
+// Snapshot 不加锁获取缓存统计信息，只读取原子计数器，不同计数器之间可能有微小的偏差
+func (c *fieldCache) Snapshot() CacheStats {
+	return c.collectStats(false)
+}
+
+// collectStats 汇总所有分片的统计信息，lock为true时读取每个分片前加锁
+func (c *fieldCache) collectStats(lock bool) CacheStats {
+	set := c.shards.Load()
+	stats := CacheStats{
+		MaxSize:  int(c.maxSize.Load()),
+		MaxBytes: c.maxBytes.Load(),
+		Shards:   len(set.shards),
+	}
+	var top []typeMissStat
+	for _, shard := range set.shards {
+		if lock {
+			shard.mu.Lock()
+		}
+		stats.CurrentSize += int(shard.entries.Load())
+		stats.CurrentBytes += shard.bytes.Load()
+		stats.Hits += shard.stats.hits.Load()
+		stats.Misses += shard.stats.misses.Load()
+		stats.Evictions += shard.stats.evictions.Load()
+		if p := shard.topMisses.Load(); p != nil {
+			top = append(top, *p...)
+		}
+		if lock {
+			shard.mu.Unlock()
+		}
+	}
+
+	total := float64(stats.Hits + stats.Misses)
 	if total > 0 {
-		hitRatio = float64(c.stats.hits) / total
+		stats.HitRatio = float64(stats.Hits) / total
 	}
 
-	return CacheStats{
-		CurrentSize: c.evictList.Len(),
-		MaxSize:     c.maxSize,
-		Hits:        c.stats.hits,
-		Misses:      c.stats.misses,
-		HitRatio:    hitRatio,
+	// 同一类型总是落在同一分片，合并时无需去重
+	slices.SortStableFunc(top, compareMisses)
+	for _, m := range top[:min(len(top), topMissesLimit)] {
+		stats.TopMisses = append(stats.TopMisses, TypeMisses{Type: typeName(m.typ), Misses: m.misses})
+	}
+	globalFragmentCache.addStats(&stats)
+	return stats
+}
+
+// compareMisses 按未命中次数降序排列
+func compareMisses(a, b typeMissStat) int {
+	return cmp.Compare(b.misses, a.misses)
+}
+
+// SetDetailedStats 设置是否按类型记录统计信息
+func (c *fieldCache) SetDetailedStats(enable bool) {
+	c.detailed.Store(enable)
+	if enable {
+		return
+	}
+	for _, shard := range c.shards.Load().shards {
+		shard.mu.Lock()
+		shard.typeStats = nil
+		shard.topMisses.Store(nil)
+		shard.mu.Unlock()
 	}
 }
 
+// GetStatsByType 获取按类型汇总的统计信息
+func (c *fieldCache) GetStatsByType() map[string]TypeCacheStats {
+	result := make(map[string]TypeCacheStats)
+	for _, shard := range c.shards.Load().shards {
+		shard.mu.Lock()
+		for typ, stat := range shard.typeStats {
+			name := typeName(typ)
+			ts := result[name]
+			ts.Hits += stat.hits
+			ts.Misses += stat.misses
+			result[name] = ts
+		}
+		// 统计按类型汇总，任一标签键的条目在缓存中即视为已缓存
+		for key := range shard.cache {
+			if ts, ok := result[typeName(key.typ)]; ok {
+				ts.Cached = true
+				result[typeName(key.typ)] = ts
+			}
+		}
+		shard.mu.Unlock()
+	}
+
+	for name, ts := range result {
+		if total := float64(ts.Hits + ts.Misses); total > 0 {
+			ts.HitRatio = float64(ts.Hits) / total
+		}
+		result[name] = ts
+	}
+	return result
+}
+
+// typeName 返回用于统计展示的类型名称
+// 具名类型使用完整包路径，避免不同包中的同名类型混淆
+func typeName(t reflect.Type) string {
+	if t.Name() != "" && t.PkgPath() != "" {
+		return t.PkgPath() + "." + t.Name()
+	}
+	return t.String()
+}
+
 // Clear 清空缓存
 func (c *fieldCache) Clear() {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	c.cache = make(map[reflect.Type]*list.Element)
-	c.evictList.Init()
-	c.stats = cacheStat{}
+	for _, shard := range c.shards.Load().shards {
+		shard.clear()
+	}
 }
 
-// getFieldsInfo 获取类型的字段信息
+// getFieldsInfo 获取类型按标签键tagKey解析的字段信息，tagKey为空时使用DefaultTagKey
 // 优先从缓存获取，不存在则解析并加入缓存
-func (c *fieldCache) getFieldsInfo(t reflect.Type, tagKey string) ([]fieldInfo, error) {
+func (c *fieldCache) getFieldsInfo(t reflect.Type, tagKey string) (*typeFields, error) {
 	// 快速检查非结构体类型
 	if t.Kind() != reflect.Struct {
-		return nil, nil
+		return emptyTypeFields, nil
+	}
+	if tagKey == "" {
+		tagKey = DefaultTagKey
 	}
+	key := fieldsKey{typ: t, tagKey: tagKey}
 
-	// 1. 首先尝试读取缓存 - 只读锁
-	c.mu.RLock()
-	if element, ok := c.cache[t]; ok {
-		entry, valid := element.Value.(*cacheEntry)
-		if valid && entry != nil {
-			c.stats.hits++
-			result := entry.value // 拷贝结果
-			c.mu.RUnlock()
-
-			// 异步更新LRU位置 - 减少持锁时间
-			go func() {
-				c.mu.Lock()
-				c.evictList.MoveToFront(element)
-				c.mu.Unlock()
-			}()
-
-			return result, nil
-		}
+	shard := c.shards.Load().shardFor(t)
+
+	detailed := c.detailed.Load()
+
+	// 先读取注册代数再解析，解析期间发生的注册会使结果在下次读取时失效
+	generation := fieldGroupsGeneration.Load()
+
+	// 1. 首先尝试读取缓存，注册代数不同的条目视为未命中
+	if info, ok := shard.get(key, detailed); ok && info.generation == generation {
+		return info, nil
+	}
+
+	// 2. 解析字段信息 - 无锁操作，解析失败时不缓存，下次调用重新解析
+	info, err := parseTypeFields(t, tagKey, generation)
+	if err != nil {
+		return nil, err
 	}
-	c.mu.RUnlock() // 缓存未命中，释放读锁
 
-	// 2. 解析字段信息 - 无锁操作
+	// 3. 缓存结果
+	return shard.add(key, info, detailed), nil
+}
+
+// parseTypeFields 解析结构体类型t的字段信息，generation为解析前读取的注册代数
+func parseTypeFields(t reflect.Type, tagKey string, generation uint64) (*typeFields, error) {
 	fields, err := parseFields(t, tagKey)
 	if err != nil {
 		return nil, err
 	}
+	info := newTypeFields(fields)
+	info.contradictions = tagContradictions(t, tagKey, "")
+	info.generation = generation
+	return info, nil
+}
+
+// skipsCache 判断类型t是否绕过字段缓存和分组过滤结果缓存：启用SkipCachingAnonymousTypes时的匿名结构体类型
+func (o *Options) skipsCache(t reflect.Type) bool {
+	return o.SkipCachingAnonymousTypes && t.Kind() == reflect.Struct && t.Name() == ""
+}
+
+// fieldsInfo 按选项的标签键获取类型t的字段信息，绕过缓存的类型每次重新解析
+func (o *Options) fieldsInfo(t reflect.Type) (*typeFields, error) {
+	if o.skipsCache(t) {
+		return parseTypeFields(t, o.TagKey, fieldGroupsGeneration.Load())
+	}
+	return globalCache.getFieldsInfo(t, o.TagKey)
+}
 
-	// 3. 缓存结果 - 写锁，但优化操作顺序
-	c.mu.Lock()
+// setMaxSize 设置分片容量，必要时淘汰多余条目
+func (s *cacheShard) setMaxSize(size int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.maxSize = size
+	// 如果新的大小小于当前缓存条目数，需要进行淘汰
+	for s.evictList.Len() > s.maxSize && s.maxSize > 0 {
+		s.evict()
+	}
+}
+
+// setMaxBytes 设置分片字节容量，必要时淘汰多余条目
+func (s *cacheShard) setMaxBytes(n int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.maxBytes = n
+	for s.maxBytes > 0 && s.bytes.Load() > s.maxBytes && s.evictList.Len() > 0 {
+		s.evict()
+	}
+}
+
+// clear 清空分片
+func (s *cacheShard) clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.cache = make(map[fieldsKey]*list.Element)
+	s.evictList.Init()
+	s.entries.Store(0)
+	s.bytes.Store(0)
+	s.stats.reset()
+	s.typeStats = nil
+	s.topMisses.Store(nil)
+}
 
-	// 二次检查，可能在竞争条件下已被其他goroutine添加
-	if element, ok := c.cache[t]; ok {
-		entry, valid := element.Value.(*cacheEntry)
-		if valid && entry != nil {
-			c.evictList.MoveToFront(element)
-			result := entry.value // 拷贝结果防止锁外修改
-			c.mu.Unlock()
-			return result, nil
+// typeStat 返回类型的统计记录，不存在时创建，调用方需持有分片锁
+func (s *cacheShard) typeStat(t reflect.Type) *typeCacheStat {
+	stat, ok := s.typeStats[t]
+	if !ok {
+		if s.typeStats == nil {
+			s.typeStats = make(map[reflect.Type]*typeCacheStat)
 		}
+		stat = &typeCacheStat{}
+		s.typeStats[t] = stat
+	}
+	return stat
+}
+
+// get 查找缓存条目，命中时更新LRU位置
+func (s *cacheShard) get(key fieldsKey, detailed bool) (*typeFields, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	element, ok := s.cache[key]
+	if !ok {
+		return nil, false
+	}
+	entry, valid := element.Value.(*cacheEntry)
+	if !valid || entry == nil {
+		return nil, false
+	}
+	s.stats.hits.Add(1)
+	if detailed {
+		s.typeStat(key.typ).hits++
+	}
+	s.evictList.MoveToFront(element)
+	return entry.value, true
+}
+
+// add 将解析结果加入分片，返回最终缓存的字段信息
+func (s *cacheShard) add(key fieldsKey, info *typeFields, detailed bool) *typeFields {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// 二次检查，可能在竞争条件下已被其他goroutine添加；注册代数不同的旧条目先移除
+	if element, ok := s.cache[key]; ok {
+		if entry, valid := element.Value.(*cacheEntry); valid && entry != nil && entry.value.generation == info.generation {
+			s.evictList.MoveToFront(element)
+			return entry.value
+		}
+		s.remove(element)
+	}
+
+	s.stats.misses.Add(1)
+	if detailed {
+		stat := s.typeStat(key.typ)
+		stat.misses++
+		s.updateTopMisses(key.typ, stat.misses)
+	}
+
+	// 单个条目超过字节容量时不缓存，避免清空整个分片
+	size := entrySize(info.fields)
+	if s.maxBytes > 0 && size > s.maxBytes {
+		return info
 	}
 
 	// 缓存管理逻辑
-	if c.maxSize > 0 {
-		// 提前批量淘汰，减少锁频率
-		for c.evictList.Len() >= c.maxSize && c.evictList.Len() > 0 {
-			_ = c.evict()
+	if s.maxSize > 0 {
+		for s.evictList.Len() >= s.maxSize && s.evictList.Len() > 0 {
+			s.evict()
+		}
+	}
+	if s.maxBytes > 0 {
+		for s.bytes.Load()+size > s.maxBytes && s.evictList.Len() > 0 {
+			s.evict()
 		}
 	}
 
 	// 添加新缓存
 	entry := &cacheEntry{
+		key:       key,
 		createdAt: time.Now(),
-		value:     fields,
+		value:     info,
+		size:      size,
 	}
-	element := c.evictList.PushFront(entry)
-	c.cache[t] = element
-	c.stats.misses++
-
-	// 拷贝结果防止锁外修改
-	result := fields
-	c.mu.Unlock()
+	s.cache[key] = s.evictList.PushFront(entry)
+	s.entries.Add(1)
+	s.bytes.Add(size)
 
-	return result, nil
+	return info
 }
 
-// evict 根据LRU淘汰策略删除一个缓存条目
-func (c *fieldCache) evict() error {
+// evict 根据LRU淘汰策略删除一个缓存条目，调用方需持有分片锁
+// 条目中记录了对应的类型，因此无需遍历映射即可删除
+func (s *cacheShard) evict() {
 	// 从列表尾部获取最近最少使用的条目
-	if c.evictList.Len() == 0 {
-		return nil // 缓存为空，无需淘汰
+	element := s.evictList.Back()
+	if element == nil {
+		return // 缓存为空，无需淘汰
 	}
 
-	// 获取最久未使用的条目
-	element := c.evictList.Back()
-	if element == nil {
-		return nil // 无可淘汰条目
+	s.remove(element)
+	s.stats.evictions.Add(1)
+}
+
+// remove 删除指定的缓存条目，调用方需持有分片锁
+func (s *cacheShard) remove(element *list.Element) {
+	s.evictList.Remove(element)
+	if entry, ok := element.Value.(*cacheEntry); ok {
+		delete(s.cache, entry.key)
+		s.bytes.Add(-entry.size)
+	}
+	s.entries.Add(-1)
+}
+
+// updateTopMisses 更新分片内未命中次数最多的类型，调用方需持有分片锁
+// 未命中次数只增不减，每次只需考虑刚增加的类型，列表因此总是准确的
+func (s *cacheShard) updateTopMisses(t reflect.Type, misses int64) {
+	var cur []typeMissStat
+	if p := s.topMisses.Load(); p != nil {
+		cur = *p
+	}
+	i := slices.IndexFunc(cur, func(m typeMissStat) bool { return m.typ == t })
+	if i < 0 && len(cur) >= topMissesLimit && misses <= cur[len(cur)-1].misses {
+		return
 	}
 
-	// 获取键值并从列表中移除
-	c.evictList.Remove(element)
+	// 复制后整体替换，读取方持有的旧列表保持不变
+	next := slices.Clone(cur)
+	if i >= 0 {
+		next[i].misses = misses
+	} else {
+		next = append(next, typeMissStat{typ: t, misses: misses})
+	}
+	slices.SortStableFunc(next, compareMisses)
+	next = next[:min(len(next), topMissesLimit)]
+	s.topMisses.Store(&next)
+}
+
+// entrySize 估算缓存条目占用的字节数
+// 包括条目本身、字段元数据结构体，以及字段中字符串和切片指向的数据
+func entrySize(fields []fieldInfo) int64 {
+	size := int64(unsafe.Sizeof(cacheEntry{})+unsafe.Sizeof(typeFields{})+2*unsafe.Sizeof(fieldSet{})) +
+		int64(len(fields))*int64(unsafe.Sizeof(fieldInfo{}))
+	for _, f := range fields {
+		size += int64(len(f.Index)) * int64(unsafe.Sizeof(int(0)))
+		size += int64(len(f.Embeds)) * int64(unsafe.Sizeof(&embedding{}))
+		size += int64(len(f.Name) + len(f.JSONName) + cap(f.EncodedKey))
+		size += int64(len(f.Groups))*int64(unsafe.Sizeof("")) + int64(cap(f.GroupMask))*8
+		for _, g := range f.Groups {
+			size += int64(len(g))
+		}
+		size += int64(len(f.Required)) * int64(unsafe.Sizeof(""))
+		for _, g := range f.Required {
+			size += int64(len(g))
+		}
+	}
+	return size
+}
 
-	// 安全地转换条目
-	entry, ok := element.Value.(*cacheEntry)
-	if !ok || entry == nil {
-		return CacheOverflowError("字段缓存", c.maxSize)
+// WarmCache 预先解析并缓存给定值的类型及其嵌套结构体字段类型
+// 适合在服务启动时调用，避免首批请求承担解析开销；
+// 返回的错误汇总了所有解析失败的类型，也可用作启动时的标签校验
+// values可以是任意值或reflect.Type，opts为nil时使用默认选项
+func WarmCache(opts *Options, values ...any) error {
+	if opts == nil {
+		opts = defaults()
 	}
 
-	// 找到对应的类型并从映射中移除
-	found := false
-	for typ, elem := range c.cache {
-		if elem == element {
-			delete(c.cache, typ)
-			found = true
-			c.stats.evictions++
+	seen := make(map[reflect.Type]bool)
+	var errs []error
+	for _, v := range values {
+		if v == nil {
+			continue
+		}
+		t, ok := v.(reflect.Type)
+		if !ok {
+			t = reflect.TypeOf(v)
+		}
+		tagKey := opts.TagKey
+		if tagKey == "" {
+			tagKey = DefaultTagKey
+		}
+		errs = warmType(t, tagKey, seen, errs)
+	}
+	return errors.Join(errs...)
+}
+
+// warmType 递归解析类型并加入缓存，返回追加了解析错误的错误列表
+func warmType(t reflect.Type, tagKey string, seen map[reflect.Type]bool, errs []error) []error {
+	// 穿透指针、切片、数组和map值类型，找到实际的结构体类型
+	for {
+		switch t.Kind() {
+		case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+			t = t.Elem()
+			continue
+		}
+		break
+	}
+
+	// 时间类型在序列化时特殊处理，不需要解析字段
+	if t.Kind() != reflect.Struct || t == timeType || seen[t] {
+		return errs
+	}
+	seen[t] = true
+
+	info, err := globalCache.getFieldsInfo(t, tagKey)
+	if err != nil {
+		return append(errs, ReflectionError(typeName(t), err))
+	}
+	for _, field := range info.fields {
+		errs = warmType(t.FieldByIndex(field.Index).Type, tagKey, seen, errs)
+	}
+	return errs
+}
+
+// filterKey 分组过滤结果缓存的键
+type filterKey struct {
+	// 结构体类型
+	typ reflect.Type
+	// 标签键名，不同标签键解析出的分组不同
+	tagKey string
+	// 规范化（排序去重）后的分组列表，包含禁止的分组
+	groups string
+	// 分组模式
+	mode GroupMode
+	// 字段信息的注册代数，重新注册字段分组后旧的过滤结果不再命中
+	generation uint64
+	// 是否继承了父字段的分组匹配
+	inherited bool
+}
+
+// fieldSet 按分组过滤后的字段集合
+type fieldSet struct {
+	// 需要序列化的字段，按声明顺序排列
+	fields []fieldInfo
+	// 不属于指定分组而被排除的字段，供跟踪日志和指标回调使用
+	excluded []fieldInfo
+	// 是否存在JSON名称重复的字段
+	// map路径中后出现的字段会覆盖先出现的字段，直接编码时需要特殊处理
+	duplicateNames bool
+}
+
+// newFieldSet 创建字段集合并检查JSON名称是否重复
+func newFieldSet(fields []fieldInfo) *fieldSet {
+	set := &fieldSet{fields: fields}
+	seen := make(map[string]struct{}, len(fields))
+	for _, f := range fields {
+		if _, ok := seen[f.JSONName]; ok {
+			set.duplicateNames = true
 			break
 		}
+		seen[f.JSONName] = struct{}{}
 	}
+	return set
+}
 
-	if !found {
-		// 无法找到映射中对应的条目，这是不应该发生的
-		return CacheOverflowError("字段缓存", c.maxSize)
+// filterCache 缓存按(类型, 分组, 模式)预先过滤的字段子集
+// 读多写少，命中时只持有读锁；超出容量时按插入顺序淘汰最早的条目
+type filterCache struct {
+	// 保护缓存的读写锁
+	mu sync.RWMutex
+	// 缓存映射：过滤键 -> 过滤后的字段列表
+	cache map[filterKey]*fieldSet
+	// 插入顺序，用于淘汰
+	order *list.List
+	// 最大缓存条目数
+	maxSize int
+}
+
+// newFilterCache 创建分组过滤结果缓存
+func newFilterCache() *filterCache {
+	return &filterCache{
+		cache:   make(map[filterKey]*fieldSet),
+		order:   list.New(),
+		maxSize: DefaultMaxFilterCacheSize,
 	}
+}
+
+// SetMaxFilterCacheSize 设置全局分组过滤结果缓存的最大条目数
+// 设置为0表示不限制（不推荐）
+func SetMaxFilterCacheSize(size int) {
+	globalFilterCache.SetMaxSize(size)
+}
+
+// SetMaxSize 设置过滤结果缓存的最大容量
+func (c *filterCache) SetMaxSize(size int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.maxSize = size
+	for c.maxSize > 0 && c.order.Len() > c.maxSize {
+		c.evict()
+	}
+}
+
+// Clear 清空过滤结果缓存
+func (c *filterCache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
-	return nil
+	c.cache = make(map[filterKey]*fieldSet)
+	c.order.Init()
+}
+
+// get 查找过滤结果
+func (c *filterCache) get(key filterKey) (*fieldSet, bool) {
+	c.mu.RLock()
+	set, ok := c.cache[key]
+	c.mu.RUnlock()
+	return set, ok
+}
+
+// add 加入过滤结果，返回最终缓存的字段集合
+func (c *filterCache) add(key filterKey, set *fieldSet) *fieldSet {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if existing, ok := c.cache[key]; ok {
+		return existing
+	}
+	for c.maxSize > 0 && c.order.Len() >= c.maxSize {
+		c.evict()
+	}
+	c.cache[key] = set
+	c.order.PushBack(key)
+	return set
+}
+
+// evict 淘汰最早插入的条目，调用方需持有写锁
+func (c *filterCache) evict() {
+	front := c.order.Front()
+	if front == nil {
+		return
+	}
+	c.order.Remove(front)
+	if key, ok := front.Value.(filterKey); ok {
+		delete(c.cache, key)
+	}
+}
+
+// getFilteredFields 获取类型在指定分组和模式下需要序列化的字段，标签键和禁止输出的分组取自opts
+// groupKey为filterGroupKey得到的键；groups和禁止的分组都为空或类型没有任何分组标签时结果是确定的，不查找过滤结果缓存。
+// inherited表示结构体位于启用CascadeToUntagged后被包含的带分组字段的子树中，此时未声明分组的字段视为匹配；
+// 启用SkipCachingAnonymousTypes时匿名结构体类型的字段信息和过滤结果都不写入缓存
+func getFilteredFields(t reflect.Type, opts *Options, groups []string, groupKey string, mode GroupMode, inherited bool) (*fieldSet, error) {
+	tagKey, deny := opts.TagKey, opts.DenyGroups
+	info, err := opts.fieldsInfo(t)
+	if err != nil {
+		return nil, err
+	}
+
+	// 未指定分组时包含所有字段，与分组模式无关
+	if len(groups) == 0 && len(deny) == 0 {
+		return info.all, nil
+	}
+	// 类型没有任何分组标签时，指定分组后不包含任何字段（继承了分组匹配时包含所有字段），禁止的分组不影响结果
+	if info.noGroupTags {
+		if len(groups) == 0 || inherited {
+			return info.all, nil
+		}
+		return info.none, nil
+	}
+
+	cached := !opts.skipsCache(t)
+	key := filterKey{typ: t, tagKey: tagKey, groups: groupKey, mode: mode, generation: info.generation, inherited: inherited}
+	if cached {
+		if set, ok := globalFilterCache.get(key); ok {
+			return set, nil
+		}
+	}
+
+	// 请求中被禁止的分组在过滤前移除；全部被移除时不匹配任何字段，而不是退化为未指定分组
+	allowed := groups
+	var denied groupMask
+	if len(deny) > 0 {
+		allowed = slices.DeleteFunc(slices.Clone(groups), func(g string) bool { return slices.Contains(deny, g) })
+		denied = newGroupQuery(deny).mask
+	}
+	query := newGroupQuery(allowed)
+	filtered := make([]fieldInfo, 0, len(info.fields))
+	var excluded []fieldInfo
+	for _, field := range info.fields {
+		include := len(groups) == 0 || (len(allowed) > 0 && shouldIncludeField(field, mode, query, inherited))
+		// 只属于被禁止分组的字段始终排除
+		if include && len(field.Groups) > 0 && denied.containsAll(field.GroupMask) {
+			include = false
+		}
+		if include {
+			filtered = append(filtered, field)
+		} else {
+			excluded = append(excluded, field)
+		}
+	}
+	set := newFieldSet(slices.Clip(filtered))
+	set.excluded = excluded
+	if !cached {
+		return set, nil
+	}
+	return globalFilterCache.add(key, set), nil
+}
+
+// filterGroupKey 返回分组过滤结果缓存使用的分组键，设置了禁止的分组时附加在请求的分组之后
+func filterGroupKey(groups, deny []string) string {
+	key := normalizeGroupKey(groups)
+	if len(deny) > 0 {
+		key += "\x01" + normalizeGroupKey(deny)
+	}
+	return key
+}
+
+// normalizeGroupKey 将分组列表规范化为缓存键：排序、去重后拼接
+// 分组的顺序和重复不影响过滤结果，因此规范化后的键可以共享缓存条目
+func normalizeGroupKey(groups []string) string {
+	switch len(groups) {
+	case 0:
+		return ""
+	case 1:
+		return groups[0]
+	}
+	sorted := slices.Clone(groups)
+	slices.Sort(sorted)
+	sorted = slices.Compact(sorted)
+	return strings.Join(sorted, "\x00")
 }
 
 // parseFields 解析结构体字段信息
-func parseFields(t reflect.Type, tagKey string) ([]fieldInfo, error) {
+// 解析过程中的panic通过命名返回值转换为错误返回，不会产生空的字段列表
+func parseFields(t reflect.Type, tagKey string) (fields []fieldInfo, err error) {
 	if t.Kind() != reflect.Struct {
 		return nil, nil
 	}
 
-	var fields []fieldInfo
-	var err error
-
 	// 捕获panic以提供友好的错误信息
 	defer func() {
 		if r := recover(); r != nil {
 			// 转换为标准错误
-			err = &Error{
+			cause, _ := r.(error)
+			fields, err = nil, &Error{
 				Type:    ErrTypeReflection,
-				Message: "解析结构体字段时发生panic",
+				Message: "解析结构体字段时发生panic: " + t.String(),
 				Value:   r,
+				Cause:   cause,
 			}
-			// 这里不能直接返回err，因为defer的返回值无法影响外部函数
-			// 但至少记录了panic，并防止程序崩溃
-			fields = nil
 		}
 	}()
 
 	// 处理所有字段
+	registered := registeredFieldGroups(t)
 	for i := range t.NumField() {
 		field := t.Field(i)
 
@@ -299,7 +1046,7 @@ func parseFields(t reflect.Type, tagKey string) ([]fieldInfo, error) {
 
 		// 获取tag标签
 		jsonTag := field.Tag.Get("json")
-		groupsTag := field.Tag.Get(tagKey)
+		groupsTag, tagged := field.Tag.Lookup(tagKey)
 
 		// 解析JSON标签
 		jsonName, omitEmpty, omitZero := parseJSONTag(field.Name, jsonTag)
@@ -307,46 +1054,91 @@ func parseFields(t reflect.Type, tagKey string) ([]fieldInfo, error) {
 			continue // 忽略标记为"-"的字段
 		}
 
-		// 解析分组标签
+		// 解析分组标签，RegisterFieldGroups注册的分组优先
 		groups := parseGroupsTag(groupsTag)
+		if g, ok := registered[field.Name]; ok {
+			groups, tagged = g, true
+		}
 
-		// 处理匿名嵌套字段
-		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+		// 处理匿名嵌套字段，json标签中指定了名称的内嵌结构体按普通字段处理
+		if flattensEmbedded(field) {
 			// 递归处理嵌套字段
 			nestedFields, nestedErr := parseFields(field.Type, tagKey)
 			if nestedErr != nil {
 				return nil, nestedErr
 			}
 
+			// 内嵌字段声明了omitempty或omitzero时，其提升的字段共同引用同一个embedding，
+			// 嵌套层级的embedding按外层的索引路径重新创建
+			var outer *embedding
+			if omitEmpty || omitZero {
+				outer = &embedding{index: []int{i}, omitEmpty: omitEmpty, omitZero: omitZero}
+			}
+			rebased := make(map[*embedding]*embedding)
+
 			// 添加嵌套字段，保持正确的索引路径
 			for _, nf := range nestedFields {
 				indexPath := append([]int{i}, nf.Index...)
 
 				fields = append(fields, fieldInfo{
-					Index:     indexPath,
-					Name:      field.Name + "." + nf.Name,
-					JSONName:  nf.JSONName,
-					Groups:    nf.Groups,
-					OmitEmpty: nf.OmitEmpty,
-					OmitZero:  nf.OmitZero,
-					Anonymous: nf.Anonymous,
+					Index:       indexPath,
+					Name:        field.Name + "." + nf.Name,
+					JSONName:    nf.JSONName,
+					EncodedKey:  nf.EncodedKey,
+					Groups:      nf.Groups,
+					GroupMask:   nf.GroupMask,
+					Tagged:      nf.Tagged,
+					Deprecation: nf.Deprecation,
+					Required:    nf.Required,
+					OmitEmpty:   nf.OmitEmpty,
+					OmitZero:    nf.OmitZero,
+					Anonymous:   nf.Anonymous,
+					Embeds:      rebaseEmbeds(outer, i, nf.Embeds, rebased),
+					Scalar:      nf.Scalar,
+					Mapper:      nf.Mapper,
+					Encoder:     nf.Encoder,
 				})
 			}
 		} else {
 			// 普通字段
+			mapper, scalar := mapperFor(field.Type)
 			fields = append(fields, fieldInfo{
-				Index:     []int{i},
-				Name:      field.Name,
-				JSONName:  jsonName,
-				Groups:    groups,
-				OmitEmpty: omitEmpty,
-				OmitZero:  omitZero,
-				Anonymous: field.Anonymous,
+				Index:       []int{i},
+				Name:        field.Name,
+				JSONName:    jsonName,
+				EncodedKey:  encodeKey(jsonName),
+				Groups:      groups,
+				GroupMask:   newGroupMask(groups),
+				Tagged:      tagged,
+				Deprecation: parseDeprecation(field.Tag),
+				Required:    parseGroupsTag(field.Tag.Get(RequiredTagKey)),
+				OmitEmpty:   omitEmpty,
+				OmitZero:    omitZero,
+				Anonymous:   field.Anonymous && field.Type.Kind() != reflect.Struct,
+				Scalar:      scalar,
+				Mapper:      mapper,
+				Encoder:     encoderFor(field.Type),
 			})
 		}
 	}
 
-	return fields, err
+	return fields, nil
+}
+
+// flattensEmbedded 判断字段是否为需要将其字段合并到外层对象的内嵌结构体
+// 与encoding/json相同，只展开json标签中未指定名称的内嵌结构体，如json:"profile"的内嵌结构体嵌套在"profile"下输出
+func flattensEmbedded(field reflect.StructField) bool {
+	if !field.Anonymous || field.Type.Kind() != reflect.Struct {
+		return false
+	}
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	return name == ""
+}
+
+// encodeKey 预先编码字段键名，包含引号、转义和冒号
+func encodeKey(name string) []byte {
+	key := appendJSONString(make([]byte, 0, len(name)+3), name)
+	return append(key, ':')
 }
 
 // parseJSONTag 解析JSON标签
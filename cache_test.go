@@ -0,0 +1,310 @@
+package jsongroup
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestCacheShardsRoundUpToPowerOfTwo(t *testing.T) {
+	c := newFieldCache()
+	for _, tc := range []struct{ n, want int }{{1, 1}, {3, 4}, {8, 8}, {9, 16}} {
+		c.SetShards(tc.n)
+		if got := c.GetStats().Shards; got != tc.want {
+			t.Errorf("SetShards(%d): got %d shards, want %d", tc.n, got, tc.want)
+		}
+	}
+}
+
+func TestCacheShardStatsAggregate(t *testing.T) {
+	c := newFieldCache()
+	c.SetShards(8)
+	types := []reflect.Type{
+		reflect.TypeFor[User](), reflect.TypeFor[Address](), reflect.TypeFor[ComplexUser](),
+		reflect.TypeFor[Profile](), reflect.TypeFor[Stats](), reflect.TypeFor[Social](),
+	}
+	for _, typ := range types {
+		for range 3 {
+			if _, err := c.getFieldsInfo(typ, DefaultTagKey); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	stats := c.GetStats()
+	if stats.CurrentSize != len(types) {
+		t.Errorf("CurrentSize = %d, want %d", stats.CurrentSize, len(types))
+	}
+	if stats.Misses != int64(len(types)) || stats.Hits != int64(2*len(types)) {
+		t.Errorf("Hits/Misses = %d/%d, want %d/%d", stats.Hits, stats.Misses, 2*len(types), len(types))
+	}
+	if snap := c.Snapshot(); snap.Hits != stats.Hits || snap.CurrentSize != stats.CurrentSize {
+		t.Errorf("Snapshot %+v differs from GetStats %+v", snap, stats)
+	}
+}
+
+func TestCacheShardsConcurrentAccess(t *testing.T) {
+	c := newFieldCache()
+	c.SetShards(4)
+	c.SetMaxSize(4)
+	types := []reflect.Type{
+		reflect.TypeFor[User](), reflect.TypeFor[Address](), reflect.TypeFor[ComplexUser](),
+		reflect.TypeFor[Profile](), reflect.TypeFor[Stats](), reflect.TypeFor[Social](),
+	}
+
+	var wg sync.WaitGroup
+	for i := range 16 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range 200 {
+				typ := types[(i+j)%len(types)]
+				info, err := c.getFieldsInfo(typ, DefaultTagKey)
+				if err != nil || len(info.fields) != typ.NumField() {
+					t.Errorf("%v: got %v fields, err %v", typ, info, err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	// 每个分片容量为1，6个类型轮流访问必然发生淘汰
+	stats := c.GetStats()
+	if stats.CurrentSize > 4 || stats.Evictions == 0 {
+		t.Errorf("CurrentSize = %d, Evictions = %d; want at most 4 entries and some evictions", stats.CurrentSize, stats.Evictions)
+	}
+}
+
+type twoTagKeys struct {
+	A int `json:"a" groups:"x" roles:"y"`
+	B int `json:"b" groups:"y" roles:"x"`
+}
+
+func TestFieldCacheKeyedByTagKey(t *testing.T) {
+	v := twoTagKeys{A: 1, B: 2}
+	// 先用默认标签键解析，再换用roles，两者不能共用缓存条目
+	assertJSONEqual(t, marshalString(t, v, New(), "x"), `{"a":1}`)
+	assertJSONEqual(t, marshalString(t, v, New().WithTagKey("roles"), "x"), `{"b":2}`)
+	assertJSONEqual(t, marshalString(t, v, New(), "x"), `{"a":1}`)
+
+	groups, err := KnownGroups(New().WithTagKey("roles"), v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(groups, []string{"x", "y"}) {
+		t.Errorf("KnownGroups = %v", groups)
+	}
+}
+
+func TestFieldCacheEmptyTagKeyUsesDefault(t *testing.T) {
+	c := newFieldCache()
+	typ := reflect.TypeFor[User]()
+	a, _ := c.getFieldsInfo(typ, "")
+	b, _ := c.getFieldsInfo(typ, DefaultTagKey)
+	if a != b {
+		t.Error("empty tag key and DefaultTagKey should share one cache entry")
+	}
+	if n := c.GetStats().CurrentSize; n != 1 {
+		t.Errorf("CurrentSize = %d, want 1", n)
+	}
+}
+
+func TestFilteredFieldsPerGroupsAndMode(t *testing.T) {
+	u := newComplexUser(1)
+	or := marshalString(t, u, New(), "public", "internal")
+	and := marshalString(t, u, New().WithGroupMode(GroupModeAnd), "public", "internal")
+	// 分组顺序不同时命中同一过滤结果
+	if again := marshalString(t, u, New(), "internal", "public"); again != or {
+		t.Errorf("group order changed output:\n%s\n%s", again, or)
+	}
+	assertJSONEqual(t, and, `{"id":1,"stats":{}}`)
+	if or == and {
+		t.Error("GroupModeOr and GroupModeAnd must not share a filtered field set")
+	}
+}
+
+// distinctStructTypes 用reflect.StructOf构造n个不同的结构体类型
+func distinctStructTypes(n int) []reflect.Type {
+	types := make([]reflect.Type, n)
+	for i := range types {
+		types[i] = reflect.StructOf([]reflect.StructField{{
+			Name: "F" + strconv.Itoa(i),
+			Type: reflect.TypeFor[int](),
+			Tag:  `json:"f" groups:"public"`,
+		}})
+	}
+	return types
+}
+
+func TestCacheEvictionKeepsMapAndListInSync(t *testing.T) {
+	c := newFieldCache()
+	c.SetShards(1)
+	c.SetMaxSize(10)
+	for _, typ := range distinctStructTypes(100) {
+		if _, err := c.getFieldsInfo(typ, DefaultTagKey); err != nil {
+			t.Fatalf("getFieldsInfo(%v): %v", typ, err)
+		}
+	}
+
+	stats := c.GetStats()
+	if stats.CurrentSize != 10 || stats.Evictions != 90 {
+		t.Errorf("CurrentSize = %d, Evictions = %d; want 10 and 90", stats.CurrentSize, stats.Evictions)
+	}
+	shard := c.shards.Load().shards[0]
+	if len(shard.cache) != shard.evictList.Len() || len(shard.cache) != 10 {
+		t.Errorf("map has %d entries, list has %d", len(shard.cache), shard.evictList.Len())
+	}
+	var bytes int64
+	for e := shard.evictList.Front(); e != nil; e = e.Next() {
+		bytes += e.Value.(*cacheEntry).size
+	}
+	if bytes != stats.CurrentBytes {
+		t.Errorf("CurrentBytes = %d, sum of entries = %d", stats.CurrentBytes, bytes)
+	}
+}
+
+func TestCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c := newFieldCache()
+	c.SetShards(1)
+	c.SetMaxSize(2)
+	a, b, d := reflect.TypeFor[User](), reflect.TypeFor[Address](), reflect.TypeFor[Profile]()
+	c.getFieldsInfo(a, DefaultTagKey)
+	c.getFieldsInfo(b, DefaultTagKey)
+	c.getFieldsInfo(a, DefaultTagKey) // a成为最近使用的条目
+	c.getFieldsInfo(d, DefaultTagKey) // 淘汰b
+
+	shard := c.shards.Load().shards[0]
+	if _, ok := shard.cache[fieldsKey{b, DefaultTagKey}]; ok {
+		t.Error("least recently used type was not evicted")
+	}
+	if _, ok := shard.cache[fieldsKey{a, DefaultTagKey}]; !ok {
+		t.Error("recently used type was evicted")
+	}
+}
+
+func TestNoGroupTagsFastPath(t *testing.T) {
+	info, err := New().fieldsInfo(reflect.TypeFor[Stats]())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.noGroupTags {
+		t.Fatal("Stats declares no groups tags")
+	}
+	if tagged, _ := New().fieldsInfo(reflect.TypeFor[Profile]()); tagged.noGroupTags {
+		t.Error("Profile declares groups tags")
+	}
+
+	// 没有分组标签的类型不查找也不写入过滤结果缓存
+	opts := New()
+	before := len(globalFilterCache.cache)
+	for _, tc := range []struct {
+		groups    []string
+		inherited bool
+		want      *fieldSet
+	}{
+		{nil, false, info.all},
+		{[]string{"public"}, false, info.none},
+		{[]string{"public", "admin"}, false, info.none},
+		{[]string{"public"}, true, info.all},
+	} {
+		set, err := getFilteredFields(reflect.TypeFor[Stats](), opts, tc.groups, filterGroupKey(tc.groups, nil), GroupModeOr, tc.inherited)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if set != tc.want {
+			t.Errorf("groups %v inherited %v: got %d fields", tc.groups, tc.inherited, len(set.fields))
+		}
+	}
+	if after := len(globalFilterCache.cache); after != before {
+		t.Errorf("filter cache grew from %d to %d entries", before, after)
+	}
+
+	u := newComplexUser(1)
+	assertJSONEqual(t, marshalString(t, u.Stats, New()), `{"followers":10,"following":3,"score":4.5}`)
+	assertJSONEqual(t, marshalString(t, u.Stats, New(), "public"), `{}`)
+	got := marshalString(t, u, New().WithCascadeToUntagged(true), "public")
+	if want := `"stats":{"followers":10,"following":3,"score":4.5}`; !strings.Contains(got, want) {
+		t.Errorf("CascadeToUntagged: %s does not contain %s", got, want)
+	}
+}
+
+// panickingType 在读取字段时panic的reflect.Type，模拟解析病态类型时发生的panic
+type panickingType struct{ reflect.Type }
+
+func (*panickingType) Field(int) reflect.StructField { panic("corrupt type") }
+
+func TestParseFieldsPanicIsReportedAndNotCached(t *testing.T) {
+	c := newFieldCache()
+	typ := &panickingType{reflect.TypeFor[User]()}
+	for range 2 {
+		info, err := c.getFieldsInfo(typ, DefaultTagKey)
+		var jerr *Error
+		if !errors.As(err, &jerr) || jerr.Type != ErrTypeReflection || info != nil {
+			t.Fatalf("got %v, %v; want a reflection error and no field info", info, err)
+		}
+	}
+	if n := c.GetStats().CurrentSize; n != 0 {
+		t.Errorf("CurrentSize = %d; the failed parse must not be cached", n)
+	}
+}
+
+// churnNamedHitRatio 在容量为8的独立缓存中，每序列化一个具名类型的值之后序列化8个新的匿名类型的值，
+// loop返回false时结束，返回具名类型的缓存命中率
+func churnNamedHitRatio(tb testing.TB, skip bool, loop func() bool) float64 {
+	tb.Helper()
+	c := newFieldCache()
+	c.SetShards(1)
+	c.SetMaxSize(8)
+	c.SetDetailedStats(true)
+	old := globalCache
+	globalCache = c
+	defer func() { globalCache = old }()
+
+	named := []any{&User{Address: &Address{}}, &Profile{Socials: []Social{{}}}, &Stats{}}
+	anon := distinctStructTypes(1024)
+	opts := New().WithSkipCachingAnonymousTypes(skip)
+	for i := 0; loop(); i++ {
+		if _, err := MarshalByGroupsWithOptions(named[i%len(named)], opts, "public"); err != nil {
+			tb.Fatal(err)
+		}
+		for j := range 8 {
+			typ := anon[(8*i+j)%len(anon)]
+			if _, err := MarshalByGroupsWithOptions(reflect.New(typ).Interface(), opts, "public"); err != nil {
+				tb.Fatal(err)
+			}
+		}
+	}
+
+	var hits, misses int64
+	for _, typ := range []reflect.Type{
+		reflect.TypeFor[User](), reflect.TypeFor[Address](), reflect.TypeFor[Profile](),
+		reflect.TypeFor[Social](), reflect.TypeFor[Stats](),
+	} {
+		stat := c.GetStatsByType()[typeName(typ)]
+		hits += stat.Hits
+		misses += stat.Misses
+	}
+	if hits+misses == 0 {
+		return 0
+	}
+	return float64(hits) / float64(hits+misses)
+}
+
+func TestSkipCachingAnonymousTypesKeepsNamedEntries(t *testing.T) {
+	rounds := func(n int) func() bool {
+		return func() bool { n--; return n >= 0 }
+	}
+	polluted := churnNamedHitRatio(t, false, rounds(300))
+	protected := churnNamedHitRatio(t, true, rounds(300))
+	if protected < 0.99 {
+		t.Errorf("named hit ratio with anonymous types skipped = %.3f, want about 1", protected)
+	}
+	// 不跳过时匿名类型不断淘汰具名类型，说明上面的命中率确实来自跳过缓存
+	if polluted > 0.5 {
+		t.Errorf("named hit ratio without skipping = %.3f, want the anonymous types to evict them", polluted)
+	}
+}
@@ -0,0 +1,161 @@
+package jsongroup
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"unicode/utf8"
+)
+
+// rejectsField 判断结构体字段的值是否无法按当前选项编码：CanonicalJSON下的NaN或Inf，
+// 以及InvalidUTF8PolicyError下包含无效UTF-8的字符串。这样的字段不走预编译的快速路径，由encodeValue等返回带字段路径的错误
+func (ctx *serializeContext) rejectsField(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return ctx.opts.CanonicalJSON && isSpecialFloat(v.Float())
+	case reflect.String:
+		return ctx.opts.InvalidUTF8Policy == InvalidUTF8PolicyError && !utf8.ValidString(v.String())
+	}
+	return false
+}
+
+// checkFloat 启用CanonicalJSON时NaN和Inf没有合法的JSON表示，返回ErrTypeUnsupportedValue错误
+func (ctx *serializeContext) checkFloat(v reflect.Value) error {
+	if !ctx.opts.CanonicalJSON || !isSpecialFloat(v.Float()) {
+		return nil
+	}
+	return UnsupportedValueError(ctx.path(), v, floatToString(v.Float())+"无法按规范JSON编码")
+}
+
+// canonicalize 将编码完成的JSON按RFC 8785（JCS）的规则重新输出
+// 对象的键按UTF-16码元排序，数值按ECMAScript的规则格式化为最短形式，字符串只转义引号、反斜杠和控制字符，不含任何空白。
+// 在最终输出上进行，因此按声明顺序输出的结构体字段、由编码后端编码的部分、顶层包装和Envelope都会规范化
+func canonicalize(b []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return appendCanonical(make([]byte, 0, len(b)), v)
+}
+
+// appendCanonical 按JCS的规则编码json.Decoder解析得到的值
+func appendCanonical(b []byte, v any) ([]byte, error) {
+	switch v := v.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		slices.SortFunc(keys, compareUTF16)
+		b = append(b, '{')
+		for i, k := range keys {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			b = appendCanonicalString(b, k)
+			b = append(b, ':')
+			var err error
+			if b, err = appendCanonical(b, v[k]); err != nil {
+				return nil, err
+			}
+		}
+		return append(b, '}'), nil
+
+	case []any:
+		b = append(b, '[')
+		for i, item := range v {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			var err error
+			if b, err = appendCanonical(b, item); err != nil {
+				return nil, err
+			}
+		}
+		return append(b, ']'), nil
+
+	case string:
+		return appendCanonicalString(b, v), nil
+
+	case json.Number:
+		// JCS中的数值都是IEEE 754双精度浮点数，超过2^53的整数会丢失精度
+		f, err := strconv.ParseFloat(string(v), 64)
+		if err != nil {
+			return nil, UnsupportedValueError("", reflect.ValueOf(v), fmt.Sprintf("数值%s超出float64的范围", v))
+		}
+		if f == 0 {
+			// -0按0输出
+			f = 0
+		}
+		return appendJSONFloat(b, f, 64), nil
+
+	case bool:
+		return strconv.AppendBool(b, v), nil
+	}
+	return append(b, "null"...), nil
+}
+
+// appendCanonicalString 按JCS的规则编码字符串
+// 只转义引号、反斜杠和U+0000至U+001F的控制字符，其中\b、\t、\n、\f、\r使用短格式，其余使用小写的\u00XX
+func appendCanonicalString(b []byte, s string) []byte {
+	b = append(b, '"')
+	start := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= 0x20 && c != '"' && c != '\\' {
+			continue
+		}
+		b = append(b, s[start:i]...)
+		switch c {
+		case '\\', '"':
+			b = append(b, '\\', c)
+		case '\b':
+			b = append(b, '\\', 'b')
+		case '\f':
+			b = append(b, '\\', 'f')
+		case '\n':
+			b = append(b, '\\', 'n')
+		case '\r':
+			b = append(b, '\\', 'r')
+		case '\t':
+			b = append(b, '\\', 't')
+		default:
+			b = append(b, '\\', 'u', '0', '0', hexDigits[c>>4], hexDigits[c&0xF])
+		}
+		start = i + 1
+	}
+	b = append(b, s[start:]...)
+	return append(b, '"')
+}
+
+// compareUTF16 按UTF-16码元比较两个字符串，JCS要求对象的键按此顺序排列
+// 与按字节比较的区别在于U+10000以上的字符（代理对）排在U+E000至U+FFFF之前
+func compareUTF16(a, b string) int {
+	for a != "" && b != "" {
+		ra, na := utf8.DecodeRuneInString(a)
+		rb, nb := utf8.DecodeRuneInString(b)
+		if ra != rb {
+			if c := cmp.Compare(firstUTF16Unit(ra), firstUTF16Unit(rb)); c != 0 {
+				return c
+			}
+			// 高位代理相同，低位代理的顺序与码点的顺序一致
+			return cmp.Compare(ra, rb)
+		}
+		a, b = a[na:], b[nb:]
+	}
+	return cmp.Compare(len(a), len(b))
+}
+
+// firstUTF16Unit 返回字符UTF-16编码的第一个码元
+func firstUTF16Unit(r rune) rune {
+	if r < 0x10000 {
+		return r
+	}
+	return 0xD800 + (r-0x10000)>>10
+}
@@ -0,0 +1,169 @@
+package jsongroup
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+// canonicalString 按CanonicalJSON序列化v
+func canonicalString(t *testing.T, v any, groups ...string) string {
+	t.Helper()
+	return marshalString(t, v, New().WithCanonicalJSON(true), groups...)
+}
+
+// TestCanonicalRFC8785Example RFC 8785第3.2.2节的示例
+func TestCanonicalRFC8785Example(t *testing.T) {
+	v := map[string]any{
+		"numbers":  []any{333333333.33333329, 1e30, 4.50, 2e-3, 0.000000000000000000000000001},
+		"string":   "\u20ac$\u000F\u000aA'B\"\\\\\"/",
+		"literals": []any{nil, true, false},
+	}
+	want := `{"literals":[null,true,false],"numbers":[333333333.3333333,1e+30,4.5,0.002,1e-27],"string":"€$\u000f\nA'B\"\\\\\"/"}`
+	if got := canonicalString(t, v); got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+}
+
+// TestCanonicalKeySortingUTF16 RFC 8785第3.2.3节的排序示例：键按UTF-16码元而不是码点或UTF-8字节排序
+func TestCanonicalKeySortingUTF16(t *testing.T) {
+	v := map[string]string{
+		"\u20ac":     "Euro Sign",
+		"\r":         "Carriage Return",
+		"\ufb33":     "Hebrew Letter Dalet With Dagesh",
+		"1":          "One",
+		"\U0001F600": "Emoji: Grinning Face",
+		"\u0080":     "Control",
+		"\u00f6":     "Latin Small Letter O With Diaeresis",
+	}
+	want := "{\"\\r\":\"Carriage Return\",\"1\":\"One\",\"\u0080\":\"Control\",\"\u00f6\":\"Latin Small Letter O With Diaeresis\"," +
+		"\"\u20ac\":\"Euro Sign\",\"\U0001F600\":\"Emoji: Grinning Face\",\"\ufb33\":\"Hebrew Letter Dalet With Dagesh\"}"
+	if got := canonicalString(t, v); got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+
+	for _, tc := range []struct {
+		a, b string
+		want int
+	}{
+		{"a", "b", -1},
+		{"a", "ab", -1},
+		{"\U0001F600", "\ufb33", -1}, // 代理对0xD83D排在0xFB33之前，按码点则相反
+		{"\U0001F600", "\U0001F601", -1},
+		{"\uffff", "\U00010000", 1},
+		{"\u00e9", "\u00e9", 0},
+	} {
+		if got := compareUTF16(tc.a, tc.b); got != tc.want {
+			t.Errorf("compareUTF16(%q, %q) = %d, want %d", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
+
+// TestCanonicalStructFieldsSorted 结构体字段、顶层包装的键同样排序，且不含空白
+func TestCanonicalStructFieldsSorted(t *testing.T) {
+	u := User{ID: 1, Name: "Bob", Email: "b@example.com", Address: &Address{Street: "1 Main St", City: "Springfield", Zip: "<1>"}}
+	got := marshalString(t, u, New().WithCanonicalJSON(true).WithTopLevelKey("data"), "admin")
+	want := `{"data":{"address":{"city":"Springfield","street":"1 Main St","zip":"<1>"},"email":"b@example.com","id":1,"name":"Bob"}}`
+	if got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+}
+
+// TestCanonicalNumbers RFC 8785附录B的数值测试向量，按IEEE 754的位模式给出
+func TestCanonicalNumbers(t *testing.T) {
+	for _, tc := range []struct {
+		bits uint64
+		want string
+	}{
+		{0x0000000000000000, "0"},
+		{0x8000000000000000, "0"},
+		{0x0000000000000001, "5e-324"},
+		{0x8000000000000001, "-5e-324"},
+		{0x7fefffffffffffff, "1.7976931348623157e+308"},
+		{0xffefffffffffffff, "-1.7976931348623157e+308"},
+		{0x4340000000000000, "9007199254740992"},
+		{0xc340000000000000, "-9007199254740992"},
+		{0x4430000000000000, "295147905179352830000"},
+		{0x44b52d02c7e14af5, "9.999999999999997e+22"},
+		{0x44b52d02c7e14af6, "1e+23"},
+		{0x44b52d02c7e14af7, "1.0000000000000001e+23"},
+		{0x444b1ae4d6e2ef4e, "999999999999999700000"},
+		{0x444b1ae4d6e2ef4f, "999999999999999900000"},
+		{0x444b1ae4d6e2ef50, "1e+21"},
+		{0x3eb0c6f7a0b5ed8c, "9.999999999999997e-7"},
+		{0x3eb0c6f7a0b5ed8d, "0.000001"},
+		{0x41b3de4355555553, "333333333.3333332"},
+		{0x41b3de4355555554, "333333333.33333325"},
+		{0x41b3de4355555555, "333333333.3333333"},
+		{0x41b3de4355555556, "333333333.3333334"},
+		{0x41b3de4355555557, "333333333.33333343"},
+		{0xbecbf647612f3696, "-0.0000033333333333333333"},
+		{0x43143ff3c1cb0959, "1424953923781206.2"},
+	} {
+		f := math.Float64frombits(tc.bits)
+		if got := canonicalString(t, f); got != tc.want {
+			t.Errorf("%#016x: got %s, want %s", tc.bits, got, tc.want)
+		}
+		// 作为结构体字段时经过预编译的编码函数，结果相同
+		field := struct {
+			F float64 `json:"f"`
+		}{f}
+		if got := canonicalString(t, field); got != `{"f":`+tc.want+`}` {
+			t.Errorf("%#016x as a field: got %s", tc.bits, got)
+		}
+	}
+
+	// 整数同样按双精度浮点数输出，超过2^53时丢失精度
+	for _, tc := range []struct {
+		v    any
+		want string
+	}{
+		{int64(1) << 53, "9007199254740992"},
+		{uint64(math.MaxUint64), "18446744073709552000"},
+		{int8(-7), "-7"},
+		{float32(0.1), "0.1"},
+	} {
+		if got := canonicalString(t, tc.v); got != tc.want {
+			t.Errorf("%T(%v): got %s, want %s", tc.v, tc.v, got, tc.want)
+		}
+	}
+}
+
+func TestCanonicalRejectsNaNAndInf(t *testing.T) {
+	type reading struct {
+		Value float64 `json:"value"`
+	}
+	for _, tc := range []struct {
+		name string
+		v    any
+		path string
+	}{
+		{"root NaN", math.NaN(), ""},
+		{"root +Inf", math.Inf(1), ""},
+		{"struct field", reading{math.Inf(-1)}, "Value"},
+		{"map value", map[string]float64{"x": math.NaN()}, "x"},
+		{"slice element", []any{1, math.Inf(1)}, "[1]"},
+		{"float32", float32(math.Inf(1)), ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := MarshalByGroupsWithOptions(tc.v, New().WithCanonicalJSON(true))
+			var jerr *Error
+			if !errors.Is(err, ErrUnsupportedValue) || !errors.As(err, &jerr) {
+				t.Fatalf("got %v, want ErrUnsupportedValue", err)
+			}
+			if jerr.Path != tc.path {
+				t.Errorf("path = %q, want %q", jerr.Path, tc.path)
+			}
+		})
+	}
+
+	// 未启用时照常输出为字符串
+	assertJSONEqual(t, marshalString(t, reading{math.Inf(1)}, New()), `{"value":"Infinity"}`)
+}
+
+func TestCanonicalConflictsWithJSSafeEscaping(t *testing.T) {
+	_, err := MarshalByGroupsWithOptions(1, New().WithCanonicalJSON(true).WithJSSafeEscaping(true))
+	if !errors.Is(err, ErrInvalidOptions) {
+		t.Errorf("got %v, want ErrInvalidOptions", err)
+	}
+}
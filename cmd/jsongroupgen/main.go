@@ -0,0 +1,467 @@
+// jsongroupgen 为指定的结构体类型生成静态的按分组序列化方法
+//
+// 用法：
+//
+//	//go:generate jsongroupgen -type User,Order
+//
+// 生成的 AppendByGroups 方法实现 jsongroup.GroupMarshaler 接口，
+// 分组过滤展开为switch语句，基本类型字段直接编码，其他字段交由 jsongroup.AppendField 按反射路径处理，
+// 沿用运行时传入的选项、递归深度和循环引用检测状态；同时生成的 MarshalByGroups 方法等同于调用 jsongroup.MarshalByGroups。
+// 生成代码假定默认选项：groups标签、Or模式、跳过nil指针字段、空值不输出null。
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// generatedHeader 生成文件的首行标记，解析包时跳过带该标记的文件
+const generatedHeader = "// Code generated by jsongroupgen. DO NOT EDIT."
+
+// libraryPath jsongroup库的导入路径
+const libraryPath = "github.com/JieBaiYou/jsongroup"
+
+func main() {
+	typeNames := flag.String("type", "", "逗号分隔的类型名列表，必填")
+	output := flag.String("output", "", "输出文件名，默认为<第一个类型名小写>_jsongroup.go")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "用法: jsongroupgen -type T1,T2 [-output file] [dir]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *typeNames == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	dir := "."
+	if flag.NArg() > 0 {
+		dir = flag.Arg(0)
+	}
+
+	names := strings.Split(*typeNames, ",")
+	out := *output
+	if out == "" {
+		out = strings.ToLower(names[0]) + "_jsongroup.go"
+	}
+	out = filepath.Join(dir, out)
+
+	src, err := generate(dir, names, filepath.Base(out))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "jsongroupgen: %v\n", err)
+		os.Exit(1)
+	}
+	if err := os.WriteFile(out, src, 0o644); err != nil {
+		fmt.Fprintf(os.Stderr, "jsongroupgen: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// generate 解析dir中的包，为names中的类型生成代码
+// 跳过输出文件和其他生成文件，避免过期的生成代码影响类型检查
+func generate(dir string, names []string, outName string) ([]byte, error) {
+	pkg, err := loadPackage(dir, outName)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &generator{}
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		obj := pkg.Scope().Lookup(name)
+		if obj == nil {
+			return nil, fmt.Errorf("包 %s 中找不到类型 %s", pkg.Name(), name)
+		}
+		named, ok := obj.Type().(*types.Named)
+		if !ok || named.TypeParams().Len() > 0 {
+			return nil, fmt.Errorf("%s 不是非泛型的命名类型", name)
+		}
+		st, ok := named.Underlying().(*types.Struct)
+		if !ok {
+			return nil, fmt.Errorf("%s 不是结构体类型", name)
+		}
+		if err := g.generateType(name, st); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+
+	var file bytes.Buffer
+	fmt.Fprintf(&file, "%s\n\npackage %s\n\n", generatedHeader, pkg.Name())
+	if g.useStrconv || g.useLibrary {
+		file.WriteString("import (\n")
+		if g.useStrconv {
+			file.WriteString("\t\"strconv\"\n")
+		}
+		if g.useStrconv && g.useLibrary {
+			file.WriteString("\n")
+		}
+		if g.useLibrary {
+			fmt.Fprintf(&file, "\t%q\n", libraryPath)
+		}
+		file.WriteString(")\n\n")
+	}
+	file.Write(g.buf.Bytes())
+
+	src, err := format.Source(file.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("格式化生成代码失败: %w", err)
+	}
+	return src, nil
+}
+
+// loadPackage 解析并类型检查dir中的非测试文件
+func loadPackage(dir, outName string) (*types.Package, error) {
+	fset := token.NewFileSet()
+	matches, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+
+	var files []*ast.File
+	for _, path := range matches {
+		base := filepath.Base(path)
+		if base == outName || strings.HasSuffix(base, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, path, nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		if isGenerated(f) {
+			continue
+		}
+		files = append(files, f)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("目录 %s 中没有Go源文件", dir)
+	}
+
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	return conf.Check(files[0].Name.Name, fset, files, nil)
+}
+
+// isGenerated 判断文件是否由jsongroupgen生成
+func isGenerated(f *ast.File) bool {
+	for _, group := range f.Comments {
+		if group.Pos() >= f.Package {
+			break
+		}
+		for _, c := range group.List {
+			if c.Text == generatedHeader {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// field 待生成代码的字段，嵌入结构体的字段已展开
+type field struct {
+	// 字段名路径，嵌入字段以"."连接，同时用于访问字段和错误路径
+	name string
+	// JSON键名
+	jsonName string
+	// 分组标签
+	groups []string
+	// 是否有omitempty或omitzero选项
+	omitEmpty bool
+	omitZero  bool
+	// 提升该字段的内嵌字段中声明了omitempty或omitzero的字段名，生成代码不支持按内嵌结构体整体省略
+	omittableEmbed string
+	// 字段类型
+	typ types.Type
+}
+
+// generator 累积生成的方法代码和所需的导入
+type generator struct {
+	buf        bytes.Buffer
+	useStrconv bool
+	useLibrary bool
+}
+
+// collectFields 按声明顺序收集字段，规则与运行时的parseFields一致
+func collectFields(st *types.Struct, prefix string) []field {
+	var fields []field
+	for i := range st.NumFields() {
+		f := st.Field(i)
+		if !f.Exported() {
+			continue
+		}
+
+		tag := reflect.StructTag(st.Tag(i))
+		jsonName, omitEmpty, omitZero := parseJSONTag(f.Name(), tag.Get("json"))
+		if jsonName == "-" {
+			continue
+		}
+
+		name := prefix + f.Name()
+		if f.Anonymous() && !hasJSONName(tag.Get("json")) {
+			if nested, ok := f.Type().Underlying().(*types.Struct); ok {
+				for _, nf := range collectFields(nested, name+".") {
+					if nf.omittableEmbed == "" && (omitEmpty || omitZero) {
+						nf.omittableEmbed = name
+					}
+					fields = append(fields, nf)
+				}
+				continue
+			}
+		}
+
+		fields = append(fields, field{
+			name:      name,
+			jsonName:  jsonName,
+			groups:    parseGroupsTag(tag.Get("groups")),
+			omitEmpty: omitEmpty,
+			omitZero:  omitZero,
+			typ:       f.Type(),
+		})
+	}
+	return fields
+}
+
+// generateType 为单个结构体类型生成AppendByGroups和MarshalByGroups方法
+func (g *generator) generateType(name string, st *types.Struct) error {
+	fields := collectFields(st, "")
+
+	seen := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		if seen[f.jsonName] {
+			return fmt.Errorf("JSON键名 %q 重复，生成代码不支持", f.jsonName)
+		}
+		if f.omittableEmbed != "" {
+			return fmt.Errorf("内嵌字段 %s 声明了omitempty或omitzero，生成代码不支持", f.omittableEmbed)
+		}
+		seen[f.jsonName] = true
+	}
+
+	// 为每个出现过的分组分配一个下标
+	var groupNames []string
+	for _, f := range fields {
+		for _, grp := range f.groups {
+			if !slices.Contains(groupNames, grp) {
+				groupNames = append(groupNames, grp)
+			}
+		}
+	}
+
+	w := &g.buf
+	g.useLibrary = true
+	fmt.Fprintf(w, "// MarshalByGroups 使用包级默认选项按分组序列化%s，等同于jsongroup.MarshalByGroups\n", name)
+	fmt.Fprintf(w, "func (v %s) MarshalByGroups(groups ...string) ([]byte, error) {\n", name)
+	w.WriteString("return jsongroup.MarshalByGroups(v, groups...)\n}\n\n")
+	fmt.Fprintf(w, "// AppendByGroups 按分组编码%s并追加到dst，实现jsongroup.GroupMarshaler\n", name)
+	fmt.Fprintf(w, "func (v %s) AppendByGroups(dst []byte, state *jsongroup.EncodeState, groups ...string) ([]byte, error) {\n", name)
+	if len(fields) == 0 {
+		w.WriteString("return append(dst, \"{}\"...), nil\n}\n\n")
+		return nil
+	}
+
+	w.WriteString("all := len(groups) == 0\n")
+	if len(groupNames) > 0 {
+		fmt.Fprintf(w, "var in [%d]bool\n", len(groupNames))
+		w.WriteString("for _, g := range groups {\nswitch g {\n")
+		for i, grp := range groupNames {
+			fmt.Fprintf(w, "case %s:\nin[%d] = true\n", strconv.Quote(grp), i)
+		}
+		w.WriteString("}\n}\n")
+	}
+	w.WriteString("buf := append(dst, '{')\nfirst := true\n")
+
+	for _, f := range fields {
+		cond := []string{"all"}
+		for _, grp := range f.groups {
+			cond = append(cond, fmt.Sprintf("in[%d]", slices.Index(groupNames, grp)))
+		}
+		g.generateField(f, strings.Join(cond, " || "))
+	}
+
+	w.WriteString("buf = append(buf, '}')\nreturn buf, nil\n}\n\n")
+	return nil
+}
+
+// generateField 生成单个字段的编码代码
+func (g *generator) generateField(f field, cond string) {
+	w := &g.buf
+	access := "v." + f.name
+	key := strconv.Quote(encodeKey(f.jsonName))
+
+	// 复数和unsafe.Pointer交由运行时处理
+	if basic, ok := f.typ.Underlying().(*types.Basic); ok && basic.Info()&scalarKinds != 0 && basic.Info()&types.IsComplex == 0 {
+		appendExpr, empty := g.basicExpr(f.typ, access)
+		if f.omitEmpty || f.omitZero {
+			cond = fmt.Sprintf("(%s) && !(%s)", cond, empty)
+		}
+		fmt.Fprintf(w, "// %s\nif %s {\n", f.name, cond)
+		fmt.Fprintf(w, "if !first {\nbuf = append(buf, ',')\n}\nfirst = false\n")
+		fmt.Fprintf(w, "buf = append(buf, %s...)\n", key)
+		fmt.Fprintf(w, "buf = %s\n}\n", appendExpr)
+		return
+	}
+
+	// 非基本类型字段：nil指针跳过，omitempty/omitzero按运行时规则判断，值交由运行时编码
+	g.useLibrary = true
+	skip := g.skipExpr(f, access)
+	if skip != "" {
+		cond = fmt.Sprintf("(%s) && !(%s)", cond, skip)
+	}
+	fmt.Fprintf(w, "// %s\nif %s {\n", f.name, cond)
+	w.WriteString("mark := len(buf)\nif !first {\nbuf = append(buf, ',')\n}\n")
+	fmt.Fprintf(w, "buf = append(buf, %s...)\n", key)
+	w.WriteString("var ok bool\nvar err error\n")
+	fmt.Fprintf(w, "buf, ok, err = jsongroup.AppendField(buf, state, %s, &%s, groups...)\n", strconv.Quote(f.name), access)
+	w.WriteString("if err != nil {\nreturn dst, err\n}\n")
+	w.WriteString("if ok {\nfirst = false\n} else {\nbuf = buf[:mark]\n}\n}\n")
+}
+
+// scalarKinds 生成代码直接编码的基本类型
+const scalarKinds = types.IsString | types.IsBoolean | types.IsInteger | types.IsFloat
+
+// basicExpr 返回基本类型字段的追加表达式和空值判断表达式
+func (g *generator) basicExpr(typ types.Type, access string) (string, string) {
+	info := typ.Underlying().(*types.Basic).Info()
+	switch {
+	case info&types.IsString != 0:
+		g.useLibrary = true
+		return fmt.Sprintf("jsongroup.AppendString(buf, %s)", convert(typ, types.String, access)), access + ` == ""`
+	case info&types.IsBoolean != 0:
+		g.useStrconv = true
+		return fmt.Sprintf("strconv.AppendBool(buf, %s)", convert(typ, types.Bool, access)), "!" + access
+	case info&types.IsFloat != 0 && typ.Underlying().(*types.Basic).Kind() == types.Float32:
+		g.useLibrary = true
+		return fmt.Sprintf("jsongroup.AppendFloat32(buf, %s)", convert(typ, types.Float32, access)), access + " == 0"
+	case info&types.IsFloat != 0:
+		g.useLibrary = true
+		return fmt.Sprintf("jsongroup.AppendFloat(buf, %s)", convert(typ, types.Float64, access)), access + " == 0"
+	case info&types.IsUnsigned != 0:
+		g.useStrconv = true
+		return fmt.Sprintf("strconv.AppendUint(buf, %s, 10)", convert(typ, types.Uint64, access)), access + " == 0"
+	}
+	// 有符号整数
+	g.useStrconv = true
+	return fmt.Sprintf("strconv.AppendInt(buf, %s, 10)", convert(typ, types.Int64, access)), access + " == 0"
+}
+
+// convert 在字段类型与目标类型不同时添加类型转换
+// 命名类型（如type Status string）的底层类型即使相同也需要转换
+func convert(typ types.Type, kind types.BasicKind, access string) string {
+	target := types.Typ[kind]
+	if types.Identical(typ, target) {
+		return access
+	}
+	return target.Name() + "(" + access + ")"
+}
+
+// skipExpr 返回非基本类型字段被省略的条件，与运行时的字段处理顺序一致
+func (g *generator) skipExpr(f field, access string) string {
+	var conds []string
+	switch u := f.typ.Underlying().(type) {
+	case *types.Pointer:
+		// 默认忽略nil指针字段
+		conds = append(conds, access+" == nil")
+	case *types.Interface:
+		if f.omitEmpty || f.omitZero {
+			conds = append(conds, access+" == nil")
+		}
+	case *types.Slice, *types.Map:
+		if f.omitEmpty {
+			conds = append(conds, "len("+access+") == 0")
+		}
+	case *types.Array:
+		if f.omitEmpty && u.Len() == 0 {
+			conds = append(conds, "true")
+		}
+	case *types.Struct:
+		if f.omitZero && isTime(f.typ) {
+			conds = append(conds, access+".IsZero()")
+		}
+	}
+	return strings.Join(conds, " || ")
+}
+
+// isTime 判断类型是否为time.Time
+func isTime(t types.Type) bool {
+	named, ok := t.(*types.Named)
+	if !ok {
+		return false
+	}
+	obj := named.Obj()
+	return obj.Pkg() != nil && obj.Pkg().Path() == "time" && obj.Name() == "Time"
+}
+
+// parseJSONTag 解析JSON标签，规则与运行时一致
+func parseJSONTag(fieldName, jsonTag string) (string, bool, bool) {
+	if jsonTag == "" {
+		return fieldName, false, false
+	}
+
+	parts := strings.Split(jsonTag, ",")
+	name := parts[0]
+	if name == "" {
+		name = fieldName
+	}
+
+	omitEmpty := slices.Contains(parts[1:], "omitempty")
+	omitZero := slices.Contains(parts[1:], "omitzero")
+	return name, omitEmpty, omitZero
+}
+
+// hasJSONName 判断JSON标签是否指定了名称，指定了名称的内嵌结构体不展开，规则与运行时一致
+func hasJSONName(jsonTag string) bool {
+	name, _, _ := strings.Cut(jsonTag, ",")
+	return name != ""
+}
+
+// parseGroupsTag 解析分组标签，规则与运行时一致
+func parseGroupsTag(groupsTag string) []string {
+	if groupsTag == "" {
+		return nil
+	}
+
+	var groups []string
+	for part := range strings.SplitSeq(groupsTag, ",") {
+		if g := strings.TrimSpace(part); g != "" {
+			groups = append(groups, g)
+		}
+	}
+	return groups
+}
+
+// encodeKey 按encoding/json的规则编码键名并附加冒号
+// 键名中的HTML敏感字符和U+2028/U+2029同样转义，与运行时的输出一致
+func encodeKey(name string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range name {
+		switch {
+		case r == '"' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r < 0x20 || r == '<' || r == '>' || r == '&' || r == '\u2028' || r == '\u2029':
+			fmt.Fprintf(&b, `\u%04x`, r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteString(`":`)
+	return b.String()
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestFixtureUpToDate 提交的测试夹具必须与当前生成器的输出一致
+func TestFixtureUpToDate(t *testing.T) {
+	dir := filepath.Join("..", "..", "internal", "gentest")
+	const out = "gentest_jsongroup.go"
+	want, err := os.ReadFile(filepath.Join(dir, out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := generate(dir, []string{"Item", "Owner", "Node", "Empty", "Tagged"}, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("%s is stale, run go generate ./internal/gentest", out)
+	}
+}
+
+func TestGenerateRejectsOmittableEmbed(t *testing.T) {
+	dir := t.TempDir()
+	src := `package p
+
+type Base struct {
+	A int ` + "`json:\"a\"`" + `
+}
+
+type T struct {
+	Base ` + "`json:\",omitempty\"`" + `
+}
+`
+	if err := os.WriteFile(filepath.Join(dir, "p.go"), []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := generate(dir, []string{"T"}, "t_jsongroup.go"); err == nil {
+		t.Error("expected an error for an embedded struct with omitempty")
+	}
+}
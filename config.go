@@ -0,0 +1,396 @@
+package jsongroup
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+)
+
+// configField 声明式配置中的一项，key为配置中的键名，field为设置后记录为显式设置的字段
+type configField struct {
+	key   string
+	field optionField
+	get   func(o *Options) any
+	set   func(o *Options, v any) error
+}
+
+// configFields 可以通过配置设置的选项，编码后端、回调和日志等运行时对象只能在代码中设置
+var configFields = []configField{
+	{"group_mode", fieldGroupMode,
+		func(o *Options) any { return groupModeNames[o.GroupMode] },
+		func(o *Options, v any) (err error) { o.GroupMode, err = configEnum(v, groupModeNames); return }},
+	{"top_level_key", fieldTopLevelKey | fieldTopLevelPath,
+		func(o *Options) any { return o.TopLevelKey },
+		func(o *Options, v any) (err error) { o.TopLevelKey, err = configString(v); return }},
+	{"top_level_path", fieldTopLevelKey | fieldTopLevelPath,
+		func(o *Options) any { return slices.Clone(o.TopLevelPath) },
+		func(o *Options, v any) (err error) { o.TopLevelPath, err = configStrings(v); return }},
+	{"tag_key", fieldTagKey,
+		func(o *Options) any { return o.TagKey },
+		func(o *Options, v any) (err error) { o.TagKey, err = configString(v); return }},
+	{"use_interface_for_nested", fieldUseInterfaceForNested,
+		func(o *Options) any { return o.UseInterfaceForNested },
+		func(o *Options, v any) (err error) { o.UseInterfaceForNested, err = configBool(v); return }},
+	{"null_if_empty", fieldNullIfEmpty,
+		func(o *Options) any { return o.NullIfEmpty },
+		func(o *Options, v any) (err error) { o.NullIfEmpty, err = configBool(v); return }},
+	{"ignore_nil_pointers", fieldIgnoreNilPointers,
+		func(o *Options) any { return o.IgnoreNilPointers },
+		func(o *Options, v any) (err error) { o.IgnoreNilPointers, err = configBool(v); return }},
+	{"max_depth", fieldMaxDepth,
+		func(o *Options) any { return o.MaxDepth },
+		func(o *Options, v any) (err error) { o.MaxDepth, err = configInt(v); return }},
+	{"disable_circular_check", fieldDisableCircularCheck,
+		func(o *Options) any { return o.DisableCircularCheck },
+		func(o *Options, v any) (err error) { o.DisableCircularCheck, err = configBool(v); return }},
+	{"max_cache_size", fieldMaxCacheSize,
+		func(o *Options) any { return o.MaxCacheSize },
+		func(o *Options, v any) (err error) { o.MaxCacheSize, err = configInt(v); return }},
+	{"parallelism", fieldParallelism,
+		func(o *Options) any { return o.Parallelism },
+		func(o *Options, v any) (err error) { o.Parallelism, err = configInt(v); return }},
+	{"error_policy", fieldErrorPolicy,
+		func(o *Options) any { return errorPolicyNames[o.ErrorPolicy] },
+		func(o *Options, v any) (err error) { o.ErrorPolicy, err = configEnum(v, errorPolicyNames); return }},
+	{"best_effort", fieldBestEffort,
+		func(o *Options) any { return o.BestEffort },
+		func(o *Options, v any) (err error) { o.BestEffort, err = configBool(v); return }},
+	{"redact_errors", fieldRedactErrors,
+		func(o *Options) any { return o.RedactErrors },
+		func(o *Options, v any) (err error) { o.RedactErrors, err = configBool(v); return }},
+	{"flatten_nested", fieldFlattenNested,
+		func(o *Options) any { return o.FlattenNested },
+		func(o *Options, v any) (err error) { o.FlattenNested, err = configBool(v); return }},
+	{"collection_items_key", fieldCollection,
+		func(o *Options) any { return o.CollectionItemsKey },
+		func(o *Options, v any) (err error) { o.CollectionItemsKey, err = configString(v); return }},
+	{"collection_count_key", fieldCollection,
+		func(o *Options) any { return o.CollectionCountKey },
+		func(o *Options, v any) (err error) { o.CollectionCountKey, err = configString(v); return }},
+	{"deny_groups", fieldDenyGroups,
+		func(o *Options) any { return slices.Clone(o.DenyGroups) },
+		func(o *Options, v any) (err error) { o.DenyGroups, err = configStrings(v); return }},
+	{"strict_deny_groups", fieldStrictDenyGroups,
+		func(o *Options) any { return o.StrictDenyGroups },
+		func(o *Options, v any) (err error) { o.StrictDenyGroups, err = configBool(v); return }},
+	{"xray", fieldXRay,
+		func(o *Options) any { return o.XRay },
+		func(o *Options, v any) (err error) { o.XRay, err = configBool(v); return }},
+	{"xray_key", fieldXRayKey,
+		func(o *Options) any { return o.XRayKey },
+		func(o *Options, v any) (err error) { o.XRayKey, err = configString(v); return }},
+	{"xray_matched_groups", fieldXRayMatchedGroups,
+		func(o *Options) any { return o.XRayMatchedGroups },
+		func(o *Options, v any) (err error) { o.XRayMatchedGroups, err = configBool(v); return }},
+	{"nan_equal", fieldNaNEqual,
+		func(o *Options) any { return o.NaNEqual },
+		func(o *Options, v any) (err error) { o.NaNEqual, err = configBool(v); return }},
+	{"max_string_len", fieldMaxStringLen,
+		func(o *Options) any { return o.MaxStringLen },
+		func(o *Options, v any) (err error) { o.MaxStringLen, err = configInt(v); return }},
+	{"max_string_marker", fieldMaxStringLen,
+		func(o *Options) any { return o.MaxStringMarker },
+		func(o *Options, v any) (err error) { o.MaxStringMarker, err = configString(v); return }},
+	{"max_slice_len", fieldMaxSliceLen,
+		func(o *Options) any { return o.MaxSliceLen },
+		func(o *Options, v any) (err error) { o.MaxSliceLen, err = configInt(v); return }},
+	{"mark_truncated_slices", fieldMarkTruncatedSlices,
+		func(o *Options) any { return o.MarkTruncatedSlices },
+		func(o *Options, v any) (err error) { o.MarkTruncatedSlices, err = configBool(v); return }},
+	{"canonical_json", fieldCanonicalJSON,
+		func(o *Options) any { return o.CanonicalJSON },
+		func(o *Options, v any) (err error) { o.CanonicalJSON, err = configBool(v); return }},
+	{"invalid_utf8_policy", fieldInvalidUTF8Policy,
+		func(o *Options) any { return invalidUTF8PolicyNames[o.InvalidUTF8Policy] },
+		func(o *Options, v any) (err error) {
+			o.InvalidUTF8Policy, err = configEnum(v, invalidUTF8PolicyNames)
+			return
+		}},
+	{"js_safe_escaping", fieldJSSafeEscaping,
+		func(o *Options) any { return o.JSSafeEscaping },
+		func(o *Options, v any) (err error) { o.JSSafeEscaping, err = configBool(v); return }},
+	{"record_separator", fieldRecordSeparator,
+		func(o *Options) any { return string(o.RecordSeparator) },
+		func(o *Options, v any) error {
+			sep, err := configString(v)
+			o.RecordSeparator = []byte(sep)
+			return err
+		}},
+	{"error_format", fieldErrorFormat,
+		func(o *Options) any { return errorFormatNames[o.ErrorFormat] },
+		func(o *Options, v any) (err error) { o.ErrorFormat, err = configEnum(v, errorFormatNames); return }},
+	{"null_nil_errors", fieldNullNilErrors,
+		func(o *Options) any { return o.NullNilErrors },
+		func(o *Options, v any) (err error) { o.NullNilErrors, err = configBool(v); return }},
+	{"error_chain", fieldErrorChain,
+		func(o *Options) any { return o.ErrorChain },
+		func(o *Options, v any) (err error) { o.ErrorChain, err = configBool(v); return }},
+	{"require_group_tags", fieldRequireGroupTags,
+		func(o *Options) any { return o.RequireGroupTags },
+		func(o *Options, v any) (err error) { o.RequireGroupTags, err = configBool(v); return }},
+	{"cascade_to_untagged", fieldCascadeToUntagged,
+		func(o *Options) any { return o.CascadeToUntagged },
+		func(o *Options, v any) (err error) { o.CascadeToUntagged, err = configBool(v); return }},
+	{"strict_tags", fieldStrictTags,
+		func(o *Options) any { return o.StrictTags },
+		func(o *Options, v any) (err error) { o.StrictTags, err = configBool(v); return }},
+	{"locking", fieldLocking,
+		func(o *Options) any { return o.Locking },
+		func(o *Options, v any) (err error) { o.Locking, err = configBool(v); return }},
+	{"skip_caching_anonymous_types", fieldSkipCachingAnonymousTypes,
+		func(o *Options) any { return o.SkipCachingAnonymousTypes },
+		func(o *Options, v any) (err error) { o.SkipCachingAnonymousTypes, err = configBool(v); return }},
+	{"empty_map_for_nil", fieldEmptyMapForNil,
+		func(o *Options) any { return o.EmptyMapForNil },
+		func(o *Options, v any) (err error) { o.EmptyMapForNil, err = configBool(v); return }},
+	{"scalar_wrapper_key", fieldScalarWrapperKey,
+		func(o *Options) any { return o.ScalarWrapperKey },
+		func(o *Options, v any) (err error) { o.ScalarWrapperKey, err = configString(v); return }},
+	{"strict_map_root", fieldStrictMapRoot,
+		func(o *Options) any { return o.StrictMapRoot },
+		func(o *Options, v any) (err error) { o.StrictMapRoot, err = configBool(v); return }},
+	{"prune_empty", fieldPruneEmpty,
+		func(o *Options) any { return o.PruneEmpty },
+		func(o *Options, v any) (err error) { o.PruneEmpty, err = configBool(v); return }},
+	{"prune_empty_collections", fieldPruneEmptyCollections,
+		func(o *Options) any { return o.PruneEmptyCollections },
+		func(o *Options, v any) (err error) { o.PruneEmptyCollections, err = configBool(v); return }},
+	{"preserve_types", fieldPreserveTypes,
+		func(o *Options) any { return o.PreserveTypes },
+		func(o *Options, v any) (err error) { o.PreserveTypes, err = configBool(v); return }},
+	{"version", fieldVersion,
+		func(o *Options) any { return o.Version },
+		func(o *Options, v any) (err error) { o.Version, err = configString(v); return }},
+	{"field_renames", fieldFieldRenames,
+		func(o *Options) any { return maps.Clone(o.FieldRenames) },
+		func(o *Options, v any) (err error) { o.FieldRenames, err = configStringMap(v); return }},
+	{"strict_field_renames", fieldStrictFieldRenames,
+		func(o *Options) any { return o.StrictFieldRenames },
+		func(o *Options, v any) (err error) { o.StrictFieldRenames, err = configBool(v); return }},
+}
+
+// groupModeNames 配置中分组模式的名称
+var groupModeNames = map[GroupMode]string{
+	GroupModeOr:  "or",
+	GroupModeAnd: "and",
+}
+
+// errorPolicyNames 配置中错误策略的名称
+var errorPolicyNames = map[ErrorPolicy]string{
+	ErrorPolicyFailFast: "fail_fast",
+	ErrorPolicyCollect:  "collect",
+}
+
+// invalidUTF8PolicyNames 配置中无效UTF-8处理策略的名称
+var invalidUTF8PolicyNames = map[InvalidUTF8Policy]string{
+	InvalidUTF8PolicyReplace: "replace",
+	InvalidUTF8PolicyError:   "error",
+}
+
+// errorFormatNames 配置中错误输出形式的名称
+var errorFormatNames = map[ErrorFormat]string{
+	ErrorFormatString:  "string",
+	ErrorFormatObject:  "object",
+	ErrorFormatReflect: "reflect",
+}
+
+// OptionsFromMap 根据声明式配置创建选项，未出现的配置项使用New()的内置默认值
+// 键名为下划线风格的字段名，例如max_depth、null_if_empty；group_mode取"or"或"and"，
+// error_policy取"fail_fast"或"collect"，invalid_utf8_policy取"replace"或"error"，
+// error_format取"string"、"object"或"reflect"，不区分大小写。未知的键、类型不符的值以及Validate发现的问题
+// 一并通过ErrTypeInvalidOptions错误返回。YAML等其他格式的配置解析为map后同样可以使用
+func OptionsFromMap(m map[string]any) (*Options, error) {
+	opts := New()
+	var problems []string
+	for _, key := range slices.Sorted(maps.Keys(m)) {
+		i := slices.IndexFunc(configFields, func(f configField) bool { return f.key == key })
+		if i < 0 {
+			problems = append(problems, fmt.Sprintf("未知的配置项%q", key))
+			continue
+		}
+		if err := configFields[i].set(opts, m[key]); err != nil {
+			problems = append(problems, fmt.Sprintf("配置项%q%v", key, err))
+			continue
+		}
+		opts.set |= configFields[i].field
+	}
+	if len(problems) > 0 {
+		return nil, InvalidOptionsError(problems)
+	}
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	return opts, nil
+}
+
+// OptionsFromJSON 解析JSON对象形式的配置，规则与OptionsFromMap相同
+func OptionsFromJSON(data []byte) (*Options, error) {
+	m, err := decodeConfig(data)
+	if err != nil {
+		return nil, err
+	}
+	return OptionsFromMap(m)
+}
+
+// ProfilesFromJSON 解析按名称分组的多套配置，例如{"dev":{"max_depth":64},"prod":{"null_if_empty":true}}
+// 每套配置分别按OptionsFromMap的规则解析，错误信息中带有配置名
+func ProfilesFromJSON(data []byte) (map[string]*Options, error) {
+	m, err := decodeConfig(data)
+	if err != nil {
+		return nil, err
+	}
+
+	profiles := make(map[string]*Options, len(m))
+	var problems []string
+	for _, name := range slices.Sorted(maps.Keys(m)) {
+		profile, ok := m[name].(map[string]any)
+		if !ok {
+			problems = append(problems, fmt.Sprintf("配置%q需要是对象，实际为%T", name, m[name]))
+			continue
+		}
+		opts, err := OptionsFromMap(profile)
+		if err != nil {
+			// 展开每个问题并加上配置名，避免错误信息中重复的前缀
+			for _, problem := range err.(*Error).Value.([]string) {
+				problems = append(problems, fmt.Sprintf("配置%q: %s", name, problem))
+			}
+			continue
+		}
+		profiles[name] = opts
+	}
+	if len(problems) > 0 {
+		return nil, InvalidOptionsError(problems)
+	}
+	return profiles, nil
+}
+
+// ConfigMap 返回选项的声明式配置形式，结果可直接传给OptionsFromMap得到等价的选项
+// 编码后端、回调和日志等运行时对象不包含在内
+func (o *Options) ConfigMap() map[string]any {
+	if o == nil {
+		o = defaults()
+	}
+	m := make(map[string]any, len(configFields))
+	for _, f := range configFields {
+		m[f.key] = f.get(o)
+	}
+	return m
+}
+
+// decodeConfig 将JSON配置解析为map，数字保留为json.Number以便准确地转换为整数
+func decodeConfig(data []byte) (map[string]any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var m map[string]any
+	if err := dec.Decode(&m); err != nil {
+		return nil, InvalidOptionsError([]string{fmt.Sprintf("配置不是有效的JSON对象: %v", err)})
+	}
+	return m, nil
+}
+
+// configBool 读取布尔配置值
+func configBool(v any) (bool, error) {
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("需要布尔值，实际为%T", v)
+	}
+	return b, nil
+}
+
+// configString 读取字符串配置值
+func configString(v any) (string, error) {
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("需要字符串，实际为%T", v)
+	}
+	return s, nil
+}
+
+// configStrings 读取字符串列表配置值，null表示空列表
+func configStrings(v any) ([]string, error) {
+	switch v := v.(type) {
+	case nil:
+		return nil, nil
+	case []string:
+		return slices.Clone(v), nil
+	case []any:
+		s := make([]string, len(v))
+		for i, item := range v {
+			str, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("第%d个元素需要字符串，实际为%T", i, item)
+			}
+			s[i] = str
+		}
+		return s, nil
+	}
+	return nil, fmt.Errorf("需要字符串列表，实际为%T", v)
+}
+
+// configStringMap 读取值为字符串的对象配置值，null表示空映射
+func configStringMap(v any) (map[string]string, error) {
+	switch v := v.(type) {
+	case nil:
+		return nil, nil
+	case map[string]string:
+		return maps.Clone(v), nil
+	case map[string]any:
+		m := make(map[string]string, len(v))
+		for k, item := range v {
+			str, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("键%s的值需要字符串，实际为%T", k, item)
+			}
+			m[k] = str
+		}
+		return m, nil
+	}
+	return nil, fmt.Errorf("需要值为字符串的对象，实际为%T", v)
+}
+
+// configInt 读取整数配置值，接受Go整数、没有小数部分的浮点数和json.Number
+func configInt(v any) (int, error) {
+	switch v := v.(type) {
+	case int:
+		return v, nil
+	case int64:
+		if v >= math.MinInt && v <= math.MaxInt {
+			return int(v), nil
+		}
+	case float64:
+		if v == math.Trunc(v) && v >= math.MinInt && v <= math.MaxInt {
+			return int(v), nil
+		}
+	case json.Number:
+		if n, err := v.Int64(); err == nil && n >= math.MinInt && n <= math.MaxInt {
+			return int(n), nil
+		}
+	default:
+		return 0, fmt.Errorf("需要整数，实际为%T", v)
+	}
+	return 0, fmt.Errorf("需要整数，实际为%v", v)
+}
+
+// configEnum 按名称读取枚举配置值，不区分大小写
+func configEnum[T comparable](v any, names map[T]string) (T, error) {
+	var zero T
+	s, ok := v.(string)
+	if !ok {
+		return zero, fmt.Errorf("需要字符串，实际为%T", v)
+	}
+	for value, name := range names {
+		if strings.EqualFold(s, name) {
+			return value, nil
+		}
+	}
+	valid := make([]string, 0, len(names))
+	for _, name := range names {
+		valid = append(valid, fmt.Sprintf("%q", name))
+	}
+	slices.Sort(valid)
+	return zero, fmt.Errorf("的值%q无效，可选值为%s", s, strings.Join(valid, "、"))
+}
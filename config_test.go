@@ -0,0 +1,168 @@
+package jsongroup
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// configProblems 返回配置错误中列出的问题，err不是选项无效错误时终止测试
+func configProblems(t *testing.T, err error) []string {
+	t.Helper()
+	var jerr *Error
+	if !errors.Is(err, ErrInvalidOptions) || !errors.As(err, &jerr) {
+		t.Fatalf("got %v, want ErrInvalidOptions", err)
+	}
+	return jerr.Value.([]string)
+}
+
+func TestConfigMapRoundTrip(t *testing.T) {
+	opts := New().
+		WithGroupMode(GroupModeAnd).
+		WithTopLevelPath("data", "item").
+		WithTagKey("roles").
+		WithNullIfEmpty(true).
+		WithMaxDepth(8).
+		WithErrorPolicy(ErrorPolicyCollect).
+		WithRedactedErrors(false).
+		WithDenyGroups("secret").
+		WithMaxStringLen(16, "...").
+		WithInvalidUTF8Policy(InvalidUTF8PolicyError).
+		WithRecordSeparator([]byte("\n")).
+		WithErrorFormat(ErrorFormatObject).
+		WithFieldRenames(map[string]string{"id": "ID"})
+	want := opts.ConfigMap()
+
+	fromMap, err := OptionsFromMap(want)
+	if err != nil {
+		t.Fatalf("OptionsFromMap: %v", err)
+	}
+	if got := fromMap.ConfigMap(); !reflect.DeepEqual(got, want) {
+		t.Errorf("OptionsFromMap round trip:\ngot  %v\nwant %v", got, want)
+	}
+
+	// 经过JSON编码后数字变为json.Number、列表变为[]any，解析结果应当相同
+	data, err := json.Marshal(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromJSON, err := OptionsFromJSON(data)
+	if err != nil {
+		t.Fatalf("OptionsFromJSON: %v", err)
+	}
+	if got := fromJSON.ConfigMap(); !reflect.DeepEqual(got, want) {
+		t.Errorf("OptionsFromJSON round trip:\ngot  %v\nwant %v", got, want)
+	}
+
+	u := newComplexUser(1)
+	if a, b := marshalString(t, u, opts, "public"), marshalString(t, u, fromJSON, "public"); a != b {
+		t.Errorf("options from config marshal differently:\n%s\n%s", b, a)
+	}
+}
+
+func TestConfigDefaultsFilled(t *testing.T) {
+	opts, err := OptionsFromJSON([]byte(`{"flatten_nested":true}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := New().WithFlattenNested(true).ConfigMap()
+	if got := opts.ConfigMap(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got  %v\nwant %v", got, want)
+	}
+	if opts.TagKey != DefaultTagKey || opts.MaxDepth != DefaultMaxDepth || !opts.RedactErrors {
+		t.Errorf("defaults not filled: TagKey %q, MaxDepth %d, RedactErrors %v", opts.TagKey, opts.MaxDepth, opts.RedactErrors)
+	}
+
+	// 配置中出现的项记为显式设置，合并时覆盖基础选项
+	merged := New().WithFlattenNested(false).WithMaxDepth(4).Merge(opts)
+	if !merged.FlattenNested || merged.MaxDepth != 4 {
+		t.Errorf("Merge: FlattenNested %v, MaxDepth %d; want true, 4", merged.FlattenNested, merged.MaxDepth)
+	}
+}
+
+func TestConfigEnumParsing(t *testing.T) {
+	for _, tc := range []struct {
+		value string
+		want  GroupMode
+	}{
+		{"or", GroupModeOr},
+		{"and", GroupModeAnd},
+		{"AND", GroupModeAnd},
+		{"Or", GroupModeOr},
+	} {
+		opts, err := OptionsFromMap(map[string]any{"group_mode": tc.value})
+		if err != nil {
+			t.Errorf("group_mode %q: %v", tc.value, err)
+			continue
+		}
+		if opts.GroupMode != tc.want {
+			t.Errorf("group_mode %q: got %v, want %v", tc.value, opts.GroupMode, tc.want)
+		}
+	}
+
+	opts, err := OptionsFromMap(map[string]any{"error_policy": "collect", "invalid_utf8_policy": "error", "error_format": "reflect"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.ErrorPolicy != ErrorPolicyCollect || opts.InvalidUTF8Policy != InvalidUTF8PolicyError || opts.ErrorFormat != ErrorFormatReflect {
+		t.Errorf("got %v, %v, %v", opts.ErrorPolicy, opts.InvalidUTF8Policy, opts.ErrorFormat)
+	}
+
+	_, err = OptionsFromMap(map[string]any{"group_mode": "xor"})
+	problems := configProblems(t, err)
+	if len(problems) != 1 || !strings.Contains(problems[0], `"and"`) || !strings.Contains(problems[0], `"or"`) {
+		t.Errorf("problems = %q; want the valid group modes listed", problems)
+	}
+}
+
+func TestConfigRejectsInvalidEntries(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		config string
+		want   []string
+	}{
+		{"unknown key", `{"max_dept":8}`, []string{`"max_dept"`}},
+		{"wrong type", `{"null_if_empty":"yes","max_depth":1.5}`, []string{`"max_depth"`, `"null_if_empty"`}},
+		{"bad list element", `{"deny_groups":["a",1]}`, []string{`"deny_groups"`}},
+		{"not an object", `[1,2]`, []string{"JSON"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			opts, err := OptionsFromJSON([]byte(tc.config))
+			if opts != nil {
+				t.Error("got options along with an error")
+			}
+			problems := configProblems(t, err)
+			if len(problems) != len(tc.want) {
+				t.Fatalf("problems = %q, want %d", problems, len(tc.want))
+			}
+			for i, want := range tc.want {
+				if !strings.Contains(problems[i], want) {
+					t.Errorf("problem %d = %q, want it to mention %s", i, problems[i], want)
+				}
+			}
+		})
+	}
+
+	// 各项单独合法时仍然经过Validate检查
+	if _, err := OptionsFromMap(map[string]any{"max_depth": 0, "disable_circular_check": true}); err == nil {
+		t.Error("conflicting options passed validation")
+	}
+}
+
+func TestProfilesFromJSON(t *testing.T) {
+	profiles, err := ProfilesFromJSON([]byte(`{"dev":{"max_depth":64},"prod":{"null_if_empty":true}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(profiles) != 2 || profiles["dev"].MaxDepth != 64 || !profiles["prod"].NullIfEmpty || profiles["prod"].MaxDepth != DefaultMaxDepth {
+		t.Errorf("profiles = %+v", profiles)
+	}
+
+	_, err = ProfilesFromJSON([]byte(`{"dev":{"max_dept":64},"prod":true,"qa":{}}`))
+	problems := configProblems(t, err)
+	if len(problems) != 2 || !strings.Contains(problems[0], `"dev"`) || !strings.Contains(problems[1], `"prod"`) {
+		t.Errorf("problems = %q; want one per broken profile, prefixed with its name", problems)
+	}
+}
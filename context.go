@@ -0,0 +1,43 @@
+package jsongroup
+
+import (
+	"context"
+	"slices"
+)
+
+// groupsContextKey 上下文中保存分组列表的键
+type groupsContextKey struct{}
+
+// ContextWithGroups 返回携带分组列表的上下文
+// 分组列表会被复制，之后修改传入的切片不影响上下文，返回的上下文可以在多个协程中使用
+func ContextWithGroups(ctx context.Context, groups ...string) context.Context {
+	return context.WithValue(ctx, groupsContextKey{}, slices.Clone(groups))
+}
+
+// GroupsFromContext 返回ContextWithGroups或GroupsFromRequest保存在上下文中的分组列表的副本，没有时返回nil
+func GroupsFromContext(ctx context.Context) []string {
+	return slices.Clone(contextGroups(ctx))
+}
+
+// contextGroups 返回上下文中保存的分组列表，调用方不得修改
+func contextGroups(ctx context.Context) []string {
+	groups, _ := ctx.Value(groupsContextKey{}).([]string)
+	return groups
+}
+
+// MarshalByGroupsContext 与MarshalByGroupsWithOptions相同，未指定分组时使用ctx中保存的分组
+// 显式传入的分组总是优先；两者都没有时与不指定分组一样输出所有字段
+func MarshalByGroupsContext(ctx context.Context, v any, opts *Options, groups ...string) ([]byte, error) {
+	if len(groups) == 0 {
+		groups = contextGroups(ctx)
+	}
+	return MarshalByGroupsWithOptions(v, opts, groups...)
+}
+
+// MarshalToMapContext 与MarshalToMapWithOptions相同，未指定分组时使用ctx中保存的分组
+func MarshalToMapContext(ctx context.Context, v any, opts *Options, groups ...string) (map[string]any, error) {
+	if len(groups) == 0 {
+		groups = contextGroups(ctx)
+	}
+	return MarshalToMapWithOptions(v, opts, groups...)
+}
@@ -0,0 +1,437 @@
+package jsongroup
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// csvColumn CSV表头中的一列，有子列时展开为多列
+// 结构体字段的子列按声明顺序排列，map键的子列按键排序
+type csvColumn struct {
+	name     string
+	children []*csvColumn
+	byName   map[string]*csvColumn
+	// scalar 是否写入过标量值，同一列不能既有标量值又有展开的子列
+	scalar bool
+}
+
+// child 返回名为name的子列，不存在时创建；sorted为true时按名称有序插入
+func (c *csvColumn) child(name string, sorted bool) *csvColumn {
+	if col, ok := c.byName[name]; ok {
+		return col
+	}
+	col := &csvColumn{name: name}
+	if c.byName == nil {
+		c.byName = make(map[string]*csvColumn)
+	}
+	c.byName[name] = col
+	if sorted {
+		i, _ := slices.BinarySearchFunc(c.children, name, func(a *csvColumn, name string) int {
+			return strings.Compare(a.name, name)
+		})
+		c.children = slices.Insert(c.children, i, col)
+	} else {
+		c.children = append(c.children, col)
+	}
+	return col
+}
+
+// appendLeaves 按顺序收集实际输出的列及其表头，展开的子列以"."连接父列名
+func (c *csvColumn) appendLeaves(prefix string, leaves []*csvColumn, header []string) ([]*csvColumn, []string) {
+	for _, col := range c.children {
+		name := prefix + col.name
+		if len(col.children) == 0 {
+			leaves = append(leaves, col)
+			header = append(header, name)
+			continue
+		}
+		leaves, header = col.appendLeaves(name+".", leaves, header)
+	}
+	return leaves, header
+}
+
+// csvWriter 将结构体切片按分组写为CSV
+type csvWriter struct {
+	opts     *Options
+	groups   []string
+	groupKey string
+	root     csvColumn
+}
+
+// WriteCSV 按指定分组将结构体切片rows写为CSV，第一行为表头
+// 表头由过滤后字段的json名称按声明顺序组成，标量的格式与JSON输出一致：字符串不加引号，
+// 布尔值为true/false，time.Time为RFC3339格式；nil指针、被省略的字段和空值输出为空单元格。
+// 嵌套的结构体和map默认返回错误，启用WithFlattenNested后展开为"addr.city"形式的多列，
+// 此时map的键可能因行而异，所有行处理完后才写出；切片和数组无法表示为单元格，始终返回错误。
+// 引号和单元格内的换行按encoding/csv的规则处理，opts为nil时使用默认选项
+func WriteCSV(w io.Writer, rows any, opts *Options, groups ...string) (err error) {
+	if opts == nil {
+		opts = defaults()
+	}
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	groups, err = opts.resolveGroups(groups)
+	if err != nil {
+		return err
+	}
+
+	// 捕获可能的panic并转换为错误
+	defer func() {
+		if r := recover(); r != nil {
+			err = panicError("Root", r)
+		}
+	}()
+
+	rv := reflect.ValueOf(rows)
+	for rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return UnsupportedTypeError("Root", rv)
+	}
+	et := rv.Type().Elem()
+	for et.Kind() == reflect.Pointer {
+		et = et.Elem()
+	}
+	if et.Kind() != reflect.Struct || et == timeType {
+		return UnsupportedTypeError("Root", rv.Type().String())
+	}
+
+	// 复制选项，避免修改调用方的配置
+	o := *opts
+	o.fillDefaults()
+	cw := &csvWriter{opts: &o, groups: groups, groupKey: filterGroupKey(groups, o.DenyGroups)}
+	if err := cw.addStructColumns(&cw.root, et, "", []reflect.Type{et}, false); err != nil {
+		return err
+	}
+
+	out := csv.NewWriter(w)
+	var leaves []*csvColumn
+	var header []string
+	if !o.FlattenNested {
+		// 不展开时列在类型确定后就已固定，逐行写出
+		leaves, header = cw.root.appendLeaves("", nil, nil)
+		if err := out.Write(header); err != nil {
+			return err
+		}
+	}
+
+	var buffered []map[*csvColumn]string
+	for i := range rv.Len() {
+		cells, err := cw.row(i, rv.Index(i))
+		if err != nil {
+			return err
+		}
+		if o.FlattenNested {
+			buffered = append(buffered, cells)
+			continue
+		}
+		if err := out.Write(csvRecord(leaves, cells)); err != nil {
+			return err
+		}
+	}
+
+	if o.FlattenNested {
+		leaves, header = cw.root.appendLeaves("", nil, nil)
+		if err := out.Write(header); err != nil {
+			return err
+		}
+		for _, cells := range buffered {
+			if err := out.Write(csvRecord(leaves, cells)); err != nil {
+				return err
+			}
+		}
+	}
+
+	out.Flush()
+	return out.Error()
+}
+
+// csvRecord 按列顺序取出一行的单元格，没有值的列为空字符串
+func csvRecord(leaves []*csvColumn, cells map[*csvColumn]string) []string {
+	record := make([]string, len(leaves))
+	for i, col := range leaves {
+		record[i] = cells[col]
+	}
+	return record
+}
+
+// addStructColumns 按分组过滤结构体字段并建立对应的列，内嵌的匿名结构体字段合并到当前层级
+// 未启用展开时在此处拒绝嵌套类型；seen为正在展开的结构体类型，递归类型只展开一层，更深的列由实际的值建立。
+// inherited与序列化上下文中的含义相同，使列与writeStruct实际写入的字段一致
+func (w *csvWriter) addStructColumns(col *csvColumn, t reflect.Type, path string, seen []reflect.Type, inherited bool) error {
+	if err := checkGroupTags(w.opts, t, path); err != nil {
+		return err
+	}
+	set, err := getFilteredFields(t, w.opts, w.groups, w.groupKey, w.opts.GroupMode, inherited)
+	if err != nil {
+		return ReflectionError(path, err)
+	}
+
+	for _, field := range set.fields {
+		ft := t.FieldByIndex(field.Index).Type
+		fieldPath := joinSchemaPath(path, field.Name)
+
+		if field.Anonymous && ft.Kind() == reflect.Struct {
+			if err := w.addStructColumns(col, ft, fieldPath, seen, inherited); err != nil {
+				return err
+			}
+			continue
+		}
+
+		child := col.child(field.JSONName, false)
+		for ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		// 实现了encoding.BinaryMarshaler的类型输出为base64字符串，占一列
+		if binaryImplOf(ft) != implNone {
+			continue
+		}
+		switch ft.Kind() {
+		case reflect.Struct:
+			if ft == timeType {
+				continue
+			}
+			if !w.opts.FlattenNested {
+				return nestedValueError(fieldPath, ft.String())
+			}
+			if slices.Contains(seen, ft) {
+				continue
+			}
+			if err := w.addStructColumns(child, ft, fieldPath, append(seen, ft), inherited || (w.opts.CascadeToUntagged && len(field.Groups) > 0)); err != nil {
+				return err
+			}
+		case reflect.Map:
+			if !w.opts.FlattenNested {
+				return nestedValueError(fieldPath, ft.String())
+			}
+		case reflect.Slice, reflect.Array:
+			return listValueError(fieldPath, ft.String())
+		}
+	}
+	return nil
+}
+
+// row 处理第i行，返回各列的单元格
+// 每行使用独立的上下文，不同行引用同一个指针不视为循环引用
+func (w *csvWriter) row(i int, v reflect.Value) (map[*csvColumn]string, error) {
+	ctx := newContext(*w.opts, w.groups)
+	defer ctx.release()
+
+	cells := make(map[*csvColumn]string)
+	if err := w.writeValue(ctx.withIndex(i), &w.root, v, cells); err != nil {
+		return nil, err
+	}
+	return cells, nil
+}
+
+// writeValue 将v写入col对应的单元格，展开的结构体和map写入子列
+// 深度限制和循环引用检测与JSON输出一致
+func (w *csvWriter) writeValue(ctx *serializeContext, col *csvColumn, v reflect.Value, cells map[*csvColumn]string) error {
+	if s, ok := csvScalar(v); ok {
+		return w.setCell(ctx, col, s, cells)
+	}
+
+	kind := v.Kind()
+	if (kind == reflect.Pointer || kind == reflect.Interface) && v.IsNil() {
+		return nil
+	}
+
+	if err := ctx.enterLevel(); err != nil {
+		return err
+	}
+	defer ctx.leaveLevel()
+
+	if kind == reflect.Pointer || kind == reflect.Map {
+		if err := ctx.checkPointer(v); err != nil {
+			return err
+		}
+	}
+
+	// 错误按ErrorFormat转换，字符串形式写入单元格，对象形式需要展开
+	if rep, ok := ctx.errorValue(v); ok {
+		return w.writeValue(ctx, col, rep, cells)
+	}
+	if rep, ok, err := ctx.binaryValue(v); err != nil {
+		return err
+	} else if ok {
+		return w.writeValue(ctx, col, rep, cells)
+	}
+
+	switch kind {
+	case reflect.Pointer, reflect.Interface:
+		return w.writeValue(ctx.withPath(""), col, v.Elem(), cells)
+
+	case reflect.Struct:
+		if v.Type() == timeType {
+			t := timeOf(v)
+			if t.IsZero() && ctx.opts.NullIfEmpty {
+				return nil
+			}
+			s, err := timeText(ctx, t)
+			if err != nil {
+				return err
+			}
+			return w.setCell(ctx, col, s, cells)
+		}
+		// 根列对应行本身，总是按字段展开
+		if !ctx.opts.FlattenNested && col != &w.root {
+			return nestedValueError(ctx.path(), v.Type().String())
+		}
+		if col.scalar {
+			return mixedColumnError(ctx.path())
+		}
+		return w.writeStruct(ctx, col, ctx.lockedSnapshot(v), cells)
+
+	case reflect.Map:
+		if !ctx.opts.FlattenNested {
+			return nestedValueError(ctx.path(), v.Type().String())
+		}
+		if col.scalar {
+			return mixedColumnError(ctx.path())
+		}
+		iter := v.MapRange()
+		for iter.Next() {
+			key := mapKeyString(iter.Key())
+			if err := w.writeValue(ctx.withPath(key), col.child(key, true), iter.Value(), cells); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case reflect.Slice, reflect.Array:
+		return listValueError(ctx.path(), v.Type().String())
+
+	default:
+		return UnsupportedTypeError(ctx.path(), v.Type().String())
+	}
+}
+
+// writeStruct 按字段写入结构体，字段的省略规则与JSON输出一致，被省略的字段对应空单元格
+func (w *csvWriter) writeStruct(ctx *serializeContext, col *csvColumn, v reflect.Value, cells map[*csvColumn]string) error {
+	if err := checkGroupTags(ctx.opts, v.Type(), ctx.path()); err != nil {
+		return err
+	}
+	set, err := getFilteredFields(v.Type(), ctx.opts, w.groups, ctx.groupKey, ctx.opts.GroupMode, ctx.inherited)
+	if err != nil {
+		return ReflectionError(ctx.path(), err)
+	}
+
+	var embeds embedOmitter
+	for _, field := range set.fields {
+		if len(field.Embeds) > 0 && embeds.omits(v, set, field) {
+			continue
+		}
+		fieldValue := v.FieldByIndex(field.Index)
+		fieldCtx := ctx.withField(field)
+		if ctx.opts.ValueFilter != nil && !fieldCtx.keepsField(v, field, fieldValue) {
+			continue
+		}
+		if ctx.opts.FieldHook != nil {
+			if fieldValue, _, err = fieldCtx.callFieldHook(v, field, fieldValue); err != nil {
+				return err
+			}
+		}
+
+		// 内嵌匿名字段的列合并到当前层级
+		if field.Anonymous && fieldValue.Kind() == reflect.Struct {
+			fieldCtx.inherited = ctx.inherited
+			if err := w.writeStruct(fieldCtx, col, fieldValue, cells); err != nil {
+				return err
+			}
+			continue
+		}
+
+		child := col.child(field.JSONName, false)
+		isNilOrEmpty := (fieldValue.Kind() == reflect.Pointer && fieldValue.IsNil()) || isEmptyValue(fieldValue)
+		if ((field.OmitEmpty || ctx.opts.NullIfEmpty) && isNilOrEmpty) ||
+			(field.OmitZero && isZeroValue(fieldValue)) {
+			continue
+		}
+		if err := w.writeValue(fieldCtx, child, fieldValue, cells); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setCell 写入标量单元格
+func (w *csvWriter) setCell(ctx *serializeContext, col *csvColumn, s string, cells map[*csvColumn]string) error {
+	if len(col.children) > 0 {
+		return mixedColumnError(ctx.path())
+	}
+	col.scalar = true
+	cells[col] = s
+	return nil
+}
+
+// csvScalar 将基本类型的值格式化为单元格内容，格式与JSON输出一致但字符串不加引号
+// 其他类型返回false
+func csvScalar(v reflect.Value) (string, bool) {
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), true
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(v.Uint(), 10), true
+	case reflect.Float32, reflect.Float64:
+		f := v.Float()
+		if isSpecialFloat(f) {
+			return floatToString(f), true
+		}
+		return string(appendJSONFloat(nil, f, floatBits(v))), true
+	case reflect.Complex64, reflect.Complex128:
+		return complex128ToString(v.Complex()), true
+	}
+	return "", false
+}
+
+// timeText 将time.Time格式化为与JSON输出一致的RFC3339文本，不带引号
+func timeText(ctx *serializeContext, t time.Time) (string, error) {
+	b, err := t.AppendText(nil)
+	if err != nil {
+		// 通过MarshalJSON获取与json.Marshal一致的错误信息
+		_, err = t.MarshalJSON()
+		return "", ReflectionError(ctx.path(), err)
+	}
+	return string(b), nil
+}
+
+// nestedValueError 创建未启用展开时遇到嵌套值的错误
+func nestedValueError(path, typeName string) *Error {
+	return &Error{
+		Type:    ErrTypeUnsupportedType,
+		Message: fmt.Sprintf("CSV不支持嵌套值: %s，可通过WithFlattenNested展开为多列", typeName),
+		Path:    path,
+		Value:   typeName,
+	}
+}
+
+// listValueError 创建遇到切片或数组的错误
+func listValueError(path, typeName string) *Error {
+	return &Error{
+		Type:    ErrTypeUnsupportedType,
+		Message: fmt.Sprintf("CSV不支持切片和数组: %s", typeName),
+		Path:    path,
+		Value:   typeName,
+	}
+}
+
+// mixedColumnError 创建同一列在不同行中既是标量又需要展开的错误
+func mixedColumnError(path string) *Error {
+	return &Error{
+		Type:    ErrTypeUnsupportedType,
+		Message: "CSV的同一列不能既包含标量值又包含嵌套值",
+		Path:    path,
+	}
+}
@@ -0,0 +1,121 @@
+package jsongroup
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+// CSVBase 内嵌的结构体需要导出，非导出类型的内嵌字段不输出
+type CSVBase struct {
+	ID int64 `json:"id" groups:"public,admin"`
+}
+
+type csvRecordRow struct {
+	CSVBase
+	Name    string            `json:"name" groups:"public,admin"`
+	Email   string            `json:"email" groups:"admin"`
+	Score   float64           `json:"score" groups:"public"`
+	Active  bool              `json:"active" groups:"public"`
+	Joined  time.Time         `json:"joined" groups:"admin"`
+	Nick    string            `json:"nick,omitempty" groups:"public"`
+	Address *Address          `json:"address" groups:"public,admin"`
+	Attrs   map[string]string `json:"attrs" groups:"admin"`
+}
+
+func newCSVRows() []csvRecordRow {
+	return []csvRecordRow{
+		{
+			CSVBase: CSVBase{ID: 1}, Name: "Alice, \"A\"", Email: "alice@example.com", Score: 0.5, Active: true,
+			Joined:  time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+			Address: &Address{Street: "1 Main St", City: "Springfield", Zip: "100"},
+			Attrs:   map[string]string{"tier": "gold"},
+		},
+		{CSVBase: CSVBase{ID: 2}, Name: "Bob\nJr", Nick: "b", Score: math.Inf(1), Attrs: map[string]string{"lang": "zh"}},
+	}
+}
+
+// csvString 写出CSV并在出错时终止测试
+func csvString(t *testing.T, rows any, opts *Options, groups ...string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, rows, opts, groups...); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestWriteCSV(t *testing.T) {
+	rows := newCSVRows()
+	for _, tc := range []struct {
+		name   string
+		rows   any
+		opts   *Options
+		groups []string
+		want   string
+	}{
+		// 表头按声明顺序，内嵌结构体的列合并到当前层级；nil指针和omitempty省略的字段为空单元格
+		{"flatten public", rows, New().WithFlattenNested(true), []string{"public"},
+			"id,name,score,active,nick,address.street,address.city\n" +
+				"1,\"Alice, \"\"A\"\"\",0.5,true,,1 Main St,Springfield\n" +
+				"2,\"Bob\nJr\",Infinity,false,b,,\n"},
+		// map的键在所有行中取并集并排序，admin分组多出zip、email和joined
+		{"flatten admin", rows, New().WithFlattenNested(true), []string{"admin"},
+			"id,name,email,joined,address.street,address.city,address.zip,attrs.lang,attrs.tier\n" +
+				"1,\"Alice, \"\"A\"\"\",alice@example.com,2024-01-02T03:04:05Z,1 Main St,Springfield,100,,gold\n" +
+				"2,\"Bob\nJr\",,0001-01-01T00:00:00Z,,,,zh,\n"},
+		{"pointer elements", []*csvRecordRow{&rows[1], nil}, New().WithFlattenNested(true), []string{"public"},
+			"id,name,score,active,nick,address.street,address.city\n" +
+				"2,\"Bob\nJr\",Infinity,false,b,,\n" +
+				",,,,,,\n"},
+		// NullIfEmpty时空值同样为空单元格
+		{"null if empty", []csvRecordRow{{}}, New().WithFlattenNested(true).WithNullIfEmpty(true), []string{"public"},
+			"id,name,score,active,nick,address.street,address.city\n,,,,,,\n"},
+		{"flat rows", []Address{{Street: "s", City: "c", Zip: "z"}, {}}, nil, []string{"admin"},
+			"street,city,zip\ns,c,z\n,,\n"},
+		{"pointer to array", &[1]Address{{City: "c"}}, nil, []string{"public"}, "street,city\n,c\n"},
+		{"no rows", []csvRecordRow{}, New().WithFlattenNested(true), []string{"public"},
+			"id,name,score,active,nick,address.street,address.city\n"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := csvString(t, tc.rows, tc.opts, tc.groups...); got != tc.want {
+				t.Errorf("got\n%s\nwant\n%s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestWriteCSVErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		rows any
+		opts *Options
+		path string
+	}{
+		// 未启用展开时，类型中声明的嵌套字段在写出任何数据之前就返回错误
+		{"nested without flatten", newCSVRows(), New(), "Address"},
+		{"slice field", []ComplexUser{newComplexUser(1)}, New().WithFlattenNested(true), "Tags"},
+		{"not a slice", csvRecordRow{}, New(), "Root"},
+		{"slice of scalars", []int{1}, New(), "Root"},
+		{"nested interface", []struct {
+			V any `json:"v" groups:"public"`
+		}{{V: Address{}}}, New(), "[0].V"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := WriteCSV(&buf, tc.rows, tc.opts, "public")
+			var jerr *Error
+			if !errors.Is(err, ErrUnsupportedType) || !errors.As(err, &jerr) {
+				t.Fatalf("got %v, want ErrUnsupportedType", err)
+			}
+			if jerr.Path != tc.path {
+				t.Errorf("path = %q, want %q", jerr.Path, tc.path)
+			}
+			if buf.Len() != 0 && tc.path != "[0].V" {
+				t.Errorf("wrote %q before failing", buf.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package jsongroup
+
+import (
+	"reflect"
+	"sync"
+)
+
+// DeprecatedTagKey 标记已弃用字段的标签键，标签值为说明，如`deprecated:"use new_field"`
+const DeprecatedTagKey = "deprecated"
+
+// DeprecationHook 已弃用的字段实际输出时调用，typeName为结构体类型名，note为deprecated标签的值
+// 在序列化所在的协程中同步调用，不同字段的回调可能并发发生
+type DeprecationHook func(typeName, jsonName, note string)
+
+// deprecation 字段deprecated标签的说明及上报状态，随字段信息缓存
+// 内嵌结构体提升的字段与内嵌类型共享同一个状态
+type deprecation struct {
+	note string
+	once sync.Once
+}
+
+// parseDeprecation 解析字段的deprecated标签，未声明时返回nil
+func parseDeprecation(tag reflect.StructTag) *deprecation {
+	note, ok := tag.Lookup(DeprecatedTagKey)
+	if !ok {
+		return nil
+	}
+	return &deprecation{note: note}
+}
+
+// reportDeprecated 已弃用的字段被输出时调用DeprecationHook，每个字段只调用一次
+// 去重状态保存在缓存的字段信息中，序列化百万行数据也只回调一次；类型的字段信息被淘汰或清除缓存后会再次回调
+func (ctx *serializeContext) reportDeprecated(t reflect.Type, field fieldInfo) {
+	h := ctx.opts.DeprecationHook
+	if h == nil || field.Deprecation == nil {
+		return
+	}
+	field.Deprecation.once.Do(func() {
+		h(t.String(), field.JSONName, field.Deprecation.note)
+	})
+}
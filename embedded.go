@@ -0,0 +1,93 @@
+package jsongroup
+
+import (
+	"reflect"
+	"slices"
+)
+
+// embedding 声明了omitempty或omitzero的内嵌结构体，其字段展开到外层后通过fieldInfo.Embeds引用
+type embedding struct {
+	// 内嵌字段在外层结构体中的索引路径
+	index []int
+	// 内嵌字段的json标签是否声明了omitempty、omitzero
+	omitEmpty bool
+	omitZero  bool
+}
+
+// rebaseEmbeds 返回外层第i个字段展开后的字段所在的内嵌层级：outer（可以为nil）在前，随后是按外层索引路径重新创建的nested
+// rebased记录已重新创建的embedding，同一内嵌结构体提升的字段仍共同引用同一个embedding
+func rebaseEmbeds(outer *embedding, i int, nested []*embedding, rebased map[*embedding]*embedding) []*embedding {
+	if outer == nil && len(nested) == 0 {
+		return nil
+	}
+	embeds := make([]*embedding, 0, len(nested)+1)
+	if outer != nil {
+		embeds = append(embeds, outer)
+	}
+	for _, n := range nested {
+		r, ok := rebased[n]
+		if !ok {
+			r = &embedding{index: append([]int{i}, n.index...), omitEmpty: n.omitEmpty, omitZero: n.omitZero}
+			rebased[n] = r
+		}
+		embeds = append(embeds, r)
+	}
+	return embeds
+}
+
+// embedOmitter 记录一次结构体序列化中各内嵌结构体是否被省略，每个内嵌结构体只判断一次；零值可直接使用
+type embedOmitter struct {
+	decided map[*embedding]bool
+}
+
+// omits 判断字段是否因提升它的内嵌结构体按omitempty或omitzero被省略而不输出，v为外层结构体，set为当前分组下的字段集合
+// omitempty在该内嵌结构体提升到set中的字段全部为空值时省略，omitzero在内嵌结构体的值为零值时省略；
+// 启用NullIfEmpty时调用方不应省略，与普通字段的omitempty一致
+func (o *embedOmitter) omits(v reflect.Value, set *fieldSet, field fieldInfo) bool {
+	for _, emb := range field.Embeds {
+		omitted, ok := o.decided[emb]
+		if !ok {
+			omitted = emb.omitted(v, set)
+			if o.decided == nil {
+				o.decided = make(map[*embedding]bool)
+			}
+			o.decided[emb] = omitted
+		}
+		if omitted {
+			return true
+		}
+	}
+	return false
+}
+
+// omitted 判断v中的该内嵌结构体是否应当省略
+func (e *embedding) omitted(v reflect.Value, set *fieldSet) bool {
+	if e.omitZero && isZeroEmbedded(v.FieldByIndex(e.index)) {
+		return true
+	}
+	if !e.omitEmpty {
+		return false
+	}
+	for _, f := range set.fields {
+		if slices.Contains(f.Embeds, e) && !isEmptyValue(v.FieldByIndex(f.Index)) {
+			return false
+		}
+	}
+	return true
+}
+
+// isZeroEmbedded 按omitzero的规则判断内嵌结构体是否为零值：实现了IsZero() bool时调用该方法，否则所有字段均为零值
+func isZeroEmbedded(v reflect.Value) bool {
+	if !v.CanInterface() {
+		return v.IsZero()
+	}
+	if z, ok := v.Interface().(interface{ IsZero() bool }); ok {
+		return z.IsZero()
+	}
+	if v.CanAddr() {
+		if z, ok := v.Addr().Interface().(interface{ IsZero() bool }); ok {
+			return z.IsZero()
+		}
+	}
+	return v.IsZero()
+}
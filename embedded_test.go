@@ -0,0 +1,155 @@
+package jsongroup
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// EmbedCore 用于内嵌的结构体，需要导出：非导出类型的内嵌字段与其他非导出字段一样不输出
+type EmbedCore struct {
+	Name string `json:"name" groups:"public"`
+	Note string `json:"note" groups:"admin"`
+}
+
+// EmbedWindow 实现IsZero，Start与End相同即为零值
+type EmbedWindow struct {
+	Start int `json:"start" groups:"public"`
+	End   int `json:"end" groups:"public"`
+}
+
+func (w EmbedWindow) IsZero() bool { return w.Start == w.End }
+
+type EmbedInner struct {
+	EmbedCore `json:",omitempty"`
+	Level     int `json:"level" groups:"public"`
+}
+
+type (
+	omitEmptyEmbed struct {
+		ID        int `json:"id" groups:"public"`
+		EmbedCore `json:",omitempty"`
+	}
+	omitZeroEmbed struct {
+		ID          int `json:"id" groups:"public"`
+		EmbedWindow `json:",omitzero"`
+	}
+	plainEmbed struct {
+		ID int `json:"id" groups:"public"`
+		EmbedCore
+	}
+	nestedEmbed struct {
+		ID         int `json:"id" groups:"public"`
+		EmbedInner `json:",omitempty"`
+	}
+	// 以指针内嵌的结构体不合并到外层对象，按普通指针字段处理
+	omitEmptyPointerEmbed struct {
+		ID       int `json:"id" groups:"public"`
+		*Address `json:",omitempty" groups:"public"`
+	}
+	pointerEmbed struct {
+		ID       int `json:"id" groups:"public"`
+		*Address `groups:"public"`
+	}
+)
+
+func TestEmbeddedOmission(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		v      any
+		opts   *Options
+		groups []string
+		want   string
+	}{
+		{"omitempty all empty", omitEmptyEmbed{ID: 1}, New(), []string{"public"}, `{"id":1}`},
+		{"omitempty some set", omitEmptyEmbed{ID: 1, EmbedCore: EmbedCore{Name: "a"}}, New(), []string{"public"}, `{"id":1,"name":"a"}`},
+		// 只有当前分组输出的字段参与判断：note不在public中
+		{"omitempty hidden field set", omitEmptyEmbed{ID: 1, EmbedCore: EmbedCore{Note: "n"}}, New(), []string{"public"}, `{"id":1}`},
+		{"omitempty visible field set", omitEmptyEmbed{ID: 1, EmbedCore: EmbedCore{Note: "n"}}, New(), []string{"admin"}, `{"note":"n"}`},
+		{"omitempty with NullIfEmpty", omitEmptyEmbed{ID: 1}, New().WithNullIfEmpty(true), []string{"public"}, `{"id":1,"name":null}`},
+		{"no option", plainEmbed{ID: 1}, New(), []string{"public"}, `{"id":1,"name":""}`},
+
+		{"omitzero IsZero", omitZeroEmbed{ID: 1, EmbedWindow: EmbedWindow{Start: 3, End: 3}}, New(), []string{"public"}, `{"id":1}`},
+		{"omitzero non-zero", omitZeroEmbed{ID: 1, EmbedWindow: EmbedWindow{Start: 0, End: 5}}, New(), []string{"public"}, `{"id":1,"start":0,"end":5}`},
+
+		{"nested all empty", nestedEmbed{ID: 1}, New(), []string{"public"}, `{"id":1}`},
+		{"nested inner omitted", nestedEmbed{ID: 1, EmbedInner: EmbedInner{Level: 2}}, New(), []string{"public"}, `{"id":1,"level":2}`},
+		{"nested all set", nestedEmbed{ID: 1, EmbedInner: EmbedInner{EmbedCore: EmbedCore{Name: "a"}, Level: 2}}, New(), []string{"public"},
+			`{"id":1,"name":"a","level":2}`},
+
+		{"pointer omitempty nil", omitEmptyPointerEmbed{ID: 1}, New(), []string{"public"}, `{"id":1}`},
+		{"pointer omitempty empty value", omitEmptyPointerEmbed{ID: 1, Address: &Address{}}, New(), []string{"public"},
+			`{"id":1,"Address":{"street":"","city":""}}`},
+		{"pointer omitempty NullIfEmpty", omitEmptyPointerEmbed{ID: 1}, New().WithNullIfEmpty(true), []string{"public"}, `{"id":1,"Address":null}`},
+		{"pointer nil", pointerEmbed{ID: 1}, New(), []string{"public"}, `{"id":1}`},
+		{"pointer set", pointerEmbed{ID: 1, Address: &Address{City: "X"}}, New(), []string{"public"}, `{"id":1,"Address":{"street":"","city":"X"}}`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := marshalString(t, tc.v, tc.opts, tc.groups...)
+			if got != tc.want {
+				t.Errorf("direct encoder:\ngot  %s\nwant %s", got, tc.want)
+			}
+
+			m, err := MarshalToMapWithOptions(tc.v, tc.opts, tc.groups...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, _ := json.Marshal(m)
+			assertJSONEqual(t, string(data), tc.want)
+
+			if n, err := EstimateSize(tc.v, tc.opts, tc.groups...); err != nil || n != len(tc.want) {
+				t.Errorf("EstimateSize = %d, %v; want %d", n, err, len(tc.want))
+			}
+		})
+	}
+}
+
+type (
+	// taggedEmbed 指定了json名称的内嵌结构体按普通字段处理，使用自己的分组标签
+	taggedEmbed struct {
+		ID        int `json:"id" groups:"public"`
+		EmbedCore `json:"core" groups:"public"`
+	}
+	// mixedEmbed 同时包含展开与嵌套的内嵌结构体
+	mixedEmbed struct {
+		EmbedWindow
+		EmbedCore `json:"core,omitempty" groups:"admin"`
+	}
+)
+
+// TestTaggedEmbedNested 与encoding/json相同，只有json标签未指定名称的内嵌结构体展开到外层对象
+func TestTaggedEmbedNested(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		v      any
+		groups []string
+		want   string
+	}{
+		{"tagged nested", taggedEmbed{ID: 1, EmbedCore: EmbedCore{Name: "a", Note: "n"}}, []string{"public"}, `{"id":1,"core":{"name":"a"}}`},
+		// 内嵌字段本身不属于请求的分组时整体不输出，即使其中的字段属于该分组
+		{"tagged excluded", taggedEmbed{ID: 1, EmbedCore: EmbedCore{Note: "n"}}, []string{"admin"}, `{}`},
+		{"untagged flattened", mixedEmbed{EmbedWindow: EmbedWindow{Start: 1, End: 2}}, []string{"public"}, `{"start":1,"end":2}`},
+		{"mixed admin", mixedEmbed{EmbedCore: EmbedCore{Name: "a", Note: "n"}}, []string{"admin"}, `{"core":{"note":"n"}}`},
+		{"mixed no groups", mixedEmbed{EmbedWindow: EmbedWindow{Start: 1, End: 2}, EmbedCore: EmbedCore{Name: "a"}}, nil,
+			`{"start":1,"end":2,"core":{"name":"a","note":""}}`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := marshalString(t, tc.v, New(), tc.groups...); got != tc.want {
+				t.Errorf("direct encoder:\ngot  %s\nwant %s", got, tc.want)
+			}
+			m, err := MarshalToMapWithOptions(tc.v, New(), tc.groups...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, _ := json.Marshal(m)
+			assertJSONEqual(t, string(data), tc.want)
+			if n, err := EstimateSize(tc.v, nil, tc.groups...); err != nil || n != len(tc.want) {
+				t.Errorf("EstimateSize = %d, %v; want %d", n, err, len(tc.want))
+			}
+			// 不指定分组时与encoding/json的结构相同
+			if tc.groups == nil {
+				std, _ := json.Marshal(tc.v)
+				assertJSONEqual(t, tc.want, string(std))
+			}
+		})
+	}
+}
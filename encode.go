@@ -0,0 +1,853 @@
+package jsongroup
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// encoder 直接将值编码为JSON字节，跳过map[string]any中间表示
+// 字段元数据、分组过滤、深度限制和循环引用检测与map路径共用同一套逻辑，
+// 输出与先构建map再调用json.Marshal的结果一致，区别在于结构体字段按声明顺序输出
+type encoder struct {
+	// 输出缓冲区
+	buf []byte
+}
+
+// scalarEncoder 预编译的基本类型编码函数，解析字段时根据字段的静态类型选定
+type scalarEncoder func(e *encoder, v reflect.Value)
+
+// encoderFor 返回基本类型对应的编码函数，其他类型返回nil
+func encoderFor(t reflect.Type) scalarEncoder {
+	switch t.Kind() {
+	case reflect.String:
+		return encodeStringValue
+	case reflect.Bool:
+		return encodeBoolValue
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return encodeIntValue
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return encodeUintValue
+	case reflect.Float32, reflect.Float64:
+		return encodeFloatValue
+	case reflect.Complex64, reflect.Complex128:
+		return encodeComplexValue
+	}
+	return nil
+}
+
+// encodeStringValue 编码字符串值
+func encodeStringValue(e *encoder, v reflect.Value) {
+	e.buf = appendJSONString(e.buf, v.String())
+}
+
+// encodeBoolValue 编码布尔值
+func encodeBoolValue(e *encoder, v reflect.Value) {
+	e.buf = strconv.AppendBool(e.buf, v.Bool())
+}
+
+// encodeIntValue 编码有符号整数值
+func encodeIntValue(e *encoder, v reflect.Value) {
+	e.buf = strconv.AppendInt(e.buf, v.Int(), 10)
+}
+
+// encodeUintValue 编码无符号整数值
+func encodeUintValue(e *encoder, v reflect.Value) {
+	e.buf = strconv.AppendUint(e.buf, v.Uint(), 10)
+}
+
+// encodeFloatValue 编码浮点值，NaN和Inf编码为字符串
+func encodeFloatValue(e *encoder, v reflect.Value) {
+	f := v.Float()
+	if isSpecialFloat(f) {
+		e.buf = appendJSONString(e.buf, floatToString(f))
+		return
+	}
+	e.buf = appendJSONFloat(e.buf, f, floatBits(v))
+}
+
+// encodeComplexValue 编码复数值
+func encodeComplexValue(e *encoder, v reflect.Value) {
+	e.buf = appendJSONString(e.buf, complex128ToString(v.Complex()))
+}
+
+// encodeValue 编码单个值，返回是否写入了内容
+// 返回false表示该值在map路径中对应nil（调用方决定省略还是输出null），此时缓冲区保持不变
+func (e *encoder) encodeValue(ctx *serializeContext, v reflect.Value, groups []string, mode GroupMode) (ok bool, err error) {
+	kind := v.Kind()
+
+	// 快速处理基本类型 - 无需增加递归深度或检查循环引用
+	switch kind {
+	case reflect.String:
+		s := v.String()
+		if s == "" && ctx.opts.NullIfEmpty {
+			return false, nil
+		}
+		if err := ctx.checkUTF8(v, s, "字符串"); err != nil {
+			return false, err
+		}
+		e.buf = appendJSONString(e.buf, ctx.truncate(s))
+		return true, nil
+
+	case reflect.Bool:
+		e.buf = strconv.AppendBool(e.buf, v.Bool())
+		return true, nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		e.buf = strconv.AppendInt(e.buf, v.Int(), 10)
+		return true, nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		e.buf = strconv.AppendUint(e.buf, v.Uint(), 10)
+		return true, nil
+
+	case reflect.Float32, reflect.Float64:
+		if err := ctx.checkFloat(v); err != nil {
+			return false, err
+		}
+		f := v.Float()
+		if isSpecialFloat(f) {
+			e.buf = appendJSONString(e.buf, floatToString(f))
+			return true, nil
+		}
+		e.buf = appendJSONFloat(e.buf, f, floatBits(v))
+		return true, nil
+
+	case reflect.Complex64, reflect.Complex128:
+		e.buf = appendJSONString(e.buf, complex128ToString(v.Complex()))
+		return true, nil
+	}
+
+	// 处理nil指针
+	if (kind == reflect.Pointer || kind == reflect.Interface) && v.IsNil() {
+		if ctx.opts.IgnoreNilPointers && kind == reflect.Pointer {
+			return false, errSkipField
+		}
+		return false, nil
+	}
+
+	// 增加递归深度并检查限制 - 只对复杂类型执行
+	if err := ctx.enterLevel(); err != nil {
+		// 超出深度限制，但对于空集合仍然可以输出
+		if (kind == reflect.Slice || kind == reflect.Map) && v.Len() == 0 {
+			ctx.leaveLevel()
+			if ctx.opts.NullIfEmpty {
+				return false, nil
+			}
+			if kind == reflect.Slice {
+				e.buf = append(e.buf, "[]"...)
+			} else {
+				e.buf = append(e.buf, "{}"...)
+			}
+			return true, nil
+		}
+		return false, err
+	}
+	// 离开当前级别，并将复杂类型处理中的panic转换为带当前路径的错误
+	defer func() {
+		ctx.leaveLevel()
+		if r := recover(); r != nil {
+			ok, err = false, panicError(ctx.path(), r)
+		}
+	}()
+
+	// 检查循环引用 - 只对可能形成循环的类型执行
+	if kind == reflect.Ptr || kind == reflect.Map || kind == reflect.Slice {
+		if err := ctx.checkPointer(v); err != nil {
+			return false, err
+		}
+	}
+
+	if rep, ok := ctx.errorValue(v); ok {
+		return e.encodeValue(ctx.withPath(""), rep, groups, mode)
+	}
+	if rep, ok, err := ctx.binaryValue(v); err != nil {
+		return false, err
+	} else if ok {
+		return e.encodeValue(ctx.withPath(""), rep, groups, mode)
+	}
+
+	switch kind {
+	case reflect.Ptr, reflect.Interface:
+		return e.encodeValue(ctx.withPath(""), v.Elem(), groups, mode)
+
+	case reflect.Struct:
+		// 特殊处理时间类型，输出与time.Time.MarshalJSON一致
+		if v.Type() == timeType {
+			t := timeOf(v)
+			if t.IsZero() && ctx.opts.NullIfEmpty {
+				return false, nil
+			}
+			return true, e.encodeTime(ctx, t)
+		}
+		if v.Type() == syncMapType {
+			snapshot, err := ctx.syncMapSnapshot(v)
+			if err != nil {
+				return false, err
+			}
+			if snapshot.Len() == 0 && ctx.opts.NullIfEmpty {
+				return false, nil
+			}
+			return true, e.encodeMap(ctx, snapshot, groups, mode)
+		}
+		if loaded, ok, err := ctx.atomicLoad(v); err != nil {
+			return false, err
+		} else if ok {
+			return e.encodeValue(ctx.withPath(""), loaded, groups, mode)
+		}
+		// 注册了选项的类型按叠加后的选项和分组模式处理
+		// 不能直接替换ctx：延迟调用的leaveLevel需要作用于进入时的上下文
+		structCtx := ctx
+		if typed := ctx.withTypeOptions(v.Type(), groups); typed != ctx {
+			structCtx, mode = typed, typed.opts.GroupMode
+		}
+		v = structCtx.lockedSnapshot(v)
+		// 实现了CacheableFragment的值优先使用缓存的字节
+		if key, ok := fragmentKeyOf(structCtx, v, mode); ok {
+			return true, e.encodeFragment(structCtx, v, key, groups, mode)
+		}
+		return true, e.encodeStructValue(structCtx, v, groups, mode)
+
+	case reflect.Map:
+		if v.Len() == 0 && ctx.opts.NullIfEmpty {
+			return false, nil
+		}
+		return true, e.encodeMap(ctx, v, groups, mode)
+
+	case reflect.Slice, reflect.Array:
+		if v.Len() == 0 {
+			// 数组不可能为nil，长度为0的数组总是输出[]
+			if ctx.opts.NullIfEmpty && v.Kind() == reflect.Slice && v.IsNil() {
+				return false, nil
+			}
+			e.buf = append(e.buf, "[]"...)
+			ctx.endArray(0)
+			return true, nil
+		}
+		_, err := e.encodeSlice(ctx, v, groups, mode)
+		ctx.endArray(v.Len())
+		return true, err
+
+	case reflect.Func:
+		// iter.Seq等迭代器按切片处理，元素直接写入缓冲区而不先收集到切片中
+		if isSeq(v.Type()) {
+			return e.encodeSeq(ctx, v, groups, mode)
+		}
+		return false, UnsupportedTypeError(ctx.path(), v.Type().String())
+
+	default:
+		// 通道、函数等类型无法编码为JSON，错误路径指向具体的字段或元素
+		return false, UnsupportedTypeError(ctx.path(), v.Type().String())
+	}
+}
+
+// encodeTime 编码时间值
+func (e *encoder) encodeTime(ctx *serializeContext, t time.Time) error {
+	mark := len(e.buf)
+	e.buf = append(e.buf, '"')
+	buf, err := t.AppendText(e.buf)
+	if err != nil {
+		e.buf = e.buf[:mark]
+		// 通过MarshalJSON获取与json.Marshal一致的错误信息
+		_, err = t.MarshalJSON()
+		return ReflectionError(ctx.path(), err)
+	}
+	e.buf = append(buf, '"')
+	return nil
+}
+
+// encodeStructValue 编码结构体值，优先使用生成的静态序列化方法
+func (e *encoder) encodeStructValue(ctx *serializeContext, v reflect.Value, groups []string, mode GroupMode) error {
+	if m, ok := groupMarshalerOf(ctx, v); ok {
+		buf, err := m.AppendByGroups(e.buf, (*EncodeState)(ctx), groups...)
+		if err != nil {
+			return err
+		}
+		e.buf = buf
+		return nil
+	}
+	return e.encodeStruct(ctx, v, groups, mode)
+}
+
+// encodeStruct 按声明顺序编码结构体字段
+func (e *encoder) encodeStruct(ctx *serializeContext, v reflect.Value, groups []string, mode GroupMode) error {
+	if err := checkGroupTags(ctx.opts, v.Type(), ctx.path()); err != nil {
+		return err
+	}
+	if err := ctx.checkRenames(v.Type()); err != nil {
+		return err
+	}
+	set, err := getFilteredFields(v.Type(), ctx.opts, groups, ctx.groupKey, mode, ctx.inherited)
+	if err != nil {
+		return ReflectionError(ctx.path(), err)
+	}
+
+	// 存在重名字段时需要后者覆盖前者的map语义，回退到map路径处理该结构体，过滤结果由map路径记录
+	if set.duplicateNames {
+		m, err := structToMap(ctx, v, groups, mode)
+		if err != nil {
+			return err
+		}
+		return e.encodeIntermediate(ctx, m)
+	}
+
+	xrayOwner := ctx.beginXRay()
+	if ctx.observing() {
+		ctx.recordExcluded(v.Type(), set, groups)
+	}
+
+	e.buf = append(e.buf, '{')
+	first := true
+	var embeds embedOmitter
+	for _, field := range set.fields {
+		if len(field.Embeds) > 0 && !ctx.opts.NullIfEmpty && embeds.omits(v, set, field) {
+			if ctx.observing() {
+				ctx.recordField(v.Type(), field, groups, decisionOmittedEmpty)
+			}
+			continue
+		}
+		ok, err := e.encodeField(ctx, v, field, groups, mode, first)
+		if err != nil {
+			return err
+		}
+		if ok {
+			first = false
+		}
+	}
+	if xrayOwner {
+		if !first {
+			e.buf = append(e.buf, ',')
+		}
+		e.buf = appendJSONString(e.buf, ctx.opts.xrayKey())
+		e.buf = append(e.buf, ':')
+		if err := e.encodeIntermediate(ctx, ctx.endXRay()); err != nil {
+			return err
+		}
+	}
+	e.buf = append(e.buf, '}')
+	ctx.endStruct(v.Type(), first && !xrayOwner)
+	return nil
+}
+
+// encodeField 编码单个结构体字段（含键名），返回是否写入了内容
+// 字段的省略规则与structToMap保持一致
+func (e *encoder) encodeField(ctx *serializeContext, v reflect.Value, field fieldInfo, groups []string, mode GroupMode, first bool) (bool, error) {
+	fieldValue := v.FieldByIndex(field.Index)
+	key := ctx.encodedFieldKey(field)
+
+	if ctx.opts.ValueFilter != nil && !ctx.withField(field).keepsField(v, field, fieldValue) {
+		if ctx.observing() {
+			ctx.recordField(v.Type(), field, groups, decisionExcludedByValue)
+		}
+		return false, nil
+	}
+
+	mark := len(e.buf)
+	if !first {
+		e.buf = append(e.buf, ',')
+	}
+
+	replaced := false
+	if ctx.opts.FieldHook != nil {
+		fieldCtx := ctx.withField(field)
+		var err error
+		if fieldValue, replaced, err = fieldCtx.callFieldHook(v, field, fieldValue); err != nil {
+			switch fieldCtx.handleError(err) {
+			case errorFail:
+				return false, err
+			case errorOmit:
+				e.buf = e.buf[:mark]
+				return false, nil
+			}
+			e.buf = append(e.buf, key...)
+			e.buf = append(e.buf, "null"...)
+			return true, nil
+		}
+	}
+
+	// 基本类型字段使用预编译的编码函数，不会出错，也无需创建带路径的上下文
+	// 需要截断的字符串字段和无法编码的值经过encodeValue，以便记录或报告带字段名的路径
+	if field.Encoder != nil && !replaced && !ctx.truncatesField(fieldValue) && !ctx.rejectsField(fieldValue) {
+		if (field.OmitEmpty || field.OmitZero || ctx.opts.NullIfEmpty) && isEmptyValue(fieldValue) {
+			if ctx.observing() {
+				ctx.recordField(v.Type(), field, groups, emptyDecision(ctx))
+			}
+			if !ctx.opts.NullIfEmpty {
+				e.buf = e.buf[:mark]
+				return false, nil
+			}
+			e.buf = append(e.buf, key...)
+			e.buf = append(e.buf, "null"...)
+			return true, nil
+		}
+		if ctx.observing() {
+			ctx.recordField(v.Type(), field, groups, decisionIncluded)
+		}
+		e.buf = append(e.buf, key...)
+		field.Encoder(e, fieldValue)
+		return true, nil
+	}
+
+	fieldCtx := ctx.withField(field)
+
+	// 处理内嵌匿名字段，将其字段合并到当前对象
+	if field.Anonymous && fieldValue.Kind() == reflect.Struct {
+		fieldCtx.xray = ctx.xray
+		fieldCtx.inherited = ctx.inherited
+		fieldCtx.renames = ctx.renames
+		set, err := getFilteredFields(fieldValue.Type(), ctx.opts, groups, ctx.groupKey, mode, ctx.inherited)
+		if err != nil {
+			return false, ReflectionError(fieldCtx.path(), err)
+		}
+		embeddedFirst := true
+		for _, ef := range set.fields {
+			ok, err := e.encodeField(fieldCtx, fieldValue, ef, groups, mode, embeddedFirst)
+			if err != nil {
+				return false, err
+			}
+			if ok {
+				embeddedFirst = false
+			}
+		}
+		if embeddedFirst {
+			e.buf = e.buf[:mark]
+			return false, nil
+		}
+		return true, nil
+	}
+
+	// 处理nil指针和空值
+	isNilPointer := fieldValue.Kind() == reflect.Ptr && fieldValue.IsNil()
+	if isNilPointer && ctx.opts.IgnoreNilPointers {
+		if ctx.observing() {
+			ctx.recordField(v.Type(), field, groups, decisionNilSkipped)
+		}
+		e.buf = e.buf[:mark]
+		return false, nil
+	}
+
+	isNilOrEmpty := isNilPointer || isEmptyValue(fieldValue)
+
+	if (field.OmitEmpty && isNilOrEmpty && !ctx.opts.NullIfEmpty) ||
+		(field.OmitZero && !ctx.opts.NullIfEmpty && isZeroValue(fieldValue)) {
+		if ctx.observing() {
+			ctx.recordField(v.Type(), field, groups, decisionOmittedEmpty)
+		}
+		e.buf = e.buf[:mark]
+		return false, nil
+	}
+
+	e.buf = append(e.buf, key...)
+
+	if isNilOrEmpty && (ctx.opts.NullIfEmpty || ctx.nullsNilError(fieldValue)) {
+		if ctx.observing() {
+			ctx.recordField(v.Type(), field, groups, decisionIncluded)
+		}
+		e.buf = append(e.buf, "null"...)
+		return true, nil
+	}
+
+	valueMark := len(e.buf)
+	ctx.resetEmpty()
+	ok, err := e.encodeValue(fieldCtx, fieldValue, groups, mode)
+	if err != nil {
+		if errors.Is(err, errSkipField) {
+			if ctx.observing() {
+				ctx.recordField(v.Type(), field, groups, decisionNilSkipped)
+			}
+			e.buf = e.buf[:mark]
+			return false, nil
+		}
+		switch fieldCtx.handleError(err) {
+		case errorFail:
+			return false, err
+		case errorOmit:
+			e.buf = e.buf[:mark]
+			return false, nil
+		}
+		// Collect策略下以null代替出错的字段
+		e.buf = append(e.buf[:valueMark], "null"...)
+		return true, nil
+	}
+	if ok && ctx.prunes(isEmptyJSON(e.buf[valueMark:])) {
+		if ctx.observing() {
+			ctx.recordField(v.Type(), field, groups, decisionOmittedEmpty)
+		}
+		e.buf = e.buf[:mark]
+		return false, nil
+	}
+	if ctx.observing() {
+		decision := decisionIncluded
+		if !ok && !ctx.opts.NullIfEmpty {
+			decision = decisionOmittedEmpty
+		}
+		ctx.recordField(v.Type(), field, groups, decision)
+	}
+	if !ok {
+		if !ctx.opts.NullIfEmpty {
+			e.buf = e.buf[:mark]
+			return false, nil
+		}
+		e.buf = append(e.buf, "null"...)
+	}
+	return true, nil
+}
+
+// mapEntry 待编码的map条目
+type mapEntry struct {
+	key   string
+	value reflect.Value
+}
+
+// encodeMap 编码map，键按字符串排序以与json.Marshal的输出保持一致
+func (e *encoder) encodeMap(ctx *serializeContext, v reflect.Value, groups []string, mode GroupMode) error {
+	entries := make([]mapEntry, 0, v.Len())
+	iter := v.MapRange()
+	for iter.Next() {
+		entries = append(entries, mapEntry{key: mapKeyString(iter.Key()), value: iter.Value()})
+	}
+	slices.SortStableFunc(entries, func(a, b mapEntry) int {
+		return strings.Compare(a.key, b.key)
+	})
+
+	e.buf = append(e.buf, '{')
+	first := true
+	for i, entry := range entries {
+		// 不同键值可能得到相同的字符串表示，只保留最后一个
+		if i+1 < len(entries) && entries[i+1].key == entry.key {
+			continue
+		}
+
+		itemCtx := ctx.withPath(entry.key)
+		// 键无效时无法以null代替，任何策略下都省略该条目
+		if err := itemCtx.checkUTF8(reflect.Value{}, entry.key, "map的键"); err != nil {
+			if itemCtx.handleError(err) == errorFail {
+				return err
+			}
+			continue
+		}
+
+		mark := len(e.buf)
+		if !first {
+			e.buf = append(e.buf, ',')
+		}
+		e.buf = appendJSONString(e.buf, entry.key)
+		e.buf = append(e.buf, ':')
+
+		valueMark := len(e.buf)
+		ctx.resetEmpty()
+		ok, err := nilIfSkipped(e.encodeValue(itemCtx, entry.value, groups, mode))
+		if err != nil {
+			switch itemCtx.handleError(err) {
+			case errorFail:
+				return err
+			case errorOmit:
+				e.buf = e.buf[:mark]
+				continue
+			}
+			e.buf = append(e.buf[:valueMark], "null"...)
+			ok = true
+		} else if ok && ctx.prunes(isEmptyJSON(e.buf[valueMark:])) {
+			e.buf = e.buf[:mark]
+			continue
+		}
+		if !ok {
+			if !ctx.opts.NullIfEmpty {
+				e.buf = e.buf[:mark]
+				continue
+			}
+			e.buf = append(e.buf, "null"...)
+		}
+		first = false
+	}
+	e.buf = append(e.buf, '}')
+	ctx.endMap(v.Len(), first)
+	return nil
+}
+
+// encodeEnvelope 将Envelope的元数据编码为最外层对象中包装键之后的其余键，键按字符串排序
+func (e *encoder) encodeEnvelope(ctx *serializeContext, env map[string]any, groups []string, mode GroupMode) error {
+	mark := len(e.buf)
+	if err := e.encodeMap(ctx, reflect.ValueOf(env), groups, mode); err != nil {
+		e.buf = e.buf[:mark]
+		return err
+	}
+	if len(e.buf)-mark == len("{}") {
+		e.buf = e.buf[:mark]
+		return nil
+	}
+	// 以逗号代替map的左括号并去掉右括号，使元数据接在包装键之后
+	e.buf[mark] = ','
+	e.buf = e.buf[:len(e.buf)-1]
+	return nil
+}
+
+// encodeSlice 编码切片和数组，超过MaxSliceLen的元素不输出
+// 返回实际输出的元素数，被省略的元素和截断标记不计入
+func (e *encoder) encodeSlice(ctx *serializeContext, v reflect.Value, groups []string, mode GroupMode) (int, error) {
+	length := ctx.limitSlice(v.Len())
+
+	var n int
+	var err error
+	if ctx.shouldParallelize(length) {
+		n, err = e.encodeSliceParallel(ctx, v, length, groups, mode)
+	} else {
+		n, err = e.encodeSliceElems(ctx, v, length, groups, mode)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if ctx.opts.MarkTruncatedSlices && length < v.Len() {
+		if n > 0 {
+			e.buf = append(e.buf, ',')
+		}
+		e.buf = append(e.buf, `{"`+TruncatedSliceKey+`":`...)
+		e.buf = strconv.AppendInt(e.buf, int64(v.Len()-length), 10)
+		e.buf = append(e.buf, '}')
+	}
+	e.buf = append(e.buf, ']')
+	return n, nil
+}
+
+// encodeSliceElems 依次编码前length个元素，写入左括号但不写入右括号
+func (e *encoder) encodeSliceElems(ctx *serializeContext, v reflect.Value, length int, groups []string, mode GroupMode) (int, error) {
+	e.buf = append(e.buf, '[')
+	// 基本类型的元素不会保留上下文（错误和截断记录的是拼接好的路径），所有元素共用一个上下文，不按元素分配
+	var scalarCtx *serializeContext
+	if encoderFor(v.Type().Elem()) != nil {
+		scalarCtx = ctx.withIndex(0)
+	}
+	n := 0
+	for i := range length {
+		mark := len(e.buf)
+		if n > 0 {
+			e.buf = append(e.buf, ',')
+		}
+
+		itemCtx := scalarCtx
+		if itemCtx != nil {
+			itemCtx.index = i
+		} else {
+			itemCtx = ctx.withIndex(i)
+		}
+		valueMark := len(e.buf)
+		ok, err := nilIfSkipped(e.encodeValue(itemCtx, v.Index(i), groups, mode))
+		if err != nil {
+			switch itemCtx.handleError(err) {
+			case errorFail:
+				return 0, err
+			case errorOmit:
+				e.buf = e.buf[:mark]
+				continue
+			}
+			e.buf = append(e.buf[:valueMark], "null"...)
+			ok = true
+		}
+		// nil元素输出null以保持其余元素的位置，不像对象的键那样省略
+		if !ok {
+			e.buf = append(e.buf, "null"...)
+		}
+		n++
+	}
+	return n, nil
+}
+
+// encodeCollection 将根切片编码为集合包装，元素数为过滤后实际输出的元素数
+// 深度、循环引用检测和空切片的处理与encodeValue中的指针和切片分支一致
+func (e *encoder) encodeCollection(ctx *serializeContext, v reflect.Value, groups []string, mode GroupMode) error {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if err := ctx.checkPointer(v); err != nil {
+			return err
+		}
+		v = v.Elem()
+	}
+	if err := ctx.checkPointer(v); err != nil {
+		return err
+	}
+
+	e.buf = append(e.buf, '{')
+	e.buf = appendJSONString(e.buf, ctx.opts.CollectionItemsKey)
+	e.buf = append(e.buf, ':')
+
+	n := 0
+	switch {
+	case v.Len() == 0 && ctx.opts.NullIfEmpty && v.Kind() == reflect.Slice && v.IsNil():
+		e.buf = append(e.buf, "null"...)
+	case v.Len() == 0:
+		e.buf = append(e.buf, "[]"...)
+	default:
+		if err := ctx.enterLevel(); err != nil {
+			return err
+		}
+		var err error
+		n, err = e.encodeSlice(ctx, v, groups, mode)
+		ctx.leaveLevel()
+		if err != nil {
+			return err
+		}
+	}
+
+	e.buf = append(e.buf, ',')
+	e.buf = appendJSONString(e.buf, ctx.opts.CollectionCountKey)
+	e.buf = append(e.buf, ':')
+	e.buf = strconv.AppendInt(e.buf, int64(n), 10)
+	e.buf = append(e.buf, '}')
+	return nil
+}
+
+// encodeSliceParallel 将大切片的前length个元素拆分为分块并行编码，每个分块写入独立的缓冲区，再按顺序拼接
+// 与encodeSliceElems一样写入左括号但不写入右括号
+func (e *encoder) encodeSliceParallel(ctx *serializeContext, v reflect.Value, length int, groups []string, mode GroupMode) (int, error) {
+	parts := make([]encoder, ctx.opts.Parallelism)
+	counts := make([]int, len(parts))
+	err := ctx.forEachChunk(length, func(chunkCtx *serializeContext, chunk, i int) error {
+		part := &parts[chunk]
+		mark := len(part.buf)
+		if mark > 0 {
+			part.buf = append(part.buf, ',')
+		}
+		itemCtx := chunkCtx.withIndex(i)
+		valueMark := len(part.buf)
+		ok, err := nilIfSkipped(part.encodeValue(itemCtx, v.Index(i), groups, mode))
+		if err != nil {
+			switch itemCtx.handleError(err) {
+			case errorFail:
+				return err
+			case errorOmit:
+				part.buf = part.buf[:mark]
+				return nil
+			}
+			part.buf = append(part.buf[:valueMark], "null"...)
+			ok = true
+		}
+		if !ok {
+			part.buf = append(part.buf, "null"...)
+		}
+		counts[chunk]++
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	e.buf = append(e.buf, '[')
+	first := true
+	n := 0
+	for chunk, part := range parts {
+		n += counts[chunk]
+		if len(part.buf) == 0 {
+			continue
+		}
+		if !first {
+			e.buf = append(e.buf, ',')
+		}
+		e.buf = append(e.buf, part.buf...)
+		first = false
+	}
+	return n, nil
+}
+
+// encodeIntermediate 编码map路径生成的中间表示
+func (e *encoder) encodeIntermediate(ctx *serializeContext, data any) error {
+	b, err := ctx.opts.backend().MarshalFinal(data)
+	if err != nil {
+		return WrapJSONError(err, ctx.path())
+	}
+	if len(b) == 0 {
+		// 自定义后端未返回任何内容时输出将不是合法的JSON
+		return ReflectionError(ctx.path(), errors.New("编码后端返回了空结果"))
+	}
+	e.buf = append(e.buf, b...)
+	return nil
+}
+
+// appendJSONFloat 按encoding/json的规则格式化浮点数，bits为32时按float32的精度输出最短表示
+func appendJSONFloat(b []byte, f float64, bits int) []byte {
+	format := byte('f')
+	if abs := math.Abs(f); abs != 0 {
+		if bits == 64 && (abs < 1e-6 || abs >= 1e21) || bits == 32 && (float32(abs) < 1e-6 || float32(abs) >= 1e21) {
+			format = 'e'
+		}
+	}
+	b = strconv.AppendFloat(b, f, format, -1, bits)
+	if format == 'e' {
+		// 将e-09规范为e-9
+		n := len(b)
+		if n >= 4 && b[n-4] == 'e' && b[n-3] == '-' && b[n-2] == '0' {
+			b[n-2] = b[n-1]
+			b = b[:n-1]
+		}
+	}
+	return b
+}
+
+// floatBits 返回浮点值的位宽，float32与encoding/json相同按32位精度格式化，
+// 否则3.1415会按展宽后的float64输出为3.1414999961853027
+func floatBits(v reflect.Value) int {
+	if v.Kind() == reflect.Float32 {
+		return 32
+	}
+	return 64
+}
+
+// hexDigits 十六进制字符表
+const hexDigits = "0123456789abcdef"
+
+// appendJSONString 按encoding/json的规则编码字符串
+// 转义HTML敏感字符和U+2028/U+2029，非法UTF-8字节替换为U+FFFD字符
+func appendJSONString(b []byte, s string) []byte {
+	b = append(b, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		if c := s[i]; c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' && c != '<' && c != '>' && c != '&' {
+				i++
+				continue
+			}
+			b = append(b, s[start:i]...)
+			switch c {
+			case '\\', '"':
+				b = append(b, '\\', c)
+			case '\b':
+				b = append(b, '\\', 'b')
+			case '\f':
+				b = append(b, '\\', 'f')
+			case '\n':
+				b = append(b, '\\', 'n')
+			case '\r':
+				b = append(b, '\\', 'r')
+			case '\t':
+				b = append(b, '\\', 't')
+			default:
+				b = append(b, '\\', 'u', '0', '0', hexDigits[c>>4], hexDigits[c&0xF])
+			}
+			i++
+			start = i
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			b = append(b, s[start:i]...)
+			b = append(b, "\uFFFD"...)
+			i += size
+			start = i
+			continue
+		}
+		if r == '\u2028' || r == '\u2029' {
+			b = append(b, s[start:i]...)
+			b = append(b, '\\', 'u', '2', '0', '2', hexDigits[r&0xF])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	b = append(b, s[start:]...)
+	return append(b, '"')
+}
@@ -0,0 +1,340 @@
+package jsongroup
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+)
+
+type celsius float64
+
+func (c celsius) MarshalJSON() ([]byte, error) {
+	return []byte(`{"c":` + json.Number(fmtFloat(float64(c))).String() + `}`), nil
+}
+
+func fmtFloat(f float64) string {
+	b, _ := json.Marshal(f)
+	return string(b)
+}
+
+type embeddedBase struct {
+	Kind    string `json:"kind"`
+	Version int    `json:"version"`
+}
+
+type kitchenSink struct {
+	embeddedBase
+	Text       string            `json:"text"`
+	HTML       string            `json:"html"`
+	Unicode    string            `json:"unicode"`
+	Int8       int8              `json:"int8"`
+	Uint64     uint64            `json:"uint64"`
+	Float      float64           `json:"float"`
+	Small      float64           `json:"small"`
+	Big        float64           `json:"big"`
+	Bool       bool              `json:"bool"`
+	NilPtr     *int              `json:"nil_ptr"`
+	Ptr        *int              `json:"ptr"`
+	NilSlice   []int             `json:"nil_slice"`
+	Empty      []string          `json:"empty"`
+	Bytes      []byte            `json:"bytes"`
+	IntKeys    map[int]string    `json:"int_keys"`
+	StrKeys    map[string]int    `json:"str_keys"`
+	Raw        json.RawMessage   `json:"raw"`
+	Temp       celsius           `json:"temp"`
+	IP         net.IP            `json:"ip"`
+	When       time.Time         `json:"when"`
+	Any        any               `json:"any"`
+	Nested     map[string][]bool `json:"nested"`
+	Array      [2]int            `json:"array"`
+	Omitted    string            `json:"omitted,omitempty"`
+	Skipped    string            `json:"-"`
+	Stringed   int               `json:"stringed,string"`
+	unexported int
+}
+
+func newKitchenSink() kitchenSink {
+	n := 42
+	return kitchenSink{
+		embeddedBase: embeddedBase{Kind: "sink", Version: 2},
+		Text:         "line\nbreak \"quoted\" \\ tab\t",
+		HTML:         "<a href=\"x\">&</a>",
+		Unicode:      "héllo 世界 \u2028\u2029",
+		Int8:         -8,
+		Uint64:       math.MaxUint64,
+		Float:        3.25,
+		Small:        1e-7,
+		Big:          1e21,
+		Bool:         true,
+		Ptr:          &n,
+		Empty:        []string{},
+		Bytes:        []byte("hello"),
+		IntKeys:      map[int]string{10: "ten", 2: "two"},
+		StrKeys:      map[string]int{"b": 2, "a": 1},
+		Raw:          json.RawMessage(`{"x":[1,2]}`),
+		Temp:         21.5,
+		IP:           net.ParseIP("192.0.2.1"),
+		When:         time.Date(2024, 5, 6, 7, 8, 9, 123, time.FixedZone("X", 3600)),
+		Any:          map[string]any{"k": []any{1, "two", nil}},
+		Nested:       map[string][]bool{"z": {true}, "y": nil},
+		Array:        [2]int{1, 2},
+		Skipped:      "never",
+		Stringed:     7,
+		unexported:   1,
+	}
+}
+
+// TestDirectEncoderMatchesEncodingJSON 未指定分组时，只含普通字段的值直接编码的输出与encoding/json逐字节相同
+func TestDirectEncoderMatchesEncodingJSON(t *testing.T) {
+	values := map[string]any{
+		"pointer": &User{ID: 1, Address: &Address{City: "X"}},
+		"complex": newComplexUser(7),
+		"slice":   []User{{ID: 1}, {ID: 2, Name: "b"}},
+		"map":     map[string]Address{"home": {Street: "1"}},
+		"strings": []string{"line\nbreak \"quoted\"", "<a href=\"x\">&</a>", "héllo 世界 \u2028\u2029"},
+		"numbers": []any{-8, uint64(math.MaxUint64), 3.25, 1e-7, 1e21, math.SmallestNonzeroFloat64},
+		"scalar":  "just a string",
+	}
+	for name, v := range values {
+		want, err := json.Marshal(v)
+		if err != nil {
+			t.Fatalf("%s: json.Marshal: %v", name, err)
+		}
+		if got := marshalString(t, v, nil); got != string(want) {
+			t.Errorf("%s:\ngot  %s\nwant %s", name, got, want)
+		}
+	}
+}
+
+// TestDirectEncoderMatchesMapPathKitchenSink 各种字段类型下直接编码与MarshalToMap的结果语义一致
+func TestDirectEncoderMatchesMapPathKitchenSink(t *testing.T) {
+	for _, opts := range []*Options{New(), New().WithIgnoreNilPointers(false), New().WithNullIfEmpty(true)} {
+		v := newKitchenSink()
+		m, err := MarshalToMapWithOptions(v, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, _ := json.Marshal(m)
+		assertJSONEqual(t, marshalString(t, v, opts), string(want))
+	}
+}
+
+// TestDirectEncoderMatchesMapPath 指定分组时直接编码与MarshalToMap的结果语义一致，字段按声明顺序输出
+func TestDirectEncoderMatchesMapPath(t *testing.T) {
+	u := newComplexUser(3)
+	for _, groups := range [][]string{{"public"}, {"admin"}, {"internal"}, {"public", "internal"}, {"unknown"}} {
+		m, err := MarshalToMap(u, groups...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, _ := json.Marshal(m)
+		assertJSONEqual(t, marshalString(t, u, nil, groups...), string(want))
+	}
+
+	got := marshalString(t, u, nil, "public")
+	want := `{"id":3,"name":"Alice","active":true,"tags":["go","json"],"profile":{"bio":"hello","website":"https://example.com","socials":[{},{}]},"stats":{}}`
+	if got != want {
+		t.Errorf("field order:\ngot  %s\nwant %s", got, want)
+	}
+}
+
+// TestDirectEncoderAllocatesLessThanMapPath 直接编码不构建中间map，分配次数应明显少于经由MarshalToMap再编码
+func TestDirectEncoderAllocatesLessThanMapPath(t *testing.T) {
+	u := newComplexUser(1)
+	direct := testing.AllocsPerRun(50, func() { MarshalByGroups(u, "public") })
+	viaMap := testing.AllocsPerRun(50, func() {
+		m, _ := MarshalToMap(u, "public")
+		json.Marshal(m)
+	})
+	if direct*2 > viaMap {
+		t.Errorf("direct encoder: %v allocs/op, map path: %v allocs/op", direct, viaMap)
+	}
+}
+
+type oddKeys struct {
+	Angle  int `json:"a<b>"`
+	Accent int `json:"héllo"`
+	Amp    int `json:"x&y"`
+	Plain  int
+}
+
+func TestEncodedKeysArePrecomputed(t *testing.T) {
+	info, err := globalCache.getFieldsInfo(reflect.TypeFor[oddKeys](), DefaultTagKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range info.fields {
+		name, _ := json.Marshal(f.JSONName)
+		if want := string(name) + ":"; string(f.EncodedKey) != want {
+			t.Errorf("%s: EncodedKey = %s, want %s", f.Name, f.EncodedKey, want)
+		}
+	}
+
+	v := oddKeys{1, 2, 3, 4}
+	want, _ := json.Marshal(v)
+	if got := marshalString(t, v, nil); got != string(want) {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+}
+
+type (
+	status   string
+	priority int8
+	flags    uint16
+	ratio    float32
+	enabled  bool
+)
+
+type scalarKinds struct {
+	Status   status     `json:"status" groups:"public"`
+	Priority priority   `json:"priority" groups:"public"`
+	Flags    flags      `json:"flags" groups:"public"`
+	Ratio    ratio      `json:"ratio" groups:"public"`
+	Enabled  enabled    `json:"enabled" groups:"public"`
+	Int      int        `json:"int" groups:"public"`
+	Uint     uintptr    `json:"uint" groups:"public"`
+	Float    float64    `json:"float" groups:"public"`
+	Nested   *Address   `json:"nested" groups:"public"`
+	Complex  complex128 `json:"-"`
+}
+
+// TestPrecompiledScalarEncoders 基本类型字段（包括以基本类型为底层类型的命名类型）在解析时选定编码函数
+func TestPrecompiledScalarEncoders(t *testing.T) {
+	info, err := globalCache.getFieldsInfo(reflect.TypeFor[scalarKinds](), DefaultTagKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range info.fields {
+		scalar := f.Name != "Nested"
+		if f.Scalar != scalar || (f.Encoder != nil) != scalar || f.Mapper == nil {
+			t.Errorf("%s: Scalar=%v Encoder set=%v Mapper set=%v", f.Name, f.Scalar, f.Encoder != nil, f.Mapper != nil)
+		}
+	}
+
+	v := scalarKinds{Status: "active", Priority: -3, Flags: 0xffff, Ratio: 0.1, Enabled: true, Int: -1, Uint: 9, Float: 2.5, Nested: &Address{City: "X"}}
+	want, _ := json.Marshal(v)
+	if got := marshalString(t, v, nil, "public"); got != string(want) {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+
+	m, err := MarshalToMap(v, "public")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m["status"] != "active" || m["priority"] != int64(-3) || m["flags"] != uint64(0xffff) || m["enabled"] != true {
+		t.Errorf("MarshalToMap = %#v", m)
+	}
+}
+
+// TestSliceElementErrorPaths 元素的路径只在出错时拼接，下标必须与出错的元素对应
+func TestSliceElementErrorPaths(t *testing.T) {
+	_, err := MarshalByGroups([]any{1, 2, make(chan int)})
+	var e *Error
+	if !errors.As(err, &e) || e.Path != "[2]" {
+		t.Errorf("chan element: err = %v, want path [2]", err)
+	}
+
+	_, err = MarshalByGroupsWithOptions([]float64{0, 1, math.NaN()}, New().WithCanonicalJSON(true))
+	if !errors.As(err, &e) || e.Path != "[2]" {
+		t.Errorf("NaN element: err = %v, want path [2]", err)
+	}
+
+	type row struct {
+		Values []string `json:"values"`
+	}
+	data, report, err := MarshalByGroupsWithReport([]row{{}, {Values: []string{"ok", "bad\xff"}}}, New().WithBestEffort(true).WithInvalidUTF8Policy(InvalidUTF8PolicyError))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Skipped) != 1 || report.Skipped[0].Path != "[1].Values[1]" {
+		t.Errorf("skipped = %+v, output %s", report.Skipped, data)
+	}
+
+	_, report, err = MarshalByGroupsWithReport([]string{"long value", "ok", "another long"}, New().WithMaxStringLen(4, "…"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(report.Truncated, []string{"[0]", "[2]"}) {
+		t.Errorf("truncated = %v, want [[0] [2]]", report.Truncated)
+	}
+}
+
+// TestScalarSliceAllocations 基本类型切片的元素不按元素分配上下文或格式化下标
+func TestScalarSliceAllocations(t *testing.T) {
+	small, large := make([]int, 10), make([]int, 10000)
+	MarshalByGroups(large)
+	a := testing.AllocsPerRun(20, func() { MarshalByGroups(small) })
+	b := testing.AllocsPerRun(20, func() { MarshalByGroups(large) })
+	// 较大的切片只多出缓冲区扩容的分配
+	if b-a > 40 {
+		t.Errorf("[]int: %v allocs for 10 elements, %v for 10000", a, b)
+	}
+}
+
+// float32Holder 字段按键名排序声明，直接编码与经由map的输出可以逐字节比较
+type float32Holder struct {
+	Any any                `json:"any"`
+	F   float32            `json:"f"`
+	M   map[string]float32 `json:"m"`
+	P   *float32           `json:"p"`
+	S   []float32          `json:"s"`
+}
+
+// TestFloat32MatchesEncodingJSON float32按32位精度输出，各条路径的结果都与encoding/json逐字节一致
+func TestFloat32MatchesEncodingJSON(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		f    float32
+	}{
+		{"0.1", 0.1},
+		{"3.1415", 3.1415},
+		{"16777217 rounds to 16777216", 16777217},
+		{"16777219", 16777219},
+		{"smallest subnormal", math.SmallestNonzeroFloat32},
+		{"largest subnormal", math.Float32frombits(0x007fffff)},
+		{"smallest normal", math.Float32frombits(0x00800000)},
+		{"max", math.MaxFloat32},
+		{"negative", -2.5e-5},
+		{"negative zero", float32(math.Copysign(0, -1))},
+		{"below 1e-6", 9.999999e-7},
+		{"1e-6", 1e-6},
+		{"1e20", 1e20},
+		{"1e21", 1e21},
+		{"precision boundary", 0.3},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			f := tc.f
+			v := float32Holder{F: f, P: &f, S: []float32{f, -f}, M: map[string]float32{"k": f}, Any: f}
+			want, err := json.Marshal(v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := marshalString(t, v, nil); got != string(want) {
+				t.Errorf("direct encoder:\ngot  %s\nwant %s", got, want)
+			}
+
+			// 经由map时float32转换为十进制形式相同的float64
+			m, err := MarshalToMap(v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, _ := json.Marshal(m); string(got) != string(want) {
+				t.Errorf("MarshalToMap:\ngot  %s\nwant %s", got, want)
+			}
+
+			if n, err := EstimateSize(v, nil); err != nil || n != len(want) {
+				t.Errorf("EstimateSize = %d, %v; want %d", n, err, len(want))
+			}
+
+			root, _ := json.Marshal(f)
+			if got := string(AppendFloat32(nil, f)); got != string(root) {
+				t.Errorf("AppendFloat32 = %s, want %s", got, root)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package jsongroup
+
+import (
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// EqualByGroups 比较a和b按相同分组过滤后的结果是否相等，同时返回不相等的路径
+// 两者都转换为与MarshalToMap相同的中间表示后逐项比较，不经过JSON编码和解析；time.Time按Equal比较，
+// 数值按大小比较而不区分整数和浮点类型，NaN默认视为不等，启用NaNEqual后视为相等。
+// 路径由输出中的键名和下标组成，如"settings.hooks[3]"，根值为空字符串，map的键按排序后的顺序比较；opts为nil时使用默认选项。
+// 任一值序列化出错时返回该错误
+func EqualByGroups(a, b any, opts *Options, groups ...string) (bool, []string, error) {
+	if opts == nil {
+		opts = defaults()
+	}
+	o := opts.Clone()
+	o.keepSpecialFloats = true
+
+	av, err := marshalToValue(a, o, groups, false)
+	if err != nil {
+		return false, nil, err
+	}
+	bv, err := marshalToValue(b, o, groups, false)
+	if err != nil {
+		return false, nil, err
+	}
+
+	c := valueComparer{nanEqual: o.NaNEqual}
+	c.compare("", av, bv)
+	return len(c.diffs) == 0, c.diffs, nil
+}
+
+// valueComparer 比较两个中间表示并记录不相等的路径
+type valueComparer struct {
+	nanEqual bool
+	diffs    []string
+}
+
+// compare 比较path处的两个值
+func (c *valueComparer) compare(path string, a, b any) {
+	switch av := a.(type) {
+	case map[string]any:
+		bv, ok := b.(map[string]any)
+		if !ok {
+			c.diffs = append(c.diffs, path)
+			return
+		}
+		// 键按排序后的顺序比较，结果与map的遍历顺序无关
+		keys := slices.Collect(maps.Keys(av))
+		for k := range bv {
+			if _, ok := av[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		slices.Sort(keys)
+		for _, k := range keys {
+			ae, aok := av[k]
+			be, bok := bv[k]
+			if aok != bok {
+				c.diffs = append(c.diffs, joinPath(path, k))
+				continue
+			}
+			c.compare(joinPath(path, k), ae, be)
+		}
+
+	case []any:
+		bv, ok := b.([]any)
+		if !ok || len(av) != len(bv) {
+			c.diffs = append(c.diffs, path)
+			return
+		}
+		for i := range av {
+			c.compare(path+"["+strconv.Itoa(i)+"]", av[i], bv[i])
+		}
+
+	case time.Time:
+		bv, ok := b.(time.Time)
+		if !ok || !av.Equal(bv) {
+			c.diffs = append(c.diffs, path)
+		}
+
+	case int64, uint64, float64:
+		if !c.numbersEqual(a, b) {
+			c.diffs = append(c.diffs, path)
+		}
+
+	default:
+		// string、bool和nil
+		if a != b {
+			c.diffs = append(c.diffs, path)
+		}
+	}
+}
+
+// numbersEqual 按数值比较两个数，b不是数值时返回false
+func (c *valueComparer) numbersEqual(a, b any) bool {
+	switch bv := b.(type) {
+	case int64:
+		switch av := a.(type) {
+		case int64:
+			return av == bv
+		case uint64:
+			return bv >= 0 && av == uint64(bv)
+		}
+	case uint64:
+		switch av := a.(type) {
+		case uint64:
+			return av == bv
+		case int64:
+			return av >= 0 && uint64(av) == bv
+		}
+	case float64:
+		if af, ok := a.(float64); ok && math.IsNaN(af) && math.IsNaN(bv) {
+			return c.nanEqual
+		}
+	default:
+		return false
+	}
+	return toFloat(a) == toFloat(b)
+}
+
+// toFloat 将中间表示中的数值转换为float64
+func toFloat(v any) float64 {
+	switch n := v.(type) {
+	case int64:
+		return float64(n)
+	case uint64:
+		return float64(n)
+	case float64:
+		return n
+	}
+	return math.NaN()
+}
+
+// joinPath 将字段名或map键追加到路径后
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
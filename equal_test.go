@@ -0,0 +1,149 @@
+package jsongroup
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type measurement struct {
+	Name  string    `json:"name" groups:"public"`
+	Value float64   `json:"value" groups:"public"`
+	At    time.Time `json:"at" groups:"public"`
+	Note  string    `json:"note" groups:"internal"`
+}
+
+func TestEqualByGroupsDiffPaths(t *testing.T) {
+	base := newComplexUser(1)
+	changed := func(edit func(u *ComplexUser)) ComplexUser {
+		// newComplexUser每次返回新分配的切片和map，修改不影响base
+		u := newComplexUser(1)
+		edit(&u)
+		return u
+	}
+
+	for _, tc := range []struct {
+		name   string
+		other  ComplexUser
+		groups []string
+		want   []string
+	}{
+		{"identical", changed(func(*ComplexUser) {}), []string{"public"}, nil},
+		{"filtered field", changed(func(u *ComplexUser) { u.Email = "bob@example.com" }), []string{"public"}, nil},
+		{"visible field", changed(func(u *ComplexUser) { u.Email = "bob@example.com" }), []string{"admin"}, []string{"email"}},
+		{"nested slice element", changed(func(u *ComplexUser) { u.Addresses[0].City = "Shelbyville" }), []string{"admin"},
+			[]string{"addresses[0].city"}},
+		{"untagged nested field", changed(func(u *ComplexUser) { u.Profile.Socials[1].Handle = "@bob" }), []string{"public"}, nil},
+		{"slice length", changed(func(u *ComplexUser) { u.Tags = append(u.Tags, "yaml") }), []string{"public"}, []string{"tags"}},
+		{"map entry", changed(func(u *ComplexUser) { u.Labels["tier"] = "silver"; u.Labels["region"] = "eu" }), []string{"internal"},
+			[]string{"labels.region", "labels.tier"}},
+		{"several fields", changed(func(u *ComplexUser) { u.Name = "Bob"; u.Profile.Bio = "bye"; u.Active = false }), []string{"public"},
+			[]string{"active", "name", "profile.bio"}},
+		{"omitempty key", changed(func(u *ComplexUser) { u.Phone = "555" }), []string{"admin"}, []string{"phone"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			equal, diffs, err := EqualByGroups(base, tc.other, nil, tc.groups...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if equal != (len(tc.want) == 0) || !reflect.DeepEqual(diffs, tc.want) {
+				t.Errorf("got %v %q, want diffs %q", equal, diffs, tc.want)
+			}
+		})
+	}
+}
+
+func TestEqualByGroupsRootValues(t *testing.T) {
+	for _, tc := range []struct {
+		a, b  any
+		equal bool
+	}{
+		{1, 1.0, true},
+		{int8(-3), int64(-3), true},
+		{uint(7), 7, true},
+		{-1, uint(math.MaxUint64), false},
+		{"1", 1, false},
+		{nil, nil, true},
+		{[]int{1, 2}, [2]int{1, 2}, true},
+		{map[string]int{"a": 1}, struct {
+			A int `json:"a"`
+		}{1}, true},
+	} {
+		equal, diffs, err := EqualByGroups(tc.a, tc.b, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if equal != tc.equal {
+			t.Errorf("EqualByGroups(%#v, %#v) = %v", tc.a, tc.b, equal)
+		}
+		if !equal && !reflect.DeepEqual(diffs, []string{""}) {
+			t.Errorf("EqualByGroups(%#v, %#v) diffs = %q, want the root path", tc.a, tc.b, diffs)
+		}
+	}
+}
+
+func TestEqualByGroupsComparesTimesWithEqual(t *testing.T) {
+	at := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	shanghai := time.FixedZone("CST", 8*3600)
+	a := measurement{Name: "temp", Value: 21.5, At: at}
+
+	// 同一时刻在不同时区的表示相等，尽管time.Time结构体本身不同
+	for _, other := range []time.Time{at.In(shanghai), at.In(time.FixedZone("EST", -5*3600))} {
+		b := a
+		b.At = other
+		if equal, diffs, err := EqualByGroups(a, b, nil, "public"); err != nil || !equal {
+			t.Errorf("%v vs %v: equal %v, diffs %q, err %v", at, other, equal, diffs, err)
+		}
+	}
+
+	b := a
+	b.At = at.Add(time.Nanosecond)
+	if equal, diffs, _ := EqualByGroups(a, b, nil, "public"); equal || !reflect.DeepEqual(diffs, []string{"at"}) {
+		t.Errorf("one nanosecond apart: equal %v, diffs %q", equal, diffs)
+	}
+}
+
+func TestEqualByGroupsNaN(t *testing.T) {
+	a := measurement{Name: "temp", Value: math.NaN()}
+	b := measurement{Name: "temp", Value: math.NaN()}
+
+	equal, diffs, err := EqualByGroups(a, b, nil, "public")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if equal || !reflect.DeepEqual(diffs, []string{"value"}) {
+		t.Errorf("default: equal %v, diffs %q; want NaN != NaN", equal, diffs)
+	}
+
+	for _, opts := range []*Options{New().WithNaNEqual(true), New().Apply(NaNEqual(true))} {
+		if equal, diffs, err := EqualByGroups(a, b, opts, "public"); err != nil || !equal {
+			t.Errorf("NaNEqual: equal %v, diffs %q, err %v", equal, diffs, err)
+		}
+	}
+
+	// NaN与数值、Inf与Inf按数值比较
+	b.Value = 1
+	if equal, _, _ := EqualByGroups(a, b, New().WithNaNEqual(true), "public"); equal {
+		t.Error("NaN compared equal to 1")
+	}
+	a.Value, b.Value = math.Inf(1), math.Inf(1)
+	if equal, _, _ := EqualByGroups(a, b, nil, "public"); !equal {
+		t.Error("+Inf compared unequal to +Inf")
+	}
+	b.Value = math.Inf(-1)
+	if equal, _, _ := EqualByGroups(a, b, nil, "public"); equal {
+		t.Error("+Inf compared equal to -Inf")
+	}
+}
+
+func TestEqualByGroupsReturnsMarshalErrors(t *testing.T) {
+	type withChan struct {
+		C chan int `json:"c"`
+	}
+	_, _, err := EqualByGroups(withChan{}, withChan{}, nil)
+	if !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf("got %v, want ErrUnsupportedType", err)
+	}
+}
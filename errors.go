@@ -5,6 +5,8 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"strings"
+	"unicode/utf8"
 )
 
 // ErrType 错误类型枚举
@@ -23,8 +25,99 @@ const (
 	ErrTypeReflection
 	// ErrTypeCacheOverflow 缓存溢出错误
 	ErrTypeCacheOverflow
+	// ErrTypeInvalidOptions 选项配置无效
+	ErrTypeInvalidOptions
+	// ErrTypeDeniedGroup 请求了被禁止的分组
+	ErrTypeDeniedGroup
+	// ErrTypeUnsupportedValue 值无法按当前选项编码，如CanonicalJSON下的NaN和Inf
+	ErrTypeUnsupportedValue
+	// ErrTypeMissingGroupTag 启用RequireGroupTags时结构体存在未声明分组标签的字段
+	ErrTypeMissingGroupTag
+	// ErrTypeContradictoryTag 字段标签的声明相互矛盾，如json:"-"的字段声明了分组
+	ErrTypeContradictoryTag
+	// ErrTypeMissingRequired 文档缺少请求的分组要求的字段
+	ErrTypeMissingRequired
+	// ErrTypeInvalidGroupParam 请求中的分组参数无效，如包含未知的分组或分组过多
+	ErrTypeInvalidGroupParam
+	// ErrTypeUnknownVersion 请求的API版本未注册
+	ErrTypeUnknownVersion
 )
 
+// errTypeNames 错误类型的稳定字符串形式，用于String和JSON输出
+// 新增错误类型时只能追加条目，已有的字符串不得修改
+var errTypeNames = map[ErrType]string{
+	ErrTypeUnknown:           "unknown",
+	ErrTypeMaxDepthExceeded:  "max_depth_exceeded",
+	ErrTypeCircularReference: "circular_reference",
+	ErrTypeUnsupportedType:   "unsupported_type",
+	ErrTypeReflection:        "reflection",
+	ErrTypeCacheOverflow:     "cache_overflow",
+	ErrTypeInvalidOptions:    "invalid_options",
+	ErrTypeDeniedGroup:       "denied_group",
+	ErrTypeUnsupportedValue:  "unsupported_value",
+	ErrTypeMissingGroupTag:   "missing_group_tag",
+	ErrTypeContradictoryTag:  "contradictory_tag",
+	ErrTypeMissingRequired:   "missing_required",
+	ErrTypeInvalidGroupParam: "invalid_group_param",
+	ErrTypeUnknownVersion:    "unknown_version",
+}
+
+// String 返回错误类型的稳定字符串形式，如"circular_reference"
+func (t ErrType) String() string {
+	if name, ok := errTypeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("ErrType(%d)", int(t))
+}
+
+// 常见错误类别的哨兵值，可通过errors.Is判断，需要路径等详细信息时仍可用errors.As获取*Error
+var (
+	// ErrMaxDepthExceeded 超过最大递归深度限制
+	ErrMaxDepthExceeded = errors.New("jsongroup: 超过最大递归深度限制")
+	// ErrCircularReference 检测到循环引用
+	ErrCircularReference = errors.New("jsongroup: 检测到循环引用")
+	// ErrUnsupportedType 不支持的类型
+	ErrUnsupportedType = errors.New("jsongroup: 不支持的类型")
+	// ErrCacheOverflow 缓存溢出
+	ErrCacheOverflow = errors.New("jsongroup: 缓存溢出")
+	// ErrInvalidOptions 选项配置无效
+	ErrInvalidOptions = errors.New("jsongroup: 选项配置无效")
+	// ErrDeniedGroup 请求了被禁止的分组
+	ErrDeniedGroup = errors.New("jsongroup: 请求了被禁止的分组")
+	// ErrUnsupportedValue 值无法按当前选项编码
+	ErrUnsupportedValue = errors.New("jsongroup: 不支持的值")
+	// ErrMissingGroupTag 字段未声明分组标签
+	ErrMissingGroupTag = errors.New("jsongroup: 字段未声明分组标签")
+	// ErrContradictoryTag 字段标签相互矛盾
+	ErrContradictoryTag = errors.New("jsongroup: 字段标签相互矛盾")
+	// ErrMissingRequired 文档缺少必需的字段
+	ErrMissingRequired = errors.New("jsongroup: 缺少必需的字段")
+	// ErrInvalidGroupParam 分组参数无效
+	ErrInvalidGroupParam = errors.New("jsongroup: 分组参数无效")
+	// ErrUnknownVersion API版本未注册
+	ErrUnknownVersion = errors.New("jsongroup: 未注册的版本")
+)
+
+// errSkipField 启用IgnoreNilPointers时遇到nil指针返回，通知结构体字段省略该值
+// 仅在包内使用，切片元素、map值和根值处按nil值处理，不会返回给调用方
+var errSkipField = errors.New("jsongroup: 跳过字段")
+
+// sentinels 错误类型到哨兵值的映射
+var sentinels = map[ErrType]error{
+	ErrTypeMaxDepthExceeded:  ErrMaxDepthExceeded,
+	ErrTypeCircularReference: ErrCircularReference,
+	ErrTypeUnsupportedType:   ErrUnsupportedType,
+	ErrTypeCacheOverflow:     ErrCacheOverflow,
+	ErrTypeInvalidOptions:    ErrInvalidOptions,
+	ErrTypeDeniedGroup:       ErrDeniedGroup,
+	ErrTypeUnsupportedValue:  ErrUnsupportedValue,
+	ErrTypeMissingGroupTag:   ErrMissingGroupTag,
+	ErrTypeContradictoryTag:  ErrContradictoryTag,
+	ErrTypeMissingRequired:   ErrMissingRequired,
+	ErrTypeInvalidGroupParam: ErrInvalidGroupParam,
+	ErrTypeUnknownVersion:    ErrUnknownVersion,
+}
+
 // Error 自定义错误结构，提供详细的错误上下文
 type Error struct {
 	// Type 错误类型
@@ -33,7 +126,10 @@ type Error struct {
 	Message string
 	// Path 错误发生的路径（字段路径）
 	Path string
-	// Value 相关的值（可能为nil）
+	// FirstSeenPath 循环引用错误中，被重复引用的值首次出现的路径，根值为空字符串
+	FirstSeenPath string
+	// Value 相关的值（可能为nil），序列化时启用RedactErrors则只记录类型名；
+	// 关闭RedactErrors时循环引用错误记录值的简短文本摘要，不持有原值
 	Value any
 	// Cause 原始错误（可能为nil）
 	Cause error
@@ -56,31 +152,163 @@ func (e *Error) Unwrap() error {
 	return e.Cause
 }
 
-// MaxDepthError 创建超出最大递归深度的错误
-func MaxDepthError(path string, value reflect.Value, maxDepth int) *Error {
-	var val any
-	if value.IsValid() {
-		val = value.Interface()
+// Is 支持errors.Is，错误类型对应的哨兵值视为相等
+func (e *Error) Is(target error) bool {
+	sentinel, ok := sentinels[e.Type]
+	return ok && target == sentinel
+}
+
+// errorJSON 错误的JSON表示
+type errorJSON struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+	Path    string `json:"path,omitempty"`
+	// FirstSeenPath 仅循环引用错误包含
+	FirstSeenPath string `json:"first_seen_path,omitempty"`
+	Cause         string `json:"cause,omitempty"`
+}
+
+// MarshalJSON 实现json.Marshaler，输出{"type":...,"message":...,"path":...}
+// 不包含Value和Cause，可直接作为API错误响应返回；需要原始错误信息时使用MarshalJSONVerbose
+func (e *Error) MarshalJSON() ([]byte, error) {
+	return json.Marshal(errorJSON{Type: e.Type.String(), Message: e.Message, Path: e.Path, FirstSeenPath: e.FirstSeenPath})
+}
+
+// MarshalJSONVerbose 与MarshalJSON相同，额外在cause字段中包含原始错误的信息
+func (e *Error) MarshalJSONVerbose() ([]byte, error) {
+	out := errorJSON{Type: e.Type.String(), Message: e.Message, Path: e.Path, FirstSeenPath: e.FirstSeenPath}
+	if e.Cause != nil {
+		out.Cause = e.Cause.Error()
+	}
+	return json.Marshal(out)
+}
+
+// valueTypeName 返回错误中记录的值的类型名
+// 不调用Interface()，避免在出错时复制可能很大的值
+func valueTypeName(value reflect.Value) any {
+	if !value.IsValid() {
+		return nil
+	}
+	return value.Type().String()
+}
+
+// valueSummaryLimit 错误中记录的值摘要的最大字节数
+const valueSummaryLimit = 60
+
+// valueSummary 返回值的简短文本摘要，格式与%v相近，长度不超过valueSummaryLimit字节
+// 引发循环引用错误的值本身包含环，不能交给fmt完整格式化：只展开最外层指针，
+// 更深的指针只输出地址，map、切片和接口逐层展开，达到长度上限后停止
+func valueSummary(value reflect.Value) string {
+	var b strings.Builder
+	writeSummary(&b, value, true)
+	s := b.String()
+	if len(s) <= valueSummaryLimit {
+		return s
+	}
+	cut := valueSummaryLimit
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + "..."
+}
+
+// writeSummary 将v的摘要写入b，top表示v是否为最外层的值
+func writeSummary(b *strings.Builder, v reflect.Value, top bool) {
+	if b.Len() > valueSummaryLimit {
+		return
+	}
+	switch v.Kind() {
+	case reflect.Invalid:
+		b.WriteString("<nil>")
+	case reflect.Pointer:
+		if v.IsNil() {
+			b.WriteString("<nil>")
+		} else if top {
+			b.WriteByte('&')
+			writeSummary(b, v.Elem(), false)
+		} else {
+			fmt.Fprintf(b, "%#x", v.Pointer())
+		}
+	case reflect.Interface:
+		if v.IsNil() {
+			b.WriteString("<nil>")
+		} else {
+			writeSummary(b, v.Elem(), top)
+		}
+	case reflect.Struct:
+		b.WriteByte('{')
+		for i := range v.NumField() {
+			if i > 0 {
+				b.WriteByte(' ')
+			}
+			writeSummary(b, v.Field(i), false)
+		}
+		b.WriteByte('}')
+	case reflect.Map:
+		b.WriteString("map[")
+		iter := v.MapRange()
+		for i := 0; iter.Next(); i++ {
+			if i > 0 {
+				b.WriteByte(' ')
+			}
+			writeSummary(b, iter.Key(), false)
+			b.WriteByte(':')
+			writeSummary(b, iter.Value(), false)
+			if b.Len() > valueSummaryLimit {
+				break
+			}
+		}
+		b.WriteByte(']')
+	case reflect.Slice, reflect.Array:
+		b.WriteByte('[')
+		for i := range v.Len() {
+			if i > 0 {
+				b.WriteByte(' ')
+			}
+			writeSummary(b, v.Index(i), false)
+			if b.Len() > valueSummaryLimit {
+				break
+			}
+		}
+		b.WriteByte(']')
+	default:
+		// fmt直接格式化reflect.Value持有的值，非导出字段同样适用；精度限制字符串的长度
+		fmt.Fprintf(b, "%.60v", v)
 	}
+}
+
+// MaxDepthError 创建超出最大递归深度的错误，Value只记录值的类型名
+func MaxDepthError(path string, value reflect.Value, maxDepth int) *Error {
 	return &Error{
 		Type:    ErrTypeMaxDepthExceeded,
 		Message: fmt.Sprintf("已超过最大递归深度限制(%d)", maxDepth),
 		Path:    path,
-		Value:   val,
+		Value:   valueTypeName(value),
 	}
 }
 
-// CircularReferenceError 创建循环引用错误
+// CircularReferenceError 创建循环引用错误，Value只记录值的类型名
 func CircularReferenceError(path string, value reflect.Value) *Error {
-	var val any
-	if value.IsValid() {
-		val = value.Interface()
-	}
 	return &Error{
 		Type:    ErrTypeCircularReference,
 		Message: "检测到循环引用",
 		Path:    path,
-		Value:   val,
+		Value:   valueTypeName(value),
+	}
+}
+
+// cycleError 创建同时记录重新进入路径和首次出现路径的循环引用错误
+func cycleError(path, firstSeenPath string, value any) *Error {
+	where := "根值"
+	if firstSeenPath != "" {
+		where = "'" + firstSeenPath + "'"
+	}
+	return &Error{
+		Type:          ErrTypeCircularReference,
+		Message:       fmt.Sprintf("检测到循环引用，该值首次出现于%s", where),
+		Path:          path,
+		FirstSeenPath: firstSeenPath,
+		Value:         value,
 	}
 }
 
@@ -109,6 +337,16 @@ func UnsupportedTypeError(path string, typeName any) *Error {
 	}
 }
 
+// UnsupportedValueError 创建值无法编码的错误，reason说明原因，Value只记录值的类型名
+func UnsupportedValueError(path string, value reflect.Value, reason string) *Error {
+	return &Error{
+		Type:    ErrTypeUnsupportedValue,
+		Message: "不支持的值: " + reason,
+		Path:    path,
+		Value:   valueTypeName(value),
+	}
+}
+
 // ReflectionError 创建反射操作错误
 func ReflectionError(path string, err error) *Error {
 	return &Error{
@@ -127,6 +365,85 @@ func CacheOverflowError(cacheType string, maxSize int) *Error {
 	}
 }
 
+// panicError 将recover得到的值转换为带路径的反射错误
+// 已经是自定义错误时原样返回，保留其中更精确的路径
+func panicError(path string, r any) error {
+	err, ok := r.(error)
+	if !ok {
+		err = fmt.Errorf("%v", r)
+	}
+	var ourErr *Error
+	if errors.As(err, &ourErr) {
+		return err
+	}
+	return ReflectionError(path, err)
+}
+
+// MissingGroupTagError 创建字段未声明分组标签的错误，fields为所有缺少标签的字段路径
+func MissingGroupTagError(path, tagKey string, fields []string) *Error {
+	return &Error{
+		Type:    ErrTypeMissingGroupTag,
+		Message: fmt.Sprintf("以下字段未声明%s标签: %s", tagKey, strings.Join(fields, ", ")),
+		Path:    path,
+		Value:   fields,
+	}
+}
+
+// ContradictoryTagError 创建字段标签相互矛盾的错误，problems为带字段路径的问题描述
+func ContradictoryTagError(path string, problems []string) *Error {
+	return &Error{
+		Type:    ErrTypeContradictoryTag,
+		Message: "字段标签相互矛盾: " + strings.Join(problems, "; "),
+		Path:    path,
+		Value:   problems,
+	}
+}
+
+// MissingRequiredError 创建文档缺少必需字段的错误，paths为所有缺少的字段的JSON路径
+func MissingRequiredError(paths []string) *Error {
+	return &Error{
+		Type:    ErrTypeMissingRequired,
+		Message: "缺少必需的字段: " + strings.Join(paths, ", "),
+		Value:   paths,
+	}
+}
+
+// InvalidGroupParamError 创建分组参数无效的错误，param为原始参数，problem为问题描述
+func InvalidGroupParamError(param, problem string) *Error {
+	return &Error{
+		Type:    ErrTypeInvalidGroupParam,
+		Message: "分组参数无效: " + problem,
+		Value:   param,
+	}
+}
+
+// UnknownVersionError 创建API版本未注册的错误
+func UnknownVersionError(version string) *Error {
+	return &Error{
+		Type:    ErrTypeUnknownVersion,
+		Message: "未注册的版本: " + version,
+		Value:   version,
+	}
+}
+
+// InvalidOptionsError 创建选项配置无效的错误，problems为发现的所有问题
+func InvalidOptionsError(problems []string) *Error {
+	return &Error{
+		Type:    ErrTypeInvalidOptions,
+		Message: "选项配置无效: " + strings.Join(problems, "; "),
+		Value:   problems,
+	}
+}
+
+// DeniedGroupError 创建请求了被禁止分组的错误，groups为请求中被禁止的分组
+func DeniedGroupError(groups []string) *Error {
+	return &Error{
+		Type:    ErrTypeDeniedGroup,
+		Message: "请求了被禁止的分组: " + strings.Join(groups, ", "),
+		Value:   groups,
+	}
+}
+
 // RecoverFromPanic 捕获并处理panic，转换为标准error
 func RecoverFromPanic(path string) func() error {
 	return func() (err error) {
@@ -164,19 +481,25 @@ func WrapJSONError(err error, path string) error {
 	}
 
 	// 转换标准JSON库错误为自定义错误
-	switch e := err.(type) {
-	case *json.UnsupportedTypeError:
-		return UnsupportedTypeError(path, e.Type.String())
-	case *json.MarshalerError:
-		return ReflectionError(path, e.Err)
-	case *json.SyntaxError, *json.InvalidUnmarshalError:
-		return ReflectionError(path, e)
+	// 使用errors.As而不是类型断言，第三方编码器包装后的标准库错误同样可以识别
+	var unsupportedErr *json.UnsupportedTypeError
+	var marshalerErr *json.MarshalerError
+	var syntaxErr *json.SyntaxError
+	var unmarshalErr *json.InvalidUnmarshalError
+	switch {
+	case errors.As(err, &unsupportedErr) && unsupportedErr.Type != nil:
+		return UnsupportedTypeError(path, unsupportedErr.Type.String())
+	case errors.As(err, &marshalerErr):
+		return ReflectionError(path, marshalerErr.Err)
+	case errors.As(err, &syntaxErr), errors.As(err, &unmarshalErr):
+		return ReflectionError(path, err)
 	default:
+		// 无法识别的错误（如第三方编码器的错误类型）保留原始错误作为Cause
 		return &Error{
 			Type:    ErrTypeUnknown,
-			Message: e.Error(),
+			Message: "编码失败",
 			Path:    path,
-			Cause:   e,
+			Cause:   err,
 		}
 	}
 }
@@ -0,0 +1,96 @@
+package jsongroup
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+type secretNode struct {
+	Token string      `json:"token" groups:"public"`
+	Next  *secretNode `json:"next" groups:"public"`
+}
+
+func cycleValue(t *testing.T, v any, opts *Options) any {
+	t.Helper()
+	_, err := MarshalByGroupsWithOptions(v, opts, "public")
+	var jerr *Error
+	if !errors.As(err, &jerr) || jerr.Type != ErrTypeCircularReference {
+		t.Fatalf("got %v, want a circular reference error", err)
+	}
+	return jerr.Value
+}
+
+func TestCycleErrorValueRedaction(t *testing.T) {
+	n := &secretNode{Token: strings.Repeat("秘", 100)}
+	n.Next = n
+
+	for name, opts := range map[string]*Options{"New": New(), "zero": {}, "OptionsFromMap": mustOptionsFromMap(t, map[string]any{})} {
+		if got := cycleValue(t, n, opts); got != "*jsongroup.secretNode" {
+			t.Errorf("%s: Value = %#v, want the type name", name, got)
+		}
+	}
+
+	got, ok := cycleValue(t, n, New().WithRedactedErrors(false)).(string)
+	if !ok {
+		t.Fatalf("Value is %T, want a string summary", got)
+	}
+	if len(got) > valueSummaryLimit+len("...") || !utf8.ValidString(got) || !strings.HasPrefix(got, "&{秘") {
+		t.Errorf("summary %q is not a bounded UTF-8 prefix", got)
+	}
+}
+
+func TestValueSummaryOfSelfReferencingCollections(t *testing.T) {
+	m := map[string]any{"k": 1}
+	m["self"] = m
+	s := []any{nil}
+	s[0] = s
+
+	for name, v := range map[string]any{"map": m, "slice": s} {
+		got, ok := cycleValue(t, v, New().WithRedactedErrors(false)).(string)
+		if !ok || len(got) > valueSummaryLimit+len("...") {
+			t.Errorf("%s: summary %q is not bounded", name, got)
+		}
+	}
+}
+
+func mustOptionsFromMap(t *testing.T, m map[string]any) *Options {
+	t.Helper()
+	opts, err := OptionsFromMap(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return opts
+}
+
+// TestUnsupportedTypePaths 不支持的类型位于map值和切片元素深处时，错误路径指向该元素而不是根值
+func TestUnsupportedTypePaths(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		v    any
+		path string
+	}{
+		{"map values", map[string]any{"settings": map[string]any{"hooks": []any{1, 2, 3, make(chan int)}}}, "settings.hooks[3]"},
+		{"struct fields", struct {
+			Settings policySettings `json:"settings" groups:"public"`
+		}{policySettings{Hooks: []any{1, 2, 3, func() {}}}}, "Settings.Hooks[3]"},
+		{"pointer elements", []*policySettings{nil, {Env: map[string]any{"x": []any{make(chan int)}}}}, "[1].Env.x[0]"},
+		{"root", make(chan int), ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := New().WithIgnoreNilPointers(false)
+			_, directErr := MarshalByGroupsWithOptions(tc.v, opts, "public")
+			_, mapErr := MarshalToValue(tc.v, opts, "public")
+			for name, err := range map[string]error{"direct": directErr, "map": mapErr} {
+				var jerr *Error
+				if !errors.Is(err, ErrUnsupportedType) || !errors.As(err, &jerr) {
+					t.Fatalf("%s: got %v, want ErrUnsupportedType", name, err)
+				}
+				if jerr.Path != tc.path {
+					t.Errorf("%s: path = %q, want %q", name, jerr.Path, tc.path)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package jsongroup
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// failingBinary MarshalBinary总是返回错误
+type failingBinary struct{}
+
+func (failingBinary) MarshalBinary() ([]byte, error) { return nil, errors.New("marshaler failed") }
+
+type policySettings struct {
+	Hooks []any          `json:"hooks" groups:"public"`
+	Env   map[string]any `json:"env" groups:"public"`
+}
+
+type policyDoc struct {
+	Name     string         `json:"name" groups:"public"`
+	Callback func()         `json:"callback" groups:"public"`
+	Custom   failingBinary  `json:"custom" groups:"public"`
+	Settings policySettings `json:"settings" groups:"public"`
+	Secret   chan int       `json:"secret" groups:"admin"`
+}
+
+func newPolicyDoc() policyDoc {
+	return policyDoc{
+		Name:     "doc",
+		Callback: func() {},
+		Settings: policySettings{
+			Hooks: []any{"a", make(chan int), 3},
+			Env:   map[string]any{"ok": 1, "bad": func() {}},
+		},
+		Secret: make(chan int),
+	}
+}
+
+var policyErrorPaths = []string{"Callback", "Custom", "Settings.Hooks[1]", "Settings.Env.bad"}
+
+func TestErrorPolicyCollect(t *testing.T) {
+	opts := New().WithErrorPolicy(ErrorPolicyCollect)
+	// 不属于请求分组的字段不会被访问，不产生错误
+	want := `{"name":"doc","callback":null,"custom":null,"settings":{"hooks":["a",null,3],"env":{"bad":null,"ok":1}}}`
+
+	data, err := MarshalByGroupsWithOptions(newPolicyDoc(), opts, "public")
+	if got := errorPaths(t, err); !reflect.DeepEqual(got, policyErrorPaths) {
+		t.Errorf("direct encoder paths = %v, want %v", got, policyErrorPaths)
+	}
+	assertJSONEqual(t, string(data), want)
+	if !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf("errors.Is(err, ErrUnsupportedType) = false for %v", err)
+	}
+
+	value, err := MarshalToValue(newPolicyDoc(), opts, "public")
+	if got := errorPaths(t, err); !reflect.DeepEqual(got, policyErrorPaths) {
+		t.Errorf("MarshalToValue paths = %v, want %v", got, policyErrorPaths)
+	}
+	if value == nil {
+		t.Error("MarshalToValue returned no result alongside the collected errors")
+	}
+
+	// 没有错误时返回nil，而不是空的汇总错误
+	if _, err := MarshalByGroupsWithOptions(newPolicyDoc(), opts, "none"); err != nil {
+		t.Errorf("got %v, want nil", err)
+	}
+
+	// 循环引用继续处理并不安全，仍然立即返回，不输出部分结果
+	d := &cycleData{Name: "d"}
+	d.Self = d
+	data, err = MarshalByGroupsWithOptions(d, opts, "public")
+	if !errors.Is(err, ErrCircularReference) || data != nil {
+		t.Errorf("cycle: got %s, %v; want ErrCircularReference and no output", data, err)
+	}
+
+	// 默认策略返回第一个错误
+	_, err = MarshalByGroupsWithOptions(newPolicyDoc(), New(), "public")
+	var jerr *Error
+	if !errors.As(err, &jerr) || jerr.Path != "Callback" {
+		t.Errorf("FailFast: got %v, want the error at Callback", err)
+	}
+}
+
+func TestBestEffort(t *testing.T) {
+	opts := New().WithBestEffort(true)
+	want := `{"name":"doc","settings":{"hooks":["a",3],"env":{"ok":1}}}`
+
+	data, report, err := MarshalByGroupsWithReport(newPolicyDoc(), opts, "public")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertJSONEqual(t, string(data), want)
+	var paths []string
+	for _, s := range report.Skipped {
+		paths = append(paths, s.Path)
+		var jerr *Error
+		if !errors.As(s.Err, &jerr) || jerr.Path != s.Path {
+			t.Errorf("skipped %s: got %v, want *Error with the same path", s.Path, s.Err)
+		}
+	}
+	if !reflect.DeepEqual(paths, policyErrorPaths) {
+		t.Errorf("skipped = %v, want %v", paths, policyErrorPaths)
+	}
+
+	// 不需要报告时同样成功
+	data, err = MarshalByGroupsWithOptions(newPolicyDoc(), opts, "public")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertJSONEqual(t, string(data), want)
+	m, err := MarshalToMapWithOptions(newPolicyDoc(), opts, "public")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m["callback"]; ok {
+		t.Errorf("MarshalToMap kept the failing field: %#v", m)
+	}
+
+	// 循环引用和超出深度限制时在该处截断
+	d := &cycleData{Name: "d"}
+	d.Self = d
+	data, report, err = MarshalByGroupsWithReport(d, opts, "public")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertJSONEqual(t, string(data), `{"name":"d"}`)
+	if len(report.Skipped) != 1 || !errors.Is(report.Skipped[0].Err, ErrCircularReference) {
+		t.Errorf("cycle: skipped = %+v", report.Skipped)
+	}
+	data, report, err = MarshalByGroupsWithReport(cycleHolder{Data: &cycleData{Name: "a", Self: &cycleData{Name: "b"}}}, opts.WithMaxDepth(3), "public")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertJSONEqual(t, string(data), `{"data":{"name":"a"}}`)
+	if len(report.Skipped) != 1 || !errors.Is(report.Skipped[0].Err, ErrMaxDepthExceeded) {
+		t.Errorf("MaxDepth: skipped = %+v", report.Skipped)
+	}
+
+	// 根值出错时输出null
+	data, report, err = MarshalByGroupsWithReport(make(chan int), opts)
+	if err != nil || string(data) != "null" || len(report.Skipped) != 1 || report.Skipped[0].Path != "" {
+		t.Errorf("root: got %s, %+v, %v", data, report, err)
+	}
+
+	if _, err := MarshalByGroupsWithOptions(1, opts.WithErrorPolicy(ErrorPolicyCollect)); !errors.Is(err, ErrInvalidOptions) {
+		t.Errorf("BestEffort with Collect: got %v, want ErrInvalidOptions", err)
+	}
+}
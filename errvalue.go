@@ -0,0 +1,132 @@
+package jsongroup
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// errorType error接口的反射类型
+var errorType = reflect.TypeFor[error]()
+
+// errorTypes 缓存各类型是否实现了error
+var errorTypes sync.Map
+
+// implementsError 判断类型是否实现了error，结果按类型缓存
+func implementsError(t reflect.Type) bool {
+	if ok, exists := errorTypes.Load(t); exists {
+		return ok.(bool)
+	}
+	ok := t.Implements(errorType)
+	errorTypes.Store(t, ok)
+	return ok
+}
+
+// errorValue 按ErrorFormat将实现了error的值转换为输出形式：字符串、{"message","type"}对象，或启用ErrorChain时的数组
+// 非nil的接口值按其动态值判断，因此以字符串、整数等为底层类型的错误同样输出Error()，%T得到的也是具体类型；
+// v不是error或ErrorFormat为ErrorFormatReflect时返回false
+func (ctx *serializeContext) errorValue(v reflect.Value) (reflect.Value, bool) {
+	if ctx.opts.ErrorFormat == ErrorFormatReflect {
+		return reflect.Value{}, false
+	}
+	if v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+	if !v.CanInterface() || !implementsError(v.Type()) {
+		return reflect.Value{}, false
+	}
+	err := v.Interface().(error)
+
+	if !ctx.opts.ErrorChain {
+		return reflect.ValueOf(ctx.errorItem(err)), true
+	}
+	chain := errorChain(err, nil)
+	if ctx.opts.ErrorFormat == ErrorFormatObject {
+		item := errorObject(chain[0])
+		causes := make([]any, 0, len(chain)-1)
+		for _, cause := range chain[1:] {
+			causes = append(causes, errorObject(cause))
+		}
+		item["causes"] = causes
+		return reflect.ValueOf(item), true
+	}
+	items := make([]any, 0, len(chain))
+	for _, e := range chain {
+		items = append(items, e.Error())
+	}
+	return reflect.ValueOf(items), true
+}
+
+// errorItem 按ErrorFormat输出单个错误，不展开错误链
+func (ctx *serializeContext) errorItem(err error) any {
+	if ctx.opts.ErrorFormat == ErrorFormatObject {
+		return errorObject(err)
+	}
+	return err.Error()
+}
+
+// errorObject 返回ErrorFormatObject形式的错误
+func errorObject(err error) map[string]any {
+	return map[string]any{
+		"message": err.Error(),
+		"type":    fmt.Sprintf("%T", err),
+	}
+}
+
+// errorChain 深度优先列出err及其通过Unwrap() error或Unwrap() []error包装的错误，nil错误跳过
+func errorChain(err error, chain []error) []error {
+	if err == nil {
+		return chain
+	}
+	chain = append(chain, err)
+	switch u := err.(type) {
+	case interface{ Unwrap() error }:
+		return errorChain(u.Unwrap(), chain)
+	case interface{ Unwrap() []error }:
+		for _, e := range u.Unwrap() {
+			chain = errorChain(e, chain)
+		}
+	}
+	return chain
+}
+
+// nullsNilError 判断字段是否为启用NullNilErrors时需要输出null的nil error接口
+func (ctx *serializeContext) nullsNilError(v reflect.Value) bool {
+	return ctx.opts.NullNilErrors && v.Kind() == reflect.Interface && v.IsNil() && implementsError(v.Type())
+}
+
+// errorSchema 返回实现了error的类型按ErrorFormat输出时的schema
+// 以基本类型为底层类型的具体错误类型在结构体字段中按其值输出，只有经由接口时才输出Error()，这里同样不做处理
+func (b *schemaBuilder) errorSchema(t reflect.Type) (map[string]any, bool) {
+	if b.opts.ErrorFormat == ErrorFormatReflect || !implementsError(t) {
+		return nil, false
+	}
+	if _, scalar := mapperFor(t); scalar {
+		return nil, false
+	}
+
+	if b.opts.ErrorFormat == ErrorFormatObject {
+		item := errorObjectSchema()
+		if b.opts.ErrorChain {
+			item["properties"].(map[string]any)["causes"] = map[string]any{"type": "array", "items": errorObjectSchema()}
+			item["required"] = []string{"causes", "message", "type"}
+		}
+		return item, true
+	}
+	if b.opts.ErrorChain {
+		return map[string]any{"type": "array", "items": map[string]any{"type": "string"}}, true
+	}
+	return map[string]any{"type": "string"}, true
+}
+
+// errorObjectSchema 返回ErrorFormatObject形式的单个错误的schema
+func errorObjectSchema() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"message": map[string]any{"type": "string"},
+			"type":    map[string]any{"type": "string"},
+		},
+		"required": []string{"message", "type"},
+	}
+}
@@ -0,0 +1,494 @@
+package jsongroup
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// EstimateSize 估算v按分组序列化后的JSON字节数，不构建map也不生成JSON
+// 字段过滤、omitempty、NullIfEmpty、IgnoreNilPointers、顶层包装和集合包装的规则与MarshalByGroupsWithOptions一致，
+// 字符串按字节数加上需要转义的字符估算，数值和时间按实际格式化的长度计算，通常与实际输出相差不超过几个百分点；
+// 不调用生成的静态序列化方法，不计入Envelope的元数据和XRay的元数据。
+// 深度限制、循环引用检测和错误策略与序列化相同，ErrorPolicyCollect策略下出错的值按null计入并返回汇总的错误
+func EstimateSize(v any, opts *Options, groups ...string) (n int, err error) {
+	if opts == nil {
+		opts = defaults()
+	}
+	if err := opts.Validate(); err != nil {
+		return 0, err
+	}
+	groups, err = opts.resolveGroups(groups)
+	if err != nil {
+		return 0, err
+	}
+
+	// 捕获可能的panic并转换为错误
+	defer func() {
+		if r := recover(); r != nil {
+			n, err = 0, panicError("Root", r)
+		}
+	}()
+
+	if v == nil {
+		return len("null"), nil
+	}
+
+	ctx := newContext(*opts, groups)
+	defer ctx.release()
+	if err := ctx.checkRenamePaths(v); err != nil {
+		return 0, err
+	}
+
+	// 每层包装为{"key":...}
+	for _, key := range opts.topLevelKeys() {
+		n += estimateString(key) + len("{:}")
+	}
+
+	rv := addressable(reflect.ValueOf(v))
+	var size int
+	var ok bool
+	if isCollection(opts, rv) {
+		size, ok, err = estimateCollection(ctx, rv, groups, opts.GroupMode)
+	} else {
+		size, ok, err = nilIfSkipped3(estimateValue(ctx, rv, groups, opts.GroupMode))
+	}
+	if err != nil {
+		if !opts.BestEffort || ctx.handleError(err) != errorOmit {
+			return 0, WrapJSONError(err, "Root")
+		}
+		ok = false
+	}
+	if !ok {
+		size = len("null")
+	}
+	return n + size, ctx.collectedErrors()
+}
+
+// nilIfSkipped3 与nilIfSkipped相同，用于返回估算长度的函数
+func nilIfSkipped3(n int, ok bool, err error) (int, bool, error) {
+	if err != nil && errors.Is(err, errSkipField) {
+		return 0, false, nil
+	}
+	return n, ok, err
+}
+
+// estimateValue 估算单个值编码后的字节数，ok为false表示该值对应nil，由调用方决定省略还是输出null
+// 与encodeValue的分支一一对应
+func estimateValue(ctx *serializeContext, v reflect.Value, groups []string, mode GroupMode) (n int, ok bool, err error) {
+	kind := v.Kind()
+
+	switch kind {
+	case reflect.String:
+		s := v.String()
+		if s == "" && ctx.opts.NullIfEmpty {
+			return 0, false, nil
+		}
+		if err := ctx.checkUTF8(v, s, "字符串"); err != nil {
+			return 0, false, err
+		}
+		if end, ok := truncatedEnd(s, ctx.opts.MaxStringLen); ok {
+			return estimateString(s[:end]) + estimateString(ctx.opts.MaxStringMarker) - 2, true, nil
+		}
+		return estimateString(s), true, nil
+	case reflect.Float32, reflect.Float64:
+		if err := ctx.checkFloat(v); err != nil {
+			return 0, false, err
+		}
+		return estimateScalar(v), true, nil
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Complex64, reflect.Complex128:
+		return estimateScalar(v), true, nil
+	}
+
+	if (kind == reflect.Pointer || kind == reflect.Interface) && v.IsNil() {
+		if ctx.opts.IgnoreNilPointers && kind == reflect.Pointer {
+			return 0, false, errSkipField
+		}
+		return 0, false, nil
+	}
+
+	if err := ctx.enterLevel(); err != nil {
+		if (kind == reflect.Slice || kind == reflect.Map) && v.Len() == 0 {
+			ctx.leaveLevel()
+			if ctx.opts.NullIfEmpty {
+				return 0, false, nil
+			}
+			return len("[]"), true, nil
+		}
+		return 0, false, err
+	}
+	defer func() {
+		ctx.leaveLevel()
+		if r := recover(); r != nil {
+			n, ok, err = 0, false, panicError(ctx.path(), r)
+		}
+	}()
+
+	if kind == reflect.Ptr || kind == reflect.Map || kind == reflect.Slice {
+		if err := ctx.checkPointer(v); err != nil {
+			return 0, false, err
+		}
+	}
+
+	if rep, ok := ctx.errorValue(v); ok {
+		return estimateValue(ctx.withPath(""), rep, groups, mode)
+	}
+	if rep, ok, err := ctx.binaryValue(v); err != nil {
+		return 0, false, err
+	} else if ok {
+		return estimateValue(ctx.withPath(""), rep, groups, mode)
+	}
+
+	switch kind {
+	case reflect.Ptr, reflect.Interface:
+		return estimateValue(ctx.withPath(""), v.Elem(), groups, mode)
+
+	case reflect.Struct:
+		if v.Type() == timeType {
+			t := timeOf(v)
+			if t.IsZero() && ctx.opts.NullIfEmpty {
+				return 0, false, nil
+			}
+			return estimateTime(t), true, nil
+		}
+		if v.Type() == syncMapType {
+			snapshot, err := ctx.syncMapSnapshot(v)
+			if err != nil {
+				return 0, false, err
+			}
+			if snapshot.Len() == 0 && ctx.opts.NullIfEmpty {
+				return 0, false, nil
+			}
+			n, err := estimateMap(ctx, snapshot, groups, mode)
+			return n, true, err
+		}
+		if loaded, ok, err := ctx.atomicLoad(v); err != nil {
+			return 0, false, err
+		} else if ok {
+			return estimateValue(ctx.withPath(""), loaded, groups, mode)
+		}
+		structCtx := ctx
+		if typed := ctx.withTypeOptions(v.Type(), groups); typed != ctx {
+			structCtx, mode = typed, typed.opts.GroupMode
+		}
+		n, err := estimateStruct(structCtx, structCtx.lockedSnapshot(v), groups, mode)
+		return n, true, err
+
+	case reflect.Map:
+		if v.Len() == 0 && ctx.opts.NullIfEmpty {
+			return 0, false, nil
+		}
+		n, err := estimateMap(ctx, v, groups, mode)
+		return n, true, err
+
+	case reflect.Slice, reflect.Array:
+		if v.Len() == 0 {
+			if ctx.opts.NullIfEmpty && v.Kind() == reflect.Slice && v.IsNil() {
+				return 0, false, nil
+			}
+			ctx.endArray(0)
+			return len("[]"), true, nil
+		}
+		n, _, err := estimateSlice(ctx, v, groups, mode)
+		ctx.endArray(v.Len())
+		return n, true, err
+
+	case reflect.Func:
+		if isSeq(v.Type()) {
+			return estimateSeq(ctx, v, groups, mode)
+		}
+		return 0, false, UnsupportedTypeError(ctx.path(), v.Type().String())
+
+	default:
+		return 0, false, UnsupportedTypeError(ctx.path(), v.Type().String())
+	}
+}
+
+// estimateStruct 估算结构体编码后的字节数，字段的省略规则与encodeField一致
+// 存在重名字段时按全部字段估算，结果可能略大于实际输出
+func estimateStruct(ctx *serializeContext, v reflect.Value, groups []string, mode GroupMode) (int, error) {
+	if err := checkGroupTags(ctx.opts, v.Type(), ctx.path()); err != nil {
+		return 0, err
+	}
+	if err := ctx.checkRenames(v.Type()); err != nil {
+		return 0, err
+	}
+	set, err := getFilteredFields(v.Type(), ctx.opts, groups, ctx.groupKey, mode, ctx.inherited)
+	if err != nil {
+		return 0, ReflectionError(ctx.path(), err)
+	}
+
+	n := len("{}")
+	count := 0
+	var embeds embedOmitter
+	for _, field := range set.fields {
+		if len(field.Embeds) > 0 && !ctx.opts.NullIfEmpty && embeds.omits(v, set, field) {
+			continue
+		}
+		size, ok, err := estimateField(ctx, v, field, groups, mode)
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			n += size
+			count++
+		}
+	}
+	if count > 1 {
+		n += count - 1
+	}
+	ctx.endStruct(v.Type(), count == 0)
+	return n, nil
+}
+
+// estimateField 估算单个字段（含键名）编码后的字节数，返回false表示字段被省略
+func estimateField(ctx *serializeContext, v reflect.Value, field fieldInfo, groups []string, mode GroupMode) (int, bool, error) {
+	fieldValue := v.FieldByIndex(field.Index)
+	key := len(ctx.encodedFieldKey(field))
+
+	if ctx.opts.ValueFilter != nil && !ctx.withField(field).keepsField(v, field, fieldValue) {
+		return 0, false, nil
+	}
+
+	replaced := false
+	if ctx.opts.FieldHook != nil {
+		fieldCtx := ctx.withField(field)
+		var err error
+		if fieldValue, replaced, err = fieldCtx.callFieldHook(v, field, fieldValue); err != nil {
+			switch fieldCtx.handleError(err) {
+			case errorFail:
+				return 0, false, err
+			case errorOmit:
+				return 0, false, nil
+			}
+			return key + len("null"), true, nil
+		}
+	}
+
+	if field.Scalar && !replaced && !ctx.truncatesField(fieldValue) && !ctx.rejectsField(fieldValue) {
+		if (field.OmitEmpty || field.OmitZero || ctx.opts.NullIfEmpty) && isEmptyValue(fieldValue) {
+			if !ctx.opts.NullIfEmpty {
+				return 0, false, nil
+			}
+			return key + len("null"), true, nil
+		}
+		if fieldValue.Kind() == reflect.String {
+			return key + estimateString(fieldValue.String()), true, nil
+		}
+		return key + estimateScalar(fieldValue), true, nil
+	}
+
+	isNilPointer := fieldValue.Kind() == reflect.Ptr && fieldValue.IsNil()
+	if isNilPointer && ctx.opts.IgnoreNilPointers {
+		return 0, false, nil
+	}
+	isNilOrEmpty := isNilPointer || isEmptyValue(fieldValue)
+	if (field.OmitEmpty && isNilOrEmpty && !ctx.opts.NullIfEmpty) ||
+		(field.OmitZero && !ctx.opts.NullIfEmpty && isZeroValue(fieldValue)) {
+		return 0, false, nil
+	}
+	if isNilOrEmpty && (ctx.opts.NullIfEmpty || ctx.nullsNilError(fieldValue)) {
+		return key + len("null"), true, nil
+	}
+
+	fieldCtx := ctx.withField(field)
+	ctx.resetEmpty()
+	size, ok, err := estimateValue(fieldCtx, fieldValue, groups, mode)
+	if err != nil {
+		if errors.Is(err, errSkipField) {
+			return 0, false, nil
+		}
+		switch fieldCtx.handleError(err) {
+		case errorFail:
+			return 0, false, err
+		case errorOmit:
+			return 0, false, nil
+		}
+		return key + len("null"), true, nil
+	}
+	if !ok {
+		if !ctx.opts.NullIfEmpty {
+			return 0, false, nil
+		}
+		return key + len("null"), true, nil
+	}
+	if ctx.prunes(size == len("{}")) {
+		return 0, false, nil
+	}
+	return key + size, true, nil
+}
+
+// estimateMap 估算map编码后的字节数
+func estimateMap(ctx *serializeContext, v reflect.Value, groups []string, mode GroupMode) (int, error) {
+	n := len("{}")
+	count := 0
+	iter := v.MapRange()
+	for iter.Next() {
+		key := mapKeyString(iter.Key())
+		itemCtx := ctx.withPath(key)
+		if err := itemCtx.checkUTF8(iter.Key(), key, "map的键"); err != nil {
+			if itemCtx.handleError(err) == errorFail {
+				return 0, err
+			}
+			continue
+		}
+		ctx.resetEmpty()
+		size, ok, err := nilIfSkipped3(estimateValue(itemCtx, iter.Value(), groups, mode))
+		if err != nil {
+			switch itemCtx.handleError(err) {
+			case errorFail:
+				return 0, err
+			case errorOmit:
+				continue
+			}
+			size, ok = len("null"), true
+		} else if ok && ctx.prunes(size == len("{}")) {
+			continue
+		}
+		if !ok {
+			if !ctx.opts.NullIfEmpty {
+				continue
+			}
+			size = len("null")
+		}
+		n += estimateString(key) + len(":") + size
+		count++
+	}
+	if count > 1 {
+		n += count - 1
+	}
+	ctx.endMap(v.Len(), count == 0)
+	return n, nil
+}
+
+// estimateSlice 估算切片和数组编码后的字节数，同时返回实际输出的元素数
+func estimateSlice(ctx *serializeContext, v reflect.Value, groups []string, mode GroupMode) (int, int, error) {
+	n := len("[]")
+	count := 0
+	length := v.Len()
+	if m := ctx.opts.MaxSliceLen; m > 0 && length > m {
+		length = m
+	}
+	for i := range length {
+		itemCtx := ctx.withIndex(i)
+		size, ok, err := nilIfSkipped3(estimateValue(itemCtx, v.Index(i), groups, mode))
+		if err != nil {
+			switch itemCtx.handleError(err) {
+			case errorFail:
+				return 0, 0, err
+			case errorOmit:
+				continue
+			}
+			size, ok = len("null"), true
+		}
+		if !ok {
+			size = len("null")
+		}
+		n += size
+		count++
+	}
+	if ctx.opts.MarkTruncatedSlices && length < v.Len() {
+		// {"$truncated":N}
+		n += len(TruncatedSliceKey) + len(`{"":}`) + digits(v.Len()-length)
+		if count > 0 {
+			n++
+		}
+	}
+	if count > 1 {
+		n += count - 1
+	}
+	return n, count, nil
+}
+
+// estimateCollection 估算根切片的集合包装编码后的字节数，规则与encodeCollection一致
+func estimateCollection(ctx *serializeContext, v reflect.Value, groups []string, mode GroupMode) (int, bool, error) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if err := ctx.checkPointer(v); err != nil {
+			return 0, false, err
+		}
+		v = v.Elem()
+	}
+	if err := ctx.checkPointer(v); err != nil {
+		return 0, false, err
+	}
+
+	items, count := len("[]"), 0
+	switch {
+	case v.Len() == 0 && ctx.opts.NullIfEmpty && v.Kind() == reflect.Slice && v.IsNil():
+		items = len("null")
+	case v.Len() > 0:
+		if err := ctx.enterLevel(); err != nil {
+			return 0, false, err
+		}
+		var err error
+		items, count, err = estimateSlice(ctx, v, groups, mode)
+		ctx.leaveLevel()
+		if err != nil {
+			return 0, false, err
+		}
+	}
+	// {"items":[...],"count":N}
+	n := len("{:,:}") + estimateString(ctx.opts.CollectionItemsKey) + items +
+		estimateString(ctx.opts.CollectionCountKey) + digits(count)
+	return n, true, nil
+}
+
+// estimateString 估算字符串编码后的字节数：字节数加引号，再加上ASCII转义字符多出的字节
+// 非ASCII字符按原样计入，无效的UTF-8和 等少见的转义不单独计算
+func estimateString(s string) int {
+	n := len(s) + 2
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"' || c == '\\' || c == '\n' || c == '\r' || c == '\t' || c == '\b' || c == '\f':
+			n++
+		case c < 0x20 || c == '<' || c == '>' || c == '&':
+			// 编码为\u00XX
+			n += 5
+		}
+	}
+	return n
+}
+
+// estimateScalar 计算字符串以外的基本类型编码后的字节数，使用栈上的缓冲区格式化，不分配内存
+func estimateScalar(v reflect.Value) int {
+	var buf [64]byte
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			return len("true")
+		}
+		return len("false")
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return len(strconv.AppendInt(buf[:0], v.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return len(strconv.AppendUint(buf[:0], v.Uint(), 10))
+	case reflect.Float32, reflect.Float64:
+		f := v.Float()
+		if isSpecialFloat(f) {
+			return len(floatToString(f)) + 2
+		}
+		return len(appendJSONFloat(buf[:0], f, floatBits(v)))
+	case reflect.Complex64, reflect.Complex128:
+		return estimateString(complex128ToString(v.Complex()))
+	}
+	return 0
+}
+
+// estimateTime 计算时间值编码后的字节数
+func estimateTime(t time.Time) int {
+	var buf [64]byte
+	return len(t.AppendFormat(buf[:0], time.RFC3339Nano)) + 2
+}
+
+// digits 返回非负整数的十进制位数
+func digits(n int) int {
+	d := 1
+	for n >= 10 {
+		n /= 10
+		d++
+	}
+	return d
+}
@@ -0,0 +1,141 @@
+package jsongroup
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+)
+
+// estimatePayload 字符串需要转义、数值长短不一的负载
+type estimatePayload struct {
+	ID      int64             `json:"id" groups:"public"`
+	Title   string            `json:"title" groups:"public"`
+	Body    string            `json:"body" groups:"public"`
+	Ratio   float64           `json:"ratio" groups:"public"`
+	Small   float32           `json:"small" groups:"public"`
+	Flags   []bool            `json:"flags" groups:"public"`
+	Attrs   map[string]string `json:"attrs" groups:"public"`
+	Owner   *User             `json:"owner,omitempty" groups:"public"`
+	Missing *User             `json:"missing" groups:"public"`
+	Secret  string            `json:"secret" groups:"admin"`
+}
+
+func newEstimatePayloads(n int) []estimatePayload {
+	items := make([]estimatePayload, n)
+	for i := range items {
+		items[i] = estimatePayload{
+			ID:     int64(i) * 7919,
+			Title:  strings.Repeat("标题", i%5) + "title",
+			Body:   "line one\nline \"two\"\t<tag> & \\ " + strings.Repeat("x", i%40),
+			Ratio:  float64(i) / 3,
+			Small:  float32(i) * 1e-7,
+			Flags:  []bool{i%2 == 0, true},
+			Attrs:  map[string]string{"k": "v", "lang": "zh-CN"},
+			Owner:  &User{ID: i, Name: "Alice", Address: &Address{Street: "1 Main St", City: "Springfield"}},
+			Secret: "hidden",
+		}
+	}
+	return items
+}
+
+func TestEstimateSizeAccuracy(t *testing.T) {
+	users := make([]ComplexUser, 50)
+	for i := range users {
+		users[i] = newComplexUser(i)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		v      any
+		opts   *Options
+		groups []string
+	}{
+		{"complex users public", users, New(), []string{"public"}},
+		{"complex users all groups", users, New(), []string{"public", "admin", "internal"}},
+		{"complex users and mode", users, New().WithGroupMode(GroupModeAnd), []string{"public", "admin"}},
+		{"escaped strings", newEstimatePayloads(100), New(), []string{"public"}},
+		{"null if empty", newEstimatePayloads(20), New().WithNullIfEmpty(true), []string{"public"}},
+		{"no groups", newEstimatePayloads(20), New(), nil},
+		{"timestamps", newTimestamps(), New(), []string{"public"}},
+		{"top level path", users[:5], New().WithTopLevelPath("result", "data"), []string{"public"}},
+		{"collection envelope", users[:5], New().WithCollectionEnvelope("items", "count"), []string{"public"}},
+		{"js safe escaping", newEstimatePayloads(20), New().WithJSSafeEscaping(true), []string{"public"}},
+		{"map root", map[string]any{"a": 1, "b": []string{"x", "y"}, "c": nil, "d": math.MaxInt64}, New(), nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := MarshalByGroupsWithOptions(tc.v, tc.opts, tc.groups...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			n, err := EstimateSize(tc.v, tc.opts, tc.groups...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := math.Abs(float64(n-len(data))) / float64(len(data)); diff > 0.10 {
+				t.Errorf("estimate %d, actual %d bytes (%.1f%% off)", n, len(data), 100*diff)
+			}
+		})
+	}
+}
+
+func TestEstimateSizeExactForSimpleValues(t *testing.T) {
+	for _, v := range []any{
+		nil, 0, -12345, uint8(255), true, "plain", 1.5, []int{1, 22, 333},
+		map[string]int{"a": 1, "bb": 22}, User{ID: 1, Name: "Bob", Email: "b@example.com"},
+	} {
+		data, err := MarshalByGroups(v, "public")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n, err := EstimateSize(v, nil, "public"); err != nil || n != len(data) {
+			t.Errorf("EstimateSize(%#v) = %d, %v; actual %s is %d bytes", v, n, err, data, len(data))
+		}
+	}
+}
+
+func TestEstimateSizeDoesNotBuildOutput(t *testing.T) {
+	if testing.Short() {
+		t.Skip("allocation counts are not meaningful in short mode")
+	}
+	// 少量节点、很长的字符串：分配只与遍历的节点数有关，不随输出的字节数增长
+	long := make([]string, 8)
+	for i := range long {
+		long[i] = strings.Repeat("a\"b", 1<<15)
+	}
+	data, err := MarshalByGroups(long)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := EstimateSize(long, nil); n != len(data) {
+		t.Errorf("estimate %d, actual %d bytes", n, len(data))
+	}
+	if b := bytesPerRun(20, func() { EstimateSize(&long, nil) }); b > uint64(len(data))/100 {
+		t.Errorf("EstimateSize allocated %d bytes for %d bytes of output", b, len(data))
+	}
+
+	// 节点很多时同样少于实际序列化分配的字节数
+	items := newEstimatePayloads(500)
+	estimate := bytesPerRun(10, func() { EstimateSize(&items, nil, "public") })
+	marshal := bytesPerRun(10, func() { MarshalByGroups(&items, "public") })
+	if estimate >= marshal {
+		t.Errorf("EstimateSize allocated %d bytes, MarshalByGroups %d", estimate, marshal)
+	}
+}
+
+func TestEstimateSizeErrors(t *testing.T) {
+	type node struct {
+		Next *node `json:"next"`
+	}
+	n := &node{}
+	n.Next = n
+	if _, err := EstimateSize(n, nil); !errors.Is(err, ErrCircularReference) {
+		t.Errorf("got %v, want ErrCircularReference", err)
+	}
+	if _, err := EstimateSize(make(chan int), nil); !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf("got %v, want ErrUnsupportedType", err)
+	}
+	if _, err := EstimateSize(1, New().WithMaxDepth(-1)); !errors.Is(err, ErrInvalidOptions) {
+		t.Errorf("got %v, want ErrInvalidOptions", err)
+	}
+}
@@ -0,0 +1,284 @@
+package jsongroup
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"slices"
+	"strconv"
+)
+
+var (
+	// jsonUnmarshalerType json.Unmarshaler接口的反射类型
+	jsonUnmarshalerType = reflect.TypeFor[json.Unmarshaler]()
+	// textUnmarshalerType encoding.TextUnmarshaler接口的反射类型
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+)
+
+// 被丢弃的键的原因，记录在DroppedKey.Reason中
+const (
+	// DropUnknownField 键不对应prototype类型的任何可序列化字段
+	DropUnknownField = "unknown_field"
+	// DropExcludedByGroup 键对应的字段不属于请求的分组
+	DropExcludedByGroup = "excluded_by_group"
+)
+
+// FilterExplanation 记录按prototype类型和分组过滤一份JSON文档时各个键的去留
+// 路径中对象的键以点号连接，切片和数组的元素记为[]，同一路径在文档中多次出现时只记录一次，按遇到的顺序排列
+type FilterExplanation struct {
+	// Kept 会被保留的键的路径
+	Kept []string
+	// Dropped 会被丢弃的键，被丢弃的键下的嵌套键不再单独列出
+	Dropped []DroppedKey
+	// Mismatches 值的JSON类型与字段的Go类型不对应的位置，这些值不会继续向下检查
+	Mismatches []TypeMismatch
+}
+
+// DroppedKey 会被丢弃的单个键
+type DroppedKey struct {
+	// Path 键的路径
+	Path string
+	// Reason 丢弃的原因，为DropUnknownField或DropExcludedByGroup
+	Reason string
+}
+
+// TypeMismatch 值的JSON类型与字段类型不对应的单个位置
+type TypeMismatch struct {
+	// Path 值的路径，根值为空字符串
+	Path string
+	// Expected 字段的Go类型
+	Expected string
+	// Got 值的JSON类型：string、number、boolean、object或array
+	Got string
+}
+
+// ExplainFilter 说明按prototype类型在指定分组下过滤JSON文档data时哪些键会被保留、哪些会被丢弃以及原因，并列出类型不对应的值
+// 适合在对已有的接入方启用严格过滤之前，用记录下来的请求评估影响。键的匹配和嵌套规则与FilterMapKeys相同，
+// 不会把data解码到prototype，也不会修改任何内容；data必须是一个JSON值，根值不是对象时只记录一处类型不对应。
+// prototype可以是值、指针或reflect.Type，opts为nil时使用默认选项
+func ExplainFilter(data []byte, prototype any, opts *Options, groups ...string) (FilterExplanation, error) {
+	if opts == nil {
+		opts = defaults()
+	}
+	if err := opts.Validate(); err != nil {
+		return FilterExplanation{}, err
+	}
+	groups, err := opts.resolveGroups(groups)
+	if err != nil {
+		return FilterExplanation{}, err
+	}
+	o := *opts
+	o.fillDefaults()
+
+	t, ok := prototype.(reflect.Type)
+	if !ok {
+		t = reflect.TypeOf(prototype)
+	}
+	if t == nil {
+		return FilterExplanation{}, UnsupportedTypeError("", "nil")
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || t == timeType {
+		return FilterExplanation{}, UnsupportedTypeError("", t.String())
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return FilterExplanation{}, WrapJSONError(err, "")
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		if err == nil {
+			err = errors.New("JSON值之后还有多余的内容")
+		}
+		return FilterExplanation{}, WrapJSONError(err, "")
+	}
+
+	e := &filterExplainer{
+		mapFilter: mapFilter{opts: &o, groups: groups, groupKey: filterGroupKey(groups, o.DenyGroups)},
+		seen:      make(map[string]struct{}),
+	}
+	if err := e.explainValue(doc, t, "", 0, false); err != nil {
+		return FilterExplanation{}, err
+	}
+	return e.result, nil
+}
+
+// filterExplainer 保存一次ExplainFilter调用的选项、分组和结果
+type filterExplainer struct {
+	mapFilter
+	result FilterExplanation
+	// 已记录的路径，每个路径只记录一次
+	seen map[string]struct{}
+}
+
+// explainStruct 按结构体类型t的字段检查对象m的每个键，键按字典序访问，结果与map的遍历顺序无关
+func (e *filterExplainer) explainStruct(m map[string]any, t reflect.Type, path string, depth int, inherited bool) error {
+	if e.opts.MaxDepth > 0 && depth > e.opts.MaxDepth {
+		return MaxDepthError(path, reflect.Value{}, e.opts.MaxDepth)
+	}
+	if err := checkGroupTags(e.opts, t, path); err != nil {
+		return err
+	}
+	set, err := getFilteredFields(t, e.opts, e.groups, e.groupKey, e.opts.GroupMode, inherited)
+	if err != nil {
+		return ReflectionError(path, err)
+	}
+
+	for _, k := range sortedKeys(m) {
+		keyPath := joinSchemaPath(path, k)
+		// JSON名称重复时与FilterMapKeys相同，使用先声明的字段
+		i := slices.IndexFunc(set.fields, func(f fieldInfo) bool { return f.JSONName == k })
+		if i < 0 {
+			reason := DropUnknownField
+			if slices.ContainsFunc(set.excluded, func(f fieldInfo) bool { return f.JSONName == k }) {
+				reason = DropExcludedByGroup
+			}
+			e.drop(keyPath, reason)
+			continue
+		}
+		field := set.fields[i]
+		if e.first(keyPath) {
+			e.result.Kept = append(e.result.Kept, keyPath)
+		}
+		ft := t.FieldByIndex(field.Index).Type
+		fieldInherited := inherited || (e.opts.CascadeToUntagged && len(field.Groups) > 0)
+		if err := e.explainValue(m[k], ft, keyPath, depth, fieldInherited); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// explainValue 检查值与类型ft是否对应，并按FilterMapKeys的规则进入嵌套的结构体和结构体集合
+func (e *filterExplainer) explainValue(value any, ft reflect.Type, path string, depth int, inherited bool) error {
+	for ft.Kind() == reflect.Pointer {
+		ft = ft.Elem()
+	}
+	if value == nil {
+		return nil
+	}
+	if !jsonKindFits(value, ft) {
+		if e.first("\x00" + path) {
+			e.result.Mismatches = append(e.result.Mismatches, TypeMismatch{Path: path, Expected: ft.String(), Got: jsonKindName(value)})
+		}
+		return nil
+	}
+	switch ft.Kind() {
+	case reflect.Struct:
+		if m, ok := value.(map[string]any); ok && ft != timeType && ft != syncMapType {
+			return e.explainStruct(m, ft, path, depth+1, inherited)
+		}
+	case reflect.Slice, reflect.Array:
+		if items, ok := value.([]any); ok {
+			for _, item := range items {
+				if err := e.explainValue(item, ft.Elem(), path+"[]", depth+1, inherited); err != nil {
+					return err
+				}
+			}
+		}
+	case reflect.Map:
+		if entries, ok := value.(map[string]any); ok {
+			for _, k := range sortedKeys(entries) {
+				if err := e.explainValue(entries[k], ft.Elem(), joinSchemaPath(path, k), depth+1, inherited); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// drop 记录被丢弃的键
+func (e *filterExplainer) drop(path, reason string) {
+	if e.first("\x01" + path) {
+		e.result.Dropped = append(e.result.Dropped, DroppedKey{Path: path, Reason: reason})
+	}
+}
+
+// first 登记路径，第一次出现时返回true；不同类别的记录以前缀区分
+func (e *filterExplainer) first(key string) bool {
+	if _, ok := e.seen[key]; ok {
+		return false
+	}
+	e.seen[key] = struct{}{}
+	return true
+}
+
+// sortedKeys 返回按字典序排列的对象键
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// jsonKindFits 判断json.Decoder解析得到的非null值能否解码为类型t（已穿透指针）
+// 实现了json.Unmarshaler的类型自行解析，不作判断；实现了encoding.TextUnmarshaler的类型接受字符串，带方法的接口类型无法解码
+func jsonKindFits(value any, t reflect.Type) bool {
+	if t == timeType {
+		_, ok := value.(string)
+		return ok
+	}
+	pt := reflect.PointerTo(t)
+	if pt.Implements(jsonUnmarshalerType) {
+		return true
+	}
+	if pt.Implements(textUnmarshalerType) {
+		_, ok := value.(string)
+		return ok
+	}
+	if t.Kind() == reflect.Interface {
+		return t.NumMethod() == 0
+	}
+	switch value := value.(type) {
+	case string:
+		// []byte按base64字符串解码
+		return t.Kind() == reflect.String || (t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8)
+	case bool:
+		return t.Kind() == reflect.Bool
+	case json.Number:
+		switch t.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			n, err := strconv.ParseInt(value.String(), 10, 64)
+			return err == nil && !reflect.Zero(t).OverflowInt(n)
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			n, err := strconv.ParseUint(value.String(), 10, 64)
+			return err == nil && !reflect.Zero(t).OverflowUint(n)
+		case reflect.Float32, reflect.Float64:
+			_, err := value.Float64()
+			return err == nil
+		}
+		return false
+	case map[string]any:
+		return t.Kind() == reflect.Struct || t.Kind() == reflect.Map
+	case []any:
+		return t.Kind() == reflect.Slice || t.Kind() == reflect.Array
+	}
+	return false
+}
+
+// jsonKindName 返回json.Decoder解析得到的值的JSON类型名称
+func jsonKindName(value any) string {
+	switch value.(type) {
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case json.Number:
+		return "number"
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	}
+	return "null"
+}
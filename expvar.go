@@ -0,0 +1,11 @@
+package jsongroup
+
+import "expvar"
+
+// PublishCacheExpvar 以name为名称注册expvar变量，每次读取时返回CacheStatsSnapshot的结果
+// name已被注册时panic，与expvar.Publish一致；注意导入expvar会在http.DefaultServeMux上注册/debug/vars
+func PublishCacheExpvar(name string) {
+	expvar.Publish(name, expvar.Func(func() any {
+		return CacheStatsSnapshot()
+	}))
+}
@@ -0,0 +1,81 @@
+package jsongroup
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// fieldGroupsRegistry RegisterFieldGroups注册的字段分组：类型 -> 字段名 -> 分组列表
+// 与typeOptionsRegistry相同，注册时复制整个映射后原子替换；未注册任何类型时为nil
+var fieldGroupsRegistry atomic.Pointer[map[reflect.Type]map[string][]string]
+
+// fieldGroupsMu 串行化注册操作
+var fieldGroupsMu sync.Mutex
+
+// fieldGroupsGeneration 注册代数，每次注册或取消注册时递增
+// 字段缓存和分组过滤结果缓存记录解析时的代数，代数不同的条目视为未命中，重新注册后不会读到过期的分组
+var fieldGroupsGeneration atomic.Uint64
+
+// RegisterFieldGroups 在代码外为结构体类型t的字段指定分组，groups为字段名（Go中的字段名，而不是JSON名）到分组列表的映射
+// 解析t的字段时注册的分组与标签合并：注册过的字段使用注册的分组并视为已声明分组标签，优先于字段上实际的标签，
+// 其余字段仍按标签解析；注册对所有标签键生效。内嵌结构体提升的字段需要为内嵌的类型单独注册。
+// 再次注册同一类型时替换之前的注册，groups为nil时取消注册；注册后已缓存的字段信息自动失效。
+// 字段名不存在或不是导出字段时立即返回错误，注册不生效；设置了注册的类型不使用生成的GroupMarshaler
+func RegisterFieldGroups(t reflect.Type, groups map[string][]string) error {
+	if t == nil || t.Kind() != reflect.Struct {
+		return UnsupportedTypeError("", fmt.Sprint(t))
+	}
+
+	var registered map[string][]string
+	if groups != nil {
+		var problems []string
+		registered = make(map[string][]string, len(groups))
+		for _, name := range slices.Sorted(maps.Keys(groups)) {
+			field, ok := t.FieldByName(name)
+			switch {
+			case !ok || len(field.Index) != 1:
+				problems = append(problems, fmt.Sprintf("%s没有字段%s", t, name))
+			case !field.IsExported():
+				problems = append(problems, fmt.Sprintf("%s.%s不是导出字段", t, name))
+			default:
+				registered[name] = slices.Clone(groups[name])
+			}
+		}
+		if len(problems) > 0 {
+			return InvalidOptionsError(problems)
+		}
+	}
+
+	fieldGroupsMu.Lock()
+	defer fieldGroupsMu.Unlock()
+
+	next := make(map[reflect.Type]map[string][]string)
+	if cur := fieldGroupsRegistry.Load(); cur != nil {
+		maps.Copy(next, *cur)
+	}
+	if registered == nil {
+		delete(next, t)
+	} else {
+		next[t] = registered
+	}
+	if len(next) == 0 {
+		fieldGroupsRegistry.Store(nil)
+	} else {
+		fieldGroupsRegistry.Store(&next)
+	}
+	fieldGroupsGeneration.Add(1)
+	return nil
+}
+
+// registeredFieldGroups 返回类型t注册的字段分组，未注册时返回nil
+func registeredFieldGroups(t reflect.Type) map[string][]string {
+	registry := fieldGroupsRegistry.Load()
+	if registry == nil {
+		return nil
+	}
+	return (*registry)[t]
+}
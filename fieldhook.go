@@ -0,0 +1,63 @@
+package jsongroup
+
+import "reflect"
+
+// FieldHook 序列化每个被分组包含的结构体字段之前调用的钩子
+// path为字段的完整路径，如"User.Address.City"；返回ok为true时以value代替字段的值，value为nil时与nil接口字段的处理相同，
+// 可由omitempty省略；ok为false时保持原值。返回的错误包装为带路径的ErrTypeUnknown错误，与其他字段错误一样受ErrorPolicy和BestEffort影响
+type FieldHook func(path string, f FieldDescriptor, v reflect.Value) (value any, ok bool, err error)
+
+// FieldDescriptor 描述FieldHook或ValueFilter被调用时的结构体字段
+type FieldDescriptor struct {
+	// Struct 正在序列化的结构体类型，从内嵌结构体提升的字段同样为外层类型
+	Struct reflect.Type
+	// Name 字段的Go名称，从内嵌结构体提升的字段带有内嵌字段名前缀，如"Base.ID"
+	Name string
+	// JSONName 输出的键名
+	JSONName string
+	// Groups 字段所属的分组，与字段缓存共享，不得修改
+	Groups []string
+	// Tag 字段的完整标签，可读取自定义的标签键
+	Tag reflect.StructTag
+}
+
+// newFieldDescriptor 创建parent中字段的描述，供FieldHook和ValueFilter使用
+func newFieldDescriptor(parent reflect.Value, field fieldInfo) FieldDescriptor {
+	return FieldDescriptor{
+		Struct:   parent.Type(),
+		Name:     field.Name,
+		JSONName: field.JSONName,
+		Groups:   field.Groups,
+		Tag:      parent.Type().FieldByIndex(field.Index).Tag,
+	}
+}
+
+// anyType any的反射类型，FieldHook以nil替换字段值时使用该类型的nil接口值
+var anyType = reflect.TypeFor[any]()
+
+// callFieldHook 对字段调用FieldHook，ctx为字段的上下文，parent为字段所在的结构体值
+// 返回替换后的值以及是否发生了替换；替换后的值类型可能与字段声明的类型不同，调用方不能再使用字段预编译的转换和编码函数。
+// 合并到外层对象的内嵌结构体本身不调用钩子，只对其中的字段调用
+func (ctx *serializeContext) callFieldHook(parent reflect.Value, field fieldInfo, v reflect.Value) (reflect.Value, bool, error) {
+	if field.Anonymous && v.Kind() == reflect.Struct {
+		return v, false, nil
+	}
+
+	path := ctx.path()
+	value, ok, err := ctx.opts.FieldHook(path, newFieldDescriptor(parent, field), v)
+	if err != nil {
+		return v, false, &Error{
+			Type:    ErrTypeUnknown,
+			Message: "FieldHook返回错误",
+			Path:    path,
+			Cause:   err,
+		}
+	}
+	if !ok {
+		return v, false, nil
+	}
+	if value == nil {
+		return reflect.Zero(anyType), true, nil
+	}
+	return reflect.ValueOf(value), true, nil
+}
@@ -0,0 +1,139 @@
+package jsongroup
+
+import (
+	"reflect"
+)
+
+// FilterMapKeys 按prototype类型在指定分组下的可见字段过滤已有的map[string]any文档，返回只包含允许的键的新map
+// 适合数据已经以map形式存在（如从数据存储读出）的场景，无需先解码为结构体再序列化。
+// 允许的键来自prototype的字段缓存，键名与序列化时的JSON名称一致；字段类型为结构体（或其指针）且值为map[string]any时递归过滤，
+// 字段类型为结构体的切片、数组或map且值为[]any或map[string]any时逐个元素过滤，其余值原样保留，不对值做任何反射。
+// m本身不会被修改，未被过滤的嵌套值与m共享；prototype可以是值、指针或reflect.Type，opts为nil时使用默认选项
+func FilterMapKeys(m map[string]any, prototype any, opts *Options, groups ...string) (map[string]any, error) {
+	if opts == nil {
+		opts = defaults()
+	}
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	groups, err := opts.resolveGroups(groups)
+	if err != nil {
+		return nil, err
+	}
+	o := *opts
+	o.fillDefaults()
+
+	t, ok := prototype.(reflect.Type)
+	if !ok {
+		t = reflect.TypeOf(prototype)
+	}
+	if t == nil {
+		return nil, UnsupportedTypeError("", "nil")
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || t == timeType {
+		return nil, UnsupportedTypeError("", t.String())
+	}
+	if m == nil {
+		return nil, nil
+	}
+
+	f := &mapFilter{opts: &o, groups: groups, groupKey: filterGroupKey(groups, o.DenyGroups)}
+	return f.filterStruct(m, t, "", 1, false)
+}
+
+// mapFilter 保存一次FilterMapKeys调用的选项和分组
+type mapFilter struct {
+	opts     *Options
+	groups   []string
+	groupKey string
+}
+
+// filterStruct 按结构体类型t的可见字段过滤m，depth为当前嵌套深度，inherited与序列化上下文中的含义相同
+func (f *mapFilter) filterStruct(m map[string]any, t reflect.Type, path string, depth int, inherited bool) (map[string]any, error) {
+	if f.opts.MaxDepth > 0 && depth > f.opts.MaxDepth {
+		return nil, MaxDepthError(path, reflect.Value{}, f.opts.MaxDepth)
+	}
+	if err := checkGroupTags(f.opts, t, path); err != nil {
+		return nil, err
+	}
+	set, err := getFilteredFields(t, f.opts, f.groups, f.groupKey, f.opts.GroupMode, inherited)
+	if err != nil {
+		return nil, ReflectionError(path, err)
+	}
+
+	// 遍历可见字段而不是m的键，无需为每层建立允许的键集合
+	result := make(map[string]any, len(set.fields))
+	for _, field := range set.fields {
+		value, ok := m[field.JSONName]
+		if !ok {
+			continue
+		}
+		ft := t.FieldByIndex(field.Index).Type
+		fieldInherited := inherited || (f.opts.CascadeToUntagged && len(field.Groups) > 0)
+		if value, err = f.filterValue(value, ft, joinSchemaPath(path, field.JSONName), depth, fieldInherited); err != nil {
+			return nil, err
+		}
+		result[field.JSONName] = value
+	}
+	return result, nil
+}
+
+// filterValue 按字段类型ft过滤嵌套的值，类型与值的形式不对应时原样返回
+func (f *mapFilter) filterValue(value any, ft reflect.Type, path string, depth int, inherited bool) (any, error) {
+	for ft.Kind() == reflect.Pointer {
+		ft = ft.Elem()
+	}
+	switch ft.Kind() {
+	case reflect.Struct:
+		if ft == timeType || ft == syncMapType {
+			return value, nil
+		}
+		if m, ok := value.(map[string]any); ok {
+			return f.filterStruct(m, ft, path, depth+1, inherited)
+		}
+	case reflect.Slice, reflect.Array:
+		items, ok := value.([]any)
+		if !ok || !hasStructElem(ft) {
+			return value, nil
+		}
+		filtered := make([]any, len(items))
+		for i, item := range items {
+			var err error
+			if filtered[i], err = f.filterValue(item, ft.Elem(), path+"[]", depth+1, inherited); err != nil {
+				return nil, err
+			}
+		}
+		return filtered, nil
+	case reflect.Map:
+		entries, ok := value.(map[string]any)
+		if !ok || !hasStructElem(ft) {
+			return value, nil
+		}
+		filtered := make(map[string]any, len(entries))
+		for k, item := range entries {
+			var err error
+			if filtered[k], err = f.filterValue(item, ft.Elem(), joinSchemaPath(path, k), depth+1, inherited); err != nil {
+				return nil, err
+			}
+		}
+		return filtered, nil
+	}
+	return value, nil
+}
+
+// hasStructElem 判断集合类型的元素（穿透指针和嵌套的集合）是否为需要过滤的结构体
+func hasStructElem(t reflect.Type) bool {
+	for {
+		t = t.Elem()
+		switch t.Kind() {
+		case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+			continue
+		case reflect.Struct:
+			return t != timeType && t != syncMapType
+		}
+		return false
+	}
+}
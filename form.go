@@ -0,0 +1,229 @@
+package jsongroup
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+)
+
+// EncodeValues 按指定分组将结构体v编码为url.Values，用于application/x-www-form-urlencoded请求
+// 键为过滤后字段的json名称，标量的格式与WriteCSV相同：布尔值为true/false，time.Time为RFC3339格式；
+// 由基本类型或time.Time组成的切片和数组按元素顺序输出为重复的键。nil指针、被省略的字段和空值不输出。
+// 嵌套的结构体和map默认返回ErrTypeUnsupportedType错误，启用WithFlattenNested后展开为"addr.city"形式的键；
+// v本身也可以是map。url.Values.Encode按键排序，同一键的值保持顺序，可直接用于计算签名；opts为nil时使用默认选项
+func EncodeValues(v any, opts *Options, groups ...string) (values url.Values, err error) {
+	if opts == nil {
+		opts = defaults()
+	}
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	groups, err = opts.resolveGroups(groups)
+	if err != nil {
+		return nil, err
+	}
+
+	// 捕获可能的panic并转换为错误
+	defer func() {
+		if r := recover(); r != nil {
+			values, err = nil, panicError("Root", r)
+		}
+	}()
+
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if (rv.Kind() != reflect.Struct || rv.Type() == timeType) && rv.Kind() != reflect.Map {
+		return nil, UnsupportedTypeError("Root", rv)
+	}
+
+	// 复制选项，避免修改调用方的配置
+	o := *opts
+	o.fillDefaults()
+	ctx := newContext(o, groups)
+	defer ctx.release()
+	if err := ctx.checkRenamePaths(v); err != nil {
+		return nil, err
+	}
+
+	values = make(url.Values)
+	if err := encodeFormValue(ctx, values, "", rv, groups); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// encodeFormValue 将v写入键key，展开的结构体和map写入"key.子键"，根值的key为空
+// 深度限制和循环引用检测与JSON输出一致
+func encodeFormValue(ctx *serializeContext, values url.Values, key string, v reflect.Value, groups []string) error {
+	if s, ok := csvScalar(v); ok {
+		values.Add(key, s)
+		return nil
+	}
+
+	kind := v.Kind()
+	if (kind == reflect.Pointer || kind == reflect.Interface) && v.IsNil() {
+		return nil
+	}
+
+	if err := ctx.enterLevel(); err != nil {
+		return err
+	}
+	defer ctx.leaveLevel()
+
+	if kind == reflect.Pointer || kind == reflect.Map {
+		if err := ctx.checkPointer(v); err != nil {
+			return err
+		}
+	}
+
+	if rep, ok := ctx.errorValue(v); ok {
+		return encodeFormValue(ctx, values, key, rep, groups)
+	}
+	if rep, ok, err := ctx.binaryValue(v); err != nil {
+		return err
+	} else if ok {
+		return encodeFormValue(ctx, values, key, rep, groups)
+	}
+
+	switch kind {
+	case reflect.Pointer, reflect.Interface:
+		return encodeFormValue(ctx.withPath(""), values, key, v.Elem(), groups)
+
+	case reflect.Struct:
+		if v.Type() == timeType {
+			return addFormTime(ctx, values, key, v)
+		}
+		if key != "" && !ctx.opts.FlattenNested {
+			return formNestedError(ctx.path(), v.Type().String())
+		}
+		return encodeFormStruct(ctx, values, key, ctx.lockedSnapshot(v), groups)
+
+	case reflect.Map:
+		if key != "" && !ctx.opts.FlattenNested {
+			return formNestedError(ctx.path(), v.Type().String())
+		}
+		iter := v.MapRange()
+		for iter.Next() {
+			k := mapKeyString(iter.Key())
+			if err := encodeFormValue(ctx.withPath(k), values, joinPath(key, k), iter.Value(), groups); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case reflect.Slice, reflect.Array:
+		for i := range v.Len() {
+			if err := addFormItem(ctx.withIndex(i), values, key, v.Index(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	default:
+		return UnsupportedTypeError(ctx.path(), v.Type().String())
+	}
+}
+
+// encodeFormStruct 按字段写入结构体，字段的省略规则与JSON输出一致，内嵌的匿名结构体字段合并到当前层级
+func encodeFormStruct(ctx *serializeContext, values url.Values, prefix string, v reflect.Value, groups []string) error {
+	if err := checkGroupTags(ctx.opts, v.Type(), ctx.path()); err != nil {
+		return err
+	}
+	if err := ctx.checkRenames(v.Type()); err != nil {
+		return err
+	}
+	set, err := getFilteredFields(v.Type(), ctx.opts, groups, ctx.groupKey, ctx.opts.GroupMode, ctx.inherited)
+	if err != nil {
+		return ReflectionError(ctx.path(), err)
+	}
+
+	var embeds embedOmitter
+	for _, field := range set.fields {
+		if len(field.Embeds) > 0 && embeds.omits(v, set, field) {
+			continue
+		}
+		fieldValue := v.FieldByIndex(field.Index)
+		fieldCtx := ctx.withField(field)
+		if ctx.opts.ValueFilter != nil && !fieldCtx.keepsField(v, field, fieldValue) {
+			continue
+		}
+		if ctx.opts.FieldHook != nil {
+			if fieldValue, _, err = fieldCtx.callFieldHook(v, field, fieldValue); err != nil {
+				return err
+			}
+		}
+
+		if field.Anonymous && fieldValue.Kind() == reflect.Struct {
+			fieldCtx.inherited = ctx.inherited
+			fieldCtx.renames = ctx.renames
+			if err := encodeFormStruct(fieldCtx, values, prefix, fieldValue, groups); err != nil {
+				return err
+			}
+			continue
+		}
+
+		isNilOrEmpty := (fieldValue.Kind() == reflect.Pointer && fieldValue.IsNil()) || isEmptyValue(fieldValue)
+		if ((field.OmitEmpty || ctx.opts.NullIfEmpty) && isNilOrEmpty) ||
+			(field.OmitZero && isZeroValue(fieldValue)) {
+			continue
+		}
+		if err := encodeFormValue(fieldCtx, values, joinPath(prefix, ctx.fieldKey(field)), fieldValue, groups); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addFormItem 将切片元素追加为key的一个值，元素只能是基本类型或time.Time，nil元素跳过
+func addFormItem(ctx *serializeContext, values url.Values, key string, v reflect.Value) error {
+	for (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) && !v.IsNil() {
+		v = v.Elem()
+	}
+	if s, ok := csvScalar(v); ok {
+		values.Add(key, s)
+		return nil
+	}
+	switch {
+	case !v.IsValid() || v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface:
+		return nil
+	case v.Type() == timeType:
+		return addFormTime(ctx, values, key, v)
+	}
+	return formListError(ctx.path(), v.Type().String())
+}
+
+// addFormTime 将time.Time追加为key的一个值，启用NullIfEmpty时零值不输出
+func addFormTime(ctx *serializeContext, values url.Values, key string, v reflect.Value) error {
+	t := timeOf(v)
+	if t.IsZero() && ctx.opts.NullIfEmpty {
+		return nil
+	}
+	s, err := timeText(ctx, t)
+	if err != nil {
+		return err
+	}
+	values.Add(key, s)
+	return nil
+}
+
+// formNestedError 创建未启用展开时遇到嵌套值的错误
+func formNestedError(path, typeName string) *Error {
+	return &Error{
+		Type:    ErrTypeUnsupportedType,
+		Message: fmt.Sprintf("表单编码不支持嵌套值: %s，可通过WithFlattenNested展开为带点号的键", typeName),
+		Path:    path,
+		Value:   typeName,
+	}
+}
+
+// formListError 创建切片元素不是基本类型的错误
+func formListError(path, typeName string) *Error {
+	return &Error{
+		Type:    ErrTypeUnsupportedType,
+		Message: fmt.Sprintf("表单编码的切片元素只能是基本类型: %s", typeName),
+		Path:    path,
+		Value:   typeName,
+	}
+}
@@ -0,0 +1,157 @@
+package jsongroup
+
+import (
+	"encoding/csv"
+	"errors"
+	"math"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type formEvent struct {
+	CSVBase
+	Name    string            `json:"name" groups:"webhook"`
+	Tags    []string          `json:"tags" groups:"webhook"`
+	Counts  [2]int            `json:"counts" groups:"webhook"`
+	Times   []*time.Time      `json:"times" groups:"webhook"`
+	Ratio   float32           `json:"ratio" groups:"webhook"`
+	Paid    bool              `json:"paid" groups:"webhook"`
+	Note    string            `json:"note,omitempty" groups:"webhook"`
+	Address *Address          `json:"address" groups:"webhook,admin"`
+	Meta    map[string]any    `json:"meta" groups:"webhook"`
+	Secret  string            `json:"secret" groups:"admin"`
+	Labels  map[string]string `json:"labels" groups:"admin"`
+}
+
+func newFormEvent() formEvent {
+	t1 := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	return formEvent{
+		CSVBase: CSVBase{ID: 7},
+		Name:    "a&b=c",
+		Tags:    []string{"x", "y", "x"},
+		Counts:  [2]int{1, 2},
+		Times:   []*time.Time{&t1, nil},
+		Ratio:   0.1,
+		Paid:    true,
+		Address: &Address{Street: "1 Main St", City: "Springfield", Zip: "100"},
+		Meta:    map[string]any{"source": "api", "retry": map[string]int{"count": 2}},
+		Secret:  "s",
+	}
+}
+
+func TestEncodeValues(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		v      any
+		opts   *Options
+		groups []string
+		want   url.Values
+	}{
+		// 切片按元素顺序输出为重复的键，nil元素跳过；嵌套的结构体和map展开为带点号的键
+		{"webhook flattened", newFormEvent(), New().WithFlattenNested(true), []string{"webhook", "public"}, url.Values{
+			"id": {"7"}, "name": {"a&b=c"}, "tags": {"x", "y", "x"}, "counts": {"1", "2"},
+			"times": {"2024-01-02T03:04:05Z"}, "ratio": {"0.1"}, "paid": {"true"},
+			"address.street": {"1 Main St"}, "address.city": {"Springfield"},
+			"meta.source": {"api"}, "meta.retry.count": {"2"},
+		}},
+		{"admin", formEvent{Secret: "s", Address: &Address{Zip: "9"}}, New().WithFlattenNested(true), []string{"admin"}, url.Values{
+			"id": {"0"}, "secret": {"s"}, "address.street": {""}, "address.city": {""}, "address.zip": {"9"},
+		}},
+		// nil指针和NullIfEmpty下的空值不输出，定长数组不为空
+		{"null if empty", formEvent{Tags: []string{}}, New().WithNullIfEmpty(true), []string{"webhook"}, url.Values{
+			"counts": {"0", "0"},
+		}},
+		{"map root", map[string]any{"b": []int{2, 1}, "a": math.Inf(-1)}, nil, nil, url.Values{"a": {"-Infinity"}, "b": {"2", "1"}}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := EncodeValues(tc.v, tc.opts, tc.groups...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got  %v\nwant %v", got, tc.want)
+			}
+		})
+	}
+
+	// Encode按键排序，同一键的值保持顺序
+	v, err := EncodeValues(newFormEvent(), New().WithFlattenNested(true), "webhook", "public")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := v.Encode(); !strings.Contains(got, "tags=x&tags=y&tags=x") || !strings.HasPrefix(got, "address.city=") {
+		t.Errorf("Encode() = %s", got)
+	}
+}
+
+func TestEncodeValuesErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		v    any
+		opts *Options
+		path string
+	}{
+		{"nested without flatten", newFormEvent(), New(), "Address"},
+		{"struct slice elements", struct {
+			Items []Address `json:"items" groups:"webhook"`
+		}{[]Address{{}}}, New(), "Items[0]"},
+		{"nested list", map[string]any{"x": [][]int{{1}}}, New(), "x[0]"},
+		{"not a struct", []int{1}, New(), "Root"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := EncodeValues(tc.v, tc.opts, "webhook")
+			var jerr *Error
+			if !errors.Is(err, ErrUnsupportedType) || !errors.As(err, &jerr) {
+				t.Fatalf("got %v, want ErrUnsupportedType", err)
+			}
+			if jerr.Path != tc.path {
+				t.Errorf("path = %q, want %q", jerr.Path, tc.path)
+			}
+		})
+	}
+}
+
+// TestEncodeValuesMatchesCSV 表单编码与WriteCSV共用标量的格式化，同一行的单元格与表单的值相同
+func TestEncodeValuesMatchesCSV(t *testing.T) {
+	type scalars struct {
+		Text  string    `json:"text"`
+		Int   int8      `json:"int"`
+		Uint  uint64    `json:"uint"`
+		Float float64   `json:"float"`
+		Small float32   `json:"small"`
+		NaN   float64   `json:"nan"`
+		Cplx  complex64 `json:"cplx"`
+		Bool  bool      `json:"bool"`
+		When  time.Time `json:"when"`
+		Home  *Address  `json:"home"`
+	}
+	row := scalars{
+		Text: "line\nbreak, \"q\"", Int: -8, Uint: math.MaxUint64, Float: 1e21, Small: 1e-7, NaN: math.NaN(),
+		Cplx: 1 + 2i, Bool: true, When: time.Date(2024, 5, 6, 7, 8, 9, 10, time.FixedZone("", 8*3600)),
+	}
+
+	var buf strings.Builder
+	if err := WriteCSV(&buf, []scalars{row}, New().WithFlattenNested(true)); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(strings.NewReader(buf.String())).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	values, err := EncodeValues(row, New().WithFlattenNested(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, key := range records[0] {
+		if got := values.Get(key); got != records[1][i] {
+			t.Errorf("%s: form %q, csv %q", key, got, records[1][i])
+		}
+	}
+	// nil指针在CSV中为空单元格，在表单中不输出
+	if len(values) != len(records[0])-3 {
+		t.Errorf("form has %d keys, csv %d columns of which 3 belong to the nil pointer", len(values), len(records[0]))
+	}
+}
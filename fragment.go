@@ -0,0 +1,317 @@
+package jsongroup
+
+import (
+	"container/list"
+	"reflect"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// CacheableFragment 由内容不可变、在输出中反复出现的结构体实现（如目录条目等参考数据），
+// 直接编码JSON时按FragmentKey缓存该值编码后的字节，再次遇到时原样写入而不重新序列化。
+// 缓存键还包含分组和影响输出的选项，同一个值在不同分组下分别缓存；值的内容变化时调用方需要返回新的FragmentKey，
+// 返回空字符串表示本次不使用缓存。只在MarshalByGroups系列函数直接编码时生效，MarshalToMap等中间表示路径不受影响
+type CacheableFragment interface {
+	FragmentKey() string
+}
+
+// fragmentType CacheableFragment接口的反射类型
+var fragmentType = reflect.TypeFor[CacheableFragment]()
+
+// methodImpl 类型实现某个接口的方式，区分值接收者和指针接收者
+type methodImpl uint8
+
+const (
+	// implNone 未实现
+	implNone methodImpl = iota
+	// implValue 值接收者实现
+	implValue
+	// implPointer 指针接收者实现，值可寻址时才能调用
+	implPointer
+)
+
+// fragmentTypes 缓存各类型实现CacheableFragment的方式
+var fragmentTypes sync.Map
+
+// fragmentImplOf 判断类型实现CacheableFragment的方式，结果按类型缓存
+func fragmentImplOf(t reflect.Type) methodImpl {
+	if impl, ok := fragmentTypes.Load(t); ok {
+		return impl.(methodImpl)
+	}
+	impl := implNone
+	switch {
+	case t.Implements(fragmentType):
+		impl = implValue
+	case reflect.PointerTo(t).Implements(fragmentType):
+		impl = implPointer
+	}
+	fragmentTypes.Store(t, impl)
+	return impl
+}
+
+// fragmentKey 片段缓存的键：值的类型和FragmentKey，以及决定编码结果的分组、深度和选项
+type fragmentKey struct {
+	typ reflect.Type
+	key string
+	// 规范化的分组键，包含禁止的分组
+	groups    string
+	mode      GroupMode
+	inherited bool
+	// 当前递归深度，同一个值在不同深度下受MaxDepth限制的情况不同
+	depth int
+	opts  fragmentOptions
+}
+
+// fragmentOptions 影响结构体子树编码结果的选项，作为缓存键的一部分
+// 只作用于根值或整个输出的选项（包装、规范JSON、JS安全转义等）不影响片段的字节
+type fragmentOptions struct {
+	tagKey              string
+	nullIfEmpty         bool
+	ignoreNilPointers   bool
+	maxDepth            int
+	maxStringLen        int
+	maxStringMarker     string
+	maxSliceLen         int
+	markTruncatedSlices bool
+	invalidUTF8Policy   InvalidUTF8Policy
+	errorFormat         ErrorFormat
+	nullNilErrors       bool
+	errorChain          bool
+	requireGroupTags    bool
+	strictTags          bool
+	cascadeToUntagged   bool
+	pruneEmpty          bool
+	pruneCollections    bool
+}
+
+// fragmentOptionsOf 提取选项中影响片段编码结果的部分
+func fragmentOptionsOf(o *Options) fragmentOptions {
+	return fragmentOptions{
+		tagKey:              o.TagKey,
+		nullIfEmpty:         o.NullIfEmpty,
+		ignoreNilPointers:   o.IgnoreNilPointers,
+		maxDepth:            o.MaxDepth,
+		maxStringLen:        o.MaxStringLen,
+		maxStringMarker:     o.MaxStringMarker,
+		maxSliceLen:         o.MaxSliceLen,
+		markTruncatedSlices: o.MarkTruncatedSlices,
+		invalidUTF8Policy:   o.InvalidUTF8Policy,
+		errorFormat:         o.ErrorFormat,
+		nullNilErrors:       o.NullNilErrors,
+		errorChain:          o.ErrorChain,
+		requireGroupTags:    o.RequireGroupTags,
+		strictTags:          o.StrictTags,
+		cascadeToUntagged:   o.CascadeToUntagged,
+		pruneEmpty:          o.PruneEmpty,
+		pruneCollections:    o.PruneEmptyCollections,
+	}
+}
+
+// cachesFragments 判断当前选项下能否使用片段缓存
+// 跟踪、指标、FieldHook、ValueFilter、DeprecationHook和XRay需要逐字段观察或改变每次的输出，设置时总是重新编码；
+// FieldRenames的键名取决于片段所在的路径，同样不使用缓存
+func (o *Options) cachesFragments() bool {
+	return len(o.FieldRenames) == 0 &&
+		o.FieldHook == nil &&
+		o.ValueFilter == nil &&
+		o.TraceLogger == nil &&
+		o.MetricsHook == nil &&
+		o.DeprecationHook == nil &&
+		!o.XRay
+}
+
+// fragmentKeyOf 返回结构体值v的片段缓存键，v未实现CacheableFragment或当前不能使用缓存时返回false
+func fragmentKeyOf(ctx *serializeContext, v reflect.Value, mode GroupMode) (fragmentKey, bool) {
+	if !globalFragmentCache.enabled() || !ctx.opts.cachesFragments() {
+		return fragmentKey{}, false
+	}
+	var f CacheableFragment
+	switch fragmentImplOf(v.Type()) {
+	case implValue:
+		if !v.CanInterface() {
+			return fragmentKey{}, false
+		}
+		f = v.Interface().(CacheableFragment)
+	case implPointer:
+		if !v.CanAddr() || !v.Addr().CanInterface() {
+			return fragmentKey{}, false
+		}
+		f = v.Addr().Interface().(CacheableFragment)
+	default:
+		return fragmentKey{}, false
+	}
+	key := f.FragmentKey()
+	if key == "" {
+		return fragmentKey{}, false
+	}
+	return fragmentKey{
+		typ:       v.Type(),
+		key:       key,
+		groups:    ctx.groupKey,
+		mode:      mode,
+		inherited: ctx.inherited,
+		depth:     ctx.depth,
+		opts:      fragmentOptionsOf(ctx.opts),
+	}, true
+}
+
+// encodeFragment 编码实现了CacheableFragment的结构体值，命中缓存时直接写入缓存的字节
+// 未命中时正常编码；编码过程中收集了错误、省略或截断了内容时不缓存，以免再次输出时丢失这些记录。
+// 启用PruneEmpty时空的片段也不缓存，每次重新编码以便调用方判断其是否因过滤而为空
+func (e *encoder) encodeFragment(ctx *serializeContext, v reflect.Value, key fragmentKey, groups []string, mode GroupMode) error {
+	if b, ok := globalFragmentCache.get(key); ok {
+		e.buf = append(e.buf, b...)
+		return nil
+	}
+
+	mark := len(e.buf)
+	state := ctx.state
+	errs, skipped, truncated := len(state.errs), len(state.skipped), len(state.truncated)
+	if err := e.encodeStructValue(ctx, v, groups, mode); err != nil {
+		return err
+	}
+	if len(state.errs) == errs && len(state.skipped) == skipped && len(state.truncated) == truncated &&
+		!(ctx.opts.PruneEmpty && isEmptyJSON(e.buf[mark:])) {
+		globalFragmentCache.add(key, slices.Clone(e.buf[mark:]))
+	}
+	return nil
+}
+
+// fragmentCache 按LRU淘汰的片段缓存，以编码后的字节数限制容量
+type fragmentCache struct {
+	// 保护缓存的互斥锁，命中时也需要调整LRU顺序
+	mu sync.Mutex
+	// 缓存映射：片段键 -> LRU列表中的条目
+	cache map[fragmentKey]*list.Element
+	// 访问顺序列表，用于LRU淘汰
+	evictList *list.List
+	// 最大缓存字节数，0表示不缓存
+	maxBytes atomic.Int64
+	// 当前条目数和字节数，在锁保护下修改，读取统计时无需加锁
+	entries atomic.Int64
+	bytes   atomic.Int64
+	// 缓存统计信息
+	stats cacheStat
+}
+
+// fragmentEntry 片段缓存条目
+type fragmentEntry struct {
+	key  fragmentKey
+	data []byte
+	size int64
+}
+
+// newFragmentCache 创建片段缓存
+func newFragmentCache() *fragmentCache {
+	c := &fragmentCache{
+		cache:     make(map[fragmentKey]*list.Element),
+		evictList: list.New(),
+	}
+	c.maxBytes.Store(DefaultMaxFragmentCacheBytes)
+	return c
+}
+
+// globalFragmentCache 全局片段缓存
+var globalFragmentCache = newFragmentCache()
+
+// SetMaxFragmentCacheBytes 设置片段缓存的字节容量，超出时按LRU顺序淘汰
+// 设置为0或负数时关闭片段缓存并清空已缓存的片段
+func SetMaxFragmentCacheBytes(n int64) {
+	globalFragmentCache.setMaxBytes(n)
+}
+
+// ClearFragmentCache 清空片段缓存，统计信息一并清零
+func ClearFragmentCache() {
+	globalFragmentCache.clear()
+}
+
+// enabled 判断片段缓存是否开启
+func (c *fragmentCache) enabled() bool {
+	return c.maxBytes.Load() > 0
+}
+
+// get 查找片段，命中时更新LRU位置
+func (c *fragmentCache) get(key fragmentKey) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.cache[key]
+	if !ok {
+		c.stats.misses.Add(1)
+		return nil, false
+	}
+	c.stats.hits.Add(1)
+	c.evictList.MoveToFront(element)
+	return element.Value.(*fragmentEntry).data, true
+}
+
+// add 加入片段，超过字节容量的片段不缓存
+func (c *fragmentCache) add(key fragmentKey, data []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	size := int64(len(data) + len(key.key) + len(key.groups))
+	maxBytes := c.maxBytes.Load()
+	if maxBytes <= 0 || size > maxBytes {
+		return
+	}
+	if element, ok := c.cache[key]; ok {
+		// 并发编码同一片段时保留先加入的条目
+		c.evictList.MoveToFront(element)
+		return
+	}
+	for c.bytes.Load()+size > maxBytes && c.evictList.Len() > 0 {
+		c.evict()
+	}
+	c.cache[key] = c.evictList.PushFront(&fragmentEntry{key: key, data: data, size: size})
+	c.entries.Add(1)
+	c.bytes.Add(size)
+}
+
+// evict 淘汰最近最少使用的条目，调用方需持有锁
+func (c *fragmentCache) evict() {
+	element := c.evictList.Back()
+	if element == nil {
+		return
+	}
+	c.evictList.Remove(element)
+	entry := element.Value.(*fragmentEntry)
+	delete(c.cache, entry.key)
+	c.entries.Add(-1)
+	c.bytes.Add(-entry.size)
+	c.stats.evictions.Add(1)
+}
+
+// setMaxBytes 设置字节容量，必要时淘汰多余条目
+func (c *fragmentCache) setMaxBytes(n int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.maxBytes.Store(max(n, 0))
+	for c.bytes.Load() > max(n, 0) && c.evictList.Len() > 0 {
+		c.evict()
+	}
+}
+
+// clear 清空片段缓存
+func (c *fragmentCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.cache = make(map[fragmentKey]*list.Element)
+	c.evictList.Init()
+	c.entries.Store(0)
+	c.bytes.Store(0)
+	c.stats.reset()
+}
+
+// addStats 将片段缓存的统计信息写入stats，只读取原子计数器
+func (c *fragmentCache) addStats(stats *CacheStats) {
+	stats.FragmentEntries = int(c.entries.Load())
+	stats.FragmentBytes = c.bytes.Load()
+	stats.MaxFragmentBytes = c.maxBytes.Load()
+	stats.FragmentHits = c.stats.hits.Load()
+	stats.FragmentMisses = c.stats.misses.Load()
+	stats.FragmentEvictions = c.stats.evictions.Load()
+}
@@ -0,0 +1,167 @@
+package jsongroup
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// catalogEntry 以Version作为缓存代次的参考数据，修改内容时应同时修改Version
+type catalogEntry struct {
+	SKU     string `json:"sku" groups:"public,admin"`
+	Title   string `json:"title" groups:"public,admin"`
+	Cost    int    `json:"cost" groups:"admin"`
+	Note    string `json:"note" groups:"public"`
+	Version string `json:"-"`
+}
+
+func (c catalogEntry) FragmentKey() string {
+	if c.Version == "" {
+		return ""
+	}
+	return c.SKU + "@" + c.Version
+}
+
+// pointerEntry 指针接收者实现CacheableFragment
+type pointerEntry struct {
+	Name string `json:"name" groups:"public"`
+	Key  string `json:"-"`
+}
+
+func (p *pointerEntry) FragmentKey() string { return p.Key }
+
+// resetFragmentCache 清空片段缓存并恢复默认容量，测试结束后同样清空
+func resetFragmentCache(t *testing.T) {
+	t.Helper()
+	SetMaxFragmentCacheBytes(DefaultMaxFragmentCacheBytes)
+	ClearFragmentCache()
+	t.Cleanup(func() {
+		SetMaxFragmentCacheBytes(DefaultMaxFragmentCacheBytes)
+		ClearFragmentCache()
+	})
+}
+
+func TestFragmentCacheHit(t *testing.T) {
+	resetFragmentCache(t)
+	entry := catalogEntry{SKU: "a1", Title: "Apple", Cost: 3, Version: "1"}
+	list := []catalogEntry{entry, entry, entry}
+
+	assertJSONEqual(t, marshalString(t, list, New(), "public"),
+		`[{"sku":"a1","title":"Apple","note":""},{"sku":"a1","title":"Apple","note":""},{"sku":"a1","title":"Apple","note":""}]`)
+	stats := GetCacheStats()
+	if stats.FragmentMisses != 1 || stats.FragmentHits != 2 || stats.FragmentEntries != 1 || stats.FragmentBytes == 0 {
+		t.Errorf("stats after first call = %+v, want 1 miss, 2 hits, 1 entry", stats)
+	}
+
+	// 键不变时直接写入缓存的字节，即使内容已被修改；缓存键包含深度，同样作为切片元素编码
+	stale := entry
+	stale.Title = "Changed"
+	assertJSONEqual(t, marshalString(t, []catalogEntry{stale}, New(), "public"), `[{"sku":"a1","title":"Apple","note":""}]`)
+	if hits := GetCacheStats().FragmentHits; hits != 3 {
+		t.Errorf("FragmentHits = %d, want 3", hits)
+	}
+
+	// 新的代次使旧条目失效
+	stale.Version = "2"
+	assertJSONEqual(t, marshalString(t, []catalogEntry{stale}, New(), "public"), `[{"sku":"a1","title":"Changed","note":""}]`)
+
+	// 空键不使用缓存
+	stale.Version = ""
+	stale.Title = "Fresh"
+	assertJSONEqual(t, marshalString(t, []catalogEntry{stale}, New(), "public"), `[{"sku":"a1","title":"Fresh","note":""}]`)
+	if entries := GetCacheStats().FragmentEntries; entries != 2 {
+		t.Errorf("FragmentEntries = %d, want 2", entries)
+	}
+}
+
+func TestFragmentCacheKeyedByGroupsAndOptions(t *testing.T) {
+	resetFragmentCache(t)
+	entry := catalogEntry{SKU: "a1", Title: "Apple", Cost: 3, Version: "1"}
+
+	cases := []struct {
+		opts   *Options
+		groups []string
+		want   string
+	}{
+		{New(), []string{"public"}, `{"sku":"a1","title":"Apple","note":""}`},
+		{New(), []string{"admin"}, `{"sku":"a1","title":"Apple","cost":3}`},
+		{New().WithNullIfEmpty(true), []string{"public"}, `{"sku":"a1","title":"Apple","note":null}`},
+		{New().WithMaxStringLen(2, "~"), []string{"public"}, `{"sku":"a1","title":"Ap~","note":""}`},
+		{New().WithGroupMode(GroupModeAnd), []string{"public", "admin"}, `{"sku":"a1","title":"Apple"}`},
+	}
+	// 两轮：第一轮逐个写入缓存，第二轮必须命中各自的条目而不是其他分组或选项的条目
+	for round := range 2 {
+		for _, c := range cases {
+			if got := marshalString(t, entry, c.opts, c.groups...); got != c.want {
+				t.Errorf("round %d, groups %v: got %s, want %s", round, c.groups, got, c.want)
+			}
+		}
+	}
+	// 截断了字符串的片段不缓存，其余每种组合各缓存一个条目
+	stats := GetCacheStats()
+	if stats.FragmentHits != int64(len(cases)-1) || stats.FragmentEntries != len(cases)-1 {
+		t.Errorf("stats = %+v, want one entry and one hit per cacheable case", stats)
+	}
+
+	// 需要逐字段观察的选项不使用缓存
+	renamed := New().WithFieldRenames(map[string]string{"title": "name"})
+	assertJSONEqual(t, marshalString(t, entry, renamed, "public"), `{"sku":"a1","name":"Apple","note":""}`)
+
+	// 容量为0时关闭缓存
+	SetMaxFragmentCacheBytes(0)
+	if stats := GetCacheStats(); stats.FragmentEntries != 0 || stats.MaxFragmentBytes != 0 {
+		t.Errorf("stats after disabling = %+v", stats)
+	}
+	assertJSONEqual(t, marshalString(t, entry, New(), "public"), `{"sku":"a1","title":"Apple","note":""}`)
+	if entries := GetCacheStats().FragmentEntries; entries != 0 {
+		t.Errorf("FragmentEntries = %d after disabling, want 0", entries)
+	}
+}
+
+func TestFragmentCacheMatchesMapPath(t *testing.T) {
+	resetFragmentCache(t)
+	doc := struct {
+		Items []catalogEntry  `json:"items" groups:"public,admin"`
+		Ptrs  []*pointerEntry `json:"ptrs" groups:"public"`
+	}{
+		Items: []catalogEntry{
+			{SKU: "a1", Title: "Apple", Cost: 3, Version: "1"},
+			{SKU: "b2", Title: "Banana", Note: "ripe", Version: "1"},
+			{SKU: "a1", Title: "Apple", Cost: 3, Version: "1"},
+		},
+		Ptrs: []*pointerEntry{{Name: "x", Key: "x"}, {Name: "x", Key: "x"}},
+	}
+
+	// 缓存命中与否，直接编码的结果都与MarshalToMap的结果一致
+	for _, groups := range [][]string{{"public"}, {"admin"}} {
+		for range 2 {
+			direct := marshalString(t, doc, New(), groups...)
+			m, err := MarshalToMap(doc, groups...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			mapped, err := json.Marshal(m)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertJSONEqual(t, direct, string(mapped))
+		}
+	}
+	if hits := GetCacheStats().FragmentHits; hits == 0 {
+		t.Error("expected fragment cache hits")
+	}
+
+	// MarshalToMap不使用片段缓存，总是反映当前内容
+	doc.Items[0].Title = "Stale"
+	m, err := MarshalToMap(doc, "public")
+	if err != nil {
+		t.Fatal(err)
+	}
+	items := m["items"].([]any)
+	if title := items[0].(map[string]any)["title"]; title != "Stale" {
+		t.Errorf("MarshalToMap title = %v, want Stale", title)
+	}
+	if !reflect.DeepEqual(items[1], map[string]any{"sku": "b2", "title": "Banana", "note": "ripe"}) {
+		t.Errorf("items[1] = %#v", items[1])
+	}
+}
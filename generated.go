@@ -0,0 +1,146 @@
+package jsongroup
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+)
+
+// GroupMarshaler 由jsongroupgen生成的静态序列化接口，也可以手动实现
+// 直接编码JSON时（MarshalByGroups、MarshalByGroupsAppend、Encoder），若选项与生成代码假定的默认行为一致，
+// 运行时优先调用AppendByGroups将结构体编码后追加到dst，而不是通过反射遍历字段；MarshalToMap始终使用反射路径。
+// state携带本次调用的选项、递归深度和循环引用检测状态，实现需要将它原样传给AppendField，
+// 嵌套的字段才能与反射路径共用这些状态；实现中不得再对同一类型调用MarshalByGroups，否则会无限递归
+type GroupMarshaler interface {
+	AppendByGroups(dst []byte, state *EncodeState, groups ...string) ([]byte, error)
+}
+
+// EncodeState 运行时调用GroupMarshaler时传入的序列化状态，只在该次调用期间有效，不得保留
+// 与当前结构体的序列化上下文是同一个对象，传递时不额外分配
+type EncodeState serializeContext
+
+// groupMarshalerType GroupMarshaler接口的反射类型
+var groupMarshalerType = reflect.TypeFor[GroupMarshaler]()
+
+// groupMarshalerTypes 缓存各类型是否实现了GroupMarshaler
+var groupMarshalerTypes sync.Map
+
+// implementsGroupMarshaler 判断类型是否实现了GroupMarshaler，结果按类型缓存
+func implementsGroupMarshaler(t reflect.Type) bool {
+	if ok, exists := groupMarshalerTypes.Load(t); exists {
+		return ok.(bool)
+	}
+	ok := t.Implements(groupMarshalerType)
+	groupMarshalerTypes.Store(t, ok)
+	return ok
+}
+
+// useGroupMarshaler 判断当前选项是否与生成代码假定的行为一致
+// 生成代码固定使用groups标签和Or模式，nil指针字段跳过，空值不输出null；启用跟踪、指标回调、FieldHook、ValueFilter或DeprecationHook时需要经过反射路径处理字段，
+// 设置禁止的分组、启用XRay、截断字符串或切片、输出规范JSON、检查无效UTF-8、要求分组标签、检查矛盾的标签、向未声明分组的嵌套字段传递分组匹配、读取字段时加锁、省略空的嵌套对象以及改变error值的输出形式时同样需要经过反射路径
+func (o *Options) useGroupMarshaler() bool {
+	return o.GroupMode == GroupModeOr &&
+		o.TagKey == DefaultTagKey &&
+		!o.NullIfEmpty &&
+		o.IgnoreNilPointers &&
+		o.TraceLogger == nil &&
+		o.MetricsHook == nil &&
+		o.FieldHook == nil &&
+		o.ValueFilter == nil &&
+		o.DeprecationHook == nil &&
+		!o.XRay &&
+		o.MaxStringLen == 0 &&
+		o.MaxSliceLen == 0 &&
+		!o.CanonicalJSON &&
+		o.InvalidUTF8Policy == InvalidUTF8PolicyReplace &&
+		o.ErrorFormat == ErrorFormatString &&
+		!o.NullNilErrors &&
+		!o.ErrorChain &&
+		!o.RequireGroupTags &&
+		!o.StrictTags &&
+		!o.CascadeToUntagged &&
+		!o.Locking &&
+		!o.PruneEmpty &&
+		len(o.FieldRenames) == 0 &&
+		len(o.DenyGroups) == 0
+}
+
+// groupMarshalerOf 返回可用于当前值的GroupMarshaler
+// 生成代码按生成时的标签过滤字段，通过RegisterFieldGroups注册过分组的类型需要经过反射路径
+func groupMarshalerOf(ctx *serializeContext, v reflect.Value) (GroupMarshaler, bool) {
+	if !ctx.opts.useGroupMarshaler() || !v.CanInterface() || !implementsGroupMarshaler(v.Type()) || registeredFieldGroups(v.Type()) != nil {
+		return nil, false
+	}
+	m, ok := v.Interface().(GroupMarshaler)
+	return m, ok
+}
+
+// AppendString 按本库的规则编码字符串并追加到dst，供生成的代码使用
+func AppendString(dst []byte, s string) []byte {
+	return appendJSONString(dst, s)
+}
+
+// AppendFloat 按本库的规则编码浮点数并追加到dst，NaN和Inf编码为字符串，供生成的代码使用
+func AppendFloat(dst []byte, f float64) []byte {
+	if isSpecialFloat(f) {
+		return appendJSONString(dst, floatToString(f))
+	}
+	return appendJSONFloat(dst, f, 64)
+}
+
+// AppendFloat32 按本库的规则以float32的精度编码浮点数并追加到dst，与encoding/json的输出一致，供生成的代码使用
+func AppendFloat32(dst []byte, f float32) []byte {
+	if isSpecialFloat(float64(f)) {
+		return appendJSONString(dst, floatToString(float64(f)))
+	}
+	return appendJSONFloat(dst, float64(f), 32)
+}
+
+// AppendField 按反射路径编码ptr指向的字段值并追加到dst，供生成的代码处理非基本类型字段
+// state为运行时传给AppendByGroups的状态，字段沿用调用方的选项、递归深度和循环引用检测，出错时按错误策略处理；
+// state为nil时使用包级默认选项开始新的序列化状态，循环引用检测和深度限制只覆盖这个字段。
+// name为字段名，作为错误路径的最后一段；返回false表示该值被省略，此时dst保持不变
+func AppendField(dst []byte, state *EncodeState, name string, ptr any, groups ...string) ([]byte, bool, error) {
+	v := reflect.ValueOf(ptr)
+	if v.Kind() != reflect.Pointer || v.IsNil() {
+		return dst, false, ReflectionError(name, errors.New("AppendField需要非nil的字段指针"))
+	}
+
+	if state == nil {
+		opts := defaults()
+		ctx := newContext(*opts, groups)
+		defer ctx.release()
+		// 与在根结构体内编码该字段时的路径和深度保持一致
+		ctx.segment = name
+		ctx.depth = 1
+
+		e := encoder{buf: dst}
+		ok, err := e.encodeValue(ctx, v.Elem(), groups, ctx.opts.GroupMode)
+		if err != nil {
+			if errors.Is(err, errSkipField) {
+				return dst, false, nil
+			}
+			return dst, false, WrapJSONError(err, "Root")
+		}
+		return e.buf, ok, nil
+	}
+
+	// 与encodeField中非基本类型字段的处理一致
+	fieldCtx := (*serializeContext)(state).withPath(name)
+	e := encoder{buf: dst}
+	ok, err := e.encodeValue(fieldCtx, v.Elem(), groups, fieldCtx.opts.GroupMode)
+	if err != nil {
+		if errors.Is(err, errSkipField) {
+			return dst, false, nil
+		}
+		switch fieldCtx.handleError(err) {
+		case errorFail:
+			return dst, false, err
+		case errorOmit:
+			return dst, false, nil
+		}
+		// Collect策略下以null代替出错的字段
+		return append(dst, "null"...), true, nil
+	}
+	return e.buf, ok, nil
+}
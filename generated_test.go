@@ -0,0 +1,108 @@
+package jsongroup
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+// handNode 手写的GroupMarshaler实现，记录被调用的次数
+type handNode struct {
+	ID    int       `json:"id" groups:"public"`
+	Next  *handNode `json:"next" groups:"public"`
+	Extra any       `json:"extra,omitempty" groups:"public"`
+}
+
+var handNodeCalls int
+
+func (v handNode) AppendByGroups(dst []byte, state *EncodeState, groups ...string) ([]byte, error) {
+	handNodeCalls++
+	buf := append(dst, `{"id":`...)
+	buf = strconv.AppendInt(buf, int64(v.ID), 10)
+	for name, ptr := range map[string]any{"next": &v.Next, "extra": &v.Extra} {
+		if reflect.ValueOf(ptr).Elem().IsZero() {
+			continue
+		}
+		mark := len(buf)
+		buf = append(buf, `,"`+name+`":`...)
+		var ok bool
+		var err error
+		buf, ok, err = AppendField(buf, state, name, ptr, groups...)
+		if err != nil {
+			return dst, err
+		}
+		if !ok {
+			buf = buf[:mark]
+		}
+	}
+	return append(buf, '}'), nil
+}
+
+func TestGroupMarshalerDispatch(t *testing.T) {
+	v := handNode{ID: 1, Next: &handNode{ID: 2}}
+	handNodeCalls = 0
+	assertJSONEqual(t, marshalString(t, v, New(), "public"), `{"id":1,"next":{"id":2}}`)
+	if handNodeCalls != 2 {
+		t.Errorf("AppendByGroups called %d times, want 2", handNodeCalls)
+	}
+
+	handNodeCalls = 0
+	keep := func(string, reflect.Value, FieldDescriptor, reflect.Value) bool { return true }
+	marshalString(t, v, New().WithValueFilter(keep), "public")
+	if handNodeCalls != 0 {
+		t.Errorf("AppendByGroups called %d times with a ValueFilter, want 0", handNodeCalls)
+	}
+}
+
+func TestGroupMarshalerSharesState(t *testing.T) {
+	a := &handNode{ID: 1}
+	a.Next = a
+	if _, err := MarshalByGroups(a, "public"); !errors.Is(err, ErrCircularReference) {
+		t.Errorf("cycle: got %v, want ErrCircularReference", err)
+	}
+
+	var head *handNode
+	for i := range 10 {
+		head = &handNode{ID: i, Next: head}
+	}
+	_, err := MarshalByGroupsWithOptions(head, New().WithMaxDepth(3), "public")
+	if !errors.Is(err, ErrMaxDepthExceeded) {
+		t.Errorf("MaxDepth: got %v, want ErrMaxDepthExceeded", err)
+	}
+
+	// 生成代码内部的字段错误按调用方的错误策略处理
+	v := handNode{ID: 1, Extra: make(chan int)}
+	data, err := MarshalByGroupsWithOptions(v, New().WithErrorPolicy(ErrorPolicyCollect), "public")
+	if !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf("Collect: got %v, want ErrUnsupportedType", err)
+	}
+	assertJSONEqual(t, string(data), `{"id":1,"extra":null}`)
+
+	data, report, err := MarshalByGroupsWithReport(v, New().WithBestEffort(true), "public")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertJSONEqual(t, string(data), `{"id":1}`)
+	if len(report.Skipped) != 1 || report.Skipped[0].Path != "extra" {
+		t.Errorf("Skipped = %+v, want one entry at extra", report.Skipped)
+	}
+}
+
+func TestAppendFieldWithoutState(t *testing.T) {
+	tags := []string{"a", "b"}
+	buf, ok, err := AppendField([]byte("x"), nil, "Tags", &tags, "public")
+	if err != nil || !ok || string(buf) != `x["a","b"]` {
+		t.Errorf("got %s, %v, %v", buf, ok, err)
+	}
+
+	var missing *Address
+	buf, ok, err = AppendField([]byte("x"), nil, "Address", &missing, "public")
+	if err != nil || ok || string(buf) != "x" {
+		t.Errorf("nil pointer: got %s, %v, %v", buf, ok, err)
+	}
+
+	if _, _, err := AppendField(nil, nil, "Tags", tags); err == nil {
+		t.Error("expected an error for a non-pointer argument")
+	}
+}
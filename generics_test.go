@@ -0,0 +1,111 @@
+package jsongroup
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type Page[T any] struct {
+	Items []T `json:"items" groups:"public"`
+	Total int `json:"total" groups:"public"`
+	Next  T   `json:"next,omitempty" groups:"admin"`
+}
+
+type Base[ID any] struct {
+	ID      ID        `json:"id" groups:"public"`
+	Updated time.Time `json:"updated" groups:"admin"`
+}
+
+// Product 内嵌泛型基类，与普通内嵌结构体一样展开到外层
+type Product struct {
+	Base[int64]
+	Name string `json:"name" groups:"public"`
+}
+
+// Tagged 以内嵌泛型基类的实例化作为类型参数，测试多层实例化
+type Tagged[T any] struct {
+	Base[T] `json:",omitempty"`
+	Label   string `json:"label" groups:"public"`
+}
+
+func TestGenericInstantiations(t *testing.T) {
+	alice := User{ID: 1, Name: "Alice", Email: "alice@example.com"}
+	updated := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	for _, tc := range []struct {
+		name   string
+		v      any
+		groups []string
+		want   string
+	}{
+		{"Page[User] public", Page[User]{Items: []User{alice}, Total: 1}, []string{"public"},
+			`{"items":[{"id":1,"name":"Alice"}],"total":1}`},
+		{"Page[User] admin", Page[User]{Items: []User{alice}, Total: 1}, []string{"admin"},
+			`{"next":{"id":0,"name":"","email":""}}`},
+		{"Page[*User] public", Page[*User]{Items: []*User{&alice, nil}, Total: 2}, []string{"public"},
+			`{"items":[{"id":1,"name":"Alice"},null],"total":2}`},
+		{"Page[*User] admin", Page[*User]{Next: &alice}, []string{"admin"},
+			`{"next":{"id":1,"name":"Alice","email":"alice@example.com"}}`},
+		{"Page[string] admin", Page[string]{Next: "cursor"}, []string{"admin"}, `{"next":"cursor"}`},
+		{"Page[Page[User]] public", Page[Page[User]]{Items: []Page[User]{{Items: []User{alice}, Total: 1}}, Total: 1}, []string{"public"},
+			`{"items":[{"items":[{"id":1,"name":"Alice"}],"total":1}],"total":1}`},
+		{"generic base public", Product{Base: Base[int64]{ID: 7, Updated: updated}, Name: "p"}, []string{"public"},
+			`{"id":7,"name":"p"}`},
+		{"generic base admin", Product{Base: Base[int64]{ID: 7, Updated: updated}, Name: "p"}, []string{"admin"},
+			`{"updated":"2024-01-02T03:04:05Z"}`},
+		{"generic base with type parameter", Tagged[string]{Base: Base[string]{ID: "sku"}, Label: "l"}, []string{"public"},
+			`{"id":"sku","label":"l"}`},
+		{"generic base omitempty", Tagged[string]{Label: "l"}, []string{"public"}, `{"label":"l"}`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := marshalString(t, tc.v, New(), tc.groups...)
+			if got != tc.want {
+				t.Errorf("direct encoder:\ngot  %s\nwant %s", got, tc.want)
+			}
+
+			m, err := MarshalToMap(tc.v, tc.groups...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, _ := json.Marshal(m)
+			assertJSONEqual(t, string(data), tc.want)
+
+			if n, err := EstimateSize(tc.v, nil, tc.groups...); err != nil || n != len(tc.want) {
+				t.Errorf("EstimateSize = %d, %v; want %d", n, err, len(tc.want))
+			}
+		})
+	}
+}
+
+// TestGenericInstantiationsCachedSeparately 每个实例化各自占用一个缓存项，Page[User]与Page[*User]的类型名互不相同
+func TestGenericInstantiationsCachedSeparately(t *testing.T) {
+	c := newFieldCache()
+	c.SetDetailedStats(true)
+	old := globalCache
+	globalCache = c
+	defer func() { globalCache = old }()
+
+	values := []any{Page[User]{}, Page[*User]{}, Page[Address]{}, Page[string]{}, Page[int]{}, Tagged[string]{}, Tagged[int64]{}}
+	for range 3 {
+		for _, v := range values {
+			if _, err := MarshalByGroups(v, "public"); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	stats := c.GetStatsByType()
+	names := make(map[string]bool)
+	for _, v := range values {
+		name := typeName(reflect.TypeOf(v))
+		if names[name] {
+			t.Fatalf("duplicate type name %s", name)
+		}
+		names[name] = true
+		// 第一次解析未命中，之后两次都命中自己的缓存项
+		if s := stats[name]; s.Misses != 1 || s.Hits != 2 {
+			t.Errorf("%s: hits %d, misses %d; want 2 and 1", name, s.Hits, s.Misses)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package jsongroup
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// ParseOption ParseGroupParam和ParseGroupValues的选项
+type ParseOption func(*groupParamConfig)
+
+// groupParamConfig 解析分组参数的配置，零值表示不限制数量、不检查白名单、保留大小写、忽略空分组
+type groupParamConfig struct {
+	max         int
+	allowed     []string
+	foldCase    bool
+	rejectEmpty bool
+}
+
+// ParamMaxGroups 限制去重后的分组数量，超过n个时返回错误；n<=0表示不限制
+func ParamMaxGroups(n int) ParseOption {
+	return func(c *groupParamConfig) { c.max = n }
+}
+
+// ParamAllowGroups 只接受groups中的分组，出现其他分组时返回列出所有未知分组的错误；多次调用时取并集
+// 可以配合KnownGroups使用DTO中实际声明的分组
+func ParamAllowGroups(groups ...string) ParseOption {
+	return func(c *groupParamConfig) { c.allowed = append(c.allowed, groups...) }
+}
+
+// ParamFoldCase 将分组转换为小写后再去重和检查白名单，白名单中的分组同样按小写比较
+func ParamFoldCase(enable bool) ParseOption {
+	return func(c *groupParamConfig) { c.foldCase = enable }
+}
+
+// ParamRejectEmpty 参数中出现空分组（如"public,,admin"或末尾的逗号）时返回错误，而不是忽略
+func ParamRejectEmpty(enable bool) ParseOption {
+	return func(c *groupParamConfig) { c.rejectEmpty = enable }
+}
+
+// ParseGroupParam 解析逗号分隔的分组参数，如查询参数?groups=public,profile
+// 每个分组去除首尾空白，重复的分组只保留第一次出现的位置；空字符串返回nil。
+// 参数不符合选项的要求时返回ErrTypeInvalidGroupParam错误，错误信息可以直接作为400响应的说明
+func ParseGroupParam(s string, opts ...ParseOption) ([]string, error) {
+	var c groupParamConfig
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&c)
+		}
+	}
+	if s == "" {
+		return nil, nil
+	}
+
+	var groups []string
+	for i, part := range strings.Split(s, ",") {
+		g := strings.TrimSpace(part)
+		if g == "" {
+			if c.rejectEmpty {
+				return nil, InvalidGroupParamError(s, fmt.Sprintf("第%d个分组为空", i+1))
+			}
+			continue
+		}
+		if c.foldCase {
+			g = strings.ToLower(g)
+		}
+		if !slices.Contains(groups, g) {
+			groups = append(groups, g)
+		}
+	}
+
+	if c.allowed != nil {
+		var unknown []string
+		for _, g := range groups {
+			if !slices.ContainsFunc(c.allowed, func(a string) bool { return a == g || (c.foldCase && strings.ToLower(a) == g) }) {
+				unknown = append(unknown, g)
+			}
+		}
+		if len(unknown) > 0 {
+			return nil, InvalidGroupParamError(s, "未知的分组: "+strings.Join(unknown, ", "))
+		}
+	}
+	if c.max > 0 && len(groups) > c.max {
+		return nil, InvalidGroupParamError(s, fmt.Sprintf("分组数量为%d，最多允许%d个", len(groups), c.max))
+	}
+	return groups, nil
+}
+
+// ParseGroupValues 合并查询参数中所有名为key的参数后按ParseGroupParam解析，如?groups=public&groups=profile,admin
+// 重复的参数按出现顺序以逗号连接，数量限制针对合并去重后的结果；参数不存在时返回nil
+func ParseGroupValues(values url.Values, key string, opts ...ParseOption) ([]string, error) {
+	return ParseGroupParam(strings.Join(values[key], ","), opts...)
+}
+
+// KnownGroups 返回types及其嵌套的结构体类型中声明的所有分组，包括RegisterFieldGroups注册的分组，按字典序排列
+// 适合在启动时计算一次，作为ParamAllowGroups的白名单；types可以是任意值或reflect.Type，opts为nil时使用默认选项，
+// 解析失败的类型汇总在返回的错误中
+func KnownGroups(opts *Options, types ...any) ([]string, error) {
+	if opts == nil {
+		opts = defaults()
+	}
+	tagKey := opts.TagKey
+	if tagKey == "" {
+		tagKey = DefaultTagKey
+	}
+
+	var groups []string
+	var errs []error
+	seen := make(map[reflect.Type]bool)
+	for _, v := range types {
+		t, ok := v.(reflect.Type)
+		if !ok {
+			t = reflect.TypeOf(v)
+		}
+		if t != nil {
+			collectGroups(t, tagKey, seen, &groups, &errs)
+		}
+	}
+	slices.Sort(groups)
+	return slices.Compact(groups), errors.Join(errs...)
+}
+
+// collectGroups 递归收集类型中字段声明的分组，遍历方式与collectTagProblems一致
+func collectGroups(t reflect.Type, tagKey string, seen map[reflect.Type]bool, groups *[]string, errs *[]error) {
+	for {
+		switch t.Kind() {
+		case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+			t = t.Elem()
+			continue
+		}
+		break
+	}
+	if t.Kind() != reflect.Struct || t == timeType || seen[t] {
+		return
+	}
+	seen[t] = true
+
+	info, err := globalCache.getFieldsInfo(t, tagKey)
+	if err != nil {
+		*errs = append(*errs, ReflectionError(typeName(t), err))
+		return
+	}
+	for _, field := range info.fields {
+		*groups = append(*groups, field.Groups...)
+		collectGroups(t.FieldByIndex(field.Index).Type, tagKey, seen, groups, errs)
+	}
+}
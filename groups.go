@@ -0,0 +1,101 @@
+package jsongroup
+
+import "sync"
+
+// groupMask 分组位图，第i位表示编号为i的分组
+// 分组名在解析字段时分配编号，通常不超过64个，只占用一个字
+type groupMask []uint64
+
+// groupRegistry 分组名到编号的全局映射，只在解析字段时增长
+var groupRegistry = struct {
+	mu  sync.RWMutex
+	ids map[string]int
+}{ids: make(map[string]int)}
+
+// internGroup 返回分组名的编号，不存在时分配新编号
+func internGroup(name string) int {
+	groupRegistry.mu.RLock()
+	id, ok := groupRegistry.ids[name]
+	groupRegistry.mu.RUnlock()
+	if ok {
+		return id
+	}
+
+	groupRegistry.mu.Lock()
+	defer groupRegistry.mu.Unlock()
+	if id, ok := groupRegistry.ids[name]; ok {
+		return id
+	}
+	id = len(groupRegistry.ids)
+	groupRegistry.ids[name] = id
+	return id
+}
+
+// newGroupMask 将字段的分组标签转换为位图
+func newGroupMask(groups []string) groupMask {
+	var mask groupMask
+	for _, g := range groups {
+		mask = mask.with(internGroup(g))
+	}
+	return mask
+}
+
+// with 返回设置了第id位的位图，必要时扩容
+func (m groupMask) with(id int) groupMask {
+	word := id / 64
+	for len(m) <= word {
+		m = append(m, 0)
+	}
+	m[word] |= 1 << (id % 64)
+	return m
+}
+
+// intersects 判断两个位图是否有公共分组
+func (m groupMask) intersects(o groupMask) bool {
+	for i := range min(len(m), len(o)) {
+		if m[i]&o[i] != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// containsAll 判断m是否包含o中的所有分组
+func (m groupMask) containsAll(o groupMask) bool {
+	for i, w := range o {
+		if i >= len(m) {
+			if w != 0 {
+				return false
+			}
+			continue
+		}
+		if m[i]&w != w {
+			return false
+		}
+	}
+	return true
+}
+
+// groupQuery 调用方指定的分组列表转换后的查询条件
+type groupQuery struct {
+	// 已知分组组成的位图
+	mask groupMask
+	// 是否包含从未在任何标签中出现过的分组，此类分组不会匹配任何字段
+	unknown bool
+}
+
+// newGroupQuery 将调用方的分组列表转换为位图，只查找已有编号，不分配新编号
+func newGroupQuery(groups []string) groupQuery {
+	var q groupQuery
+	groupRegistry.mu.RLock()
+	defer groupRegistry.mu.RUnlock()
+	for _, g := range groups {
+		id, ok := groupRegistry.ids[g]
+		if !ok {
+			q.unknown = true
+			continue
+		}
+		q.mask = q.mask.with(id)
+	}
+	return q
+}
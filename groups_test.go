@@ -0,0 +1,93 @@
+package jsongroup
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+// naiveInclude 按分组名逐个比较的参考实现
+func naiveInclude(fieldGroups []string, mode GroupMode, groups []string) bool {
+	if len(fieldGroups) == 0 {
+		return false
+	}
+	if mode == GroupModeAnd {
+		for _, g := range groups {
+			if !slices.Contains(fieldGroups, g) {
+				return false
+			}
+		}
+		return true
+	}
+	for _, g := range groups {
+		if slices.Contains(fieldGroups, g) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestGroupMaskMatchesNaiveInclusion(t *testing.T) {
+	// 超过64个分组时位图跨越多个字
+	var many []string
+	for i := range 130 {
+		many = append(many, fmt.Sprintf("mask-test-%d", i))
+	}
+	fieldGroups := [][]string{
+		nil,
+		{"public"},
+		{"public", "admin"},
+		{many[0], many[70], many[129]},
+		many,
+	}
+	queries := [][]string{
+		{"public"},
+		{"admin", "public"},
+		{"public", "public"},
+		{"never-declared"},
+		{"public", "never-declared"},
+		{many[70]},
+		{many[0], many[129]},
+		{many[1], many[129]},
+		many,
+	}
+
+	for _, fg := range fieldGroups {
+		field := fieldInfo{Groups: fg, GroupMask: newGroupMask(fg)}
+		for _, groups := range queries {
+			query := newGroupQuery(groups)
+			for _, mode := range []GroupMode{GroupModeOr, GroupModeAnd} {
+				want := naiveInclude(fg, mode, groups)
+				if got := shouldIncludeField(field, mode, query, false); got != want {
+					t.Errorf("field %d groups, query %v, mode %v: got %v, want %v", len(fg), groups, mode, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestInternGroupIsStable(t *testing.T) {
+	a, b := internGroup("intern-a"), internGroup("intern-b")
+	if a == b || internGroup("intern-a") != a {
+		t.Errorf("ids %d, %d are not stable and distinct", a, b)
+	}
+	// 查询只查找已有编号，未声明的分组不分配编号
+	if q := newGroupQuery([]string{"intern-never-declared"}); !q.unknown || len(q.mask) != 0 {
+		t.Errorf("unknown group query = %+v", q)
+	}
+	if _, ok := groupRegistry.ids["intern-never-declared"]; ok {
+		t.Error("querying an unknown group registered it")
+	}
+}
+
+func TestUntaggedFieldsOnlyMatchWhenInherited(t *testing.T) {
+	query := newGroupQuery([]string{"public"})
+	for _, mode := range []GroupMode{GroupModeOr, GroupModeAnd} {
+		if shouldIncludeField(fieldInfo{}, mode, query, false) {
+			t.Errorf("mode %v: untagged field included", mode)
+		}
+		if !shouldIncludeField(fieldInfo{}, mode, query, true) {
+			t.Errorf("mode %v: untagged field excluded under an inherited match", mode)
+		}
+	}
+}
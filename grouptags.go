@@ -0,0 +1,139 @@
+package jsongroup
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// checkGroupTags 启用StrictTags或RequireGroupTags时检查结构体类型t，字段标签相互矛盾或存在未声明分组标签的导出字段时返回列出这些字段的错误
+// 检查结果来自按类型缓存的字段信息，每个类型只在解析时统计一次；path为结构体所在的路径
+func checkGroupTags(opts *Options, t reflect.Type, path string) error {
+	if !opts.RequireGroupTags && !opts.StrictTags {
+		return nil
+	}
+	info, err := opts.fieldsInfo(t)
+	if err != nil {
+		// 解析错误由随后的getFilteredFields返回
+		return nil
+	}
+	if opts.StrictTags && len(info.contradictions) > 0 {
+		return ContradictoryTagError(path, prefixPaths(path, info.contradictions))
+	}
+	if opts.RequireGroupTags && len(info.untagged) > 0 {
+		return MissingGroupTagError(path, opts.TagKey, prefixPaths(path, info.untagged))
+	}
+	return nil
+}
+
+// prefixPaths 为相对于结构体的字段路径加上结构体所在的路径
+func prefixPaths(path string, names []string) []string {
+	paths := make([]string, len(names))
+	for i, name := range names {
+		paths[i] = joinSchemaPath(path, name)
+	}
+	return paths
+}
+
+// tagContradictions 检查结构体类型t的字段标签中相互矛盾的声明，返回以字段路径开头的问题描述
+// 包括json:"-"的字段声明了分组标签（字段在读取分组之前就被忽略）以及同一分组标签中的分组重复；
+// 未指定json名称的内嵌结构体与parseFields一样展开，路径以内嵌字段名为前缀
+func tagContradictions(t reflect.Type, tagKey, prefix string) []string {
+	var problems []string
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := joinSchemaPath(prefix, field.Name)
+		groupsTag, tagged := field.Tag.Lookup(tagKey)
+
+		if jsonName, _, _ := parseJSONTag(field.Name, field.Tag.Get("json")); jsonName == "-" {
+			if tagged {
+				problems = append(problems, fmt.Sprintf(`%s: 标记为json:"-"的字段不会输出，%s标签不起作用`, name, tagKey))
+			}
+			continue
+		}
+		if dup := duplicateGroups(parseGroupsTag(groupsTag)); len(dup) > 0 {
+			problems = append(problems, fmt.Sprintf("%s: %s标签中的分组重复: %s", name, tagKey, strings.Join(dup, ", ")))
+		}
+		if flattensEmbedded(field) {
+			problems = append(problems, tagContradictions(field.Type, tagKey, name)...)
+		}
+	}
+	return problems
+}
+
+// duplicateGroups 返回分组列表中重复出现的分组，按首次重复的顺序排列
+func duplicateGroups(groups []string) []string {
+	var dup []string
+	for i, g := range groups {
+		if slices.Contains(groups[:i], g) && !slices.Contains(dup, g) {
+			dup = append(dup, g)
+		}
+	}
+	return dup
+}
+
+// ValidateType 检查v的类型及其嵌套的结构体类型，列出所有未声明分组标签的导出字段和相互矛盾的标签，与是否启用RequireGroupTags或StrictTags无关
+// 适合在测试或服务启动时校验DTO的标签：未声明标签的字段在不指定分组时会被输出，往往意味着遗漏；
+// json:"-"的字段声明了分组、同一标签中分组重复说明作者对输出结果的预期有误。
+// 非导出字段不检查，json:"-"的字段只检查是否声明了分组；v可以是任意值或reflect.Type，opts为nil时使用默认选项。
+// 缺少标签的字段通过ErrTypeMissingGroupTag错误、矛盾的标签通过ErrTypeContradictoryTag错误一并返回，
+// 同一类型出现在多处时只按首次出现的路径列出，解析失败的类型同样汇总在返回的错误中
+func ValidateType(v any, opts *Options) error {
+	if opts == nil {
+		opts = defaults()
+	}
+	if v == nil {
+		return nil
+	}
+	t, ok := v.(reflect.Type)
+	if !ok {
+		t = reflect.TypeOf(v)
+	}
+	tagKey := opts.TagKey
+	if tagKey == "" {
+		tagKey = DefaultTagKey
+	}
+
+	var fields, contradictions []string
+	var errs []error
+	collectTagProblems(t, tagKey, "", make(map[reflect.Type]bool), &fields, &contradictions, &errs)
+	if len(contradictions) > 0 {
+		errs = append([]error{ContradictoryTagError("", contradictions)}, errs...)
+	}
+	if len(fields) > 0 {
+		errs = append([]error{MissingGroupTagError("", tagKey, fields)}, errs...)
+	}
+	return errors.Join(errs...)
+}
+
+// collectTagProblems 递归收集类型中未声明分组标签的字段路径和相互矛盾的标签，遍历方式与warmType一致
+func collectTagProblems(t reflect.Type, tagKey, path string, seen map[reflect.Type]bool, fields, contradictions *[]string, errs *[]error) {
+	for {
+		switch t.Kind() {
+		case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+			t = t.Elem()
+			continue
+		}
+		break
+	}
+	if t.Kind() != reflect.Struct || t == timeType || seen[t] {
+		return
+	}
+	seen[t] = true
+
+	info, err := globalCache.getFieldsInfo(t, tagKey)
+	if err != nil {
+		*errs = append(*errs, ReflectionError(typeName(t), err))
+		return
+	}
+	*fields = append(*fields, prefixPaths(path, info.untagged)...)
+	*contradictions = append(*contradictions, prefixPaths(path, info.contradictions)...)
+	for _, field := range info.fields {
+		collectTagProblems(t.FieldByIndex(field.Index).Type, tagKey, joinSchemaPath(path, field.Name), seen, fields, contradictions, errs)
+	}
+}
@@ -0,0 +1,64 @@
+package jsongroup
+
+import "net/http"
+
+// GroupsFromRequest 返回一个中间件，每个请求调用一次fn计算分组列表并保存到请求上下文中
+// fn可以从请求头、JWT声明或查询参数等任意来源决定分组；之后的处理器可通过GroupsFromContext读取，
+// 调用WriteJSON时未指定分组则自动使用该列表
+func GroupsFromRequest(fn func(*http.Request) []string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := ContextWithGroups(r.Context(), fn(r)...)
+			next.ServeHTTP(&groupsResponseWriter{ResponseWriter: w, groups: contextGroups(ctx)}, r.WithContext(ctx))
+		})
+	}
+}
+
+// groupsResponseWriter 携带分组列表的ResponseWriter，使只接收ResponseWriter的WriteJSON也能取得分组
+type groupsResponseWriter struct {
+	http.ResponseWriter
+	groups []string
+}
+
+// Unwrap 返回原始的ResponseWriter，供http.ResponseController使用
+func (w *groupsResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Flush 在原始的ResponseWriter支持时刷新缓冲的数据
+func (w *groupsResponseWriter) Flush() {
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// groupsFromWriter 沿Unwrap链查找GroupsFromRequest保存的分组列表
+func groupsFromWriter(w http.ResponseWriter) ([]string, bool) {
+	for w != nil {
+		if gw, ok := w.(*groupsResponseWriter); ok {
+			return gw.groups, true
+		}
+		u, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			break
+		}
+		w = u.Unwrap()
+	}
+	return nil, false
+}
+
+// WriteJSON 按分组序列化v并写入HTTP响应，状态码为status，Content-Type为application/json
+// 未指定分组时使用GroupsFromRequest中间件为当前请求计算的分组；序列化失败时不写入任何内容，
+// 调用方可以自行返回错误响应。opts为nil时使用默认选项
+func WriteJSON(w http.ResponseWriter, status int, v any, opts *Options, groups ...string) error {
+	if len(groups) == 0 {
+		groups, _ = groupsFromWriter(w)
+	}
+	data, err := MarshalByGroupsWithOptions(v, opts, groups...)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_, err = w.Write(data)
+	return err
+}
@@ -0,0 +1,367 @@
+// Code generated by jsongroupgen. DO NOT EDIT.
+
+package gentest
+
+import (
+	"strconv"
+
+	"github.com/JieBaiYou/jsongroup"
+)
+
+// MarshalByGroups 使用包级默认选项按分组序列化Item，等同于jsongroup.MarshalByGroups
+func (v Item) MarshalByGroups(groups ...string) ([]byte, error) {
+	return jsongroup.MarshalByGroups(v, groups...)
+}
+
+// AppendByGroups 按分组编码Item并追加到dst，实现jsongroup.GroupMarshaler
+func (v Item) AppendByGroups(dst []byte, state *jsongroup.EncodeState, groups ...string) ([]byte, error) {
+	all := len(groups) == 0
+	var in [2]bool
+	for _, g := range groups {
+		switch g {
+		case "public":
+			in[0] = true
+		case "admin":
+			in[1] = true
+		}
+	}
+	buf := append(dst, '{')
+	first := true
+	// Base.Kind
+	if all || in[0] {
+		if !first {
+			buf = append(buf, ',')
+		}
+		first = false
+		buf = append(buf, "\"kind\":"...)
+		buf = jsongroup.AppendString(buf, v.Base.Kind)
+	}
+	// Base.Rev
+	if all || in[1] {
+		if !first {
+			buf = append(buf, ',')
+		}
+		first = false
+		buf = append(buf, "\"rev\":"...)
+		buf = strconv.AppendInt(buf, int64(v.Base.Rev), 10)
+	}
+	// ID
+	if all || in[0] || in[1] {
+		if !first {
+			buf = append(buf, ',')
+		}
+		first = false
+		buf = append(buf, "\"id\":"...)
+		buf = strconv.AppendInt(buf, v.ID, 10)
+	}
+	// Name
+	if (all || in[0]) && !(v.Name == "") {
+		if !first {
+			buf = append(buf, ',')
+		}
+		first = false
+		buf = append(buf, "\"name\":"...)
+		buf = jsongroup.AppendString(buf, v.Name)
+	}
+	// Ratio
+	if all || in[0] {
+		if !first {
+			buf = append(buf, ',')
+		}
+		first = false
+		buf = append(buf, "\"ratio\":"...)
+		buf = jsongroup.AppendFloat32(buf, v.Ratio)
+	}
+	// Score
+	if all || in[0] {
+		if !first {
+			buf = append(buf, ',')
+		}
+		first = false
+		buf = append(buf, "\"score\":"...)
+		buf = jsongroup.AppendFloat(buf, v.Score)
+	}
+	// Weight
+	if (all || in[1]) && !(v.Weight == 0) {
+		if !first {
+			buf = append(buf, ',')
+		}
+		first = false
+		buf = append(buf, "\"weight\":"...)
+		buf = jsongroup.AppendFloat32(buf, v.Weight)
+	}
+	// Active
+	if (all || in[1]) && !(!v.Active) {
+		if !first {
+			buf = append(buf, ',')
+		}
+		first = false
+		buf = append(buf, "\"active\":"...)
+		buf = strconv.AppendBool(buf, v.Active)
+	}
+	// Level
+	if all || in[0] {
+		if !first {
+			buf = append(buf, ',')
+		}
+		first = false
+		buf = append(buf, "\"level\":"...)
+		buf = strconv.AppendUint(buf, uint64(v.Level), 10)
+	}
+	// Tags
+	if (all || in[0]) && !(len(v.Tags) == 0) {
+		mark := len(buf)
+		if !first {
+			buf = append(buf, ',')
+		}
+		buf = append(buf, "\"tags\":"...)
+		var ok bool
+		var err error
+		buf, ok, err = jsongroup.AppendField(buf, state, "Tags", &v.Tags, groups...)
+		if err != nil {
+			return dst, err
+		}
+		if ok {
+			first = false
+		} else {
+			buf = buf[:mark]
+		}
+	}
+	// Attrs
+	if all || in[1] {
+		mark := len(buf)
+		if !first {
+			buf = append(buf, ',')
+		}
+		buf = append(buf, "\"attrs\":"...)
+		var ok bool
+		var err error
+		buf, ok, err = jsongroup.AppendField(buf, state, "Attrs", &v.Attrs, groups...)
+		if err != nil {
+			return dst, err
+		}
+		if ok {
+			first = false
+		} else {
+			buf = buf[:mark]
+		}
+	}
+	// Owner
+	if (all || in[0] || in[1]) && !(v.Owner == nil) {
+		mark := len(buf)
+		if !first {
+			buf = append(buf, ',')
+		}
+		buf = append(buf, "\"owner\":"...)
+		var ok bool
+		var err error
+		buf, ok, err = jsongroup.AppendField(buf, state, "Owner", &v.Owner, groups...)
+		if err != nil {
+			return dst, err
+		}
+		if ok {
+			first = false
+		} else {
+			buf = buf[:mark]
+		}
+	}
+	// Created
+	if (all || in[1]) && !(v.Created.IsZero()) {
+		mark := len(buf)
+		if !first {
+			buf = append(buf, ',')
+		}
+		buf = append(buf, "\"created\":"...)
+		var ok bool
+		var err error
+		buf, ok, err = jsongroup.AppendField(buf, state, "Created", &v.Created, groups...)
+		if err != nil {
+			return dst, err
+		}
+		if ok {
+			first = false
+		} else {
+			buf = buf[:mark]
+		}
+	}
+	// Secret
+	if all {
+		if !first {
+			buf = append(buf, ',')
+		}
+		first = false
+		buf = append(buf, "\"secret\":"...)
+		buf = jsongroup.AppendString(buf, v.Secret)
+	}
+	buf = append(buf, '}')
+	return buf, nil
+}
+
+// MarshalByGroups 使用包级默认选项按分组序列化Owner，等同于jsongroup.MarshalByGroups
+func (v Owner) MarshalByGroups(groups ...string) ([]byte, error) {
+	return jsongroup.MarshalByGroups(v, groups...)
+}
+
+// AppendByGroups 按分组编码Owner并追加到dst，实现jsongroup.GroupMarshaler
+func (v Owner) AppendByGroups(dst []byte, state *jsongroup.EncodeState, groups ...string) ([]byte, error) {
+	all := len(groups) == 0
+	var in [2]bool
+	for _, g := range groups {
+		switch g {
+		case "public":
+			in[0] = true
+		case "admin":
+			in[1] = true
+		}
+	}
+	buf := append(dst, '{')
+	first := true
+	// Name
+	if all || in[0] {
+		if !first {
+			buf = append(buf, ',')
+		}
+		first = false
+		buf = append(buf, "\"name\":"...)
+		buf = jsongroup.AppendString(buf, v.Name)
+	}
+	// Email
+	if (all || in[1]) && !(v.Email == "") {
+		if !first {
+			buf = append(buf, ',')
+		}
+		first = false
+		buf = append(buf, "\"email\":"...)
+		buf = jsongroup.AppendString(buf, v.Email)
+	}
+	buf = append(buf, '}')
+	return buf, nil
+}
+
+// MarshalByGroups 使用包级默认选项按分组序列化Node，等同于jsongroup.MarshalByGroups
+func (v Node) MarshalByGroups(groups ...string) ([]byte, error) {
+	return jsongroup.MarshalByGroups(v, groups...)
+}
+
+// AppendByGroups 按分组编码Node并追加到dst，实现jsongroup.GroupMarshaler
+func (v Node) AppendByGroups(dst []byte, state *jsongroup.EncodeState, groups ...string) ([]byte, error) {
+	all := len(groups) == 0
+	var in [1]bool
+	for _, g := range groups {
+		switch g {
+		case "public":
+			in[0] = true
+		}
+	}
+	buf := append(dst, '{')
+	first := true
+	// Name
+	if all || in[0] {
+		if !first {
+			buf = append(buf, ',')
+		}
+		first = false
+		buf = append(buf, "\"name\":"...)
+		buf = jsongroup.AppendString(buf, v.Name)
+	}
+	// Next
+	if (all || in[0]) && !(v.Next == nil) {
+		mark := len(buf)
+		if !first {
+			buf = append(buf, ',')
+		}
+		buf = append(buf, "\"next\":"...)
+		var ok bool
+		var err error
+		buf, ok, err = jsongroup.AppendField(buf, state, "Next", &v.Next, groups...)
+		if err != nil {
+			return dst, err
+		}
+		if ok {
+			first = false
+		} else {
+			buf = buf[:mark]
+		}
+	}
+	buf = append(buf, '}')
+	return buf, nil
+}
+
+// MarshalByGroups 使用包级默认选项按分组序列化Empty，等同于jsongroup.MarshalByGroups
+func (v Empty) MarshalByGroups(groups ...string) ([]byte, error) {
+	return jsongroup.MarshalByGroups(v, groups...)
+}
+
+// AppendByGroups 按分组编码Empty并追加到dst，实现jsongroup.GroupMarshaler
+func (v Empty) AppendByGroups(dst []byte, state *jsongroup.EncodeState, groups ...string) ([]byte, error) {
+	return append(dst, "{}"...), nil
+}
+
+// MarshalByGroups 使用包级默认选项按分组序列化Tagged，等同于jsongroup.MarshalByGroups
+func (v Tagged) MarshalByGroups(groups ...string) ([]byte, error) {
+	return jsongroup.MarshalByGroups(v, groups...)
+}
+
+// AppendByGroups 按分组编码Tagged并追加到dst，实现jsongroup.GroupMarshaler
+func (v Tagged) AppendByGroups(dst []byte, state *jsongroup.EncodeState, groups ...string) ([]byte, error) {
+	all := len(groups) == 0
+	var in [2]bool
+	for _, g := range groups {
+		switch g {
+		case "public":
+			in[0] = true
+		case "admin":
+			in[1] = true
+		}
+	}
+	buf := append(dst, '{')
+	first := true
+	// Base.Kind
+	if all || in[0] {
+		if !first {
+			buf = append(buf, ',')
+		}
+		first = false
+		buf = append(buf, "\"kind\":"...)
+		buf = jsongroup.AppendString(buf, v.Base.Kind)
+	}
+	// Base.Rev
+	if all || in[1] {
+		if !first {
+			buf = append(buf, ',')
+		}
+		first = false
+		buf = append(buf, "\"rev\":"...)
+		buf = strconv.AppendInt(buf, int64(v.Base.Rev), 10)
+	}
+	// Stamp
+	if all || in[0] || in[1] {
+		mark := len(buf)
+		if !first {
+			buf = append(buf, ',')
+		}
+		buf = append(buf, "\"stamp\":"...)
+		var ok bool
+		var err error
+		buf, ok, err = jsongroup.AppendField(buf, state, "Stamp", &v.Stamp, groups...)
+		if err != nil {
+			return dst, err
+		}
+		if ok {
+			first = false
+		} else {
+			buf = buf[:mark]
+		}
+	}
+	// Title
+	if all || in[0] {
+		if !first {
+			buf = append(buf, ',')
+		}
+		first = false
+		buf = append(buf, "\"title\":"...)
+		buf = jsongroup.AppendString(buf, v.Title)
+	}
+	buf = append(buf, '}')
+	return buf, nil
+}
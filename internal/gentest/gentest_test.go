@@ -0,0 +1,194 @@
+package gentest
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/JieBaiYou/jsongroup"
+)
+
+// reflectOnly 设置ValueFilter后运行时不再调用生成的方法，所有字段经过反射路径
+func reflectOnly(opts *jsongroup.Options) *jsongroup.Options {
+	return opts.WithValueFilter(func(string, reflect.Value, jsongroup.FieldDescriptor, reflect.Value) bool { return true })
+}
+
+var groupSets = [][]string{
+	nil,
+	{"public"},
+	{"admin"},
+	{"public", "admin"},
+	{"unknown"},
+	{"unknown", "public"},
+}
+
+func goldenItems() map[string]Item {
+	return map[string]Item{
+		"zero": {},
+		"full": {
+			Base:    Base{Kind: "widget", Rev: 3},
+			ID:      42,
+			Name:    "héllo \"<world>\"\n",
+			Ratio:   0.1,
+			Score:   1e21,
+			Weight:  16777217,
+			Active:  true,
+			Level:   7,
+			Tags:    []string{"a", "b"},
+			Attrs:   map[string]int{"y": 2, "x": 1},
+			Owner:   &Owner{Name: "alice", Email: "alice@example.com"},
+			Created: time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC),
+			Secret:  "s3cret",
+		},
+		"empty-collections": {
+			Tags:  []string{},
+			Attrs: map[string]int{},
+			Owner: &Owner{},
+		},
+		"special-floats": {
+			Ratio:  float32(math.Inf(1)),
+			Score:  math.NaN(),
+			Weight: float32(math.Inf(-1)),
+		},
+		"subnormal-floats": {
+			Ratio:  math.SmallestNonzeroFloat32,
+			Score:  math.SmallestNonzeroFloat64,
+			Weight: -0.000001,
+		},
+	}
+}
+
+func TestGeneratedMatchesReflection(t *testing.T) {
+	for name, item := range goldenItems() {
+		for _, groups := range groupSets {
+			want, err := jsongroup.MarshalByGroupsWithOptions(item, reflectOnly(jsongroup.New()), groups...)
+			if err != nil {
+				t.Fatalf("%s %v: reflection: %v", name, groups, err)
+			}
+			got, err := jsongroup.MarshalByGroups(item, groups...)
+			if err != nil {
+				t.Fatalf("%s %v: %v", name, groups, err)
+			}
+			if string(got) != string(want) {
+				t.Errorf("%s %v:\ngenerated  %s\nreflection %s", name, groups, got, want)
+			}
+			direct, err := item.AppendByGroups([]byte("prefix"), nil, groups...)
+			if err != nil {
+				t.Fatalf("%s %v: AppendByGroups: %v", name, groups, err)
+			}
+			if string(direct) != "prefix"+string(want) {
+				t.Errorf("%s %v: AppendByGroups without state:\ngot  %s\nwant prefix%s", name, groups, direct, want)
+			}
+		}
+	}
+}
+
+func TestGeneratedNestedInReflection(t *testing.T) {
+	items := []*Item{{ID: 1, Owner: &Owner{Name: "a"}}, nil, {ID: 2}}
+	for _, groups := range groupSets {
+		want, err := jsongroup.MarshalByGroupsWithOptions(items, reflectOnly(jsongroup.New()), groups...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := jsongroup.MarshalByGroups(items, groups...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != string(want) {
+			t.Errorf("%v:\ngenerated  %s\nreflection %s", groups, got, want)
+		}
+	}
+}
+
+func TestGeneratedEmptyStruct(t *testing.T) {
+	got, err := jsongroup.MarshalByGroups(Empty{}, "public")
+	if err != nil || string(got) != "{}" {
+		t.Errorf("got %s, %v", got, err)
+	}
+}
+
+func TestGeneratedDetectsCycles(t *testing.T) {
+	a := &Node{Name: "a"}
+	a.Next = &Node{Name: "b", Next: a}
+
+	for _, opts := range []*jsongroup.Options{jsongroup.New(), reflectOnly(jsongroup.New())} {
+		if _, err := jsongroup.MarshalByGroupsWithOptions(*a, opts, "public"); !errors.Is(err, jsongroup.ErrCircularReference) {
+			t.Errorf("value root: got %v, want ErrCircularReference", err)
+		}
+		if _, err := jsongroup.MarshalByGroupsWithOptions(a, opts, "public"); !errors.Is(err, jsongroup.ErrCircularReference) {
+			t.Errorf("pointer root: got %v, want ErrCircularReference", err)
+		}
+	}
+	if _, err := a.MarshalByGroups("public"); !errors.Is(err, jsongroup.ErrCircularReference) {
+		t.Errorf("MarshalByGroups method: got %v, want ErrCircularReference", err)
+	}
+}
+
+func TestGeneratedHonorsCallerOptions(t *testing.T) {
+	var head *Node
+	for i := range 10 {
+		head = &Node{Name: string(rune('a' + i)), Next: head}
+	}
+
+	// 深度限制跨越生成代码的边界累计
+	shallow := jsongroup.New().WithMaxDepth(4)
+	_, genErr := jsongroup.MarshalByGroupsWithOptions(head, shallow, "public")
+	_, refErr := jsongroup.MarshalByGroupsWithOptions(head, reflectOnly(shallow), "public")
+	if !errors.Is(genErr, jsongroup.ErrMaxDepthExceeded) || genErr.Error() != refErr.Error() {
+		t.Errorf("MaxDepth:\ngenerated  %v\nreflection %v", genErr, refErr)
+	}
+
+	// 尽力模式下出错的字段被省略，报告与反射路径一致
+	a := &Node{Name: "a"}
+	a.Next = a
+	lenient := jsongroup.New().WithBestEffort(true)
+	got, gotReport, err := jsongroup.MarshalByGroupsWithReport(a, lenient, "public")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, wantReport, err := jsongroup.MarshalByGroupsWithReport(a, reflectOnly(lenient), "public")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("BestEffort:\ngenerated  %s\nreflection %s", got, want)
+	}
+	paths := func(r *jsongroup.Report) []string {
+		var p []string
+		for _, s := range r.Skipped {
+			p = append(p, s.Path)
+		}
+		return p
+	}
+	if !slices.Equal(paths(gotReport), paths(wantReport)) || len(gotReport.Skipped) == 0 {
+		t.Errorf("skipped paths: generated %v, reflection %v", paths(gotReport), paths(wantReport))
+	}
+}
+
+// TestGeneratedTaggedEmbed 生成代码与运行时一样：指定了json名称的内嵌结构体嵌套输出，未指定名称的展开
+func TestGeneratedTaggedEmbed(t *testing.T) {
+	v := Tagged{Base: Base{Kind: "k", Rev: 2}, Stamp: Stamp{At: 5, By: "bob"}, Title: "t"}
+	for _, tc := range []struct {
+		groups []string
+		want   string
+	}{
+		{[]string{"public"}, `{"kind":"k","stamp":{"at":5},"title":"t"}`},
+		{[]string{"admin"}, `{"rev":2,"stamp":{"by":"bob"}}`},
+		{nil, `{"kind":"k","rev":2,"stamp":{"at":5,"by":"bob"},"title":"t"}`},
+	} {
+		reflected, err := jsongroup.MarshalByGroupsWithOptions(v, reflectOnly(jsongroup.New()), tc.groups...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		generated, err := v.AppendByGroups(nil, nil, tc.groups...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(generated) != tc.want || string(reflected) != tc.want {
+			t.Errorf("%v:\ngenerated  %s\nreflection %s\nwant       %s", tc.groups, generated, reflected, tc.want)
+		}
+	}
+}
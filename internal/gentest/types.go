@@ -0,0 +1,60 @@
+// Package gentest 存放jsongroupgen的测试夹具，生成的代码与反射路径的输出逐字节比较
+package gentest
+
+import "time"
+
+//go:generate go run github.com/JieBaiYou/jsongroup/cmd/jsongroupgen -type Item,Owner,Node,Empty,Tagged -output gentest_jsongroup.go
+
+// Level 底层为基本类型的命名类型
+type Level uint8
+
+// Base 被Item内嵌，其字段提升到外层对象
+type Base struct {
+	Kind string `json:"kind" groups:"public"`
+	Rev  int    `json:"rev" groups:"admin"`
+}
+
+// Item 覆盖生成代码支持的各类字段
+type Item struct {
+	Base
+	ID      int64          `json:"id" groups:"public,admin"`
+	Name    string         `json:"name,omitempty" groups:"public"`
+	Ratio   float32        `json:"ratio" groups:"public"`
+	Score   float64        `json:"score" groups:"public"`
+	Weight  float32        `json:"weight,omitempty" groups:"admin"`
+	Active  bool           `json:"active,omitzero" groups:"admin"`
+	Level   Level          `json:"level" groups:"public"`
+	Tags    []string       `json:"tags,omitempty" groups:"public"`
+	Attrs   map[string]int `json:"attrs" groups:"admin"`
+	Owner   *Owner         `json:"owner" groups:"public,admin"`
+	Created time.Time      `json:"created,omitzero" groups:"admin"`
+	Secret  string         `json:"secret"`
+}
+
+// Owner 作为指针字段嵌套在Item中，同样生成了静态方法
+type Owner struct {
+	Name  string `json:"name" groups:"public"`
+	Email string `json:"email,omitempty" groups:"admin"`
+}
+
+// Node 通过指针引用自身，用于检查循环引用和深度限制
+type Node struct {
+	Name string `json:"name" groups:"public"`
+	Next *Node  `json:"next" groups:"public"`
+}
+
+// Empty 没有字段的结构体
+type Empty struct{}
+
+// Stamp 以指定了json名称的方式被Tagged内嵌
+type Stamp struct {
+	At int64  `json:"at" groups:"public"`
+	By string `json:"by" groups:"admin"`
+}
+
+// Tagged 未指定json名称的内嵌结构体展开到外层对象，指定了名称的内嵌结构体与encoding/json一样嵌套输出
+type Tagged struct {
+	Base
+	Stamp `json:"stamp" groups:"public,admin"`
+	Title string `json:"title" groups:"public"`
+}
@@ -0,0 +1,119 @@
+package jsongroup
+
+import (
+	"reflect"
+	"sync"
+	"unsafe"
+)
+
+// rLocker 提供读锁的类型，如内嵌sync.RWMutex的结构体
+type rLocker interface {
+	RLock()
+	RUnlock()
+}
+
+var (
+	rLockerType = reflect.TypeFor[rLocker]()
+	lockerType  = reflect.TypeFor[sync.Locker]()
+	mutexType   = reflect.TypeFor[sync.Mutex]()
+	rwMutexType = reflect.TypeFor[sync.RWMutex]()
+)
+
+// lockPlan 在锁保护下复制结构体时逐个处理的字段，按类型缓存
+// 字段路径只沿按值嵌套的结构体展开，不经过指针
+type lockPlan struct {
+	// copies 在结构体自身的锁保护下直接复制的字段（包括非导出字段）
+	copies [][]int
+	// nested 按值嵌套的导出加锁结构体字段，其他goroutine可能正持有它自己的锁修改它，
+	// 在外层的锁释放后于它自己的锁保护下复制
+	nested [][]int
+}
+
+// lockPlans 缓存各结构体类型的lockPlan
+var lockPlans sync.Map
+
+// lockPlanOf 返回结构体类型t的lockPlan
+func lockPlanOf(t reflect.Type) *lockPlan {
+	if plan, ok := lockPlans.Load(t); ok {
+		return plan.(*lockPlan)
+	}
+	plan := &lockPlan{}
+	plan.collect(t, nil, true)
+	actual, _ := lockPlans.LoadOrStore(t, plan)
+	return actual.(*lockPlan)
+}
+
+// collect 收集t的字段，exported表示从根到t的路径是否都可导出
+// sync.Mutex和sync.RWMutex不复制，副本中保持未加锁的零值；
+// 包含锁或加锁结构体的结构体字段继续展开，其余字段整体复制
+func (p *lockPlan) collect(t reflect.Type, prefix []int, exported bool) {
+	for i := range t.NumField() {
+		f := t.Field(i)
+		index := append(prefix[:len(prefix):len(prefix)], i)
+		switch {
+		case f.Type == mutexType || f.Type == rwMutexType:
+		case f.Type.Kind() == reflect.Struct && exported && f.IsExported() && isLockable(f.Type):
+			p.nested = append(p.nested, index)
+		case f.Type.Kind() == reflect.Struct && containsLock(f.Type):
+			p.collect(f.Type, index, exported && f.IsExported())
+		default:
+			p.copies = append(p.copies, index)
+		}
+	}
+}
+
+// isLockable 判断结构体类型的指针是否提供读锁或互斥锁
+func isLockable(t reflect.Type) bool {
+	pt := reflect.PointerTo(t)
+	return pt.Implements(rLockerType) || pt.Implements(lockerType)
+}
+
+// containsLock 判断结构体类型是否按值包含sync.Mutex、sync.RWMutex或加锁的结构体
+func containsLock(t reflect.Type) bool {
+	for i := range t.NumField() {
+		ft := t.Field(i).Type
+		if ft == mutexType || ft == rwMutexType || ft.Kind() == reflect.Struct && (isLockable(ft) || containsLock(ft)) {
+			return true
+		}
+	}
+	return false
+}
+
+// lockedSnapshot 开启Locking时，在结构体的读锁（或互斥锁）保护下复制v，返回复制得到的值
+// 只有可寻址（经由指针到达）的结构体才能加锁：按值传入的根值本身已是调用方数据的副本。
+// 锁在复制完成后立即释放，之后对副本递归序列化，指针指向的子结构体在处理到时各自独立加锁，
+// 不会同时持有多把锁，自引用的结构体由循环引用检测报告错误而不会死锁。
+// 锁字段不复制，副本中的锁都未加锁；按值嵌套的加锁结构体在外层的锁释放后于它自己的锁保护下从原值复制，
+// 不会读到被其他goroutine持有锁修改中的字段，也不会对复制来的、停留在被写者持有状态的锁加锁。
+// 内嵌的sync.Mutex、sync.RWMutex没有导出字段，在输出中不产生任何内容
+func (ctx *serializeContext) lockedSnapshot(v reflect.Value) reflect.Value {
+	if !ctx.opts.Locking || !v.CanAddr() || !v.Addr().CanInterface() {
+		return v
+	}
+	var unlock func()
+	switch l := v.Addr().Interface().(type) {
+	case rLocker:
+		l.RLock()
+		unlock = l.RUnlock
+	case sync.Locker:
+		l.Lock()
+		unlock = l.Unlock
+	default:
+		return v
+	}
+
+	plan := lockPlanOf(v.Type())
+	snapshot := reflect.New(v.Type()).Elem()
+	for _, index := range plan.copies {
+		src, dst := v.FieldByIndex(index), snapshot.FieldByIndex(index)
+		// 非导出字段无法直接Set，通过地址访问
+		reflect.NewAt(dst.Type(), unsafe.Pointer(dst.UnsafeAddr())).Elem().
+			Set(reflect.NewAt(src.Type(), unsafe.Pointer(src.UnsafeAddr())).Elem())
+	}
+	unlock()
+
+	for _, index := range plan.nested {
+		snapshot.FieldByIndex(index).Set(ctx.lockedSnapshot(v.FieldByIndex(index)))
+	}
+	return snapshot
+}
@@ -0,0 +1,166 @@
+package jsongroup
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+type lockedInner struct {
+	sync.RWMutex
+	X int `json:"x" groups:"public"`
+}
+
+// guardedInner 通过非导出的互斥锁实现sync.Locker
+type guardedInner struct {
+	mu sync.Mutex
+	Y  int `json:"y" groups:"public"`
+}
+
+func (g *guardedInner) Lock()   { g.mu.Lock() }
+func (g *guardedInner) Unlock() { g.mu.Unlock() }
+
+type lockedWrapper struct {
+	In lockedInner `json:"in" groups:"public"`
+}
+
+type lockedOuter struct {
+	sync.RWMutex
+	Name    string        `json:"name" groups:"public"`
+	In      lockedInner   `json:"in" groups:"public"`
+	Guarded guardedInner  `json:"guarded" groups:"public"`
+	Wrapper lockedWrapper `json:"wrapper" groups:"public"`
+}
+
+type plainOuter struct {
+	In lockedInner `json:"in" groups:"public"`
+}
+
+type lockedNode struct {
+	sync.Mutex
+	Name string      `json:"name" groups:"public"`
+	Next *lockedNode `json:"next" groups:"public"`
+}
+
+type marshalResult struct {
+	data []byte
+	err  error
+}
+
+// marshalAsync 在新的goroutine中序列化v
+func marshalAsync(v any, opts *Options, groups ...string) <-chan marshalResult {
+	done := make(chan marshalResult, 1)
+	go func() {
+		data, err := MarshalByGroupsWithOptions(v, opts, groups...)
+		done <- marshalResult{data, err}
+	}()
+	return done
+}
+
+// awaitMarshal 等待序列化完成，超过2秒视为死锁
+func awaitMarshal(t *testing.T, done <-chan marshalResult) marshalResult {
+	t.Helper()
+	select {
+	case r := <-done:
+		return r
+	case <-time.After(2 * time.Second):
+		t.Fatal("marshal still blocked after 2s")
+		return marshalResult{}
+	}
+}
+
+func TestLockingNestedLockHeldByWriter(t *testing.T) {
+	opts := New().WithLocking(true)
+
+	t.Run("locked outer", func(t *testing.T) {
+		o := &lockedOuter{Name: "o", In: lockedInner{X: 1}, Guarded: guardedInner{Y: 1}, Wrapper: lockedWrapper{In: lockedInner{X: 1}}}
+		o.In.Lock()
+		o.Guarded.Lock()
+		o.Wrapper.In.Lock()
+		done := marshalAsync(o, opts, "public")
+		// 留出时间让序列化在嵌套的锁被持有时开始复制；持有锁时修改，序列化必须等到锁释放后读取
+		time.Sleep(50 * time.Millisecond)
+		o.In.X = 2
+		o.Guarded.Y = 2
+		o.Wrapper.In.X = 2
+		o.In.Unlock()
+		o.Guarded.Unlock()
+		o.Wrapper.In.Unlock()
+
+		r := awaitMarshal(t, done)
+		if r.err != nil {
+			t.Fatal(r.err)
+		}
+		assertJSONEqual(t, string(r.data), `{"name":"o","in":{"x":2},"guarded":{"y":2},"wrapper":{"in":{"x":2}}}`)
+	})
+
+	t.Run("plain outer", func(t *testing.T) {
+		o := &plainOuter{In: lockedInner{X: 1}}
+		o.In.Lock()
+		done := marshalAsync(o, opts, "public")
+		time.Sleep(50 * time.Millisecond)
+		o.In.X = 2
+		o.In.Unlock()
+
+		r := awaitMarshal(t, done)
+		if r.err != nil {
+			t.Fatal(r.err)
+		}
+		assertJSONEqual(t, string(r.data), `{"in":{"x":2}}`)
+	})
+}
+
+func TestLockingLeavesCallerLocksUsable(t *testing.T) {
+	o := &lockedOuter{Name: "o"}
+	if _, err := MarshalByGroupsWithOptions(o, New().WithLocking(true), "public"); err != nil {
+		t.Fatal(err)
+	}
+	// 序列化释放了所有锁，写者可以立即加锁
+	if !o.TryLock() || !o.In.TryLock() || !o.Guarded.mu.TryLock() {
+		t.Error("a lock is still held after marshaling")
+	}
+}
+
+func TestLockingSelfReference(t *testing.T) {
+	a := &lockedNode{Name: "a"}
+	a.Next = &lockedNode{Name: "b", Next: a}
+
+	r := awaitMarshal(t, marshalAsync(a, New().WithLocking(true), "public"))
+	if !errors.Is(r.err, ErrCircularReference) {
+		t.Errorf("got %v, want ErrCircularReference", r.err)
+	}
+	if !a.TryLock() || !a.Next.TryLock() {
+		t.Error("a lock is still held after the circular reference error")
+	}
+}
+
+func TestLockingConcurrentWriters(t *testing.T) {
+	o := &lockedOuter{Name: "o"}
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			o.Lock()
+			o.Name = "o"
+			o.Unlock()
+			o.In.Lock()
+			o.In.X = i
+			o.In.Unlock()
+		}
+	}()
+	for range 200 {
+		if _, err := MarshalByGroupsWithOptions(o, New().WithLocking(true), "public"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	close(stop)
+	wg.Wait()
+}
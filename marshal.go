@@ -1,61 +1,222 @@
 package jsongroup
 
 import (
-	"encoding/json"
+	"bytes"
 	"errors"
 	"fmt"
+	"maps"
 	"math"
 	"reflect"
 	"slices"
 	"strconv"
+	"sync"
 	"time"
 )
 
+// timeType time.Time的反射类型，序列化时特殊处理
+var timeType = reflect.TypeOf(time.Time{})
+
 // serializeContext 序列化上下文，用于跟踪递归深度和循环引用
 type serializeContext struct {
-	// 当前路径，用于错误信息
-	path string
+	// 父上下文，根上下文为nil
+	parent *serializeContext
+	// 相对父上下文的路径片段，完整路径只在构造错误时才拼接
+	segment string
+	// 切片元素下标，indexed为true时片段格式化为"[index]"
+	index   int
+	indexed bool
 	// 当前递归深度
 	depth int
-	// 已处理指针的地址映射，用于检测循环引用
-	// key为指针地址，value为路径
-	pointers map[uintptr]string
+	// 本次序列化调用共享的状态，所有派生上下文共用
+	state *serializeState
 	// 序列化选项
 	opts *Options
+	// 规范化后的分组键，用于查找分组过滤结果缓存
+	groupKey string
+	// 启用XRay时当前结构体对象的过滤元数据，派生上下文不继承
+	xray *xrayObject
+	// 启用CascadeToUntagged时，是否位于被包含的带分组字段的子树中；派生上下文继承，兄弟字段互不影响
+	inherited bool
+	// 设置FieldRenames时当前位置对应的重命名节点，不在任何重命名路径上时为nil；派生上下文继承，进入结构体字段时取子节点
+	renames *renameNode
 }
 
-// newContext 创建新的序列化上下文
-func newContext(opts Options) *serializeContext {
-	return &serializeContext{
-		path:     "",
-		depth:    0,
-		pointers: make(map[uintptr]string),
-		opts:     &opts,
+// serializeState 单次序列化调用内共享的状态，通过对象池复用
+type serializeState struct {
+	// 已处理指针的地址映射，用于检测循环引用
+	// key为指针地址，value为首次遇到该地址时的上下文，路径只在报告循环引用时拼接；首次遇到指针、map或切片时才分配
+	pointers map[uintptr]*serializeContext
+	// 选项副本，避免调用过程中外部修改影响序列化
+	opts Options
+	// 根上下文
+	root serializeContext
+	// 是否运行在并行处理的分块中，分块内不再嵌套并行
+	inChunk bool
+	// ErrorPolicyCollect策略下记录的错误，按遇到的顺序排列
+	errs []error
+	// 尽力模式下被省略的字段和元素
+	skipped []SkippedField
+	// 被MaxStringLen截断的字符串路径
+	truncated []string
+	// 已记录的跟踪日志，设置TraceLogger时才分配
+	traced map[traceKey]struct{}
+	// 进入注册了选项的类型时合并得到的选项，首次遇到时才分配
+	typeOpts map[typeOptionsKey]*Options
+	// 启用PruneEmpty时最近处理完的对象或数组为空的原因
+	empty emptyKind
+}
+
+// maxPooledPointers 归还对象池时保留指针映射的最大条目数
+// 超过该值的映射直接丢弃，避免对象池长期持有大块内存
+const maxPooledPointers = 1024
+
+// statePool 序列化状态对象池
+var statePool = sync.Pool{
+	New: func() any { return new(serializeState) },
+}
+
+// newContext 从对象池获取序列化上下文，使用完毕后需调用release归还
+func newContext(opts Options, groups []string) *serializeContext {
+	state := statePool.Get().(*serializeState)
+	state.opts = opts
+	state.opts.fillDefaults()
+	state.root = serializeContext{
+		state:    state,
+		opts:     &state.opts,
+		groupKey: filterGroupKey(groups, opts.DenyGroups),
+		renames:  newRenameTree(opts.FieldRenames),
 	}
+	return &state.root
 }
 
-// withPath 创建带新路径的上下文副本
-func (ctx *serializeContext) withPath(segment string) *serializeContext {
-	newPath := ctx.path
-	if newPath == "" {
-		newPath = segment
+// release 将上下文共享的状态归还对象池，之后不得再使用该上下文及其派生上下文
+func (ctx *serializeContext) release() {
+	state := ctx.state
+	if len(state.pointers) > maxPooledPointers {
+		state.pointers = nil
 	} else {
-		newPath = newPath + "." + segment
+		clear(state.pointers)
 	}
+	state.opts = Options{}
+	state.root = serializeContext{}
+	state.inChunk = false
+	state.errs = nil
+	state.skipped = nil
+	state.truncated = nil
+	state.traced = nil
+	state.typeOpts = nil
+	state.empty = emptyNone
+	statePool.Put(state)
+}
+
+// withPath 创建带新路径片段的子上下文，只记录父上下文和片段，不拼接字符串
+func (ctx *serializeContext) withPath(segment string) *serializeContext {
+	return &serializeContext{
+		parent:    ctx,
+		segment:   segment,
+		depth:     ctx.depth,
+		state:     ctx.state,
+		opts:      ctx.opts,
+		groupKey:  ctx.groupKey,
+		inherited: ctx.inherited,
+		renames:   ctx.renames,
+	}
+}
+
+// withField 创建结构体字段的子上下文
+// 启用CascadeToUntagged时，声明了分组的字段被包含后其子树继承分组匹配
+func (ctx *serializeContext) withField(field fieldInfo) *serializeContext {
+	child := ctx.withPath(field.Name)
+	child.renames = ctx.renames.child(field.JSONName)
+	if ctx.opts.CascadeToUntagged && len(field.Groups) > 0 {
+		child.inherited = true
+	}
+	return child
+}
 
+// withIndex 创建切片元素的子上下文，下标只在构造错误时才格式化
+func (ctx *serializeContext) withIndex(i int) *serializeContext {
 	return &serializeContext{
-		path:     newPath,
-		depth:    ctx.depth,
-		pointers: ctx.pointers,
-		opts:     ctx.opts,
+		parent:    ctx,
+		index:     i,
+		indexed:   true,
+		depth:     ctx.depth,
+		state:     ctx.state,
+		opts:      ctx.opts,
+		groupKey:  ctx.groupKey,
+		inherited: ctx.inherited,
+		renames:   ctx.renames,
 	}
 }
 
+// path 拼接当前完整路径，用于错误信息，格式如"Settings.Hooks[3]"
+// 字段名和map键以"."连接，下标直接附加在父路径后；指针和接口解引用使用空片段，不出现在路径中
+func (ctx *serializeContext) path() string {
+	if ctx.parent == nil {
+		return ctx.segment
+	}
+	parent := ctx.parent.path()
+	if ctx.indexed {
+		return parent + "[" + strconv.Itoa(ctx.index) + "]"
+	}
+	if ctx.segment == "" {
+		return parent
+	}
+	if parent == "" {
+		return ctx.segment
+	}
+	return parent + "." + ctx.segment
+}
+
+// errorAction 字段、切片元素或map值出错后的处理方式
+type errorAction int
+
+const (
+	// errorFail 将错误返回给调用方
+	errorFail errorAction = iota
+	// errorNull 以null代替出错的值（ErrorPolicyCollect策略）
+	errorNull
+	// errorOmit 省略出错的值（尽力模式）
+	errorOmit
+)
+
+// handleError 决定ctx对应的字段或元素出错后的处理方式，并记录错误
+// 尽力模式下所有错误都记入报告并省略该值，超出深度限制和循环引用相当于在此处截断；
+// Collect策略下循环引用和超出深度限制时继续处理并不安全，这类错误始终交由调用方返回
+func (ctx *serializeContext) handleError(err error) errorAction {
+	if ctx.opts.BestEffort {
+		ctx.state.skipped = append(ctx.state.skipped, SkippedField{Path: ctx.path(), Err: WrapJSONError(err, ctx.path())})
+		return errorOmit
+	}
+	if ctx.opts.ErrorPolicy != ErrorPolicyCollect ||
+		errors.Is(err, ErrCircularReference) || errors.Is(err, ErrMaxDepthExceeded) {
+		return errorFail
+	}
+	ctx.state.errs = append(ctx.state.errs, WrapJSONError(err, ctx.path()))
+	return errorNull
+}
+
+// nilIfSkipped 用于切片元素、map值和根值：IgnoreNilPointers只省略结构体字段，
+// 这些位置上的nil指针与nil接口一样按nil值处理
+func nilIfSkipped[T any](v T, err error) (T, error) {
+	if err != nil && errors.Is(err, errSkipField) {
+		var zero T
+		return zero, nil
+	}
+	return v, err
+}
+
+// collectedErrors 返回记录的所有错误，没有错误时返回nil
+// 返回的错误实现Unwrap() []error，可通过errors.Is和errors.As逐个检查
+func (ctx *serializeContext) collectedErrors() error {
+	return errors.Join(ctx.state.errs...)
+}
+
 // enterLevel 增加递归深度并检查限制
 func (ctx *serializeContext) enterLevel() error {
 	ctx.depth++
 	if ctx.opts.MaxDepth > 0 && ctx.depth > ctx.opts.MaxDepth {
-		return MaxDepthError(ctx.path, reflect.Value{}, ctx.opts.MaxDepth)
+		return MaxDepthError(ctx.path(), reflect.Value{}, ctx.opts.MaxDepth)
 	}
 	return nil
 }
@@ -79,78 +240,289 @@ func (ctx *serializeContext) checkPointer(ptr reflect.Value) error {
 	if (ptr.Kind() == reflect.Ptr || ptr.Kind() == reflect.Map ||
 		ptr.Kind() == reflect.Slice) && !ptr.IsNil() {
 		addr := ptr.Pointer()
-		if _, exists := ctx.pointers[addr]; exists {
-			return CircularReferenceError(ctx.path, ptr)
+		if first, exists := ctx.state.pointers[addr]; exists {
+			// 脱敏时只记录类型名；否则记录长度有限的文本摘要，错误不持有可能包含敏感数据的原值
+			var value any = ptr.Type().String()
+			if !ctx.opts.RedactErrors {
+				value = valueSummary(ptr)
+			}
+			return cycleError(ctx.path(), first.path(), value)
 		}
-		ctx.pointers[addr] = ctx.path
+		if ctx.state.pointers == nil {
+			ctx.state.pointers = make(map[uintptr]*serializeContext)
+		}
+		ctx.state.pointers[addr] = ctx
 	}
 	return nil
 }
 
+// isCollection 判断是否对根值应用集合包装：设置了CollectionItemsKey且根值沿非nil指针解引用后为切片或数组
+func isCollection(opts *Options, v reflect.Value) bool {
+	if opts.CollectionItemsKey == "" {
+		return false
+	}
+	for (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) && !v.IsNil() {
+		v = v.Elem()
+	}
+	return v.Kind() == reflect.Slice || v.Kind() == reflect.Array
+}
+
 // MarshalByGroups 用于按指定 groups 过滤字段并输出 JSON 字节
 func MarshalByGroups(v any, groups ...string) ([]byte, error) {
-	return MarshalByGroupsWithOptions(v, New(), groups...)
+	return MarshalByGroupsWithOptions(v, defaults(), groups...)
 }
 
 // MarshalByGroupsWithOptions 带更多可选配置的序列化函数
 func MarshalByGroupsWithOptions(v any, opts *Options, groups ...string) ([]byte, error) {
-	// 捕获可能的panic并转换为错误
+	// 使用对象池中的缓冲区编码，返回前复制结果，调用方拿到的内存不会被复用
+	e := getEncoder()
+	defer putEncoder(e)
+
+	partial, err := e.marshal(v, opts, groups, nil)
+	if err != nil && !partial {
+		return nil, err
+	}
+	return bytes.Clone(e.buf), err
+}
+
+// marshal 将v编码后追加到缓冲区，出错时缓冲区恢复到调用前的长度
+// ErrorPolicyCollect策略下记录了字段错误时partial为true，缓冲区保留完整的输出，err为汇总的错误
+// report非nil时填入尽力模式下被省略的路径
+func (e *encoder) marshal(v any, opts *Options, groups []string, report *Report) (partial bool, err error) {
+	if opts == nil {
+		opts = defaults()
+	}
+	if err := opts.Validate(); err != nil {
+		return false, err
+	}
+	groups, err = opts.resolveGroups(groups)
+	if err != nil {
+		return false, err
+	}
+
+	// 在panic转换为错误之后调用指标回调，确保回调看到的是最终返回的错误
+	start := len(e.buf)
+	if end := startMetrics(opts); end != nil {
+		defer func() { end(len(e.buf)-start, err) }()
+	}
+
+	// 捕获可能的panic并转换为错误，同时丢弃已写入的部分输出
 	defer func() {
 		if r := recover(); r != nil {
-			// 如果是标准错误则尝试包装
-			if err, ok := r.(error); ok {
-				panic(WrapJSONError(err, "Root"))
-			}
-			// 否则作为未知错误
-			panic(ReflectionError("Root", fmt.Errorf("%v", r)))
+			e.buf = e.buf[:start]
+			partial, err = false, panicError("Root", r)
 		}
 	}()
 
 	if v == nil {
-		return []byte("null"), nil
+		e.buf = append(e.buf, "null"...)
+		e.buf = append(e.buf, opts.RecordSeparator...)
+		return false, nil
+	}
+
+	env, err := opts.envelope()
+	if err != nil {
+		return false, err
 	}
 
 	// 创建序列化上下文
-	ctx := newContext(*opts)
+	ctx := newContext(*opts, groups)
+	defer ctx.release()
+	if err := ctx.checkRenamePaths(v); err != nil {
+		return false, err
+	}
 
-	// 获取值的中间表示
-	data, err := valueToMap(ctx, reflect.ValueOf(v), groups, opts.GroupMode)
-	if err != nil {
-		// 包装可能的标准JSON错误
-		return nil, WrapJSONError(err, "Root")
+	mark := len(e.buf)
+	if opts.PostProcess != nil {
+		// 钩子作用于完整的中间表示，需要先经由map路径构建，再交给编码后端
+		result, err := buildValue(ctx, v, groups, true, env)
+		if err == nil {
+			err = e.encodeIntermediate(ctx, result)
+		}
+		if err != nil {
+			e.buf = e.buf[:mark]
+			return false, WrapJSONError(err, "Root")
+		}
+		return e.finish(ctx, mark, report)
 	}
 
-	// 添加顶层包装键
-	if opts.TopLevelKey != "" {
-		wrappedData := make(map[string]any)
-		wrappedData[opts.TopLevelKey] = data
-		data = wrappedData
+	// 直接编码为JSON字节，不构建中间map
+	wrap := opts.topLevelKeys()
+	for _, key := range wrap {
+		// 添加顶层包装键
+		e.buf = append(e.buf, '{')
+		e.buf = appendJSONString(e.buf, key)
+		e.buf = append(e.buf, ':')
 	}
 
-	// 使用标准json包进行最终序列化
-	jsonData, err := json.Marshal(data)
+	valueMark := len(e.buf)
+	rv := addressable(reflect.ValueOf(v))
+	var ok bool
+	if isCollection(opts, rv) {
+		ok, err = true, e.encodeCollection(ctx, rv, groups, opts.GroupMode)
+	} else {
+		ok, err = nilIfSkipped(e.encodeValue(ctx, rv, groups, opts.GroupMode))
+	}
 	if err != nil {
-		// 包装标准JSON错误
-		return nil, WrapJSONError(err, "Root")
+		if !opts.BestEffort || ctx.handleError(err) != errorOmit {
+			e.buf = e.buf[:mark]
+			// 包装可能的标准JSON错误
+			return false, WrapJSONError(err, "Root")
+		}
+		// 尽力模式下根值出错时输出null
+		e.buf = e.buf[:valueMark]
+		ok = false
+	}
+	if !ok {
+		e.buf = append(e.buf, "null"...)
+	}
+
+	for i := range wrap {
+		if i == len(wrap)-1 && env != nil {
+			// 元数据与最外层的包装键并列
+			if err := e.encodeEnvelope(ctx, env, groups, opts.GroupMode); err != nil {
+				e.buf = e.buf[:mark]
+				return false, WrapJSONError(err, "Root")
+			}
+		}
+		e.buf = append(e.buf, '}')
+	}
+	return e.finish(ctx, mark, report)
+}
+
+// finish 对从mark开始的完整文档做最后的处理：规范化或按编码后端的特性调整转义，追加分隔符并填写报告
+func (e *encoder) finish(ctx *serializeContext, mark int, report *Report) (partial bool, err error) {
+	opts := ctx.opts
+	if opts.CanonicalJSON {
+		// 规范JSON有自己的转义规则，不受编码后端特性的影响
+		canonical, err := canonicalize(e.buf[mark:])
+		if err != nil {
+			e.buf = e.buf[:mark]
+			return false, WrapJSONError(err, "Root")
+		}
+		e.buf = append(e.buf[:mark], canonical...)
+	} else {
+		// 按编码后端的特性调整转义，默认后端时不做任何处理
+		caps := opts.backend().Capabilities()
+		if opts.JSSafeEscaping && !caps.EscapeForJS {
+			// 直接编码的部分保留\u2028、\u2029，由编码后端编码的部分仍可能包含原始字符，再转义一次
+			caps.EscapeForJS = true
+			e.buf = append(e.buf[:mark], applyCapabilities(e.buf[mark:], caps)...)
+			e.buf = append(e.buf[:mark], escapeLineTerminators(e.buf[mark:])...)
+		} else {
+			e.buf = append(e.buf[:mark], applyCapabilities(e.buf[mark:], caps)...)
+		}
 	}
 
-	return jsonData, nil
+	// 分隔符在完整的文档之后追加，出错返回之前不会写入
+	e.buf = append(e.buf, opts.RecordSeparator...)
+
+	if report != nil {
+		report.Skipped = ctx.state.skipped
+		report.Truncated = ctx.state.truncated
+	}
+
+	if err := ctx.collectedErrors(); err != nil {
+		return true, err
+	}
+	return false, nil
 }
 
 // MarshalToMap 将对象序列化为map[string]any形式
+// 结果是独立于v的深拷贝：其中的map和切片都是新分配的，修改结果不会影响v，之后修改v也不会影响结果
 func MarshalToMap(v any, groups ...string) (map[string]any, error) {
-	return MarshalToMapWithOptions(v, New(), groups...)
+	return MarshalToMapWithOptions(v, defaults(), groups...)
 }
 
 // MarshalToMapWithOptions 带选项的Map序列化
+// 与MarshalByGroupsWithOptions一样按TopLevelKey或TopLevelPath包装结果。
+// 根值为nil接口或nil指针时返回nil，启用EmptyMapForNil时按空对象处理并返回已分配的map；
+// 结果不是对象时按ScalarWrapperKey包装，启用StrictMapRoot时返回ErrTypeUnsupportedType错误
 func MarshalToMapWithOptions(v any, opts *Options, groups ...string) (map[string]any, error) {
+	if opts == nil {
+		opts = defaults()
+	}
+	if opts.PreserveTypes {
+		o := *opts
+		o.preserveTypes = true
+		opts = &o
+	}
+	if isNilRoot(v) {
+		v = nil
+		if opts.EmptyMapForNil {
+			// 与所有字段都被过滤的结构体相同，输出没有任何字段的对象
+			v = map[string]any{}
+		}
+	}
+	result, err := marshalToValue(v, opts, groups, true)
+	if result == nil && err != nil {
+		return nil, err
+	}
+	if v == nil {
+		return nil, err
+	}
+
+	// 转换为map[string]any
+	if m, ok := result.(map[string]any); ok {
+		return m, err
+	}
+
+	// 如果结果不是map，创建一个包含单个键的map
+	if opts.StrictMapRoot {
+		return nil, UnsupportedTypeError("Root", reflect.TypeOf(v).String())
+	}
+	key := opts.ScalarWrapperKey
+	if key == "" {
+		key = DefaultScalarWrapperKey
+	}
+	return map[string]any{key: result}, err
+}
+
+// isNilRoot 判断根值是否为nil接口或（多级）nil指针
+func isNilRoot(v any) bool {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return true
+		}
+		rv = rv.Elem()
+	}
+	return !rv.IsValid()
+}
+
+// MarshalToValue 按分组将v转换为中间表示，供其他编码格式使用
+// 结果由map[string]any、[]any、string、bool、int64、uint64、float64、time.Time和nil组成，
+// 根值不是结构体或map时不做包装，TopLevelKey和TopLevelPath由调用方通过Options.WrapTopLevel按需处理，
+// 设置了集合包装时根切片返回包含元素和元素数的map；opts为nil时使用默认选项。
+// 结果中的map和切片都是新分配的，不与v共享：源值中的切片（包括[]byte和json.RawMessage）和map逐个元素重建，
+// 没有原样放入结果的分支；time.Time按值复制，其中的*time.Location与字符串一样不可变，无需复制。
+// PostProcess钩子返回的值除外，由钩子自行保证。
+// ErrorPolicyCollect策略下记录了字段错误时，同时返回结果和汇总的错误
+func MarshalToValue(v any, opts *Options, groups ...string) (any, error) {
+	return marshalToValue(v, opts, groups, false)
+}
+
+// marshalToValue 实现MarshalToValue，wrap为true时按顶层包装键包装结果并合并Envelope的元数据
+func marshalToValue(v any, opts *Options, groups []string, wrap bool) (_ any, err error) {
+	if opts == nil {
+		opts = defaults()
+	}
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	groups, err = opts.resolveGroups(groups)
+	if err != nil {
+		return nil, err
+	}
+
+	if end := startMetrics(opts); end != nil {
+		defer func() { end(0, err) }()
+	}
+
 	// 捕获可能的panic并转换为错误
 	defer func() {
 		if r := recover(); r != nil {
-			if err, ok := r.(error); ok {
-				panic(WrapJSONError(err, "Root"))
-			}
-			panic(ReflectionError("Root", fmt.Errorf("%v", r)))
+			err = panicError("Root", r)
 		}
 	}()
 
@@ -158,36 +530,94 @@ func MarshalToMapWithOptions(v any, opts *Options, groups ...string) (map[string
 		return nil, nil
 	}
 
+	var env map[string]any
+	if wrap {
+		if env, err = opts.envelope(); err != nil {
+			return nil, err
+		}
+	}
+
 	// 创建序列化上下文
-	ctx := newContext(*opts)
+	ctx := newContext(*opts, groups)
+	defer ctx.release()
+	if err := ctx.checkRenamePaths(v); err != nil {
+		return nil, err
+	}
+
+	result, err := buildValue(ctx, v, groups, wrap, env)
+	if err != nil {
+		return nil, err
+	}
+
+	// ErrorPolicyCollect策略下随结果一并返回记录的错误
+	return result, ctx.collectedErrors()
+}
+
+// buildValue 构建v的中间表示，wrap为true时按顶层包装键包装并合并Envelope的元数据，最后调用PostProcess钩子
+func buildValue(ctx *serializeContext, v any, groups []string, wrap bool, env map[string]any) (any, error) {
+	opts := ctx.opts
 
 	// 获取值的中间表示
-	result, err := valueToMap(ctx, reflect.ValueOf(v), groups, opts.GroupMode)
+	rv := addressable(reflect.ValueOf(v))
+	result, err := nilIfSkipped(valueToMap(ctx, rv, groups, opts.GroupMode))
 	if err != nil {
 		// 包装可能的标准JSON错误
 		return nil, WrapJSONError(err, "Root")
 	}
-
-	// 转换为map[string]any
-	if m, ok := result.(map[string]any); ok {
-		return m, nil
+	if isCollection(opts, rv) {
+		items, _ := result.([]any)
+		count := len(items)
+		if items == nil && result != nil {
+			// PreserveTypes保留了原类型的切片
+			count = reflect.ValueOf(result).Len()
+		}
+		if len(ctx.state.truncated) > 0 && opts.MarkTruncatedSlices && count > 0 {
+			// 截断标记对象不计入数量
+			if last, ok := items[count-1].(map[string]any); ok && len(last) == 1 && last[TruncatedSliceKey] != nil {
+				count--
+			}
+		}
+		result = map[string]any{opts.CollectionItemsKey: result, opts.CollectionCountKey: int64(count)}
+	}
+	if wrap {
+		result = opts.WrapTopLevel(result)
 	}
+	if env != nil {
+		// 元数据按相同的分组过滤后与最外层的包装键并列
+		meta, err := valueToMap(ctx, reflect.ValueOf(env), groups, opts.GroupMode)
+		if err != nil {
+			return nil, WrapJSONError(err, "Root")
+		}
+		if meta, ok := meta.(map[string]any); ok {
+			maps.Copy(result.(map[string]any), meta)
+		}
+	}
+	return postProcess(opts, result)
+}
 
-	// 如果结果不是map，创建一个包含单个键的map
-	tmp := make(map[string]any)
-	tmp["value"] = result
-	return tmp, nil
+// postProcess 调用PostProcess钩子，钩子返回的错误包装为ErrTypeUnknown
+func postProcess(opts *Options, result any) (any, error) {
+	if opts.PostProcess == nil {
+		return result, nil
+	}
+	processed, err := opts.PostProcess(result)
+	if err != nil {
+		return nil, &Error{
+			Type:    ErrTypeUnknown,
+			Message: "PostProcess钩子返回错误",
+			Path:    "Root",
+			Cause:   err,
+		}
+	}
+	return processed, nil
 }
 
 // valueToMap 将value转换成Map，根据分组和选项设置过滤字段
-func valueToMap(ctx *serializeContext, v reflect.Value, groups []string, mode GroupMode) (any, error) {
-	// 捕获潜在的panic并转换为我们的自定义错误
+func valueToMap(ctx *serializeContext, v reflect.Value, groups []string, mode GroupMode) (result any, err error) {
+	// 捕获潜在的panic并转换为带当前路径的错误，由上层按错误策略处理
 	defer func() {
 		if r := recover(); r != nil {
-			if err, ok := r.(error); ok {
-				panic(WrapJSONError(err, ctx.path))
-			}
-			panic(ReflectionError(ctx.path, fmt.Errorf("%v", r)))
+			result, err = nil, panicError(ctx.path(), r)
 		}
 	}()
 
@@ -202,24 +632,30 @@ func valueToMap(ctx *serializeContext, v reflect.Value, groups []string, mode Gr
 		if s == "" && ctx.opts.NullIfEmpty {
 			return nil, nil
 		}
-		return s, nil
+		if err := ctx.checkUTF8(v, s, "字符串"); err != nil {
+			return nil, err
+		}
+		return ctx.preserveType(v, ctx.truncate(s)), nil
 
 	case reflect.Bool:
-		return v.Bool(), nil
+		return ctx.preserveType(v, v.Bool()), nil
 
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		return v.Int(), nil
+		return ctx.preserveType(v, v.Int()), nil
 
 	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
-		return v.Uint(), nil
+		return ctx.preserveType(v, v.Uint()), nil
 
 	case reflect.Float32, reflect.Float64:
 		// 处理浮点类型 - 特殊处理NaN和Inf
+		if err := ctx.checkFloat(v); err != nil {
+			return nil, err
+		}
 		f := v.Float()
-		if isSpecialFloat(f) {
+		if isSpecialFloat(f) && !ctx.opts.keepSpecialFloats {
 			return floatToString(f), nil
 		}
-		return f, nil
+		return ctx.preserveType(v, mapFloatValue(v, f)), nil
 
 	case reflect.Complex64, reflect.Complex128:
 		// 处理复数类型
@@ -230,7 +666,7 @@ func valueToMap(ctx *serializeContext, v reflect.Value, groups []string, mode Gr
 	// 处理nil指针
 	if (kind == reflect.Pointer || kind == reflect.Interface) && v.IsNil() {
 		if ctx.opts.IgnoreNilPointers && kind == reflect.Pointer {
-			return nil, errors.New("skip_field")
+			return nil, errSkipField
 		}
 		return nil, nil
 	}
@@ -264,6 +700,17 @@ func valueToMap(ctx *serializeContext, v reflect.Value, groups []string, mode Gr
 		}
 	}
 
+	// 实现了error接口的值按ErrorFormat输出为字符串或对象，而不是反射其字段
+	if rep, ok := ctx.errorValue(v); ok {
+		return valueToMap(ctx.withPath(""), rep, groups, mode)
+	}
+	// 实现了encoding.BinaryMarshaler的值输出为base64字符串
+	if rep, ok, err := ctx.binaryValue(v); err != nil {
+		return nil, err
+	} else if ok {
+		return valueToMap(ctx.withPath(""), rep, groups, mode)
+	}
+
 	// 根据类型进行不同处理
 	switch kind {
 	case reflect.Ptr, reflect.Interface:
@@ -272,15 +719,35 @@ func valueToMap(ctx *serializeContext, v reflect.Value, groups []string, mode Gr
 
 	case reflect.Struct:
 		// 特殊处理时间类型
-		if v.Type() == reflect.TypeOf(time.Time{}) {
-			t := v.Interface().(time.Time)
+		if v.Type() == timeType {
+			t := timeOf(v)
 			if t.IsZero() && ctx.opts.NullIfEmpty {
 				return nil, nil
 			}
 			return t, nil
 		}
-		// 处理结构体类型
-		return structToMap(ctx, v, groups, mode)
+		// sync.Map按遍历得到的快照输出为对象
+		if v.Type() == syncMapType {
+			snapshot, err := ctx.syncMapSnapshot(v)
+			if err != nil {
+				return nil, err
+			}
+			if snapshot.Len() == 0 && ctx.opts.NullIfEmpty {
+				return nil, nil
+			}
+			return mapToMap(ctx, snapshot, groups, mode)
+		}
+		// sync/atomic中的类型输出Load的结果
+		if loaded, ok, err := ctx.atomicLoad(v); err != nil {
+			return nil, err
+		} else if ok {
+			return valueToMap(ctx.withPath(""), loaded, groups, mode)
+		}
+		// 处理结构体类型，注册了选项的类型按叠加后的选项和分组模式处理
+		if typed := ctx.withTypeOptions(v.Type(), groups); typed != ctx {
+			return structToMap(typed, typed.lockedSnapshot(v), groups, typed.opts.GroupMode)
+		}
+		return structToMap(ctx, ctx.lockedSnapshot(v), groups, mode)
 
 	case reflect.Map:
 		// 处理map类型
@@ -290,27 +757,145 @@ func valueToMap(ctx *serializeContext, v reflect.Value, groups []string, mode Gr
 		return mapToMap(ctx, v, groups, mode)
 
 	case reflect.Slice, reflect.Array:
-		// 处理切片和数组类型
+		// 处理切片和数组类型，启用PreserveTypes时基本类型的切片复制为原类型
+		if !(ctx.opts.NullIfEmpty && v.Kind() == reflect.Slice && v.IsNil()) {
+			if preserved, ok := ctx.preservedSlice(v); ok {
+				ctx.endArray(v.Len())
+				return preserved, nil
+			}
+		}
 		if v.Len() == 0 {
 			if ctx.opts.NullIfEmpty {
-				// 对于nil切片，返回null
-				if v.IsNil() {
+				// 对于nil切片，返回null；数组不可能为nil
+				if v.Kind() == reflect.Slice && v.IsNil() {
 					return nil, nil
 				}
 				// 对于非nil的空切片，返回空数组
+				ctx.endArray(0)
 				return []any{}, nil
 			}
 			// 默认处理
+			ctx.endArray(0)
 			return []any{}, nil
 		}
-		return sliceToSlice(ctx, v, groups, mode)
+		result, err := sliceToSlice(ctx, v, groups, mode)
+		ctx.endArray(v.Len())
+		return result, err
+
+	case reflect.Func:
+		// iter.Seq等迭代器按切片处理
+		if isSeq(v.Type()) {
+			return seqToSlice(ctx, v, groups, mode)
+		}
+		return nil, UnsupportedTypeError(ctx.path(), v.Type().String())
 
 	default:
-		// 处理其他类型
-		return v.Interface(), nil
+		// 基本类型（包括以基本类型为底层类型的具名类型）已在上方按Kind处理，
+		// 能到达这里的只有通道、其他函数和unsafe.Pointer，在此报告不支持的类型以保留完整路径
+		return nil, UnsupportedTypeError(ctx.path(), v.Type().String())
 	}
 }
 
+// timeOf 取出time.Time值
+// 可寻址时通过指针读取，避免Interface()对结构体装箱产生的内存分配
+func timeOf(v reflect.Value) time.Time {
+	if v.CanAddr() && v.CanInterface() {
+		return *v.Addr().Interface().(*time.Time)
+	}
+	return v.Interface().(time.Time)
+}
+
+// addressable 返回可寻址的结构体值
+// 按值传入的结构体不可寻址，复制一份后其字段均可寻址，读取时间等结构体字段时无需装箱
+func addressable(v reflect.Value) reflect.Value {
+	if v.Kind() != reflect.Struct || v.CanAddr() {
+		return v
+	}
+	c := reflect.New(v.Type()).Elem()
+	c.Set(v)
+	return c
+}
+
+// valueMapper 预编译的字段值转换函数，解析字段时根据字段的静态类型选定
+type valueMapper func(ctx *serializeContext, v reflect.Value, groups []string, mode GroupMode) (any, error)
+
+// mapperFor 根据字段类型选择转换函数，并返回该类型是否为基本类型
+// 基本类型直接读取值，跳过valueToMap中的类型分支和panic恢复；其他类型交由valueToMap处理
+func mapperFor(t reflect.Type) (valueMapper, bool) {
+	switch t.Kind() {
+	case reflect.String:
+		return mapString, true
+	case reflect.Bool:
+		return mapBool, true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return mapInt, true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return mapUint, true
+	case reflect.Float32, reflect.Float64:
+		return mapFloat, true
+	case reflect.Complex64, reflect.Complex128:
+		return mapComplex, true
+	}
+	return valueToMap, false
+}
+
+// mapString 转换字符串值，需要截断的字符串由structToMap以字段的上下文调用
+func mapString(ctx *serializeContext, v reflect.Value, _ []string, _ GroupMode) (any, error) {
+	s := v.String()
+	if s == "" && ctx.opts.NullIfEmpty {
+		return nil, nil
+	}
+	if err := ctx.checkUTF8(v, s, "字符串"); err != nil {
+		return nil, err
+	}
+	return ctx.truncate(s), nil
+}
+
+// mapBool 转换布尔值
+func mapBool(_ *serializeContext, v reflect.Value, _ []string, _ GroupMode) (any, error) {
+	return v.Bool(), nil
+}
+
+// mapInt 转换有符号整数值
+func mapInt(_ *serializeContext, v reflect.Value, _ []string, _ GroupMode) (any, error) {
+	return v.Int(), nil
+}
+
+// mapUint 转换无符号整数值
+func mapUint(_ *serializeContext, v reflect.Value, _ []string, _ GroupMode) (any, error) {
+	return v.Uint(), nil
+}
+
+// mapFloat 转换浮点值，NaN和Inf转换为字符串，启用CanonicalJSON时返回错误
+func mapFloat(ctx *serializeContext, v reflect.Value, _ []string, _ GroupMode) (any, error) {
+	if err := ctx.checkFloat(v); err != nil {
+		return nil, err
+	}
+	f := v.Float()
+	if isSpecialFloat(f) && !ctx.opts.keepSpecialFloats {
+		return floatToString(f), nil
+	}
+	return mapFloatValue(v, f), nil
+}
+
+// mapFloatValue 返回浮点值在中间表示中的float64
+// float32转换为与其最短十进制表示相等的float64，中间表示按float64编码时与encoding/json对float32的输出一致
+func mapFloatValue(v reflect.Value, f float64) float64 {
+	if v.Kind() != reflect.Float32 || isSpecialFloat(f) {
+		return f
+	}
+	var buf [32]byte
+	if d, err := strconv.ParseFloat(string(strconv.AppendFloat(buf[:0], f, 'g', -1, 32)), 64); err == nil {
+		return d
+	}
+	return f
+}
+
+// mapComplex 转换复数值
+func mapComplex(_ *serializeContext, v reflect.Value, _ []string, _ GroupMode) (any, error) {
+	return complex128ToString(v.Complex()), nil
+}
+
 // isZeroValue 判断值是否为"零值"（非空集合）
 // 与isEmptyValue的区别：isZeroValue不会将空切片/空映射视为零值
 func isZeroValue(v reflect.Value) bool {
@@ -329,8 +914,8 @@ func isZeroValue(v reflect.Value) bool {
 		return v.IsNil()
 	// 时间类型特殊处理
 	case reflect.Struct:
-		if v.Type() == reflect.TypeOf(time.Time{}) {
-			return v.Interface().(time.Time).IsZero()
+		if v.Type() == timeType {
+			return timeOf(v).IsZero()
 		}
 		return false
 	// 集合类型不视为零值，即使它们是空的
@@ -342,32 +927,98 @@ func isZeroValue(v reflect.Value) bool {
 
 // structToMap 将结构体转换为map
 func structToMap(ctx *serializeContext, v reflect.Value, groups []string, mode GroupMode) (any, error) {
-	// 估计map容量
-	t := v.Type()
-	numField := t.NumField()
-	result := make(map[string]any, numField)
-
-	// 获取字段信息（从缓存或解析）
-	fields, err := globalCache.getFieldsInfo(t, ctx.opts.TagKey)
+	if err := checkGroupTags(ctx.opts, v.Type(), ctx.path()); err != nil {
+		return nil, err
+	}
+	if err := ctx.checkRenames(v.Type()); err != nil {
+		return nil, err
+	}
+	// 获取按分组过滤后的字段信息（从缓存或解析）
+	set, err := getFilteredFields(v.Type(), ctx.opts, groups, ctx.groupKey, mode, ctx.inherited)
 	if err != nil {
-		return nil, ReflectionError(ctx.path, err)
+		return nil, ReflectionError(ctx.path(), err)
 	}
+	xrayOwner := ctx.beginXRay()
+	if ctx.observing() {
+		ctx.recordExcluded(v.Type(), set, groups)
+	}
+
+	// 按过滤后的字段数估计map容量
+	result := make(map[string]any, len(set.fields))
 
-	for _, field := range fields {
-		// 检查字段是否属于指定分组
-		if !shouldIncludeField(field, mode, groups...) {
+	var embeds embedOmitter
+	for _, field := range set.fields {
+		// 声明了omitempty或omitzero的内嵌结构体为空时，其提升的字段都不输出
+		if len(field.Embeds) > 0 && !ctx.opts.NullIfEmpty && embeds.omits(v, set, field) {
+			if ctx.observing() {
+				ctx.recordField(v.Type(), field, groups, decisionOmittedEmpty)
+			}
 			continue
 		}
 
-		// 创建新上下文，包含字段路径
-		fieldCtx := ctx.withPath(field.Name)
-
-		// 获取字段值
+		// 获取字段值和输出的键名
 		fieldValue := v.FieldByIndex(field.Index)
+		key := ctx.fieldKey(field)
+		mapper := field.Mapper
+
+		// ValueFilter在FieldHook之前按字段的原始值判断是否输出
+		if ctx.opts.ValueFilter != nil && !ctx.withField(field).keepsField(v, field, fieldValue) {
+			if ctx.observing() {
+				ctx.recordField(v.Type(), field, groups, decisionExcludedByValue)
+			}
+			continue
+		}
+
+		// FieldHook在omitempty等规则之前调用，替换后的值按其实际类型经过valueToMap处理
+		replaced := false
+		if ctx.opts.FieldHook != nil {
+			fieldCtx := ctx.withField(field)
+			var err error
+			if fieldValue, replaced, err = fieldCtx.callFieldHook(v, field, fieldValue); err != nil {
+				switch fieldCtx.handleError(err) {
+				case errorFail:
+					return nil, err
+				case errorNull:
+					result[key] = nil
+				}
+				continue
+			}
+			if replaced {
+				mapper = valueToMap
+			}
+		}
+
+		// 基本类型字段使用预编译的转换函数，不会出错，也无需创建带路径的上下文
+		// 需要截断的字符串字段和无法编码的值经过valueToMap，以便记录或报告带字段名的路径
+		if field.Scalar && !replaced && !ctx.truncatesField(fieldValue) && !ctx.rejectsField(fieldValue) {
+			if field.OmitEmpty || field.OmitZero || ctx.opts.NullIfEmpty {
+				if isEmptyValue(fieldValue) {
+					if ctx.opts.NullIfEmpty {
+						result[key] = nil
+					}
+					if ctx.observing() {
+						ctx.recordField(v.Type(), field, groups, emptyDecision(ctx))
+					}
+					continue
+				}
+			}
+			mapped, _ := field.Mapper(ctx, fieldValue, groups, mode)
+			result[key] = ctx.preserveType(fieldValue, mapped)
+			if ctx.observing() {
+				ctx.recordField(v.Type(), field, groups, decisionIncluded)
+			}
+			continue
+		}
+
+		// 创建新上下文，包含字段路径
+		fieldCtx := ctx.withField(field)
 
 		// 处理内嵌匿名字段
 		if field.Anonymous && fieldValue.Kind() == reflect.Struct {
-			// 递归处理匿名字段
+			// 递归处理匿名字段，过滤元数据记入外层对象
+			fieldCtx.xray = ctx.xray
+			fieldCtx.inherited = ctx.inherited
+			fieldCtx.renames = ctx.renames
 			embedded, err := structToMap(fieldCtx, fieldValue, groups, mode)
 			if err != nil {
 				return nil, err
@@ -385,42 +1036,80 @@ func structToMap(ctx *serializeContext, v reflect.Value, groups []string, mode G
 		// 处理nil指针和空值
 		isNilPointer := fieldValue.Kind() == reflect.Ptr && fieldValue.IsNil()
 		if isNilPointer && ctx.opts.IgnoreNilPointers {
+			if ctx.observing() {
+				ctx.recordField(v.Type(), field, groups, decisionNilSkipped)
+			}
 			continue
 		}
 
 		// 检查是否为空值或零值
 		isNilOrEmpty := isNilPointer || isEmptyValue(fieldValue)
-		isZero := isZeroValue(fieldValue)
 
-		// 处理omitempty和omitzero
+		// 处理omitempty和omitzero，零值判断只在需要时执行
 		if (field.OmitEmpty && isNilOrEmpty && !ctx.opts.NullIfEmpty) ||
-			(field.OmitZero && isZero && !ctx.opts.NullIfEmpty) {
+			(field.OmitZero && !ctx.opts.NullIfEmpty && isZeroValue(fieldValue)) {
+			if ctx.observing() {
+				ctx.recordField(v.Type(), field, groups, decisionOmittedEmpty)
+			}
 			continue
 		}
 
-		if isNilOrEmpty && ctx.opts.NullIfEmpty {
-			result[field.JSONName] = nil
+		if isNilOrEmpty && (ctx.opts.NullIfEmpty || ctx.nullsNilError(fieldValue)) {
+			result[key] = nil
+			if ctx.observing() {
+				ctx.recordField(v.Type(), field, groups, decisionIncluded)
+			}
 			continue
 		}
 
 		// 递归处理字段值
-		fieldInterface, err := valueToMap(fieldCtx, fieldValue, groups, mode)
+		ctx.resetEmpty()
+		fieldInterface, err := mapper(fieldCtx, fieldValue, groups, mode)
 		if err != nil {
 			// 跳过已标记为需要忽略的字段
-			if err.Error() == "skip_field" {
+			if errors.Is(err, errSkipField) {
+				if ctx.observing() {
+					ctx.recordField(v.Type(), field, groups, decisionNilSkipped)
+				}
 				continue
 			}
-			return nil, err
+			switch fieldCtx.handleError(err) {
+			case errorFail:
+				return nil, err
+			case errorNull:
+				// Collect策略下以null代替出错的字段
+				result[key] = nil
+			}
+			continue
+		}
+
+		// 启用PruneEmpty时省略因过滤而为空的嵌套对象
+		if ctx.prunes(isEmptyResult(fieldInterface)) {
+			if ctx.observing() {
+				ctx.recordField(v.Type(), field, groups, decisionOmittedEmpty)
+			}
+			continue
 		}
 
 		// 添加结果到map
 		if fieldInterface != nil {
-			result[field.JSONName] = fieldInterface
+			result[key] = fieldInterface
 		} else if ctx.opts.NullIfEmpty {
-			result[field.JSONName] = nil
+			result[key] = nil
+		}
+		if ctx.observing() {
+			decision := decisionIncluded
+			if fieldInterface == nil && !ctx.opts.NullIfEmpty {
+				decision = decisionOmittedEmpty
+			}
+			ctx.recordField(v.Type(), field, groups, decision)
 		}
 	}
 
+	if xrayOwner {
+		result[ctx.opts.xrayKey()] = ctx.endXRay()
+	}
+	ctx.endStruct(v.Type(), len(result) == 0)
 	return result, nil
 }
 
@@ -437,26 +1126,33 @@ func mapToMap(ctx *serializeContext, v reflect.Value, groups []string, mode Grou
 		mapVal := iter.Value()
 
 		// 获取key的字符串表示
-		var keyStr string
-		switch k.Kind() {
-		case reflect.String:
-			keyStr = k.String()
-		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-			keyStr = strconv.FormatInt(k.Int(), 10)
-		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-			keyStr = strconv.FormatUint(k.Uint(), 10)
-		default:
-			// 其他类型转换为字符串
-			keyStr = fmt.Sprint(k.Interface())
-		}
+		keyStr := mapKeyString(k)
 
 		// 为map元素创建上下文
 		itemCtx := ctx.withPath(keyStr)
 
+		// 键无效时无法以null代替，任何策略下都省略该条目
+		if err := itemCtx.checkUTF8(k, keyStr, "map的键"); err != nil {
+			if itemCtx.handleError(err) == errorFail {
+				return nil, err
+			}
+			continue
+		}
+
 		// 递归处理值
-		valInterface, err := valueToMap(itemCtx, mapVal, groups, mode)
+		ctx.resetEmpty()
+		valInterface, err := nilIfSkipped(valueToMap(itemCtx, mapVal, groups, mode))
 		if err != nil {
-			return nil, err
+			switch itemCtx.handleError(err) {
+			case errorFail:
+				return nil, err
+			case errorNull:
+				resultMap[keyStr] = nil
+			}
+			continue
+		}
+		if ctx.prunes(isEmptyResult(valInterface)) {
+			continue
 		}
 
 		// 非nil值添加到结果
@@ -465,36 +1161,81 @@ func mapToMap(ctx *serializeContext, v reflect.Value, groups []string, mode Grou
 		}
 	}
 
+	ctx.endMap(size, len(resultMap) == 0)
 	return resultMap, nil
 }
 
+// mapKeyString 获取map键的字符串表示
+func mapKeyString(k reflect.Value) string {
+	switch k.Kind() {
+	case reflect.String:
+		return k.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(k.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(k.Uint(), 10)
+	default:
+		// 其他类型转换为字符串
+		return fmt.Sprint(k.Interface())
+	}
+}
+
 // sliceToSlice 处理切片和数组
 func sliceToSlice(ctx *serializeContext, v reflect.Value, groups []string, mode GroupMode) (any, error) {
 	// 空切片检查在valueToMap已处理
 
-	// 预分配合理容量的切片
-	length := v.Len()
+	// 预分配合理容量的切片，超过MaxSliceLen的元素不输出
+	length := ctx.limitSlice(v.Len())
+
+	// 大切片拆分为分块并行处理，再按顺序拼接
+	if ctx.shouldParallelize(length) {
+		parts := make([][]any, ctx.opts.Parallelism)
+		err := ctx.forEachChunk(length, func(chunkCtx *serializeContext, chunk, i int) error {
+			itemCtx := chunkCtx.withIndex(i)
+			item, err := nilIfSkipped(valueToMap(itemCtx, v.Index(i), groups, mode))
+			if err != nil {
+				switch itemCtx.handleError(err) {
+				case errorFail:
+					return err
+				case errorNull:
+					parts[chunk] = append(parts[chunk], nil)
+				}
+				return nil
+			}
+			parts[chunk] = append(parts[chunk], item)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		return ctx.markTruncated(slices.Concat(parts...), v.Len()-length), nil
+	}
+
 	result := make([]any, 0, length)
 
 	for i := 0; i < length; i++ {
 		item := v.Index(i)
 
 		// 为数组元素创建上下文
-		itemCtx := ctx.withPath(fmt.Sprintf("[%d]", i))
+		itemCtx := ctx.withIndex(i)
 
 		// 递归处理元素
-		itemInterface, err := valueToMap(itemCtx, item, groups, mode)
+		itemInterface, err := nilIfSkipped(valueToMap(itemCtx, item, groups, mode))
 		if err != nil {
-			return nil, err
+			switch itemCtx.handleError(err) {
+			case errorFail:
+				return nil, err
+			case errorNull:
+				result = append(result, nil)
+			}
+			continue
 		}
 
-		// 非nil值添加到结果
-		if itemInterface != nil || ctx.opts.NullIfEmpty {
-			result = append(result, itemInterface)
-		}
+		// nil元素同样保留，输出为null
+		result = append(result, itemInterface)
 	}
 
-	return result, nil
+	return ctx.markTruncated(result, v.Len()-length), nil
 }
 
 // isEmptyValue 判断值是否为空
@@ -517,36 +1258,22 @@ func isEmptyValue(v reflect.Value) bool {
 }
 
 // shouldIncludeField 判断字段是否属于指定分组
-func shouldIncludeField(field fieldInfo, mode GroupMode, groups ...string) bool {
-	// 如果没有指定分组，则包含所有字段
-	if len(groups) == 0 {
-		return true
-	}
-
-	// 如果字段没有分组标签，则不包含
+// query由调用方的非空分组列表转换而来，判断只需对位图做按位运算；inherited为true时未声明分组的字段视为匹配
+func shouldIncludeField(field fieldInfo, mode GroupMode, query groupQuery, inherited bool) bool {
+	// 如果字段没有分组标签，只在继承了父字段的分组匹配时包含
 	if len(field.Groups) == 0 {
-		return false
+		return inherited
 	}
 
 	// 根据模式判断
 	switch mode {
 	case GroupModeOr:
 		// 或模式：字段分组包含任意一个指定分组即可
-		for _, g := range groups {
-			if slices.Contains(field.Groups, g) {
-				return true
-			}
-		}
-		return false
+		return field.GroupMask.intersects(query.mask)
 
 	case GroupModeAnd:
-		// 与模式：字段分组必须包含所有指定分组
-		for _, g := range groups {
-			if !slices.Contains(field.Groups, g) {
-				return false
-			}
-		}
-		return true
+		// 与模式：字段分组必须包含所有指定分组，未知分组不可能被包含
+		return !query.unknown && field.GroupMask.containsAll(query.mask)
 	}
 
 	return false
@@ -0,0 +1,183 @@
+package jsongroup
+
+import (
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestMarshalByGroupsBasic(t *testing.T) {
+	u := User{ID: 1, Name: "Alice", Email: "a@example.com", Password: "secret", Address: &Address{Street: "Main", City: "X", Zip: "1"}}
+	assertJSONEqual(t, marshalString(t, u, nil, "public"), `{"id":1,"name":"Alice","address":{"street":"Main","city":"X"}}`)
+	assertJSONEqual(t, marshalString(t, u, nil, "admin"), `{"id":1,"name":"Alice","email":"a@example.com","address":{"street":"Main","city":"X","zip":"1"}}`)
+	assertJSONEqual(t, marshalString(t, u, nil), `{"id":1,"name":"Alice","email":"a@example.com","password":"secret","address":{"street":"Main","city":"X","zip":"1"}}`)
+}
+
+type flatUser struct {
+	ID     int    `json:"id" groups:"public"`
+	Name   string `json:"name" groups:"public"`
+	Active bool   `json:"active" groups:"public"`
+}
+
+// TestMarshalAllocations 锁定典型调用的分配次数：序列化状态来自对象池，没有指针的结构体不分配指针映射
+func TestMarshalAllocations(t *testing.T) {
+	if testing.Short() || raceEnabled {
+		t.Skip("allocation counts are not meaningful in short mode or with the race detector")
+	}
+	flat := flatUser{ID: 1, Name: "Alice", Active: true}
+	complexUser := newComplexUser(1)
+	tests := []struct {
+		name string
+		max  float64
+		fn   func()
+	}{
+		{"flat", 7, func() { MarshalByGroups(flat, "public") }},
+		{"flat pointer", 7, func() { MarshalByGroups(&flat, "public") }},
+		{"complex", 20, func() { MarshalByGroups(complexUser, "public") }},
+		{"flat map", 10, func() { MarshalToMap(flat, "public") }},
+	}
+	for _, tc := range tests {
+		tc.fn() // 预热字段缓存和对象池
+		if got := testing.AllocsPerRun(100, tc.fn); got > tc.max {
+			t.Errorf("%s: %v allocs/op, want at most %v", tc.name, got, tc.max)
+		}
+	}
+}
+
+// TestPooledStateIsReset 归还对象池的状态不能影响下一次调用
+func TestPooledStateIsReset(t *testing.T) {
+	addr := &Address{Street: "Main", City: "X"}
+	u := User{ID: 1, Address: addr}
+	for range 3 {
+		// 同一指针在连续的调用中出现，上一次调用记录的地址不能被当作循环引用
+		assertJSONEqual(t, marshalString(t, u, nil, "public"), `{"id":1,"name":"","address":{"street":"Main","city":"X"}}`)
+	}
+	// 上一次调用的顶层键不能带入下一次调用
+	assertJSONEqual(t, marshalString(t, u, New().WithTopLevelKey("data"), "public"), `{"data":{"id":1,"name":"","address":{"street":"Main","city":"X"}}}`)
+	assertJSONEqual(t, marshalString(t, u, nil, "public"), `{"id":1,"name":"","address":{"street":"Main","city":"X"}}`)
+}
+
+// wideUser 字段很多、每个分组只包含少数字段的结构体
+type wideUser struct {
+	ID   int    `json:"id" groups:"public"`
+	Name string `json:"name" groups:"public"`
+	F01  string `json:"f01" groups:"admin"`
+	F02  string `json:"f02" groups:"admin"`
+	F03  string `json:"f03" groups:"admin"`
+	F04  string `json:"f04" groups:"admin"`
+	F05  string `json:"f05" groups:"admin"`
+	F06  string `json:"f06" groups:"admin"`
+	F07  string `json:"f07" groups:"admin"`
+	F08  string `json:"f08" groups:"admin"`
+	F09  string `json:"f09" groups:"admin"`
+	F10  string `json:"f10" groups:"admin"`
+	F11  string `json:"f11" groups:"admin"`
+	F12  string `json:"f12" groups:"admin"`
+	F13  string `json:"f13" groups:"admin"`
+	F14  string `json:"f14" groups:"admin"`
+	F15  string `json:"f15" groups:"admin"`
+	F16  string `json:"f16" groups:"admin"`
+	F17  string `json:"f17" groups:"admin"`
+	F18  string `json:"f18" groups:"admin"`
+	F19  string `json:"f19" groups:"admin"`
+	F20  string `json:"f20" groups:"admin"`
+	F21  string `json:"f21" groups:"admin"`
+	F22  string `json:"f22" groups:"admin"`
+	F23  string `json:"f23" groups:"admin"`
+	F24  string `json:"f24" groups:"admin"`
+	F25  string `json:"f25" groups:"admin"`
+	F26  string `json:"f26" groups:"admin"`
+	F27  string `json:"f27" groups:"admin"`
+	F28  string `json:"f28" groups:"admin"`
+	F29  string `json:"f29" groups:"admin"`
+	F30  string `json:"f30" groups:"admin"`
+}
+
+// narrowUser 只有wideUser中public分组的字段
+type narrowUser struct {
+	ID   int    `json:"id" groups:"public"`
+	Name string `json:"name" groups:"public"`
+}
+
+// bytesPerRun 返回f每次运行分配的平均字节数
+func bytesPerRun(runs int, f func()) uint64 {
+	f()
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	for range runs {
+		f()
+	}
+	runtime.ReadMemStats(&after)
+	return (after.TotalAlloc - before.TotalAlloc) / uint64(runs)
+}
+
+// TestMapSizedByFilteredFields 结果map按过滤后的字段数分配，与结构体的字段总数无关
+func TestMapSizedByFilteredFields(t *testing.T) {
+	if testing.Short() || raceEnabled {
+		t.Skip("allocation counts are not meaningful in short mode or with the race detector")
+	}
+	// 传入指针，避免按值装箱时复制整个结构体
+	wide, narrow := wideUser{ID: 1, Name: "a"}, narrowUser{ID: 1, Name: "a"}
+	wideFn := func() { MarshalToMap(&wide, "public") }
+	narrowFn := func() { MarshalToMap(&narrow, "public") }
+
+	if w, n := testing.AllocsPerRun(100, wideFn), testing.AllocsPerRun(100, narrowFn); w > n {
+		t.Errorf("wide struct: %v allocs/op, narrow struct: %v", w, n)
+	}
+	if w, n := bytesPerRun(1000, wideFn), bytesPerRun(1000, narrowFn); w > n+n/4 {
+		t.Errorf("wide struct: %d B/op, narrow struct: %d B/op", w, n)
+	}
+}
+
+// TestMarshalToMapEmptyRoots nil接口、nil指针和所有字段都被过滤的结构体三种“空”根值的结果
+func TestMarshalToMapEmptyRoots(t *testing.T) {
+	var nilUser *User
+	var nilUserPtr **User = &nilUser
+	roots := []struct {
+		name      string
+		v         any
+		filterAll bool
+	}{
+		{"nil interface", nil, false},
+		{"nil pointer", nilUser, false},
+		{"pointer to nil pointer", nilUserPtr, false},
+		{"all fields filtered", User{ID: 1, Password: "secret"}, true},
+	}
+
+	for _, tc := range []struct {
+		opts *Options
+		// nilWant 为nil根值的期望结果，filteredWant为所有字段都被过滤的结构体的期望结果
+		nilWant, filteredWant map[string]any
+	}{
+		{New(), nil, map[string]any{}},
+		{New().WithEmptyMapForNil(true), map[string]any{}, map[string]any{}},
+		{New().WithTopLevelKey("data"), nil, map[string]any{"data": map[string]any{}}},
+		{New().WithTopLevelKey("data").WithEmptyMapForNil(true), map[string]any{"data": map[string]any{}}, map[string]any{"data": map[string]any{}}},
+	} {
+		for _, root := range roots {
+			m, err := MarshalToMapWithOptions(root.v, tc.opts, "unknown")
+			if err != nil {
+				t.Fatalf("%s: %v", root.name, err)
+			}
+			want := tc.nilWant
+			if root.filterAll {
+				want = tc.filteredWant
+			}
+			// nil与空map需要区分，reflect.DeepEqual认为两者不等
+			if !reflect.DeepEqual(m, want) {
+				t.Errorf("%s, EmptyMapForNil=%v TopLevelKey=%q: got %#v, want %#v",
+					root.name, tc.opts.EmptyMapForNil, tc.opts.TopLevelKey, m, want)
+			}
+			// 返回的空map可以直接写入
+			if m != nil {
+				m["x"] = 1
+			}
+		}
+	}
+
+	// 不影响字节输出：nil根值仍然编码为null
+	if got := marshalString(t, nilUser, New().WithEmptyMapForNil(true)); got != "null" {
+		t.Errorf("MarshalByGroups = %s, want null", got)
+	}
+}
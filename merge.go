@@ -0,0 +1,247 @@
+package jsongroup
+
+import (
+	"maps"
+	"slices"
+)
+
+// optionField Options字段的位掩码，记录哪些字段被显式设置过
+type optionField uint64
+
+const (
+	fieldGroupMode optionField = 1 << iota
+	fieldTopLevelKey
+	fieldTopLevelPath
+	fieldTagKey
+	fieldUseInterfaceForNested
+	fieldNullIfEmpty
+	fieldIgnoreNilPointers
+	fieldMaxDepth
+	fieldDisableCircularCheck
+	fieldMaxCacheSize
+	fieldParallelism
+	fieldErrorPolicy
+	fieldBestEffort
+	fieldRedactErrors
+	fieldBackend
+	fieldFlattenNested
+	fieldTraceLogger
+	fieldMetricsHook
+	// fieldCollection 同时对应CollectionItemsKey和CollectionCountKey，两者总是一起设置
+	fieldCollection
+	fieldEnvelope
+	fieldDenyGroups
+	fieldStrictDenyGroups
+	fieldXRay
+	fieldXRayKey
+	fieldXRayMatchedGroups
+	fieldNaNEqual
+	// fieldMaxStringLen 同时对应MaxStringLen和MaxStringMarker
+	fieldMaxStringLen
+	fieldMaxSliceLen
+	fieldMarkTruncatedSlices
+	fieldCanonicalJSON
+	fieldInvalidUTF8Policy
+	fieldJSSafeEscaping
+	fieldRecordSeparator
+	fieldErrorFormat
+	fieldNullNilErrors
+	fieldErrorChain
+	fieldPostProcess
+	fieldFieldHook
+	fieldRequireGroupTags
+	fieldDeprecationHook
+	fieldCascadeToUntagged
+	fieldStrictTags
+	fieldValueFilter
+	fieldLocking
+	fieldSkipCachingAnonymousTypes
+	fieldEmptyMapForNil
+	fieldScalarWrapperKey
+	fieldStrictMapRoot
+	fieldPruneEmpty
+	fieldPruneEmptyCollections
+	fieldPreserveTypes
+	fieldVersion
+	fieldFieldRenames
+	fieldStrictFieldRenames
+)
+
+// Merge 返回以o为基础、叠加override中显式设置过的字段后的副本，o和override都不会被修改
+// 显式设置指通过With*方法、函数式选项或OptionsFromMap设置，设置为false、0或空字符串同样会覆盖基础配置；
+// 直接给字段赋值无法与零值区分，不会被识别为显式设置。override为nil时返回o的副本，o为nil时以包级默认选项为基础
+// 合并结果记录两者显式设置过的全部字段，可以继续作为其他配置的基础或覆盖
+func (o *Options) Merge(override *Options) *Options {
+	c := o.Clone()
+	if override == nil {
+		return c
+	}
+
+	m := override.set
+	if m&fieldGroupMode != 0 {
+		c.GroupMode = override.GroupMode
+	}
+	if m&fieldTopLevelKey != 0 {
+		c.TopLevelKey = override.TopLevelKey
+	}
+	if m&fieldTopLevelPath != 0 {
+		c.TopLevelPath = slices.Clone(override.TopLevelPath)
+	}
+	if m&fieldTagKey != 0 {
+		c.TagKey = override.TagKey
+	}
+	if m&fieldUseInterfaceForNested != 0 {
+		c.UseInterfaceForNested = override.UseInterfaceForNested
+	}
+	if m&fieldNullIfEmpty != 0 {
+		c.NullIfEmpty = override.NullIfEmpty
+	}
+	if m&fieldIgnoreNilPointers != 0 {
+		c.IgnoreNilPointers = override.IgnoreNilPointers
+	}
+	if m&fieldMaxDepth != 0 {
+		c.MaxDepth = override.MaxDepth
+	}
+	if m&fieldDisableCircularCheck != 0 {
+		c.DisableCircularCheck = override.DisableCircularCheck
+	}
+	if m&fieldMaxCacheSize != 0 {
+		c.MaxCacheSize = override.MaxCacheSize
+	}
+	if m&fieldParallelism != 0 {
+		c.Parallelism = override.Parallelism
+	}
+	if m&fieldErrorPolicy != 0 {
+		c.ErrorPolicy = override.ErrorPolicy
+	}
+	if m&fieldBestEffort != 0 {
+		c.BestEffort = override.BestEffort
+	}
+	if m&fieldRedactErrors != 0 {
+		c.RedactErrors = override.RedactErrors
+	}
+	if m&fieldBackend != 0 {
+		c.Backend = override.Backend
+	}
+	if m&fieldFlattenNested != 0 {
+		c.FlattenNested = override.FlattenNested
+	}
+	if m&fieldTraceLogger != 0 {
+		c.TraceLogger = override.TraceLogger
+	}
+	if m&fieldMetricsHook != 0 {
+		c.MetricsHook = override.MetricsHook
+	}
+	if m&fieldCollection != 0 {
+		c.CollectionItemsKey = override.CollectionItemsKey
+		c.CollectionCountKey = override.CollectionCountKey
+	}
+	if m&fieldEnvelope != 0 {
+		c.Envelope = override.Envelope
+	}
+	if m&fieldDenyGroups != 0 {
+		c.DenyGroups = slices.Clone(override.DenyGroups)
+	}
+	if m&fieldStrictDenyGroups != 0 {
+		c.StrictDenyGroups = override.StrictDenyGroups
+	}
+	if m&fieldXRay != 0 {
+		c.XRay = override.XRay
+	}
+	if m&fieldXRayKey != 0 {
+		c.XRayKey = override.XRayKey
+	}
+	if m&fieldXRayMatchedGroups != 0 {
+		c.XRayMatchedGroups = override.XRayMatchedGroups
+	}
+	if m&fieldNaNEqual != 0 {
+		c.NaNEqual = override.NaNEqual
+	}
+	if m&fieldMaxStringLen != 0 {
+		c.MaxStringLen = override.MaxStringLen
+		c.MaxStringMarker = override.MaxStringMarker
+	}
+	if m&fieldMaxSliceLen != 0 {
+		c.MaxSliceLen = override.MaxSliceLen
+	}
+	if m&fieldMarkTruncatedSlices != 0 {
+		c.MarkTruncatedSlices = override.MarkTruncatedSlices
+	}
+	if m&fieldCanonicalJSON != 0 {
+		c.CanonicalJSON = override.CanonicalJSON
+	}
+	if m&fieldInvalidUTF8Policy != 0 {
+		c.InvalidUTF8Policy = override.InvalidUTF8Policy
+	}
+	if m&fieldJSSafeEscaping != 0 {
+		c.JSSafeEscaping = override.JSSafeEscaping
+	}
+	if m&fieldRecordSeparator != 0 {
+		c.RecordSeparator = slices.Clone(override.RecordSeparator)
+	}
+	if m&fieldErrorFormat != 0 {
+		c.ErrorFormat = override.ErrorFormat
+	}
+	if m&fieldNullNilErrors != 0 {
+		c.NullNilErrors = override.NullNilErrors
+	}
+	if m&fieldErrorChain != 0 {
+		c.ErrorChain = override.ErrorChain
+	}
+	if m&fieldPostProcess != 0 {
+		c.PostProcess = override.PostProcess
+	}
+	if m&fieldFieldHook != 0 {
+		c.FieldHook = override.FieldHook
+	}
+	if m&fieldRequireGroupTags != 0 {
+		c.RequireGroupTags = override.RequireGroupTags
+	}
+	if m&fieldDeprecationHook != 0 {
+		c.DeprecationHook = override.DeprecationHook
+	}
+	if m&fieldCascadeToUntagged != 0 {
+		c.CascadeToUntagged = override.CascadeToUntagged
+	}
+	if m&fieldStrictTags != 0 {
+		c.StrictTags = override.StrictTags
+	}
+	if m&fieldValueFilter != 0 {
+		c.ValueFilter = override.ValueFilter
+	}
+	if m&fieldLocking != 0 {
+		c.Locking = override.Locking
+	}
+	if m&fieldSkipCachingAnonymousTypes != 0 {
+		c.SkipCachingAnonymousTypes = override.SkipCachingAnonymousTypes
+	}
+	if m&fieldEmptyMapForNil != 0 {
+		c.EmptyMapForNil = override.EmptyMapForNil
+	}
+	if m&fieldScalarWrapperKey != 0 {
+		c.ScalarWrapperKey = override.ScalarWrapperKey
+	}
+	if m&fieldStrictMapRoot != 0 {
+		c.StrictMapRoot = override.StrictMapRoot
+	}
+	if m&fieldPruneEmpty != 0 {
+		c.PruneEmpty = override.PruneEmpty
+	}
+	if m&fieldPruneEmptyCollections != 0 {
+		c.PruneEmptyCollections = override.PruneEmptyCollections
+	}
+	if m&fieldPreserveTypes != 0 {
+		c.PreserveTypes = override.PreserveTypes
+	}
+	if m&fieldVersion != 0 {
+		c.Version = override.Version
+	}
+	if m&fieldFieldRenames != 0 {
+		c.FieldRenames = maps.Clone(override.FieldRenames)
+	}
+	if m&fieldStrictFieldRenames != 0 {
+		c.StrictFieldRenames = override.StrictFieldRenames
+	}
+	c.set |= m
+	return c
+}
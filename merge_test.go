@@ -0,0 +1,255 @@
+package jsongroup
+
+import (
+	"encoding/json"
+	"log/slog"
+	"reflect"
+	"testing"
+)
+
+// mergeCases 每个可设置的字段：on把字段设为与零值不同的值，off用零值、false或nil显式设置同一字段
+var mergeCases = []struct {
+	fields []string
+	on     func(*Options) *Options
+	off    func(*Options) *Options
+}{
+	{[]string{"GroupMode"},
+		func(o *Options) *Options { return o.WithGroupMode(GroupModeAnd) },
+		func(o *Options) *Options { return o.WithGroupMode(GroupModeOr) }},
+	{[]string{"TopLevelKey", "TopLevelPath"},
+		func(o *Options) *Options { return o.WithTopLevelKey("data") },
+		func(o *Options) *Options { return o.WithTopLevelKey("") }},
+	{[]string{"TopLevelPath", "TopLevelKey"},
+		func(o *Options) *Options { return o.WithTopLevelPath("result", "data") },
+		func(o *Options) *Options { return o.WithTopLevelPath() }},
+	{[]string{"TagKey"},
+		func(o *Options) *Options { return o.WithTagKey("roles") },
+		func(o *Options) *Options { return o.WithTagKey("") }},
+	{[]string{"UseInterfaceForNested"},
+		func(o *Options) *Options { return o.WithUseInterfaceForNested(true) },
+		func(o *Options) *Options { return o.WithUseInterfaceForNested(false) }},
+	{[]string{"NullIfEmpty"},
+		func(o *Options) *Options { return o.WithNullIfEmpty(true) },
+		func(o *Options) *Options { return o.WithNullIfEmpty(false) }},
+	{[]string{"IgnoreNilPointers"},
+		func(o *Options) *Options { return o.WithIgnoreNilPointers(true) },
+		func(o *Options) *Options { return o.WithIgnoreNilPointers(false) }},
+	{[]string{"MaxDepth"},
+		func(o *Options) *Options { return o.WithMaxDepth(8) },
+		func(o *Options) *Options { return o.WithMaxDepth(0) }},
+	{[]string{"DisableCircularCheck"},
+		func(o *Options) *Options { return o.WithDisableCircularCheck(true) },
+		func(o *Options) *Options { return o.WithDisableCircularCheck(false) }},
+	{[]string{"MaxCacheSize"},
+		func(o *Options) *Options { return o.WithMaxCacheSize(10) },
+		func(o *Options) *Options { return o.WithMaxCacheSize(0) }},
+	{[]string{"Parallelism"},
+		func(o *Options) *Options { return o.WithParallelism(4) },
+		func(o *Options) *Options { return o.WithParallelism(0) }},
+	{[]string{"ErrorPolicy"},
+		func(o *Options) *Options { return o.WithErrorPolicy(ErrorPolicyCollect) },
+		func(o *Options) *Options { return o.WithErrorPolicy(ErrorPolicyFailFast) }},
+	{[]string{"BestEffort"},
+		func(o *Options) *Options { return o.WithBestEffort(true) },
+		func(o *Options) *Options { return o.WithBestEffort(false) }},
+	{[]string{"RedactErrors"},
+		func(o *Options) *Options { return o.WithRedactedErrors(true) },
+		func(o *Options) *Options { return o.WithRedactedErrors(false) }},
+	{[]string{"Backend"},
+		func(o *Options) *Options { return o.WithFinalEncoder(json.Marshal) },
+		func(o *Options) *Options { return o.WithBackend(nil) }},
+	{[]string{"FlattenNested"},
+		func(o *Options) *Options { return o.WithFlattenNested(true) },
+		func(o *Options) *Options { return o.WithFlattenNested(false) }},
+	{[]string{"TraceLogger"},
+		func(o *Options) *Options { return o.WithTraceLogger(slog.Default()) },
+		func(o *Options) *Options { return o.WithTraceLogger(nil) }},
+	{[]string{"MetricsHook"},
+		func(o *Options) *Options { return o.WithMetricsHook(NopMetricsHook{}) },
+		func(o *Options) *Options { return o.WithMetricsHook(nil) }},
+	{[]string{"CollectionItemsKey", "CollectionCountKey"},
+		func(o *Options) *Options { return o.WithCollectionEnvelope("items", "count") },
+		func(o *Options) *Options { return o.WithCollectionEnvelope("", "") }},
+	{[]string{"Envelope"},
+		func(o *Options) *Options { return o.WithEnvelope(func() map[string]any { return nil }) },
+		func(o *Options) *Options { return o.WithEnvelope(nil) }},
+	{[]string{"DenyGroups"},
+		func(o *Options) *Options { return o.WithDenyGroups("internal") },
+		func(o *Options) *Options { return o.WithDenyGroups() }},
+	{[]string{"StrictDenyGroups"},
+		func(o *Options) *Options { return o.WithStrictDenyGroups(true) },
+		func(o *Options) *Options { return o.WithStrictDenyGroups(false) }},
+	{[]string{"XRay"},
+		func(o *Options) *Options { return o.WithXRay(true) },
+		func(o *Options) *Options { return o.WithXRay(false) }},
+	{[]string{"XRayKey"},
+		func(o *Options) *Options { return o.WithXRayKey("_debug") },
+		func(o *Options) *Options { return o.WithXRayKey("") }},
+	{[]string{"XRayMatchedGroups"},
+		func(o *Options) *Options { return o.WithXRayMatchedGroups(true) },
+		func(o *Options) *Options { return o.WithXRayMatchedGroups(false) }},
+	{[]string{"NaNEqual"},
+		func(o *Options) *Options { return o.WithNaNEqual(true) },
+		func(o *Options) *Options { return o.WithNaNEqual(false) }},
+	{[]string{"MaxStringLen", "MaxStringMarker"},
+		func(o *Options) *Options { return o.WithMaxStringLen(16, "...") },
+		func(o *Options) *Options { return o.WithMaxStringLen(0, "") }},
+	{[]string{"MaxSliceLen"},
+		func(o *Options) *Options { return o.WithMaxSliceLen(3) },
+		func(o *Options) *Options { return o.WithMaxSliceLen(0) }},
+	{[]string{"MarkTruncatedSlices"},
+		func(o *Options) *Options { return o.WithMarkTruncatedSlices(true) },
+		func(o *Options) *Options { return o.WithMarkTruncatedSlices(false) }},
+	{[]string{"CanonicalJSON"},
+		func(o *Options) *Options { return o.WithCanonicalJSON(true) },
+		func(o *Options) *Options { return o.WithCanonicalJSON(false) }},
+	{[]string{"InvalidUTF8Policy"},
+		func(o *Options) *Options { return o.WithInvalidUTF8Policy(InvalidUTF8PolicyError) },
+		func(o *Options) *Options { return o.WithInvalidUTF8Policy(InvalidUTF8PolicyReplace) }},
+	{[]string{"JSSafeEscaping"},
+		func(o *Options) *Options { return o.WithJSSafeEscaping(true) },
+		func(o *Options) *Options { return o.WithJSSafeEscaping(false) }},
+	{[]string{"RecordSeparator"},
+		func(o *Options) *Options { return o.WithRecordSeparator([]byte("\n")) },
+		func(o *Options) *Options { return o.WithRecordSeparator(nil) }},
+	{[]string{"ErrorFormat"},
+		func(o *Options) *Options { return o.WithErrorFormat(ErrorFormatObject) },
+		func(o *Options) *Options { return o.WithErrorFormat(ErrorFormatString) }},
+	{[]string{"NullNilErrors"},
+		func(o *Options) *Options { return o.WithNullNilErrors(true) },
+		func(o *Options) *Options { return o.WithNullNilErrors(false) }},
+	{[]string{"ErrorChain"},
+		func(o *Options) *Options { return o.WithErrorChain(true) },
+		func(o *Options) *Options { return o.WithErrorChain(false) }},
+	{[]string{"PostProcess"},
+		func(o *Options) *Options { return o.WithPostProcess(func(root any) (any, error) { return root, nil }) },
+		func(o *Options) *Options { return o.WithPostProcess(nil) }},
+	{[]string{"FieldHook"},
+		func(o *Options) *Options {
+			return o.WithFieldHook(func(string, FieldDescriptor, reflect.Value) (any, bool, error) { return nil, false, nil })
+		},
+		func(o *Options) *Options { return o.WithFieldHook(nil) }},
+	{[]string{"RequireGroupTags"},
+		func(o *Options) *Options { return o.WithRequireGroupTags(true) },
+		func(o *Options) *Options { return o.WithRequireGroupTags(false) }},
+	{[]string{"DeprecationHook"},
+		func(o *Options) *Options { return o.WithDeprecationHook(func(string, string, string) {}) },
+		func(o *Options) *Options { return o.WithDeprecationHook(nil) }},
+	{[]string{"CascadeToUntagged"},
+		func(o *Options) *Options { return o.WithCascadeToUntagged(true) },
+		func(o *Options) *Options { return o.WithCascadeToUntagged(false) }},
+	{[]string{"StrictTags"},
+		func(o *Options) *Options { return o.WithStrictTags(true) },
+		func(o *Options) *Options { return o.WithStrictTags(false) }},
+	{[]string{"ValueFilter"},
+		func(o *Options) *Options {
+			return o.WithValueFilter(func(string, reflect.Value, FieldDescriptor, reflect.Value) bool { return true })
+		},
+		func(o *Options) *Options { return o.WithValueFilter(nil) }},
+	{[]string{"Locking"},
+		func(o *Options) *Options { return o.WithLocking(true) },
+		func(o *Options) *Options { return o.WithLocking(false) }},
+	{[]string{"SkipCachingAnonymousTypes"},
+		func(o *Options) *Options { return o.WithSkipCachingAnonymousTypes(true) },
+		func(o *Options) *Options { return o.WithSkipCachingAnonymousTypes(false) }},
+	{[]string{"EmptyMapForNil"},
+		func(o *Options) *Options { return o.WithEmptyMapForNil(true) },
+		func(o *Options) *Options { return o.WithEmptyMapForNil(false) }},
+	{[]string{"ScalarWrapperKey"},
+		func(o *Options) *Options { return o.WithScalarWrapperKey("value") },
+		func(o *Options) *Options { return o.WithScalarWrapperKey("") }},
+	{[]string{"StrictMapRoot"},
+		func(o *Options) *Options { return o.WithStrictMapRoot(true) },
+		func(o *Options) *Options { return o.WithStrictMapRoot(false) }},
+	{[]string{"PruneEmpty"},
+		func(o *Options) *Options { return o.WithPruneEmpty(true) },
+		func(o *Options) *Options { return o.WithPruneEmpty(false) }},
+	{[]string{"PruneEmptyCollections"},
+		func(o *Options) *Options { return o.WithPruneEmptyCollections(true) },
+		func(o *Options) *Options { return o.WithPruneEmptyCollections(false) }},
+	{[]string{"PreserveTypes"},
+		func(o *Options) *Options { return o.WithPreserveTypes(true) },
+		func(o *Options) *Options { return o.WithPreserveTypes(false) }},
+	{[]string{"Version"},
+		func(o *Options) *Options { return o.WithVersion("v2") },
+		func(o *Options) *Options { return o.WithVersion("") }},
+	{[]string{"FieldRenames"},
+		func(o *Options) *Options { return o.WithFieldRenames(map[string]string{"id": "ID"}) },
+		func(o *Options) *Options { return o.WithFieldRenames(nil) }},
+	{[]string{"StrictFieldRenames"},
+		func(o *Options) *Options { return o.WithStrictFieldRenames(true) },
+		func(o *Options) *Options { return o.WithStrictFieldRenames(false) }},
+}
+
+// optionValue 读取Options中名为name的字段，函数和接口字段只比较是否为nil
+func optionValue(o *Options, name string) any {
+	v := reflect.ValueOf(o).Elem().FieldByName(name)
+	switch v.Kind() {
+	case reflect.Func, reflect.Interface, reflect.Pointer:
+		return v.IsNil()
+	}
+	return v.Interface()
+}
+
+func TestMergeOverridesEveryField(t *testing.T) {
+	var covered optionField
+	for _, tc := range mergeCases {
+		t.Run(tc.fields[0], func(t *testing.T) {
+			on, off := tc.on(New()), tc.off(New())
+			covered |= off.set
+
+			// 显式设置的零值、false和nil覆盖基础配置
+			merged := on.Merge(off)
+			for _, name := range tc.fields {
+				if got, want := optionValue(merged, name), optionValue(off, name); !reflect.DeepEqual(got, want) {
+					t.Errorf("%s: got %v, want the override's %v", name, got, want)
+				}
+			}
+			if reflect.DeepEqual(optionValue(merged, tc.fields[0]), optionValue(on, tc.fields[0])) {
+				t.Errorf("%s: on and off set the same value; the case tests nothing", tc.fields[0])
+			}
+
+			// 覆盖配置中未设置的字段保留基础配置
+			kept := on.Merge(New())
+			for _, name := range tc.fields {
+				if got, want := optionValue(kept, name), optionValue(on, name); !reflect.DeepEqual(got, want) {
+					t.Errorf("%s: empty override changed %v to %v", name, want, got)
+				}
+			}
+			if kept.set != on.set {
+				t.Errorf("empty override changed the set mask from %b to %b", on.set, kept.set)
+			}
+
+			// 反方向同样按覆盖配置为准
+			if got, want := optionValue(off.Merge(on), tc.fields[0]), optionValue(on, tc.fields[0]); !reflect.DeepEqual(got, want) {
+				t.Errorf("%s: got %v, want %v", tc.fields[0], got, want)
+			}
+		})
+	}
+
+	if all := fieldStrictFieldRenames<<1 - 1; covered != all {
+		t.Errorf("fields without a merge case: %b", all&^covered)
+	}
+}
+
+func TestMergeDoesNotAliasSlicesAndMaps(t *testing.T) {
+	override := New().WithDenyGroups("a").WithTopLevelPath("x", "y").WithFieldRenames(map[string]string{"id": "ID"})
+	merged := New().Merge(override)
+	merged.DenyGroups[0] = "changed"
+	merged.TopLevelPath[0] = "changed"
+	merged.FieldRenames["id"] = "changed"
+	if override.DenyGroups[0] != "a" || override.TopLevelPath[0] != "x" || override.FieldRenames["id"] != "ID" {
+		t.Errorf("modifying the merged options changed the override: %+v", override)
+	}
+}
+
+func TestMergeNilReceiverAndOverride(t *testing.T) {
+	base := New().WithMaxDepth(4)
+	if c := base.Merge(nil); c == base || c.MaxDepth != 4 {
+		t.Errorf("Merge(nil) = %+v; want a copy of the base", c)
+	}
+	var o *Options
+	if c := o.Merge(New().WithNullIfEmpty(true)); !c.NullIfEmpty || c.TagKey != DefaultTagKey {
+		t.Errorf("nil.Merge = %+v; want the defaults with the override applied", c)
+	}
+}
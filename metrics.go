@@ -0,0 +1,41 @@
+package jsongroup
+
+import "time"
+
+// MetricsHook 序列化指标回调，可接入Prometheus等监控系统
+// 回调在序列化所在的协程中同步调用，不持有任何缓存锁；启用并行处理时OnFieldExcluded可能被并发调用，
+// 实现需要保证并发安全且尽量轻量
+type MetricsHook interface {
+	// OnMarshalStart 每次序列化开始时调用
+	OnMarshalStart()
+	// OnMarshalEnd 每次序列化结束时调用，bytes为写入的字节数（map路径为0），err为返回给调用方的错误
+	OnMarshalEnd(duration time.Duration, bytes int, err error)
+	// OnFieldExcluded 结构体字段未输出时调用，reason为"excluded_by_group"、"omitted_empty"或"nil_skipped"
+	// 每个结构体值的每个字段各调用一次，可直接用作计数器
+	OnFieldExcluded(typeName, jsonName, reason string)
+}
+
+// NopMetricsHook 不做任何事的MetricsHook，可嵌入自定义实现中只覆盖需要的回调
+type NopMetricsHook struct{}
+
+// OnMarshalStart 实现MetricsHook
+func (NopMetricsHook) OnMarshalStart() {}
+
+// OnMarshalEnd 实现MetricsHook
+func (NopMetricsHook) OnMarshalEnd(time.Duration, int, error) {}
+
+// OnFieldExcluded 实现MetricsHook
+func (NopMetricsHook) OnFieldExcluded(string, string, string) {}
+
+// startMetrics 在设置了MetricsHook时调用OnMarshalStart，返回结束时调用的函数；未设置时返回nil
+func startMetrics(opts *Options) func(bytes int, err error) {
+	h := opts.MetricsHook
+	if h == nil {
+		return nil
+	}
+	h.OnMarshalStart()
+	start := time.Now()
+	return func(bytes int, err error) {
+		h.OnMarshalEnd(time.Since(start), bytes, err)
+	}
+}
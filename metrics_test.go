@@ -0,0 +1,121 @@
+package jsongroup
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingHook 记录所有回调的MetricsHook
+type recordingHook struct {
+	mu       sync.Mutex
+	starts   int
+	ends     int
+	bytes    int
+	err      error
+	excluded []string
+}
+
+func (h *recordingHook) OnMarshalStart() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.starts++
+}
+
+func (h *recordingHook) OnMarshalEnd(d time.Duration, bytes int, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.ends++
+	h.bytes, h.err = bytes, err
+}
+
+func (h *recordingHook) OnFieldExcluded(typeName, jsonName, reason string) {
+	// 回调中读取缓存统计，验证调用时不持有缓存锁
+	GetCacheStats()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.excluded = append(h.excluded, typeName+"."+jsonName+":"+reason)
+}
+
+func TestMetricsHookCallbacks(t *testing.T) {
+	h := &recordingHook{}
+	u := User{ID: 1, Name: "a", Address: &Address{City: "X"}}
+	data := marshalString(t, u, New().WithMetricsHook(h), "admin")
+
+	if h.starts != 1 || h.ends != 1 || h.bytes != len(data) || h.err != nil {
+		t.Errorf("starts %d, ends %d, bytes %d (output %d), err %v", h.starts, h.ends, h.bytes, len(data), h.err)
+	}
+	want := []string{
+		"jsongroup.User.password:excluded_by_group",
+		"jsongroup.Address.zip:omitted_empty",
+	}
+	slices.Sort(want)
+	slices.Sort(h.excluded)
+	if !slices.Equal(h.excluded, want) {
+		t.Errorf("excluded = %v, want %v", h.excluded, want)
+	}
+
+	h = &recordingHook{}
+	if _, err := MarshalToMapWithOptions(User{ID: 1}, New().WithMetricsHook(h), "public"); err != nil {
+		t.Fatal(err)
+	}
+	if h.ends != 1 || h.bytes != 0 || !slices.Contains(h.excluded, "jsongroup.User.address:nil_skipped") {
+		t.Errorf("map path: ends %d, bytes %d, excluded %v", h.ends, h.bytes, h.excluded)
+	}
+}
+
+func TestMetricsHookSeesErrors(t *testing.T) {
+	h := &recordingHook{}
+	a := &cycleData{Name: "a"}
+	a.Self = a
+	_, err := MarshalByGroupsWithOptions(a, New().WithMetricsHook(h), "public")
+	if h.ends != 1 || !errors.Is(h.err, ErrCircularReference) || h.err != err {
+		t.Errorf("OnMarshalEnd got %v, caller got %v", h.err, err)
+	}
+}
+
+// startCounter 嵌入NopMetricsHook，只覆盖OnMarshalStart
+type startCounter struct {
+	NopMetricsHook
+	n int
+}
+
+func (c *startCounter) OnMarshalStart() { c.n++ }
+
+func TestNopMetricsHookCanBeEmbedded(t *testing.T) {
+	h := &startCounter{}
+	marshalString(t, User{ID: 1}, New().WithMetricsHook(h), "public")
+	if h.n != 1 {
+		t.Errorf("OnMarshalStart called %d times", h.n)
+	}
+}
+
+// durationHistogram 示例：按序列化耗时分桶计数，可替换为Prometheus的Histogram
+type durationHistogram struct {
+	NopMetricsHook
+	mu      sync.Mutex
+	buckets map[string]int
+}
+
+func (h *durationHistogram) OnMarshalEnd(d time.Duration, bytes int, err error) {
+	bucket := "<1ms"
+	if d >= time.Millisecond {
+		bucket = ">=1ms"
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.buckets[bucket]++
+}
+
+func ExampleMetricsHook() {
+	h := &durationHistogram{buckets: make(map[string]int)}
+	opts := New().WithMetricsHook(h)
+	for i := range 3 {
+		MarshalByGroupsWithOptions(User{ID: i}, opts, "public")
+	}
+	fmt.Println(h.buckets["<1ms"] + h.buckets[">=1ms"])
+	// Output: 3
+}
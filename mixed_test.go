@@ -0,0 +1,91 @@
+package jsongroup
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// newMixedEvents 返回混合了结构体、nil、map、未声明分组的结构体和基本类型的[]any
+func newMixedEvents() []any {
+	return []any{
+		User{ID: 1, Name: "a", Email: "a@example.com"},
+		nil,
+		(*User)(nil),
+		map[string]any{"user": &User{ID: 2, Name: "b", Password: "secret"}, "n": nil},
+		Stats{Followers: 1, Following: 2, Score: 0.5},
+		3,
+		"s",
+	}
+}
+
+type mixedHolder struct {
+	Events []any `json:"events" groups:"public"`
+}
+
+func TestMixedSliceElements(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		v      any
+		opts   *Options
+		groups []string
+		want   string
+	}{
+		{"public", newMixedEvents(), New(), []string{"public"},
+			`[{"id":1,"name":"a"},null,null,{"user":{"id":2,"name":"b"}},{},3,"s"]`},
+		{"no groups", newMixedEvents(), New(), nil,
+			`[{"id":1,"name":"a","email":"a@example.com","password":""},null,null,` +
+				`{"user":{"id":2,"name":"b","email":"","password":"secret"}},{"followers":1,"following":2,"score":0.5},3,"s"]`},
+		// nil元素总是输出null，map中的nil值只在NullIfEmpty时输出
+		{"null if empty", newMixedEvents(), New().WithNullIfEmpty(true), []string{"public"},
+			`[{"id":1,"name":"a","address":null},null,null,{"n":null,"user":{"id":2,"name":"b","address":null}},{},3,"s"]`},
+		// 继承分组匹配后所有元素中未声明分组的字段都输出，声明了分组的字段仍按分组过滤
+		{"cascade under grouped field", mixedHolder{newMixedEvents()}, New().WithCascadeToUntagged(true), []string{"public"},
+			`{"events":[{"id":1,"name":"a","password":""},null,null,{"user":{"id":2,"name":"b","password":"secret"}},` +
+				`{"followers":1,"following":2,"score":0.5},3,"s"]}`},
+		{"iterator", slices.Values(newMixedEvents()), New(), []string{"public"},
+			`[{"id":1,"name":"a"},null,null,{"user":{"id":2,"name":"b"}},{},3,"s"]`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assertJSONEqual(t, marshalString(t, tc.v, tc.opts, tc.groups...), tc.want)
+
+			value, err := MarshalToValue(tc.v, tc.opts, tc.groups...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, _ := json.Marshal(value)
+			assertJSONEqual(t, string(data), tc.want)
+
+			if _, ok := tc.v.([]any); ok {
+				want := marshalString(t, tc.v, tc.opts, tc.groups...)
+				if n, err := EstimateSize(tc.v, tc.opts, tc.groups...); err != nil || n != len(want) {
+					t.Errorf("EstimateSize = %d, %v; want %d", n, err, len(want))
+				}
+			}
+		})
+	}
+}
+
+// TestMixedSliceParallel 并行编码时nil元素同样输出null，元素的位置不变
+func TestMixedSliceParallel(t *testing.T) {
+	var events []any
+	for range 500 {
+		events = append(events, newMixedEvents()...)
+	}
+	sequential := marshalString(t, events, New(), "public")
+	parallel := marshalString(t, events, New().WithParallelism(4), "public")
+	if sequential != parallel {
+		t.Fatal("parallel output differs from sequential output")
+	}
+	var decoded []any
+	if err := json.Unmarshal([]byte(parallel), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded) != len(events) || decoded[1] != nil || decoded[len(decoded)-6] != nil {
+		t.Errorf("got %d elements, want %d with nil elements in place", len(decoded), len(events))
+	}
+	if n := strings.Count(parallel, "null"); n != 2*500 {
+		t.Errorf("got %d nulls, want %d", n, 2*500)
+	}
+}
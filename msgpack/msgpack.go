@@ -0,0 +1,181 @@
+// Package msgpack 按分组将值序列化为MessagePack
+//
+// 过滤规则、NullIfEmpty、深度限制和循环引用检测与jsongroup完全一致：
+// 先通过jsongroup.MarshalToValue得到按分组过滤后的中间表示，再编码为MessagePack，
+// 只有最终的编码格式不同。编码器按MessagePack规范手写实现，不依赖任何第三方库。
+package msgpack
+
+import (
+	"encoding/binary"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/JieBaiYou/jsongroup"
+)
+
+// timestampExt MessagePack时间戳扩展的类型码
+const timestampExt = 0xff // -1
+
+// MarshalMsgpackByGroups 按指定分组将v序列化为MessagePack
+// 整数按数值选用最短的编码，浮点数编码为float64，time.Time使用时间戳扩展类型（-1），
+// map的键按字符串排序以保证输出稳定；设置TopLevelKey或TopLevelPath时与JSON输出一样包装并合并Envelope的元数据。
+// ErrorPolicyCollect策略下记录了字段错误时，同时返回输出和汇总的错误
+func MarshalMsgpackByGroups(v any, opts *jsongroup.Options, groups ...string) ([]byte, error) {
+	if opts == nil {
+		opts = jsongroup.GetDefaultOptions()
+	}
+	var value any
+	var err error
+	if opts.TopLevelKey != "" || len(opts.TopLevelPath) > 0 {
+		// 包装后的结果必然是map，顶层包装和Envelope的处理与MarshalToMapWithOptions一致；
+		// appendValue只处理规范化的中间表示，不保留原始类型
+		if opts.PreserveTypes {
+			opts = opts.WithPreserveTypes(false)
+		}
+		var m map[string]any
+		if m, err = jsongroup.MarshalToMapWithOptions(v, opts, groups...); m != nil {
+			value = m
+		}
+	} else {
+		value, err = jsongroup.MarshalToValue(v, opts, groups...)
+	}
+	if value == nil && err != nil {
+		return nil, err
+	}
+
+	buf, encErr := appendValue(nil, value)
+	if encErr != nil {
+		return nil, encErr
+	}
+	return buf, err
+}
+
+// appendValue 将中间表示中的值编码后追加到buf
+func appendValue(buf []byte, v any) ([]byte, error) {
+	switch v := v.(type) {
+	case nil:
+		return append(buf, 0xc0), nil
+	case bool:
+		if v {
+			return append(buf, 0xc3), nil
+		}
+		return append(buf, 0xc2), nil
+	case int64:
+		return appendInt(buf, v), nil
+	case uint64:
+		return appendUint(buf, v), nil
+	case float64:
+		buf = append(buf, 0xcb)
+		return binary.BigEndian.AppendUint64(buf, math.Float64bits(v)), nil
+	case string:
+		return appendString(buf, v), nil
+	case time.Time:
+		return appendTime(buf, v), nil
+	case []any:
+		buf = appendLength(buf, len(v), 0x90, 0xdc)
+		for _, item := range v {
+			var err error
+			if buf, err = appendValue(buf, item); err != nil {
+				return nil, err
+			}
+		}
+		return buf, nil
+	case map[string]any:
+		buf = appendLength(buf, len(v), 0x80, 0xde)
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		for _, k := range keys {
+			buf = appendString(buf, k)
+			var err error
+			if buf, err = appendValue(buf, v[k]); err != nil {
+				return nil, err
+			}
+		}
+		return buf, nil
+	default:
+		return nil, jsongroup.UnsupportedTypeError("", v)
+	}
+}
+
+// appendInt 编码有符号整数，非负数按无符号整数编码
+func appendInt(buf []byte, n int64) []byte {
+	switch {
+	case n >= 0:
+		return appendUint(buf, uint64(n))
+	case n >= -32:
+		// negative fixint
+		return append(buf, byte(int8(n)))
+	case n >= math.MinInt8:
+		return append(buf, 0xd0, byte(int8(n)))
+	case n >= math.MinInt16:
+		return binary.BigEndian.AppendUint16(append(buf, 0xd1), uint16(int16(n)))
+	case n >= math.MinInt32:
+		return binary.BigEndian.AppendUint32(append(buf, 0xd2), uint32(int32(n)))
+	default:
+		return binary.BigEndian.AppendUint64(append(buf, 0xd3), uint64(n))
+	}
+}
+
+// appendUint 编码无符号整数
+func appendUint(buf []byte, n uint64) []byte {
+	switch {
+	case n <= 0x7f:
+		// positive fixint
+		return append(buf, byte(n))
+	case n <= math.MaxUint8:
+		return append(buf, 0xcc, byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(buf, 0xcd), uint16(n))
+	case n <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(buf, 0xce), uint32(n))
+	default:
+		return binary.BigEndian.AppendUint64(append(buf, 0xcf), n)
+	}
+}
+
+// appendString 编码字符串
+func appendString(buf []byte, s string) []byte {
+	switch n := len(s); {
+	case n < 32:
+		buf = append(buf, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		buf = append(buf, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		buf = binary.BigEndian.AppendUint16(append(buf, 0xda), uint16(n))
+	default:
+		buf = binary.BigEndian.AppendUint32(append(buf, 0xdb), uint32(n))
+	}
+	return append(buf, s...)
+}
+
+// appendLength 编码数组或map的长度，fix为长度小于16时的前缀，code16为16位长度的类型码，32位长度的类型码为code16+1
+func appendLength(buf []byte, n int, fix, code16 byte) []byte {
+	switch {
+	case n < 16:
+		return append(buf, fix|byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(buf, code16), uint16(n))
+	default:
+		return binary.BigEndian.AppendUint32(append(buf, code16+1), uint32(n))
+	}
+}
+
+// appendTime 按时间戳扩展类型编码时间，根据秒数和纳秒选用32位、64位或96位格式
+func appendTime(buf []byte, t time.Time) []byte {
+	sec, nsec := t.Unix(), uint64(t.Nanosecond())
+	if uint64(sec)>>34 == 0 {
+		if nsec == 0 && sec <= math.MaxUint32 {
+			buf = append(buf, 0xd6, timestampExt)
+			return binary.BigEndian.AppendUint32(buf, uint32(sec))
+		}
+		buf = append(buf, 0xd7, timestampExt)
+		return binary.BigEndian.AppendUint64(buf, nsec<<34|uint64(sec))
+	}
+	buf = append(buf, 0xc7, 12, timestampExt)
+	buf = binary.BigEndian.AppendUint32(buf, uint32(nsec))
+	return binary.BigEndian.AppendUint64(buf, uint64(sec))
+}
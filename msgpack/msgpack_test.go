@@ -0,0 +1,280 @@
+package msgpack
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JieBaiYou/jsongroup"
+)
+
+type address struct {
+	Street string `json:"street" groups:"public"`
+	City   string `json:"city" groups:"public,admin"`
+	Zip    string `json:"zip,omitempty" groups:"admin"`
+}
+
+type account struct {
+	ID       int64          `json:"id" groups:"public,admin"`
+	Name     string         `json:"name" groups:"public"`
+	Balance  int32          `json:"balance" groups:"admin"`
+	Quota    uint64         `json:"quota" groups:"admin"`
+	Ratio    float64        `json:"ratio" groups:"public"`
+	Active   bool           `json:"active" groups:"public"`
+	Address  *address       `json:"address" groups:"public,admin"`
+	Previous []*address     `json:"previous" groups:"public"`
+	Extra    map[string]any `json:"extra" groups:"public"`
+	Created  time.Time      `json:"created" groups:"admin"`
+	Password string         `json:"password" groups:"internal"`
+}
+
+func newAccount() account {
+	return account{
+		ID:       -40000,
+		Name:     strings.Repeat("n", 40),
+		Balance:  math.MinInt32,
+		Quota:    math.MaxUint64,
+		Ratio:    0.25,
+		Active:   true,
+		Address:  &address{Street: "1 Main St", City: "Springfield", Zip: "12345"},
+		Previous: []*address{{City: "Shelbyville"}, nil},
+		Extra:    map[string]any{"small": -3, "byte": 200, "short": -200, "word": 70000, "text": strings.Repeat("x", 300), "none": nil},
+		Created:  time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC),
+		Password: "secret",
+	}
+}
+
+// TestMarshalMsgpackRoundTrip 解码输出的MessagePack，结果应与同样选项和分组下MarshalToValue的结果相同
+func TestMarshalMsgpackRoundTrip(t *testing.T) {
+	many := make([]any, 70000)
+	for i := range many {
+		many[i] = i % 3
+	}
+	wide := map[string]any{}
+	for i := range 20 {
+		wide[fmt.Sprint("k", i)] = i
+	}
+
+	for _, tc := range []struct {
+		name   string
+		v      any
+		opts   *jsongroup.Options
+		groups []string
+	}{
+		{"public", newAccount(), jsongroup.New(), []string{"public"}},
+		{"admin", newAccount(), jsongroup.New(), []string{"admin"}},
+		{"no groups", newAccount(), jsongroup.New(), nil},
+		{"nil values", account{Previous: []*address{nil}}, jsongroup.New().WithNullIfEmpty(true), []string{"public"}},
+		{"nil root", (*account)(nil), jsongroup.New(), nil},
+		{"nested slice of structs", []account{newAccount(), {}}, jsongroup.New(), []string{"public"}},
+		{"long collections", map[string]any{"many": many, "wide": wide, "long": strings.Repeat("y", 70000)}, jsongroup.New(), nil},
+		{"timestamps", []time.Time{time.Unix(1, 0), time.Unix(1, 5), time.Unix(1<<35, 5), time.Unix(-1, 0)}, jsongroup.New(), nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := MarshalMsgpackByGroups(tc.v, tc.opts, tc.groups...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, rest, err := decode(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(rest) != 0 {
+				t.Fatalf("%d trailing bytes", len(rest))
+			}
+			want, err := jsongroup.MarshalToValue(tc.v, tc.opts, tc.groups...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := normalize(got), normalize(want); !reflect.DeepEqual(got, want) {
+				t.Errorf("decoded\n%#v\nwant\n%#v", got, want)
+			}
+		})
+	}
+}
+
+func TestMarshalMsgpackGroupsAndWrapping(t *testing.T) {
+	data, err := MarshalMsgpackByGroups(newAccount(), jsongroup.New().WithTopLevelKey("data"), "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, _, err := decode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inner, ok := v.(map[string]any)["data"].(map[string]any)
+	if !ok {
+		t.Fatalf("got %#v, want a map wrapped in data", v)
+	}
+	for _, key := range []string{"name", "ratio", "password", "extra"} {
+		if _, ok := inner[key]; ok {
+			t.Errorf("admin output contains %q", key)
+		}
+	}
+	if inner["balance"] != int64(math.MinInt32) || inner["quota"] != uint64(math.MaxUint64) {
+		t.Errorf("balance = %#v, quota = %#v", inner["balance"], inner["quota"])
+	}
+	if addr := inner["address"].(map[string]any); addr["zip"] != "12345" || addr["street"] != nil {
+		t.Errorf("address = %#v", addr)
+	}
+	if created := inner["created"].(time.Time); !created.Equal(newAccount().Created) {
+		t.Errorf("created = %v", created)
+	}
+
+	// Collect策略下同时返回输出和错误
+	type holder struct {
+		Name string   `json:"name" groups:"public"`
+		Ch   chan int `json:"ch" groups:"public"`
+	}
+	data, err = MarshalMsgpackByGroups(holder{Name: "a", Ch: make(chan int)}, jsongroup.New().WithErrorPolicy(jsongroup.ErrorPolicyCollect), "public")
+	if !errors.Is(err, jsongroup.ErrUnsupportedType) {
+		t.Fatalf("got %v, want ErrUnsupportedType", err)
+	}
+	if v, _, _ := decode(data); !reflect.DeepEqual(v, map[string]any{"name": "a", "ch": nil}) {
+		t.Errorf("got %#v", v)
+	}
+}
+
+// normalize 将整数统一为能表示该值的int64或uint64，时间统一为UTC，便于与解码结果比较
+func normalize(v any) any {
+	switch v := v.(type) {
+	case uint64:
+		if v <= math.MaxInt64 {
+			return int64(v)
+		}
+	case time.Time:
+		return v.UTC()
+	case []any:
+		out := make([]any, len(v))
+		for i, e := range v {
+			out[i] = normalize(e)
+		}
+		return out
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, e := range v {
+			out[k] = normalize(e)
+		}
+		return out
+	}
+	return v
+}
+
+// decode 解码MarshalMsgpackByGroups使用的MessagePack类型，返回值和剩余的字节
+// 负整数解码为int64，非负整数解码为uint64，时间戳扩展解码为UTC的time.Time
+func decode(b []byte) (any, []byte, error) {
+	if len(b) == 0 {
+		return nil, nil, errors.New("unexpected end of input")
+	}
+	c, b := b[0], b[1:]
+	switch {
+	case c <= 0x7f:
+		return uint64(c), b, nil
+	case c >= 0xe0:
+		return int64(int8(c)), b, nil
+	case c&0xe0 == 0xa0:
+		return str(b, int(c&0x1f))
+	case c&0xf0 == 0x90:
+		return array(b, int(c&0x0f))
+	case c&0xf0 == 0x80:
+		return object(b, int(c&0x0f))
+	}
+	switch c {
+	case 0xc0:
+		return nil, b, nil
+	case 0xc2, 0xc3:
+		return c == 0xc3, b, nil
+	case 0xcc:
+		return uint64(b[0]), b[1:], nil
+	case 0xcd:
+		return uint64(binary.BigEndian.Uint16(b)), b[2:], nil
+	case 0xce:
+		return uint64(binary.BigEndian.Uint32(b)), b[4:], nil
+	case 0xcf:
+		return binary.BigEndian.Uint64(b), b[8:], nil
+	case 0xd0:
+		return int64(int8(b[0])), b[1:], nil
+	case 0xd1:
+		return int64(int16(binary.BigEndian.Uint16(b))), b[2:], nil
+	case 0xd2:
+		return int64(int32(binary.BigEndian.Uint32(b))), b[4:], nil
+	case 0xd3:
+		return int64(binary.BigEndian.Uint64(b)), b[8:], nil
+	case 0xcb:
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), b[8:], nil
+	case 0xd9:
+		return str(b[1:], int(b[0]))
+	case 0xda:
+		return str(b[2:], int(binary.BigEndian.Uint16(b)))
+	case 0xdb:
+		return str(b[4:], int(binary.BigEndian.Uint32(b)))
+	case 0xdc:
+		return array(b[2:], int(binary.BigEndian.Uint16(b)))
+	case 0xdd:
+		return array(b[4:], int(binary.BigEndian.Uint32(b)))
+	case 0xde:
+		return object(b[2:], int(binary.BigEndian.Uint16(b)))
+	case 0xdf:
+		return object(b[4:], int(binary.BigEndian.Uint32(b)))
+	case 0xd6:
+		if b[0] != timestampExt {
+			break
+		}
+		return time.Unix(int64(binary.BigEndian.Uint32(b[1:])), 0).UTC(), b[5:], nil
+	case 0xd7:
+		if b[0] != timestampExt {
+			break
+		}
+		n := binary.BigEndian.Uint64(b[1:])
+		return time.Unix(int64(n&(1<<34-1)), int64(n>>34)).UTC(), b[9:], nil
+	case 0xc7:
+		if b[0] != 12 || b[1] != timestampExt {
+			break
+		}
+		nsec := binary.BigEndian.Uint32(b[2:])
+		sec := int64(binary.BigEndian.Uint64(b[6:]))
+		return time.Unix(sec, int64(nsec)).UTC(), b[14:], nil
+	}
+	return nil, nil, fmt.Errorf("unexpected type code %#x", c)
+}
+
+func str(b []byte, n int) (any, []byte, error) {
+	if len(b) < n {
+		return nil, nil, errors.New("string exceeds input")
+	}
+	return string(b[:n]), b[n:], nil
+}
+
+func array(b []byte, n int) (any, []byte, error) {
+	list := make([]any, n)
+	for i := range list {
+		var err error
+		if list[i], b, err = decode(b); err != nil {
+			return nil, nil, err
+		}
+	}
+	return list, b, nil
+}
+
+func object(b []byte, n int) (any, []byte, error) {
+	m := make(map[string]any, n)
+	for range n {
+		k, rest, err := decode(b)
+		if err != nil {
+			return nil, nil, err
+		}
+		key, ok := k.(string)
+		if !ok {
+			return nil, nil, fmt.Errorf("map key %#v is not a string", k)
+		}
+		if m[key], b, err = decode(rest); err != nil {
+			return nil, nil, err
+		}
+	}
+	return m, b, nil
+}
@@ -0,0 +1,5 @@
+//go:build !race
+
+package jsongroup
+
+const raceEnabled = false
@@ -0,0 +1,77 @@
+package jsongroup
+
+import (
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// GenerateOpenAPIComponents 按指定分组为types中的结构体类型生成OpenAPI 3.0的components对象
+// 返回值形如{"schemas": {...}}，每个类型及其嵌套的具名结构体各生成一个条目，名称为类型名加分组后缀，
+// 如分组public下的User命名为"UserPublic"；条目之间通过$ref引用，不内联嵌套结构体。
+// 启用NullIfEmpty时可能为null的字段标记nullable，time.Time映射为date-time格式的字符串；
+// 不同分组的结果名称互不冲突，可以合并到同一个components.schemas中
+func GenerateOpenAPIComponents(types []any, opts *Options, groups ...string) (map[string]any, error) {
+	if opts == nil {
+		opts = defaults()
+	}
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	groups, err := opts.resolveGroups(groups)
+	if err != nil {
+		return nil, err
+	}
+	o := *opts
+	o.fillDefaults()
+
+	b := newSchemaBuilder(&o, groups)
+	b.refPrefix = "#/components/schemas/"
+	b.openAPI = true
+	suffix := groupSuffix(groups)
+	b.defName = func(t reflect.Type) string { return schemaTypeName(t) + suffix }
+
+	for _, v := range types {
+		t, ok := v.(reflect.Type)
+		if !ok {
+			t = reflect.TypeOf(v)
+		}
+		for t != nil && t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		if t == nil || t.Kind() != reflect.Struct || t.Name() == "" || t == timeType {
+			typeStr := "nil"
+			if t != nil {
+				typeStr = t.String()
+			}
+			return nil, &Error{
+				Type:    ErrTypeUnsupportedType,
+				Message: "OpenAPI组件需要具名结构体类型: " + typeStr,
+			}
+		}
+		if _, err := b.structRef(t, t.Name()); err != nil {
+			return nil, err
+		}
+	}
+	return map[string]any{"schemas": b.defs}, nil
+}
+
+// groupSuffix 将分组列表转换为组件名称后缀，如["public", "read-only"]转换为"PublicReadOnly"
+func groupSuffix(groups []string) string {
+	var sb strings.Builder
+	for _, g := range groups {
+		upper := true
+		for _, r := range g {
+			if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+				upper = true
+				continue
+			}
+			if upper {
+				r = unicode.ToUpper(r)
+				upper = false
+			}
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
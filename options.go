@@ -1,5 +1,13 @@
 package jsongroup
 
+import (
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"sync/atomic"
+)
+
 // GroupMode 定义分组模式，决定字段是否被序列化的逻辑
 type GroupMode int
 
@@ -10,12 +18,57 @@ const (
 	GroupModeAnd
 )
 
+// ErrorPolicy 定义字段序列化出错时的处理策略
+type ErrorPolicy int
+
+const (
+	// ErrorPolicyFailFast 默认策略：遇到第一个错误立即返回
+	ErrorPolicyFailFast ErrorPolicy = iota
+	// ErrorPolicyCollect 记录每个字段、元素的错误并以null代替该值，序列化结束后返回输出和汇总的错误
+	// 循环引用和超出深度限制的错误仍会立即返回
+	ErrorPolicyCollect
+)
+
+// InvalidUTF8Policy 定义字符串包含无效UTF-8字节时的处理策略
+type InvalidUTF8Policy int
+
+const (
+	// InvalidUTF8PolicyReplace 默认策略：与encoding/json一致，最终编码时每个无效字节替换为U+FFFD
+	// MarshalToMap返回的中间表示保留原始字符串
+	InvalidUTF8PolicyReplace InvalidUTF8Policy = iota
+	// InvalidUTF8PolicyError 返回带路径的ErrTypeUnsupportedValue错误，字符串值和map的键都会检查，
+	// 错误与其他字段错误一样受ErrorPolicy和BestEffort影响
+	InvalidUTF8PolicyError
+)
+
+// ErrorFormat 定义实现了error接口的值的输出形式
+type ErrorFormat int
+
+const (
+	// ErrorFormatString 默认形式：输出Error()返回的字符串
+	ErrorFormatString ErrorFormat = iota
+	// ErrorFormatObject 输出{"message":Error()的结果,"type":按%T格式化的动态类型}
+	ErrorFormatObject
+	// ErrorFormatReflect 不做特殊处理，与其他值一样按类型反射其导出字段
+	ErrorFormatReflect
+)
+
 // 默认设置常量
 const (
 	// DefaultMaxDepth 默认的最大递归深度限制
 	DefaultMaxDepth = 32
+	// DefaultTagKey 默认的分组标签键名，Options.TagKey为空时使用
+	DefaultTagKey = "groups"
+	// DefaultScalarWrapperKey MarshalToMap包装非对象结果时的默认键名，Options.ScalarWrapperKey为空时使用
+	DefaultScalarWrapperKey = "value"
 	// DefaultMaxCacheSize 默认的字段缓存条目上限
 	DefaultMaxCacheSize = 1000
+	// DefaultMaxFilterCacheSize 默认的分组过滤结果缓存条目上限
+	DefaultMaxFilterCacheSize = 4096
+	// DefaultMaxFragmentCacheBytes 默认的片段缓存字节容量
+	DefaultMaxFragmentCacheBytes = 8 << 20
+	// ParallelThreshold 启用并行处理时，切片元素数超过该值才拆分处理
+	ParallelThreshold = 1024
 )
 
 // Options 定义序列化的选项配置
@@ -24,7 +77,10 @@ type Options struct {
 	GroupMode GroupMode
 	// TopLevelKey 顶层包装的键名，为空则不包装
 	TopLevelKey string
-	// TagKey 结构体标签键名，默认为 "groups"
+	// TopLevelPath 多层顶层包装的键名，按从外到内的顺序逐层包装，与TopLevelKey不能同时设置
+	// 每个元素是一个完整的键名，键名中的"."等字符不做解析
+	TopLevelPath []string
+	// TagKey 结构体标签键名，默认为 "groups"，为空时同样使用 "groups"
 	TagKey string
 	// UseInterfaceForNested 是否在递归序列化时使用 any 而非具体类型
 	UseInterfaceForNested bool
@@ -32,9 +88,10 @@ type Options struct {
 	// 注意：此选项会覆盖omitempty的行为
 	NullIfEmpty bool
 	// IgnoreNilPointers 忽略所有nil指针字段，不输出（优先级高于NullIfEmpty）
+	// 默认为true，关闭需要通过WithIgnoreNilPointers(false)或启用NullIfEmpty
 	IgnoreNilPointers bool
 	// MaxDepth 最大递归深度限制，防止栈溢出，默认为32
-	// 设置为0表示不限制深度（不推荐）
+	// 通过WithMaxDepth(0)设置为0表示不限制深度（不推荐），直接赋值为0时按默认值处理
 	MaxDepth int
 	// DisableCircularCheck 是否禁用循环引用检测，默认为false
 	// 禁用可能提高性能，但遇到循环引用时会导致栈溢出
@@ -42,79 +99,803 @@ type Options struct {
 	// MaxCacheSize 字段缓存的最大条目数，默认为1000
 	// 设置为0表示不限制缓存大小（不推荐用于生产环境）
 	MaxCacheSize int
+	// Parallelism 处理大切片时的并行协程数，默认为0
+	// 小于等于1表示不并行；元素数超过ParallelThreshold的切片才会拆分处理
+	Parallelism int
+	// ErrorPolicy 字段出错时的处理策略，默认为ErrorPolicyFailFast
+	ErrorPolicy ErrorPolicy
+	// BestEffort 尽力模式：出错的字段、元素直接省略，超出深度限制和循环引用时在该处截断，序列化总能成功
+	// 被省略的路径可通过MarshalByGroupsWithReport获取，不能与ErrorPolicyCollect同时使用
+	BestEffort bool
+	// RedactErrors 错误中只记录相关值的类型名，不保留值本身，默认为true
+	// 避免包含密码、令牌等敏感字段的结构体随错误被打印或序列化到日志中；关闭需要通过WithRedactedErrors(false)
+	RedactErrors bool
+	// Backend 编码后端，为nil时使用基于encoding/json的StdlibBackend
+	Backend Backend
+	// FlattenNested WriteCSV将嵌套的结构体和map展开为以"."连接的列名，默认为false
+	// 未启用时嵌套值返回ErrTypeUnsupportedType错误
+	FlattenNested bool
+	// TraceLogger 非nil时以Debug级别记录每个结构体字段的过滤结果，用于排查字段为何未输出
+	// 同一类型的同一字段每种结果只记录一次，启用后不使用生成的静态序列化方法
+	TraceLogger *slog.Logger
+	// MetricsHook 非nil时在每次序列化开始、结束和字段未输出时回调，用于采集监控指标
+	// 启用后不使用生成的静态序列化方法
+	MetricsHook MetricsHook
+	// CollectionItemsKey 非空时根值为切片或数组的输出改为{CollectionItemsKey:[...],CollectionCountKey:N}
+	// N为过滤后实际输出的元素数；根值不是切片或数组时不生效，设置TopLevelKey时位于包装键之内
+	CollectionItemsKey string
+	// CollectionCountKey 集合包装中元素数的键名，与CollectionItemsKey同时设置
+	CollectionCountKey string
+	// Envelope 非nil时每次序列化调用一次，返回的键值与顶层包装键合并在最外层，用于附加版本号、生成时间等元数据
+	// 需要同时设置TopLevelKey或TopLevelPath；值按相同的分组过滤，键与顶层包装键冲突时返回错误
+	Envelope func() map[string]any
+	// DenyGroups 禁止输出的分组：请求的分组中属于该列表的会在过滤前被移除，只属于这些分组的字段在任何请求下都不输出
+	// 未指定分组时同样生效，启用后不使用生成的静态序列化方法
+	DenyGroups []string
+	// StrictDenyGroups 请求的分组中包含DenyGroups中的分组时返回ErrTypeDeniedGroup错误，而不是移除该分组
+	StrictDenyGroups bool
+	// XRay 调试模式：每个结构体对象附加XRayKey键，列出未输出的字段及原因，不应在生产环境中使用
+	// 启用后不使用生成的静态序列化方法
+	XRay bool
+	// XRayKey 过滤元数据的键名，默认为"__jsongroup"，应避免与字段的json名称相同
+	XRayKey string
+	// XRayMatchedGroups 启用XRay时同时列出每个输出字段匹配的分组
+	XRayMatchedGroups bool
+	// NaNEqual EqualByGroups比较时将两个NaN视为相等，默认按IEEE 754的规则视为不等
+	NaNEqual bool
+	// MaxStringLen 字符串值的最大字符数，超过时截断并追加MaxStringMarker，默认为0表示不截断
+	// 按UTF-8字符计数，不会拆开多字节序列；结构体字段、map值和切片元素中的字符串都会截断，map的键不截断
+	MaxStringLen int
+	// MaxStringMarker 截断后追加的标记，例如"..."
+	MaxStringMarker string
+	// MaxSliceLen 切片和数组最多输出的元素数，超过时只输出前MaxSliceLen个元素，默认为0表示不限制
+	// 被截断的路径记录在Report.Truncated中
+	MaxSliceLen int
+	// MarkTruncatedSlices 被截断的切片末尾追加{"$truncated":剩余元素数}标记对象
+	MarkTruncatedSlices bool
+	// CanonicalJSON 按RFC 8785（JCS）输出规范JSON：键按UTF-16码元排序，数值为最短形式，字符串只做必要的转义，不含空白
+	// 相同的值总是得到相同的字节，可用于签名和哈希；NaN和Inf返回ErrTypeUnsupportedValue错误，启用后忽略Backend的转义特性
+	CanonicalJSON bool
+	// InvalidUTF8Policy 字符串包含无效UTF-8字节时的处理策略，默认为InvalidUTF8PolicyReplace
+	InvalidUTF8Policy InvalidUTF8Policy
+	// JSSafeEscaping 无论编码后端是否转义，字符串中的U+2028和U+2029总是输出为\u2028、\u2029，
+	// 便于将输出内嵌在<script>中；与HTML字符的转义相互独立，不能与CanonicalJSON同时使用
+	JSSafeEscaping bool
+	// RecordSeparator 每个JSON文档之后追加的分隔符，例如"\n"用于每行一个对象的日志管道
+	// 对MarshalByGroups系列函数和Encoder都生效，出错而没有输出时不追加；默认为nil不追加
+	RecordSeparator []byte
+	// ErrorFormat 实现了error接口的值的输出形式，默认为ErrorFormatString
+	ErrorFormat ErrorFormat
+	// NullNilErrors 值为nil的error接口字段输出null，默认与其他nil接口一样省略；声明了omitempty的字段仍然省略
+	NullNilErrors bool
+	// ErrorChain 同时输出通过Unwrap包装的错误链：ErrorFormatString下输出为从外到内的Error()字符串数组，
+	// ErrorFormatObject下在"causes"中按相同顺序列出被包装的错误；errors.Join等包装多个错误时深度优先展开
+	ErrorChain bool
+	// PostProcess 非nil时在编码之前对完整的中间表示调用一次，可以修改、替换后返回新的树，例如注入链接或重命名字段
+	// 调用发生在顶层包装和合并Envelope之后；设置后MarshalByGroups系列函数经由map路径构建中间表示再交给编码后端，
+	// MarshalToMap、MarshalToValue同样会调用；返回的错误包装为ErrTypeUnknown并中止序列化。EstimateSize不调用该钩子
+	PostProcess func(root any) (any, error)
+	// FieldHook 非nil时对每个被分组包含的结构体字段调用，可以替换字段的值或返回错误，例如解密字段、换算单位
+	// 在omitempty等省略规则之前调用，被分组排除的字段不会调用
+	FieldHook FieldHook
+	// RequireGroupTags 结构体存在未声明分组标签的导出字段时返回ErrTypeMissingGroupTag错误，而不是在不指定分组时输出这些字段
+	// json:"-"和非导出字段除外；每个类型只在解析字段时统计一次，错误列出该类型所有缺少标签的字段路径
+	RequireGroupTags bool
+	// DeprecationHook 非nil时，声明了deprecated标签的字段实际输出时回调，可用于统计仍在接收弃用字段的客户端
+	// 每个字段只回调一次，序列化大量数据不会产生等量的回调
+	DeprecationHook DeprecationHook
+	// CascadeToUntagged 指定分组时，声明了分组的字段被包含后，其嵌套结构体中未声明分组标签的字段随之输出
+	// 嵌套结构体中声明了分组的字段仍按分组严格过滤；继承只沿该字段的子树向下传递，不影响兄弟字段
+	CascadeToUntagged bool
+	// StrictTags 结构体字段的标签相互矛盾时返回ErrTypeContradictoryTag错误，如json:"-"的字段声明了分组、同一分组标签中分组重复
+	// 与RequireGroupTags相同，每个类型只在解析字段时检查一次
+	StrictTags bool
+	// ValueFilter 非nil时对每个通过分组过滤的结构体字段调用，返回false时省略该字段，用于按值决定可见性，
+	// 例如LocationPrivate为true时不输出location；为nil时没有任何开销
+	ValueFilter ValueFilter
+	// Locking 结构体（的指针）实现sync.Locker或提供RLock/RUnlock时，读取其字段前加读锁（或互斥锁），
+	// 复制完字段后即释放，递归处理指针指向的子结构体时各自独立加锁；只对经由指针到达的结构体生效
+	Locking bool
+	// SkipCachingAnonymousTypes 匿名结构体类型（如按请求构造的struct{...}字面量、reflect.StructOf创建的类型）的字段信息不写入缓存，每次重新解析，
+	// 避免一次性的类型挤占LRU容量、淘汰常用的具名类型
+	SkipCachingAnonymousTypes bool
+	// EmptyMapForNil MarshalToMap系列函数的根值为nil（nil接口或nil指针）时按没有任何字段的对象处理，返回已分配的空map，
+	// 与所有字段都被过滤的结构体结果相同；设置了顶层包装键时返回包装后的空对象。默认返回nil
+	EmptyMapForNil bool
+	// ScalarWrapperKey MarshalToMap系列函数的结果不是对象（根值为基本类型、切片等）时包装结果使用的键名，为空时使用DefaultScalarWrapperKey
+	ScalarWrapperKey string
+	// StrictMapRoot MarshalToMap系列函数的结果不是对象时返回ErrTypeUnsupportedType错误，而不是按ScalarWrapperKey包装
+	StrictMapRoot bool
+	// PruneEmpty 嵌套的结构体或map的字段、条目全部被过滤后不再输出为{}，而是省略所在的字段或map条目，并逐层向上传递，
+	// 整个分支都为空时一并省略；源值本身为空的集合和null照常输出，根值不会被省略。
+	// 切片和数组的元素不会被删除，因过滤而为空的元素仍输出为{}，元素全部为空的切片如[{},{}]也不会被省略
+	PruneEmpty bool
+	// PruneEmptyCollections 启用PruneEmpty时，源值本身为空的map、切片、数组和没有字段的结构体同样省略；
+	// 只按源值的长度判断，不删除切片中因过滤而为空的元素
+	PruneEmptyCollections bool
+	// PreserveTypes MarshalToMap系列函数的结果中基本类型的值保留原始类型（如int32、具名的字符串类型），元素为基本类型的切片和数组复制为原类型
+	// （如[]string仍为[]string），只有结构体和map重建为map[string]any；MarshalToValue、EqualByGroups和JSON等字节输出不受影响
+	PreserveTypes bool
+	// Version 非空时按RegisterVersion注册的API版本确定分组，调用时指定的分组追加在版本的分组之后；
+	// 版本在序列化开始时解析，未注册时返回ErrTypeUnknownVersion错误，在RegisterTypeOptions注册的选项中不起作用
+	Version string
+	// FieldRenames 按从根值开始、以点号连接的JSON名称路径重命名输出的键，如{"id": "identifier", "address.zip": "postal_code"}
+	// 切片、数组、map和指针不占用路径；新名称与同一对象中的其他键相同时返回ErrTypeInvalidOptions错误。
+	// 作用于JSON输出、MarshalToMap、MarshalToValue、EstimateSize和EncodeValues，不影响WriteCSV和schema；启用后不使用生成的静态序列化方法和片段缓存
+	FieldRenames map[string]string
+	// StrictFieldRenames FieldRenames中的路径在根值的类型中不存在时返回ErrTypeInvalidOptions错误，而不是忽略
+	StrictFieldRenames bool
+
+	// set 记录通过With*方法、函数式选项或声明式配置显式设置过的字段，供Merge判断覆盖哪些字段
+	set optionField
+	// initialized 由New()设置，为false表示零值Options，fillDefaults只为零值Options填充默认值
+	initialized bool
+	// keepSpecialFloats 中间表示中的NaN和Inf保留为float64而不转换为字符串，仅供EqualByGroups使用
+	keepSpecialFloats bool
+	// preserveTypes 由MarshalToMapWithOptions按PreserveTypes设置，其他使用中间表示的路径保持规范化的类型
+	preserveTypes bool
 }
 
-// New 返回默认选项配置
+// New 返回内置的默认选项配置，不受SetDefaultOptions影响
 func New() *Options {
 	return &Options{
 		GroupMode:             GroupModeOr,
 		TopLevelKey:           "",
-		TagKey:                "groups",
+		TagKey:                DefaultTagKey,
 		UseInterfaceForNested: false,
 		NullIfEmpty:           false,
 		IgnoreNilPointers:     true,
 		MaxDepth:              DefaultMaxDepth,
 		DisableCircularCheck:  false,
 		MaxCacheSize:          DefaultMaxCacheSize,
+		RedactErrors:          true,
+		initialized:           true,
+	}
+}
+
+// defaultOptions SetDefaultOptions设置的包级默认选项，为nil时使用New()
+// 保存的是调用方选项的副本且之后不再修改，序列化过程中可以直接读取而无需加锁
+var defaultOptions atomic.Pointer[Options]
+
+// SetDefaultOptions 设置包级默认选项，MarshalByGroups、MarshalToMap以及opts为nil的函数都使用该选项
+// 保存的是opts的副本，之后修改opts不会影响默认选项；已经开始的序列化继续使用开始时读取的选项。
+// opts为nil时恢复为New()返回的内置默认值。测试中修改默认选项后应恢复原值：
+//
+//	prev := jsongroup.GetDefaultOptions()
+//	defer jsongroup.SetDefaultOptions(prev)
+func SetDefaultOptions(opts *Options) {
+	if opts == nil {
+		defaultOptions.Store(nil)
+		return
+	}
+	defaultOptions.Store(opts.Clone())
+}
+
+// GetDefaultOptions 返回当前包级默认选项的副本
+func GetDefaultOptions() *Options {
+	return defaults().Clone()
+}
+
+// defaults 返回当前的包级默认选项，返回值在内部共享，调用方不能修改
+func defaults() *Options {
+	if opts := defaultOptions.Load(); opts != nil {
+		return opts
 	}
+	return New()
 }
 
-// WithTopLevelKey 设置顶层包装键名
+// fillDefaults 将未显式设置且为零值的字段替换为New()中的默认值，使零值Options与New()的行为一致
+// 由New()创建的选项已包含默认值，直接赋值为0或false的字段保持原值；
+// 零值Options上通过With*方法、函数式选项或声明式配置设置为0或false的字段同样保持原值
+func (o *Options) fillDefaults() {
+	if o.TagKey == "" {
+		o.TagKey = DefaultTagKey
+	}
+	if o.initialized {
+		return
+	}
+	if o.MaxDepth == 0 && o.set&fieldMaxDepth == 0 {
+		o.MaxDepth = DefaultMaxDepth
+	}
+	if !o.RedactErrors && o.set&fieldRedactErrors == 0 {
+		o.RedactErrors = true
+	}
+	// 启用NullIfEmpty时自动禁用IgnoreNilPointers，与WithNullIfEmpty一致
+	if !o.IgnoreNilPointers && o.set&fieldIgnoreNilPointers == 0 && !o.NullIfEmpty {
+		o.IgnoreNilPointers = true
+	}
+}
+
+// Clone 返回选项的副本，o为nil时返回包级默认选项的副本
+// With*方法都基于副本修改，不会影响接收者，因此共享的Options可以安全地作为派生配置的起点
+func (o *Options) Clone() *Options {
+	if o == nil {
+		o = defaults()
+	}
+	c := *o
+	return &c
+}
+
+// WithTopLevelKey 设置顶层包装键名，同时清除TopLevelPath
 func (o *Options) WithTopLevelKey(key string) *Options {
-	o.TopLevelKey = key
-	return o
+	c := o.Clone()
+	c.set |= fieldTopLevelKey | fieldTopLevelPath
+	c.TopLevelKey = key
+	c.TopLevelPath = nil
+	return c
+}
+
+// WithTopLevelPath 设置多层顶层包装，例如WithTopLevelPath("result", "data")输出{"result":{"data":...}}
+// 同时清除TopLevelKey；不传参数时不包装
+func (o *Options) WithTopLevelPath(keys ...string) *Options {
+	c := o.Clone()
+	c.set |= fieldTopLevelKey | fieldTopLevelPath
+	c.TopLevelKey = ""
+	c.TopLevelPath = slices.Clone(keys)
+	return c
 }
 
 // WithGroupMode 设置分组模式
 func (o *Options) WithGroupMode(mode GroupMode) *Options {
-	o.GroupMode = mode
-	return o
+	c := o.Clone()
+	c.set |= fieldGroupMode
+	c.GroupMode = mode
+	return c
 }
 
 // WithTagKey 设置标签键名
 func (o *Options) WithTagKey(key string) *Options {
-	o.TagKey = key
-	return o
+	c := o.Clone()
+	c.set |= fieldTagKey
+	c.TagKey = key
+	return c
 }
 
 // WithNullIfEmpty 设置是否对空值输出null
 func (o *Options) WithNullIfEmpty(enable bool) *Options {
-	o.NullIfEmpty = enable
+	c := o.Clone()
+	c.NullIfEmpty = enable
+	c.set |= fieldNullIfEmpty
 	// 当启用NullIfEmpty时，自动禁用IgnoreNilPointers
 	if enable {
-		o.IgnoreNilPointers = false
+		c.IgnoreNilPointers = false
+		c.set |= fieldIgnoreNilPointers
 	}
-	return o
+	return c
 }
 
 // WithIgnoreNilPointers 设置是否忽略nil指针字段
 func (o *Options) WithIgnoreNilPointers(enable bool) *Options {
-	o.IgnoreNilPointers = enable
-	return o
+	c := o.Clone()
+	c.set |= fieldIgnoreNilPointers
+	c.IgnoreNilPointers = enable
+	return c
 }
 
 // WithUseInterfaceForNested 设置是否对嵌套结构使用any
 func (o *Options) WithUseInterfaceForNested(enable bool) *Options {
-	o.UseInterfaceForNested = enable
-	return o
+	c := o.Clone()
+	c.set |= fieldUseInterfaceForNested
+	c.UseInterfaceForNested = enable
+	return c
 }
 
 // WithMaxDepth 设置最大递归深度限制
 // depth应为正数，设置为0表示不限制（不推荐）
 func (o *Options) WithMaxDepth(depth int) *Options {
-	o.MaxDepth = depth
-	return o
+	c := o.Clone()
+	c.set |= fieldMaxDepth
+	c.MaxDepth = depth
+	return c
 }
 
 // WithDisableCircularCheck 设置是否禁用循环引用检测
 func (o *Options) WithDisableCircularCheck(disable bool) *Options {
-	o.DisableCircularCheck = disable
-	return o
+	c := o.Clone()
+	c.set |= fieldDisableCircularCheck
+	c.DisableCircularCheck = disable
+	return c
 }
 
 // WithMaxCacheSize 设置字段缓存的最大条目数
 // size应为正数，设置为0表示不限制（不推荐）
 func (o *Options) WithMaxCacheSize(size int) *Options {
-	o.MaxCacheSize = size
-	return o
+	c := o.Clone()
+	c.set |= fieldMaxCacheSize
+	c.MaxCacheSize = size
+	return c
+}
+
+// WithParallelism 设置处理大切片时的并行协程数
+// n小于等于1表示不并行
+func (o *Options) WithParallelism(n int) *Options {
+	c := o.Clone()
+	c.set |= fieldParallelism
+	c.Parallelism = n
+	return c
+}
+
+// WithErrorPolicy 设置字段出错时的处理策略
+func (o *Options) WithErrorPolicy(policy ErrorPolicy) *Options {
+	c := o.Clone()
+	c.set |= fieldErrorPolicy
+	c.ErrorPolicy = policy
+	return c
+}
+
+// WithBestEffort 设置是否启用尽力模式
+func (o *Options) WithBestEffort(enable bool) *Options {
+	c := o.Clone()
+	c.set |= fieldBestEffort
+	c.BestEffort = enable
+	return c
+}
+
+// WithRedactedErrors 设置错误中是否只记录值的类型名
+// 关闭后循环引用错误的Error.Value记录值的简短文本摘要（最多60字节），仅建议在调试时使用
+func (o *Options) WithRedactedErrors(enable bool) *Options {
+	c := o.Clone()
+	c.set |= fieldRedactErrors
+	c.RedactErrors = enable
+	return c
+}
+
+// WithBackend 设置编码后端
+func (o *Options) WithBackend(b Backend) *Options {
+	c := o.Clone()
+	c.set |= fieldBackend
+	c.Backend = b
+	return c
+}
+
+// WithFinalEncoder 使用fn代替json.Marshal完成最终编码，便于接入第三方JSON库
+// fn的转义行为应与encoding/json一致，否则应实现Backend并通过WithBackend设置；fn为nil时恢复默认后端
+func (o *Options) WithFinalEncoder(fn func(any) ([]byte, error)) *Options {
+	c := o.Clone()
+	c.set |= fieldBackend
+	if fn == nil {
+		c.Backend = nil
+		return c
+	}
+	c.Backend = funcBackend(fn)
+	return c
+}
+
+// WithFlattenNested 设置WriteCSV是否将嵌套的结构体和map展开为多列
+func (o *Options) WithFlattenNested(enable bool) *Options {
+	c := o.Clone()
+	c.set |= fieldFlattenNested
+	c.FlattenNested = enable
+	return c
+}
+
+// WithTraceLogger 设置记录字段过滤结果的日志器，为nil时关闭跟踪
+func (o *Options) WithTraceLogger(logger *slog.Logger) *Options {
+	c := o.Clone()
+	c.set |= fieldTraceLogger
+	c.TraceLogger = logger
+	return c
+}
+
+// WithMetricsHook 设置序列化指标回调，为nil时关闭
+func (o *Options) WithMetricsHook(h MetricsHook) *Options {
+	c := o.Clone()
+	c.set |= fieldMetricsHook
+	c.MetricsHook = h
+	return c
+}
+
+// WithCollectionEnvelope 设置根切片的集合包装，例如WithCollectionEnvelope("items", "count")输出{"items":[...],"count":2}
+// 启用NullIfEmpty时nil切片输出为"items":null，元素数为0
+func (o *Options) WithCollectionEnvelope(itemsKey, countKey string) *Options {
+	c := o.Clone()
+	c.set |= fieldCollection
+	c.CollectionItemsKey = itemsKey
+	c.CollectionCountKey = countKey
+	return c
+}
+
+// WithEnvelope 设置最外层与包装后的数据合并的元数据，fn在每次序列化时调用，时间戳等值因此每次都是最新的
+func (o *Options) WithEnvelope(fn func() map[string]any) *Options {
+	c := o.Clone()
+	c.set |= fieldEnvelope
+	c.Envelope = fn
+	return c
+}
+
+// WithDenyGroups 设置禁止输出的分组，例如WithDenyGroups("internal", "debug")
+// 即使调用方请求了这些分组，只属于这些分组的字段也不会输出；不传参数时取消限制
+func (o *Options) WithDenyGroups(groups ...string) *Options {
+	c := o.Clone()
+	c.set |= fieldDenyGroups
+	c.DenyGroups = slices.Clone(groups)
+	return c
+}
+
+// WithStrictDenyGroups 设置请求被禁止的分组时是否返回错误
+func (o *Options) WithStrictDenyGroups(strict bool) *Options {
+	c := o.Clone()
+	c.set |= fieldStrictDenyGroups
+	c.StrictDenyGroups = strict
+	return c
+}
+
+// WithXRay 设置是否在输出中附加字段过滤的元数据，用于调试接口，不应在生产环境中使用
+// 每个结构体对象增加{"__jsongroup":{"excluded":{"secret":"excluded_by_group"}}}形式的键，原因与跟踪日志相同
+func (o *Options) WithXRay(enable bool) *Options {
+	c := o.Clone()
+	c.set |= fieldXRay
+	c.XRay = enable
+	return c
+}
+
+// WithXRayKey 设置过滤元数据的键名，为空时使用DefaultXRayKey
+func (o *Options) WithXRayKey(key string) *Options {
+	c := o.Clone()
+	c.set |= fieldXRayKey
+	c.XRayKey = key
+	return c
+}
+
+// WithXRayMatchedGroups 设置过滤元数据中是否列出输出字段匹配的分组，结果位于"included"键下
+func (o *Options) WithXRayMatchedGroups(enable bool) *Options {
+	c := o.Clone()
+	c.set |= fieldXRayMatchedGroups
+	c.XRayMatchedGroups = enable
+	return c
+}
+
+// WithNaNEqual 设置EqualByGroups是否将两个NaN视为相等
+func (o *Options) WithNaNEqual(enable bool) *Options {
+	c := o.Clone()
+	c.set |= fieldNaNEqual
+	c.NaNEqual = enable
+	return c
+}
+
+// WithMaxStringLen 设置字符串值的最大字符数，超过n个字符的字符串截断后追加marker
+// n为0时不截断；被截断的路径可通过MarshalByGroupsWithReport获取
+func (o *Options) WithMaxStringLen(n int, marker string) *Options {
+	c := o.Clone()
+	c.set |= fieldMaxStringLen
+	c.MaxStringLen = n
+	c.MaxStringMarker = marker
+	return c
+}
+
+// WithMaxSliceLen 设置切片和数组最多输出的元素数，n为0时不限制
+func (o *Options) WithMaxSliceLen(n int) *Options {
+	c := o.Clone()
+	c.set |= fieldMaxSliceLen
+	c.MaxSliceLen = n
+	return c
+}
+
+// WithMarkTruncatedSlices 设置是否在被截断的切片末尾追加{"$truncated":剩余元素数}
+func (o *Options) WithMarkTruncatedSlices(enable bool) *Options {
+	c := o.Clone()
+	c.set |= fieldMarkTruncatedSlices
+	c.MarkTruncatedSlices = enable
+	return c
+}
+
+// WithCanonicalJSON 设置是否按RFC 8785（JCS）输出规范JSON
+func (o *Options) WithCanonicalJSON(enable bool) *Options {
+	c := o.Clone()
+	c.set |= fieldCanonicalJSON
+	c.CanonicalJSON = enable
+	return c
+}
+
+// WithInvalidUTF8Policy 设置字符串包含无效UTF-8字节时的处理策略
+func (o *Options) WithInvalidUTF8Policy(policy InvalidUTF8Policy) *Options {
+	c := o.Clone()
+	c.set |= fieldInvalidUTF8Policy
+	c.InvalidUTF8Policy = policy
+	return c
+}
+
+// WithJSSafeEscaping 设置是否总是将U+2028和U+2029转义为\u2028、\u2029
+func (o *Options) WithJSSafeEscaping(enable bool) *Options {
+	c := o.Clone()
+	c.set |= fieldJSSafeEscaping
+	c.JSSafeEscaping = enable
+	return c
+}
+
+// WithRecordSeparator 设置每个JSON文档之后追加的分隔符，sep为nil时不追加
+func (o *Options) WithRecordSeparator(sep []byte) *Options {
+	c := o.Clone()
+	c.set |= fieldRecordSeparator
+	c.RecordSeparator = slices.Clone(sep)
+	return c
+}
+
+// WithErrorFormat 设置实现了error接口的值的输出形式
+func (o *Options) WithErrorFormat(format ErrorFormat) *Options {
+	c := o.Clone()
+	c.set |= fieldErrorFormat
+	c.ErrorFormat = format
+	return c
+}
+
+// WithNullNilErrors 设置值为nil的error接口字段是否输出null
+func (o *Options) WithNullNilErrors(enable bool) *Options {
+	c := o.Clone()
+	c.set |= fieldNullNilErrors
+	c.NullNilErrors = enable
+	return c
+}
+
+// WithErrorChain 设置是否同时输出通过Unwrap包装的错误链
+func (o *Options) WithErrorChain(enable bool) *Options {
+	c := o.Clone()
+	c.set |= fieldErrorChain
+	c.ErrorChain = enable
+	return c
+}
+
+// WithPostProcess 设置编码前对中间表示调用的钩子，为nil时不调用
+func (o *Options) WithPostProcess(fn func(root any) (any, error)) *Options {
+	c := o.Clone()
+	c.set |= fieldPostProcess
+	c.PostProcess = fn
+	return c
+}
+
+// WithFieldHook 设置对每个被包含的结构体字段调用的钩子，为nil时不调用
+func (o *Options) WithFieldHook(h FieldHook) *Options {
+	c := o.Clone()
+	c.set |= fieldFieldHook
+	c.FieldHook = h
+	return c
+}
+
+// WithRequireGroupTags 设置是否要求所有导出字段都声明分组标签
+func (o *Options) WithRequireGroupTags(enable bool) *Options {
+	c := o.Clone()
+	c.set |= fieldRequireGroupTags
+	c.RequireGroupTags = enable
+	return c
+}
+
+// WithDeprecationHook 设置已弃用字段被输出时的回调，为nil时关闭
+func (o *Options) WithDeprecationHook(h DeprecationHook) *Options {
+	c := o.Clone()
+	c.set |= fieldDeprecationHook
+	c.DeprecationHook = h
+	return c
+}
+
+// WithCascadeToUntagged 设置被包含字段的嵌套结构体中未声明分组标签的字段是否随之输出
+func (o *Options) WithCascadeToUntagged(enable bool) *Options {
+	c := o.Clone()
+	c.set |= fieldCascadeToUntagged
+	c.CascadeToUntagged = enable
+	return c
+}
+
+// WithStrictTags 设置字段标签相互矛盾时是否返回错误
+func (o *Options) WithStrictTags(enable bool) *Options {
+	c := o.Clone()
+	c.set |= fieldStrictTags
+	c.StrictTags = enable
+	return c
+}
+
+// WithValueFilter 设置按值决定字段是否输出的回调，为nil时不调用
+func (o *Options) WithValueFilter(f ValueFilter) *Options {
+	c := o.Clone()
+	c.set |= fieldValueFilter
+	c.ValueFilter = f
+	return c
+}
+
+// WithLocking 设置读取结构体字段时是否对实现了锁接口的结构体加锁
+func (o *Options) WithLocking(enable bool) *Options {
+	c := o.Clone()
+	c.set |= fieldLocking
+	c.Locking = enable
+	return c
+}
+
+// WithSkipCachingAnonymousTypes 设置匿名结构体类型是否绕过字段缓存
+func (o *Options) WithSkipCachingAnonymousTypes(skip bool) *Options {
+	c := o.Clone()
+	c.set |= fieldSkipCachingAnonymousTypes
+	c.SkipCachingAnonymousTypes = skip
+	return c
+}
+
+// WithEmptyMapForNil 设置MarshalToMap系列函数的根值为nil时是否返回空map
+func (o *Options) WithEmptyMapForNil(enable bool) *Options {
+	c := o.Clone()
+	c.set |= fieldEmptyMapForNil
+	c.EmptyMapForNil = enable
+	return c
+}
+
+// WithScalarWrapperKey 设置MarshalToMap包装非对象结果使用的键名
+func (o *Options) WithScalarWrapperKey(key string) *Options {
+	c := o.Clone()
+	c.set |= fieldScalarWrapperKey
+	c.ScalarWrapperKey = key
+	return c
+}
+
+// WithStrictMapRoot 设置MarshalToMap的结果不是对象时是否返回错误
+func (o *Options) WithStrictMapRoot(enable bool) *Options {
+	c := o.Clone()
+	c.set |= fieldStrictMapRoot
+	c.StrictMapRoot = enable
+	return c
+}
+
+// WithPruneEmpty 设置是否省略因过滤而为空的嵌套对象
+func (o *Options) WithPruneEmpty(enable bool) *Options {
+	c := o.Clone()
+	c.set |= fieldPruneEmpty
+	c.PruneEmpty = enable
+	return c
+}
+
+// WithPruneEmptyCollections 设置启用PruneEmpty时是否同时省略源值本身为空的集合
+func (o *Options) WithPruneEmptyCollections(enable bool) *Options {
+	c := o.Clone()
+	c.set |= fieldPruneEmptyCollections
+	c.PruneEmptyCollections = enable
+	return c
+}
+
+// WithPreserveTypes 设置MarshalToMap的结果是否保留基本类型和基本类型切片的原始类型
+func (o *Options) WithPreserveTypes(enable bool) *Options {
+	c := o.Clone()
+	c.set |= fieldPreserveTypes
+	c.PreserveTypes = enable
+	return c
+}
+
+// WithVersion 设置按哪个API版本注册的分组序列化
+func (o *Options) WithVersion(version string) *Options {
+	c := o.Clone()
+	c.set |= fieldVersion
+	c.Version = version
+	return c
+}
+
+// WithFieldRenames 设置按JSON名称路径重命名输出的键，保存的是renames的副本
+func (o *Options) WithFieldRenames(renames map[string]string) *Options {
+	c := o.Clone()
+	c.set |= fieldFieldRenames
+	c.FieldRenames = maps.Clone(renames)
+	return c
+}
+
+// WithStrictFieldRenames 设置FieldRenames中的路径不存在时是否返回错误
+func (o *Options) WithStrictFieldRenames(strict bool) *Options {
+	c := o.Clone()
+	c.set |= fieldStrictFieldRenames
+	c.StrictFieldRenames = strict
+	return c
+}
+
+// Validate 检查选项配置，返回列出所有问题的ErrTypeInvalidOptions错误，配置有效时返回nil
+// 序列化函数在开始前会调用该方法；nil和零值的Options均视为有效
+func (o *Options) Validate() error {
+	if o == nil {
+		return nil
+	}
+
+	var problems []string
+	if o.GroupMode != GroupModeOr && o.GroupMode != GroupModeAnd {
+		problems = append(problems, fmt.Sprintf("未知的分组模式(%d)", o.GroupMode))
+	}
+	if o.MaxDepth < 0 {
+		problems = append(problems, fmt.Sprintf("MaxDepth不能为负数(%d)", o.MaxDepth))
+	}
+	if o.MaxCacheSize < 0 {
+		problems = append(problems, fmt.Sprintf("MaxCacheSize不能为负数(%d)", o.MaxCacheSize))
+	}
+	if o.MaxStringLen < 0 {
+		problems = append(problems, fmt.Sprintf("MaxStringLen不能为负数(%d)", o.MaxStringLen))
+	}
+	if o.MaxSliceLen < 0 {
+		problems = append(problems, fmt.Sprintf("MaxSliceLen不能为负数(%d)", o.MaxSliceLen))
+	}
+	if o.Parallelism < 0 {
+		problems = append(problems, fmt.Sprintf("Parallelism不能为负数(%d)", o.Parallelism))
+	}
+	if o.ErrorPolicy != ErrorPolicyFailFast && o.ErrorPolicy != ErrorPolicyCollect {
+		problems = append(problems, fmt.Sprintf("未知的错误策略(%d)", o.ErrorPolicy))
+	}
+	if o.InvalidUTF8Policy != InvalidUTF8PolicyReplace && o.InvalidUTF8Policy != InvalidUTF8PolicyError {
+		problems = append(problems, fmt.Sprintf("未知的无效UTF-8处理策略(%d)", o.InvalidUTF8Policy))
+	}
+	if o.ErrorFormat < ErrorFormatString || o.ErrorFormat > ErrorFormatReflect {
+		problems = append(problems, fmt.Sprintf("未知的错误输出形式(%d)", o.ErrorFormat))
+	}
+	if o.BestEffort && o.ErrorPolicy == ErrorPolicyCollect {
+		problems = append(problems, "BestEffort与ErrorPolicyCollect不能同时使用")
+	}
+	if o.CanonicalJSON && o.JSSafeEscaping {
+		problems = append(problems, "CanonicalJSON与JSSafeEscaping不能同时使用")
+	}
+	if o.TopLevelKey != "" && len(o.TopLevelPath) > 0 {
+		problems = append(problems, "TopLevelKey与TopLevelPath不能同时设置")
+	}
+	if (o.CollectionItemsKey == "") != (o.CollectionCountKey == "") {
+		problems = append(problems, "CollectionItemsKey与CollectionCountKey必须同时设置")
+	} else if o.CollectionItemsKey != "" && o.CollectionItemsKey == o.CollectionCountKey {
+		problems = append(problems, fmt.Sprintf("CollectionItemsKey与CollectionCountKey不能相同(%q)", o.CollectionItemsKey))
+	}
+	if o.Envelope != nil && len(o.topLevelKeys()) == 0 {
+		problems = append(problems, "Envelope需要同时设置TopLevelKey或TopLevelPath")
+	}
+	if o.PruneEmptyCollections && !o.PruneEmpty {
+		problems = append(problems, "PruneEmptyCollections需要同时启用PruneEmpty")
+	}
+	problems = append(problems, renameProblems(o.FieldRenames)...)
+	if o.DisableCircularCheck && o.MaxDepth == 0 && o.set&fieldMaxDepth != 0 {
+		// 两项保护同时关闭时，循环引用会导致栈溢出
+		problems = append(problems, "禁用循环引用检测时必须设置MaxDepth")
+	}
+
+	if len(problems) > 0 {
+		return InvalidOptionsError(problems)
+	}
+	return nil
+}
+
+// checkDeniedGroups 启用StrictDenyGroups时检查请求的分组，包含被禁止的分组时返回ErrTypeDeniedGroup错误
+// 未启用时返回nil，被禁止的分组在过滤字段时移除
+func (o *Options) checkDeniedGroups(groups []string) error {
+	if !o.StrictDenyGroups || len(o.DenyGroups) == 0 {
+		return nil
+	}
+	var denied []string
+	for _, g := range groups {
+		if slices.Contains(o.DenyGroups, g) && !slices.Contains(denied, g) {
+			denied = append(denied, g)
+		}
+	}
+	if len(denied) > 0 {
+		return DeniedGroupError(denied)
+	}
+	return nil
+}
+
+// topLevelKeys 返回从外到内的顶层包装键名，不包装时返回nil
+func (o *Options) topLevelKeys() []string {
+	if o.TopLevelKey != "" {
+		return []string{o.TopLevelKey}
+	}
+	return o.TopLevelPath
+}
+
+// envelope 调用Envelope取得本次序列化的元数据，未设置时返回nil
+// 元数据的键与最外层的包装键相同时返回ErrTypeInvalidOptions错误
+func (o *Options) envelope() (map[string]any, error) {
+	if o.Envelope == nil {
+		return nil, nil
+	}
+	env := o.Envelope()
+	if key := o.topLevelKeys()[0]; env != nil {
+		if _, ok := env[key]; ok {
+			return nil, InvalidOptionsError([]string{fmt.Sprintf("Envelope的键%q与顶层包装键冲突", key)})
+		}
+	}
+	return env, nil
+}
+
+// WrapTopLevel 按TopLevelKey或TopLevelPath将value逐层包装为map[string]any，未设置时原样返回
+// 供MarshalToValue的调用方在其他编码格式中得到与JSON输出一致的结构；o为nil时使用包级默认选项
+func (o *Options) WrapTopLevel(value any) any {
+	if o == nil {
+		o = defaults()
+	}
+	keys := o.topLevelKeys()
+	for i := len(keys) - 1; i >= 0; i-- {
+		value = map[string]any{keys[i]: value}
+	}
+	return value
 }
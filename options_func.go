@@ -0,0 +1,309 @@
+package jsongroup
+
+import "log/slog"
+
+// Option 函数式选项，与With*方法一一对应，底层仍然修改Options
+// 选项名与Options的字段名相同；与已有类型同名的GroupMode、ErrorPolicy、Backend、MetricsHook等加Use前缀
+type Option func(*Options)
+
+// Apply 返回依次应用opts后的选项副本，不修改接收者；o为nil时以包级默认选项为起点
+func (o *Options) Apply(opts ...Option) *Options {
+	c := o.Clone()
+	for _, opt := range opts {
+		if opt != nil {
+			opt(c)
+		}
+	}
+	return c
+}
+
+// MarshalByGroupsOpt 以包级默认选项为起点应用opts后按分组序列化v
+// 选项组合无效时与MarshalByGroupsWithOptions一样返回ErrTypeInvalidOptions错误
+func MarshalByGroupsOpt(v any, groups []string, opts ...Option) ([]byte, error) {
+	return MarshalByGroupsWithOptions(v, defaults().Apply(opts...), groups...)
+}
+
+// MarshalToMapOpt 以包级默认选项为起点应用opts后按分组将v序列化为map
+func MarshalToMapOpt(v any, groups []string, opts ...Option) (map[string]any, error) {
+	return MarshalToMapWithOptions(v, defaults().Apply(opts...), groups...)
+}
+
+// fromWith 将With*方法的结果写回被修改的选项
+func fromWith(with func(*Options) *Options) Option {
+	return func(o *Options) { *o = *with(o) }
+}
+
+// TopLevelKey 对应WithTopLevelKey
+func TopLevelKey(key string) Option {
+	return fromWith(func(o *Options) *Options { return o.WithTopLevelKey(key) })
+}
+
+// TopLevelPath 对应WithTopLevelPath
+func TopLevelPath(keys ...string) Option {
+	return fromWith(func(o *Options) *Options { return o.WithTopLevelPath(keys...) })
+}
+
+// UseGroupMode 对应WithGroupMode
+func UseGroupMode(mode GroupMode) Option {
+	return fromWith(func(o *Options) *Options { return o.WithGroupMode(mode) })
+}
+
+// TagKey 对应WithTagKey
+func TagKey(key string) Option {
+	return fromWith(func(o *Options) *Options { return o.WithTagKey(key) })
+}
+
+// NullIfEmpty 对应WithNullIfEmpty
+func NullIfEmpty(enable bool) Option {
+	return fromWith(func(o *Options) *Options { return o.WithNullIfEmpty(enable) })
+}
+
+// IgnoreNilPointers 对应WithIgnoreNilPointers
+func IgnoreNilPointers(enable bool) Option {
+	return fromWith(func(o *Options) *Options { return o.WithIgnoreNilPointers(enable) })
+}
+
+// UseInterfaceForNested 对应WithUseInterfaceForNested
+func UseInterfaceForNested(enable bool) Option {
+	return fromWith(func(o *Options) *Options { return o.WithUseInterfaceForNested(enable) })
+}
+
+// MaxDepth 对应WithMaxDepth
+func MaxDepth(depth int) Option {
+	return fromWith(func(o *Options) *Options { return o.WithMaxDepth(depth) })
+}
+
+// DisableCircularCheck 对应WithDisableCircularCheck
+func DisableCircularCheck(disable bool) Option {
+	return fromWith(func(o *Options) *Options { return o.WithDisableCircularCheck(disable) })
+}
+
+// MaxCacheSize 对应WithMaxCacheSize
+func MaxCacheSize(size int) Option {
+	return fromWith(func(o *Options) *Options { return o.WithMaxCacheSize(size) })
+}
+
+// Parallelism 对应WithParallelism
+func Parallelism(n int) Option {
+	return fromWith(func(o *Options) *Options { return o.WithParallelism(n) })
+}
+
+// UseErrorPolicy 对应WithErrorPolicy
+func UseErrorPolicy(policy ErrorPolicy) Option {
+	return fromWith(func(o *Options) *Options { return o.WithErrorPolicy(policy) })
+}
+
+// BestEffort 对应WithBestEffort
+func BestEffort(enable bool) Option {
+	return fromWith(func(o *Options) *Options { return o.WithBestEffort(enable) })
+}
+
+// RedactErrors 对应WithRedactedErrors
+func RedactErrors(enable bool) Option {
+	return fromWith(func(o *Options) *Options { return o.WithRedactedErrors(enable) })
+}
+
+// UseBackend 对应WithBackend
+func UseBackend(b Backend) Option {
+	return fromWith(func(o *Options) *Options { return o.WithBackend(b) })
+}
+
+// FinalEncoder 对应WithFinalEncoder
+func FinalEncoder(fn func(any) ([]byte, error)) Option {
+	return fromWith(func(o *Options) *Options { return o.WithFinalEncoder(fn) })
+}
+
+// FlattenNested 对应WithFlattenNested
+func FlattenNested(enable bool) Option {
+	return fromWith(func(o *Options) *Options { return o.WithFlattenNested(enable) })
+}
+
+// TraceLogger 对应WithTraceLogger
+func TraceLogger(logger *slog.Logger) Option {
+	return fromWith(func(o *Options) *Options { return o.WithTraceLogger(logger) })
+}
+
+// UseMetricsHook 对应WithMetricsHook
+func UseMetricsHook(h MetricsHook) Option {
+	return fromWith(func(o *Options) *Options { return o.WithMetricsHook(h) })
+}
+
+// CollectionEnvelope 对应WithCollectionEnvelope
+func CollectionEnvelope(itemsKey, countKey string) Option {
+	return fromWith(func(o *Options) *Options { return o.WithCollectionEnvelope(itemsKey, countKey) })
+}
+
+// Envelope 对应WithEnvelope
+func Envelope(fn func() map[string]any) Option {
+	return fromWith(func(o *Options) *Options { return o.WithEnvelope(fn) })
+}
+
+// DenyGroups 对应WithDenyGroups
+func DenyGroups(groups ...string) Option {
+	return fromWith(func(o *Options) *Options { return o.WithDenyGroups(groups...) })
+}
+
+// StrictDenyGroups 对应WithStrictDenyGroups
+func StrictDenyGroups(strict bool) Option {
+	return fromWith(func(o *Options) *Options { return o.WithStrictDenyGroups(strict) })
+}
+
+// XRay 对应WithXRay
+func XRay(enable bool) Option {
+	return fromWith(func(o *Options) *Options { return o.WithXRay(enable) })
+}
+
+// XRayKey 对应WithXRayKey
+func XRayKey(key string) Option {
+	return fromWith(func(o *Options) *Options { return o.WithXRayKey(key) })
+}
+
+// XRayMatchedGroups 对应WithXRayMatchedGroups
+func XRayMatchedGroups(enable bool) Option {
+	return fromWith(func(o *Options) *Options { return o.WithXRayMatchedGroups(enable) })
+}
+
+// NaNEqual 对应WithNaNEqual
+func NaNEqual(enable bool) Option {
+	return fromWith(func(o *Options) *Options { return o.WithNaNEqual(enable) })
+}
+
+// MaxStringLen 对应WithMaxStringLen
+func MaxStringLen(n int, marker string) Option {
+	return fromWith(func(o *Options) *Options { return o.WithMaxStringLen(n, marker) })
+}
+
+// MaxSliceLen 对应WithMaxSliceLen
+func MaxSliceLen(n int) Option {
+	return fromWith(func(o *Options) *Options { return o.WithMaxSliceLen(n) })
+}
+
+// MarkTruncatedSlices 对应WithMarkTruncatedSlices
+func MarkTruncatedSlices(enable bool) Option {
+	return fromWith(func(o *Options) *Options { return o.WithMarkTruncatedSlices(enable) })
+}
+
+// CanonicalJSON 对应WithCanonicalJSON
+func CanonicalJSON(enable bool) Option {
+	return fromWith(func(o *Options) *Options { return o.WithCanonicalJSON(enable) })
+}
+
+// UseInvalidUTF8Policy 对应WithInvalidUTF8Policy
+func UseInvalidUTF8Policy(policy InvalidUTF8Policy) Option {
+	return fromWith(func(o *Options) *Options { return o.WithInvalidUTF8Policy(policy) })
+}
+
+// JSSafeEscaping 对应WithJSSafeEscaping
+func JSSafeEscaping(enable bool) Option {
+	return fromWith(func(o *Options) *Options { return o.WithJSSafeEscaping(enable) })
+}
+
+// RecordSeparator 对应WithRecordSeparator
+func RecordSeparator(sep []byte) Option {
+	return fromWith(func(o *Options) *Options { return o.WithRecordSeparator(sep) })
+}
+
+// UseErrorFormat 对应WithErrorFormat
+func UseErrorFormat(format ErrorFormat) Option {
+	return fromWith(func(o *Options) *Options { return o.WithErrorFormat(format) })
+}
+
+// NullNilErrors 对应WithNullNilErrors
+func NullNilErrors(enable bool) Option {
+	return fromWith(func(o *Options) *Options { return o.WithNullNilErrors(enable) })
+}
+
+// ErrorChain 对应WithErrorChain
+func ErrorChain(enable bool) Option {
+	return fromWith(func(o *Options) *Options { return o.WithErrorChain(enable) })
+}
+
+// PostProcess 对应WithPostProcess
+func PostProcess(fn func(root any) (any, error)) Option {
+	return fromWith(func(o *Options) *Options { return o.WithPostProcess(fn) })
+}
+
+// UseFieldHook 对应WithFieldHook
+func UseFieldHook(h FieldHook) Option {
+	return fromWith(func(o *Options) *Options { return o.WithFieldHook(h) })
+}
+
+// RequireGroupTags 对应WithRequireGroupTags
+func RequireGroupTags(enable bool) Option {
+	return fromWith(func(o *Options) *Options { return o.WithRequireGroupTags(enable) })
+}
+
+// UseDeprecationHook 对应WithDeprecationHook
+func UseDeprecationHook(h DeprecationHook) Option {
+	return fromWith(func(o *Options) *Options { return o.WithDeprecationHook(h) })
+}
+
+// CascadeToUntagged 对应WithCascadeToUntagged
+func CascadeToUntagged(enable bool) Option {
+	return fromWith(func(o *Options) *Options { return o.WithCascadeToUntagged(enable) })
+}
+
+// StrictTags 对应WithStrictTags
+func StrictTags(enable bool) Option {
+	return fromWith(func(o *Options) *Options { return o.WithStrictTags(enable) })
+}
+
+// UseValueFilter 对应WithValueFilter
+func UseValueFilter(f ValueFilter) Option {
+	return fromWith(func(o *Options) *Options { return o.WithValueFilter(f) })
+}
+
+// Locking 对应WithLocking
+func Locking(enable bool) Option {
+	return fromWith(func(o *Options) *Options { return o.WithLocking(enable) })
+}
+
+// SkipCachingAnonymousTypes 对应WithSkipCachingAnonymousTypes
+func SkipCachingAnonymousTypes(skip bool) Option {
+	return fromWith(func(o *Options) *Options { return o.WithSkipCachingAnonymousTypes(skip) })
+}
+
+// EmptyMapForNil 对应WithEmptyMapForNil
+func EmptyMapForNil(enable bool) Option {
+	return fromWith(func(o *Options) *Options { return o.WithEmptyMapForNil(enable) })
+}
+
+// ScalarWrapperKey 对应WithScalarWrapperKey
+func ScalarWrapperKey(key string) Option {
+	return fromWith(func(o *Options) *Options { return o.WithScalarWrapperKey(key) })
+}
+
+// StrictMapRoot 对应WithStrictMapRoot
+func StrictMapRoot(enable bool) Option {
+	return fromWith(func(o *Options) *Options { return o.WithStrictMapRoot(enable) })
+}
+
+// PruneEmpty 对应WithPruneEmpty
+func PruneEmpty(enable bool) Option {
+	return fromWith(func(o *Options) *Options { return o.WithPruneEmpty(enable) })
+}
+
+// PruneEmptyCollections 对应WithPruneEmptyCollections
+func PruneEmptyCollections(enable bool) Option {
+	return fromWith(func(o *Options) *Options { return o.WithPruneEmptyCollections(enable) })
+}
+
+// PreserveTypes 对应WithPreserveTypes
+func PreserveTypes(enable bool) Option {
+	return fromWith(func(o *Options) *Options { return o.WithPreserveTypes(enable) })
+}
+
+// Version 对应WithVersion
+func Version(version string) Option {
+	return fromWith(func(o *Options) *Options { return o.WithVersion(version) })
+}
+
+// FieldRenames 对应WithFieldRenames
+func FieldRenames(renames map[string]string) Option {
+	return fromWith(func(o *Options) *Options { return o.WithFieldRenames(renames) })
+}
+
+// StrictFieldRenames 对应WithStrictFieldRenames
+func StrictFieldRenames(strict bool) Option {
+	return fromWith(func(o *Options) *Options { return o.WithStrictFieldRenames(strict) })
+}
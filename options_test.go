@@ -0,0 +1,268 @@
+package jsongroup
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// chainNode 链表节点，用于构造任意深度的嵌套
+type chainNode struct {
+	Name string     `json:"name" groups:"public"`
+	Next *chainNode `json:"next" groups:"public"`
+}
+
+// newChain 返回长度为n的链表
+func newChain(n int) *chainNode {
+	var head *chainNode
+	for range n {
+		head = &chainNode{Name: "n", Next: head}
+	}
+	return head
+}
+
+func TestZeroOptionsBehaveLikeNew(t *testing.T) {
+	for name, opts := range map[string]*Options{
+		"zero":   {},
+		"merged": (&Options{}).Merge(&Options{}),
+	} {
+		t.Run(name, func(t *testing.T) {
+			if err := opts.Validate(); err != nil {
+				t.Fatalf("Validate: %v", err)
+			}
+
+			// nil指针字段被跳过
+			u := User{ID: 1, Name: "a"}
+			if got, want := marshalString(t, u, opts, "public"), marshalString(t, u, New(), "public"); got != want {
+				t.Errorf("got %s, want %s", got, want)
+			}
+
+			// 深度限制为DefaultMaxDepth
+			_, err := MarshalByGroupsWithOptions(newChain(DefaultMaxDepth+5), opts, "public")
+			if !errors.Is(err, ErrMaxDepthExceeded) {
+				t.Errorf("deep chain: got %v, want ErrMaxDepthExceeded", err)
+			}
+
+			// 错误中的值默认脱敏
+			a := &chainNode{Name: "a"}
+			a.Next = a
+			_, err = MarshalByGroupsWithOptions(a, opts, "public")
+			var jerr *Error
+			if !errors.As(err, &jerr) || jerr.Value != "*jsongroup.chainNode" {
+				t.Errorf("cycle error value = %#v, want the type name", jerr)
+			}
+		})
+	}
+}
+
+func TestExplicitZeroOptionsAreKept(t *testing.T) {
+	opts := (&Options{}).WithMaxDepth(0).WithIgnoreNilPointers(false)
+	if _, err := MarshalByGroupsWithOptions(newChain(DefaultMaxDepth+5), opts, "public"); err != nil {
+		t.Errorf("WithMaxDepth(0) should disable the depth limit: %v", err)
+	}
+	filled := *opts
+	filled.fillDefaults()
+	if filled.IgnoreNilPointers || filled.MaxDepth != 0 || !filled.RedactErrors {
+		t.Errorf("fillDefaults changed explicit settings: %+v", filled)
+	}
+
+	// 显式关闭的设置在Merge和声明式配置中同样保留
+	merged := New().Merge(New().WithMaxDepth(0))
+	if _, err := MarshalByGroupsWithOptions(newChain(DefaultMaxDepth+5), merged, "public"); err != nil {
+		t.Errorf("merged WithMaxDepth(0): %v", err)
+	}
+	fromMap, err := OptionsFromMap(map[string]any{"max_depth": 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := MarshalByGroupsWithOptions(newChain(DefaultMaxDepth+5), fromMap, "public"); err != nil {
+		t.Errorf("max_depth 0 from config: %v", err)
+	}
+}
+
+// TestFieldsAssignedAfterNewAreKept 直接给New()的结果赋零值与With*方法效果相同，不会被默认值覆盖
+func TestFieldsAssignedAfterNewAreKept(t *testing.T) {
+	type holder struct {
+		P *Address `json:"p" groups:"a"`
+	}
+	// 不跳过nil指针时字段按空值省略，而不是作为nil指针跳过
+	h := &recordingHook{}
+	opts := New().WithMetricsHook(h)
+	opts.IgnoreNilPointers = false
+	marshalString(t, holder{}, opts, "a")
+	if len(h.excluded) != 1 || !strings.HasSuffix(h.excluded[0], ".p:omitted_empty") {
+		t.Errorf("IgnoreNilPointers = false: excluded = %v", h.excluded)
+	}
+
+	opts = New()
+	opts.MaxDepth = 0
+	if _, err := MarshalByGroupsWithOptions(newChain(DefaultMaxDepth+5), opts, "public"); err != nil {
+		t.Errorf("MaxDepth = 0 should disable the depth limit: %v", err)
+	}
+
+	opts = New()
+	opts.RedactErrors = false
+	a := &chainNode{Name: "a"}
+	a.Next = a
+	_, err := MarshalByGroupsWithOptions(a, opts, "public")
+	var jerr *Error
+	if !errors.As(err, &jerr) || jerr.Value == "*jsongroup.chainNode" {
+		t.Errorf("RedactErrors = false: cycle error value = %#v, want the unredacted value", jerr)
+	}
+
+	filled := *opts
+	filled.fillDefaults()
+	if filled.RedactErrors || !filled.IgnoreNilPointers || filled.MaxDepth != DefaultMaxDepth {
+		t.Errorf("fillDefaults changed fields of New(): %+v", filled)
+	}
+}
+
+func TestValidateUsesEffectiveMaxDepth(t *testing.T) {
+	if err := (&Options{DisableCircularCheck: true}).Validate(); err != nil {
+		t.Errorf("unset MaxDepth falls back to the default: %v", err)
+	}
+	err := New().WithMaxDepth(0).WithDisableCircularCheck(true).Validate()
+	if !errors.Is(err, ErrInvalidOptions) {
+		t.Errorf("got %v, want ErrInvalidOptions", err)
+	}
+}
+
+func TestWithMethodsDoNotMutateReceiver(t *testing.T) {
+	base := New()
+	before := *base
+	denied := []string{"internal"}
+	renames := map[string]string{"Name": "full_name"}
+
+	variants := []*Options{
+		base.WithNullIfEmpty(true),
+		base.WithMaxDepth(3),
+		base.WithTagKey("roles"),
+		base.WithGroupMode(GroupModeAnd),
+		base.WithDenyGroups(denied...),
+		base.WithFieldRenames(renames),
+		base.WithTopLevelPath("data", "item"),
+	}
+	if !reflect.DeepEqual(*base, before) {
+		t.Errorf("base changed:\n%+v\n%+v", *base, before)
+	}
+	for i, v := range variants {
+		if v == base {
+			t.Errorf("variant %d returned the receiver", i)
+		}
+	}
+
+	// 传入的切片和map被复制，之后修改不影响已构建的选项
+	denied[0] = "public"
+	renames["Name"] = "changed"
+	if variants[4].DenyGroups[0] != "internal" || variants[5].FieldRenames["Name"] != "full_name" {
+		t.Error("options alias the caller's slice or map")
+	}
+
+	if c := base.Clone(); c == base || !reflect.DeepEqual(*c, *base) {
+		t.Error("Clone should return an equal, distinct copy")
+	}
+}
+
+// TestSharedOptionsConcurrentUse 在-race下运行：共享的选项被并发用于序列化，同时另一个协程从它派生新配置
+func TestSharedOptionsConcurrentUse(t *testing.T) {
+	shared := New().WithTopLevelKey("data")
+	u := User{ID: 1, Name: "a", Address: &Address{City: "X"}}
+	want := marshalString(t, u, shared, "public")
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			v := shared.WithNullIfEmpty(true).WithMaxDepth(2).WithDenyGroups("admin").WithTagKey("roles")
+			_ = v.Merge(shared)
+		}
+	}()
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 200 {
+				data, err := MarshalByGroupsWithOptions(u, shared, "public")
+				if err != nil || string(data) != want {
+					t.Errorf("got %s, %v; want %s", data, err, want)
+					return
+				}
+			}
+		}()
+	}
+	// 当前协程同时经由map路径使用共享的选项
+	for range 200 {
+		if _, err := MarshalToMapWithOptions(u, shared, "public"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	close(stop)
+	wg.Wait()
+}
+
+func TestSetDefaultOptions(t *testing.T) {
+	prev := GetDefaultOptions()
+	defer SetDefaultOptions(prev)
+
+	u := User{ID: 1, Name: "a"}
+	custom := New().WithTopLevelKey("data")
+	SetDefaultOptions(custom)
+	// 保存的是副本，之后修改custom不影响默认选项
+	custom.TopLevelKey = "changed"
+
+	data, err := MarshalByGroups(u, "public")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertJSONEqual(t, string(data), `{"data":{"id":1,"name":"a"}}`)
+	m, err := MarshalToMap(u, "public")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m["data"]; !ok {
+		t.Errorf("MarshalToMap ignored the default options: %v", m)
+	}
+
+	// GetDefaultOptions返回副本
+	got := GetDefaultOptions()
+	got.TopLevelKey = "other"
+	if GetDefaultOptions().TopLevelKey != "data" {
+		t.Error("modifying the result of GetDefaultOptions changed the defaults")
+	}
+
+	SetDefaultOptions(nil)
+	assertJSONEqual(t, marshalString(t, u, nil, "public"), `{"id":1,"name":"a"}`)
+	if !reflect.DeepEqual(GetDefaultOptions(), New()) {
+		t.Error("SetDefaultOptions(nil) did not restore New()")
+	}
+}
+
+func TestDefaultOptionsChangeDoesNotAffectInFlightMarshal(t *testing.T) {
+	prev := GetDefaultOptions()
+	defer SetDefaultOptions(prev)
+
+	// 第一个字段的回调中替换默认选项，同一次序列化的其余字段仍使用开始时的选项
+	hook := func(path string, f FieldDescriptor, v reflect.Value) (any, bool, error) {
+		SetDefaultOptions(New())
+		if v.Kind() == reflect.String {
+			return "redacted", true, nil
+		}
+		return nil, false, nil
+	}
+	SetDefaultOptions(New().WithFieldHook(hook))
+	data, err := MarshalByGroups(User{ID: 1, Name: "a", Email: "e"}, "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertJSONEqual(t, string(data), `{"id":1,"name":"redacted","email":"redacted"}`)
+	assertJSONEqual(t, marshalString(t, User{ID: 1, Name: "a"}, nil, "public"), `{"id":1,"name":"a"}`)
+}
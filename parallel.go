@@ -0,0 +1,135 @@
+package jsongroup
+
+import (
+	"maps"
+	"math"
+	"sync"
+	"sync/atomic"
+)
+
+// shouldParallelize 判断长度为length的切片是否需要并行处理
+func (ctx *serializeContext) shouldParallelize(length int) bool {
+	return ctx.opts.Parallelism > 1 && length > ParallelThreshold && !ctx.state.inChunk
+}
+
+// forEachChunk 将[0, length)按Parallelism拆分为连续分块，由工作协程并行处理，对每个下标调用fn
+// 每个分块使用独立的上下文：指针映射从父上下文复制，分块内的循环引用检测照常进行，
+// 但不同分块之间共享的指针不会被检测到；全部成功后分块记录的指针合并回父上下文。
+// 跟踪日志的去重键同样从父上下文复制、处理后合并，同一字段的同一结果在每个分块中最多各记录一次。
+// 某个分块出错时，序号更大的分块提前结束，返回序号最小的分块的错误，与顺序处理的结果一致。
+// 分块内的panic在调用方协程中重新抛出
+func (ctx *serializeContext) forEachChunk(length int, fn func(chunkCtx *serializeContext, chunk, i int) error) error {
+	n := ctx.opts.Parallelism
+	size := (length + n - 1) / n
+
+	var (
+		wg       sync.WaitGroup
+		failed   atomic.Int64
+		errs     = make([]error, n)
+		panics   = make([]any, n)
+		children = make([]*serializeState, n)
+	)
+	failed.Store(math.MaxInt64)
+
+	// 记录出错的分块序号，只保留最小值
+	markFailed := func(c int) {
+		for {
+			cur := failed.Load()
+			if int64(c) >= cur || failed.CompareAndSwap(cur, int64(c)) {
+				return
+			}
+		}
+	}
+
+	for c := range n {
+		start := c * size
+		if start >= length {
+			break
+		}
+		end := min(start+size, length)
+
+		state := &serializeState{inChunk: true}
+		if !ctx.opts.DisableCircularCheck && len(ctx.state.pointers) > 0 {
+			state.pointers = maps.Clone(ctx.state.pointers)
+		}
+		if len(ctx.state.traced) > 0 {
+			state.traced = maps.Clone(ctx.state.traced)
+		}
+		children[c] = state
+		chunkCtx := &serializeContext{
+			parent:    ctx.parent,
+			segment:   ctx.segment,
+			index:     ctx.index,
+			indexed:   ctx.indexed,
+			depth:     ctx.depth,
+			state:     state,
+			opts:      ctx.opts,
+			groupKey:  ctx.groupKey,
+			inherited: ctx.inherited,
+			renames:   ctx.renames,
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() {
+				if r := recover(); r != nil {
+					panics[c] = r
+					markFailed(c)
+				}
+			}()
+
+			for i := start; i < end; i++ {
+				// 序号更小的分块已出错时提前结束
+				if failed.Load() < int64(c) {
+					return
+				}
+				if err := fn(chunkCtx, c, i); err != nil {
+					errs[c] = err
+					markFailed(c)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	for c := range n {
+		if panics[c] != nil {
+			panic(panics[c])
+		}
+		if errs[c] != nil {
+			return errs[c]
+		}
+	}
+
+	// 按分块顺序合并记录的错误、被省略和被截断的路径，以及已记录的跟踪日志
+	for _, state := range children {
+		if state == nil {
+			continue
+		}
+		ctx.state.errs = append(ctx.state.errs, state.errs...)
+		ctx.state.skipped = append(ctx.state.skipped, state.skipped...)
+		ctx.state.truncated = append(ctx.state.truncated, state.truncated...)
+		if len(state.traced) > 0 {
+			if ctx.state.traced == nil {
+				ctx.state.traced = make(map[traceKey]struct{}, len(state.traced))
+			}
+			maps.Copy(ctx.state.traced, state.traced)
+		}
+	}
+
+	// 合并分块记录的指针，使后续兄弟节点的检测结果与顺序处理一致
+	if !ctx.opts.DisableCircularCheck {
+		for _, state := range children {
+			if state == nil || len(state.pointers) == 0 {
+				continue
+			}
+			if ctx.state.pointers == nil {
+				ctx.state.pointers = make(map[uintptr]*serializeContext, len(state.pointers))
+			}
+			maps.Copy(ctx.state.pointers, state.pointers)
+		}
+	}
+	return nil
+}
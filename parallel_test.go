@@ -0,0 +1,233 @@
+package jsongroup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type parallelRow struct {
+	ID    int      `json:"id" groups:"public"`
+	Owner *User    `json:"owner,omitempty" groups:"public"`
+	Tags  []string `json:"tags" groups:"public"`
+	Hook  any      `json:"hook,omitempty" groups:"public"`
+}
+
+// newParallelRows 返回超过ParallelThreshold的切片，bad中的下标的Hook字段为不支持的通道类型
+func newParallelRows(n int, bad ...int) []parallelRow {
+	rows := make([]parallelRow, n)
+	for i := range rows {
+		rows[i] = parallelRow{ID: i, Tags: []string{fmt.Sprint(i)}}
+		if i%3 == 0 {
+			rows[i].Owner = &User{ID: i, Name: "owner"}
+		}
+	}
+	for _, i := range bad {
+		rows[i].Hook = make(chan int)
+	}
+	return rows
+}
+
+func TestParallelMatchesSerial(t *testing.T) {
+	rows := newParallelRows(3*ParallelThreshold + 7)
+	for _, opts := range []*Options{
+		New(),
+		New().WithNullIfEmpty(true),
+		New().WithTopLevelKey("data"),
+		New().WithMaxSliceLen(2*ParallelThreshold + 1),
+	} {
+		serial := marshalString(t, rows, opts, "public")
+		serialMap, err := MarshalToValue(rows, opts, "public")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, n := range []int{2, 3, 8} {
+			parallel := opts.WithParallelism(n)
+			if got := marshalString(t, rows, parallel, "public"); got != serial {
+				t.Errorf("Parallelism=%d: direct encoder output differs from serial output", n)
+			}
+			got, err := MarshalToValue(rows, parallel, "public")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, serialMap) {
+				t.Errorf("Parallelism=%d: MarshalToValue result differs from serial result", n)
+			}
+		}
+	}
+
+	// 不超过阈值的切片不拆分，结果同样一致
+	small := newParallelRows(ParallelThreshold)
+	if marshalString(t, small, New().WithParallelism(4), "public") != marshalString(t, small, New(), "public") {
+		t.Error("output differs below the threshold")
+	}
+}
+
+func TestParallelErrors(t *testing.T) {
+	// 出错的元素分布在不同分块中
+	bad := []int{5, 1500, 2000, 3000}
+	rows := newParallelRows(3*ParallelThreshold+7, bad...)
+	wantPaths := make([]string, len(bad))
+	for i, b := range bad {
+		wantPaths[i] = fmt.Sprintf("[%d].Hook", b)
+	}
+
+	t.Run("fail fast", func(t *testing.T) {
+		// 与顺序处理相同，返回下标最小的错误
+		for _, toMap := range []bool{false, true} {
+			var err error
+			if toMap {
+				_, err = MarshalToValue(rows, New().WithParallelism(4), "public")
+			} else {
+				_, err = MarshalByGroupsWithOptions(rows, New().WithParallelism(4), "public")
+			}
+			var jerr *Error
+			if !errors.Is(err, ErrUnsupportedType) || !errors.As(err, &jerr) || jerr.Path != wantPaths[0] {
+				t.Errorf("MarshalToValue=%v: got %v, want unsupported type at %s", toMap, err, wantPaths[0])
+			}
+		}
+	})
+
+	t.Run("collect", func(t *testing.T) {
+		opts := New().WithParallelism(4).WithErrorPolicy(ErrorPolicyCollect)
+		serial, serialErr := MarshalByGroupsWithOptions(rows, opts.WithParallelism(0), "public")
+		data, err := MarshalByGroupsWithOptions(rows, opts, "public")
+		if string(data) != string(serial) {
+			t.Error("parallel output differs from serial output")
+		}
+		if got := errorPaths(t, err); !reflect.DeepEqual(got, wantPaths) {
+			t.Errorf("direct encoder paths = %v, want %v", got, wantPaths)
+		}
+		if got := errorPaths(t, serialErr); !reflect.DeepEqual(got, wantPaths) {
+			t.Errorf("serial paths = %v, want %v", got, wantPaths)
+		}
+		_, err = MarshalToValue(rows, opts, "public")
+		if got := errorPaths(t, err); !reflect.DeepEqual(got, wantPaths) {
+			t.Errorf("MarshalToValue paths = %v, want %v", got, wantPaths)
+		}
+	})
+
+	t.Run("best effort", func(t *testing.T) {
+		opts := New().WithParallelism(4).WithBestEffort(true)
+		serial, _, err := MarshalByGroupsWithReport(rows, opts.WithParallelism(0), "public")
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, report, err := MarshalByGroupsWithReport(rows, opts, "public")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != string(serial) {
+			t.Error("parallel output differs from serial output")
+		}
+		var got []string
+		for _, s := range report.Skipped {
+			got = append(got, s.Path)
+		}
+		if !reflect.DeepEqual(got, wantPaths) {
+			t.Errorf("skipped = %v, want %v", got, wantPaths)
+		}
+	})
+}
+
+// errorPaths 返回errors.Join汇总的每个错误的路径
+func errorPaths(t *testing.T, err error) []string {
+	t.Helper()
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		t.Fatalf("got %v, want a joined error", err)
+	}
+	var paths []string
+	for _, e := range joined.Unwrap() {
+		var jerr *Error
+		if !errors.As(e, &jerr) {
+			t.Fatalf("got %v, want *Error", e)
+		}
+		paths = append(paths, jerr.Path)
+	}
+	return paths
+}
+
+// TestParallelCircularReference 分块内的循环引用照常检测
+func TestParallelCircularReference(t *testing.T) {
+	type node struct {
+		Next *node `json:"next"`
+	}
+	nodes := make([]*node, 2*ParallelThreshold)
+	for i := range nodes {
+		nodes[i] = &node{}
+	}
+	nodes[1800].Next = nodes[1800]
+	_, err := MarshalByGroupsWithOptions(nodes, New().WithParallelism(4))
+	if !errors.Is(err, ErrCircularReference) {
+		t.Errorf("got %v, want ErrCircularReference", err)
+	}
+}
+
+// traceCapture 记录跟踪日志中每条记录的路径，可以被并行处理的分块并发调用
+type traceCapture struct {
+	mu    sync.Mutex
+	paths []string
+}
+
+func (h *traceCapture) Enabled(context.Context, slog.Level) bool { return true }
+func (h *traceCapture) WithAttrs([]slog.Attr) slog.Handler       { return h }
+func (h *traceCapture) WithGroup(string) slog.Handler            { return h }
+
+func (h *traceCapture) Handle(_ context.Context, r slog.Record) error {
+	r.Attrs(func(a slog.Attr) bool {
+		if a.Key == "path" {
+			h.mu.Lock()
+			h.paths = append(h.paths, a.Value.String())
+			h.mu.Unlock()
+		}
+		return true
+	})
+	return nil
+}
+
+type tracedRows struct {
+	Head []parallelRow `json:"head" groups:"public"`
+	Rows []parallelRow `json:"rows" groups:"public"`
+	Tail parallelRow   `json:"tail" groups:"public"`
+}
+
+// tracePaths 返回按Parallelism=n序列化v时记录的跟踪日志路径，已排序
+func tracePaths(t *testing.T, v any, n int) []string {
+	t.Helper()
+	h := &traceCapture{}
+	opts := New().WithTraceLogger(slog.New(h)).WithParallelism(n)
+	if _, err := MarshalByGroupsWithOptions(v, opts, "public"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := MarshalToValue(v, opts, "public"); err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(h.paths)
+	return h.paths
+}
+
+func TestParallelTraceDeduplicated(t *testing.T) {
+	// 切片之前已经记录过的结果不在分块中重复记录，与顺序处理的日志相同
+	doc := tracedRows{Head: newParallelRows(2), Rows: newParallelRows(3 * ParallelThreshold)}
+	serial := tracePaths(t, doc, 0)
+	for _, n := range []int{2, 8} {
+		if got := tracePaths(t, doc, n); !slices.Equal(got, serial) {
+			t.Errorf("Parallelism=%d: traced %d records %v, want the %d serial records", n, len(got), got, len(serial))
+		}
+	}
+
+	// 分块中记录过的结果合并回父上下文，切片之后的兄弟字段不再重复记录
+	doc.Head = nil
+	for _, path := range tracePaths(t, doc, 8) {
+		if strings.HasPrefix(path, "Tail.") {
+			t.Errorf("traced %s again after the parallel slice", path)
+		}
+	}
+}
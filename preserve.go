@@ -0,0 +1,73 @@
+package jsongroup
+
+import (
+	"reflect"
+)
+
+// preserveType 启用PreserveTypes时将基本类型值的中间表示r换回v的原始类型
+// 截断后的字符串转换为v的字符串类型；NaN、Inf和复数转换得到的字符串以及NullIfEmpty得到的nil无法用原类型表示，原样返回
+func (ctx *serializeContext) preserveType(v reflect.Value, r any) any {
+	if !ctx.opts.preserveTypes || !v.CanInterface() {
+		return r
+	}
+	switch r := r.(type) {
+	case string:
+		if v.Kind() != reflect.String {
+			return r
+		}
+		if r != v.String() {
+			return reflect.ValueOf(r).Convert(v.Type()).Interface()
+		}
+	case bool, int64, uint64, float64:
+	default:
+		return r
+	}
+	return v.Interface()
+}
+
+// preservedSlice 启用PreserveTypes时将元素为基本类型的切片或数组复制为原类型的值，如[]string仍为[]string，[]byte仍为[]byte
+// 切片需要按MaxSliceLen截断，或有元素需要截断、拒绝、转换为字符串或null时返回false，由调用方逐个元素处理
+func (ctx *serializeContext) preservedSlice(v reflect.Value) (any, bool) {
+	if !ctx.opts.preserveTypes || !v.CanInterface() || !preservesElem(v.Type().Elem()) {
+		return nil, false
+	}
+	if n := ctx.opts.MaxSliceLen; n > 0 && v.Len() > n {
+		return nil, false
+	}
+	for i := range v.Len() {
+		if !ctx.keepsScalar(v.Index(i)) {
+			return nil, false
+		}
+	}
+	if v.Kind() == reflect.Array {
+		// 数组是值类型，Interface返回的已经是副本
+		return v.Interface(), true
+	}
+	// 复制元素，结果不与源切片共享底层数组
+	c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+	reflect.Copy(c, v)
+	return c.Interface(), true
+}
+
+// preservesElem 判断元素类型为t的切片能否整体保留原类型：布尔、整数、浮点数和字符串，且不按error输出
+func preservesElem(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		return !implementsError(t)
+	}
+	return false
+}
+
+// keepsScalar 判断基本类型的值在中间表示中是否与原值相同：字符串不需要截断、不会被拒绝，也不会按NullIfEmpty输出为null，浮点数不是NaN或Inf
+func (ctx *serializeContext) keepsScalar(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.String:
+		return !(v.Len() == 0 && ctx.opts.NullIfEmpty) && !ctx.truncatesField(v) && !ctx.rejectsField(v)
+	case reflect.Float32, reflect.Float64:
+		return !isSpecialFloat(v.Float())
+	}
+	return true
+}
@@ -0,0 +1,119 @@
+package jsongroup
+
+import (
+	"reflect"
+)
+
+// emptyKind 最近处理完的对象或数组为空的原因，供PruneEmpty判断字段和map条目是否应当省略
+// 结构体、map、切片和迭代器处理完毕时记录，调用方在处理值之前重置为emptyNone，
+// 判断时还要求值的结果本身是空对象或空数组，不会因嵌套值留下的状态误删非空的结果
+type emptyKind uint8
+
+const (
+	// emptyNone 结果不为空，或不是对象和数组
+	emptyNone emptyKind = iota
+	// emptyExplicit 源值本身为空：长度为0的map、切片、数组和迭代器，或没有可序列化字段的结构体
+	emptyExplicit
+	// emptyFiltered 源值有字段或条目，但全部被分组过滤、省略规则或剪枝去掉
+	emptyFiltered
+)
+
+// resetEmpty 在处理字段或map条目的值之前清除上一个值留下的记录
+func (ctx *serializeContext) resetEmpty() {
+	if ctx.opts.PruneEmpty {
+		ctx.state.empty = emptyNone
+	}
+}
+
+// endStruct 记录结构体处理完毕，empty表示没有输出任何字段
+func (ctx *serializeContext) endStruct(t reflect.Type, empty bool) {
+	if !ctx.opts.PruneEmpty {
+		return
+	}
+	ctx.state.empty = emptyNone
+	if empty {
+		ctx.state.empty = emptyExplicit
+		if info, err := ctx.opts.fieldsInfo(t); err == nil && len(info.fields) > 0 {
+			ctx.state.empty = emptyFiltered
+		}
+	}
+}
+
+// endMap 记录map处理完毕，length为源map的条目数，empty表示没有输出任何条目
+func (ctx *serializeContext) endMap(length int, empty bool) {
+	if !ctx.opts.PruneEmpty {
+		return
+	}
+	switch {
+	case !empty:
+		ctx.state.empty = emptyNone
+	case length > 0:
+		ctx.state.empty = emptyFiltered
+	default:
+		ctx.state.empty = emptyExplicit
+	}
+}
+
+// endArray 记录切片、数组或迭代器处理完毕，length为源值的元素数
+// 元素不会因过滤被删除，只有没有元素的源值才输出空数组
+func (ctx *serializeContext) endArray(length int) {
+	if !ctx.opts.PruneEmpty {
+		return
+	}
+	ctx.state.empty = emptyNone
+	if length == 0 {
+		ctx.state.empty = emptyExplicit
+	}
+}
+
+// prunes 判断刚处理完的值是否按PruneEmpty省略，empty表示该值的结果为空对象或空数组
+// 因过滤而为空的值总是省略，源值本身为空的值只在启用PruneEmptyCollections时省略；null不是空集合，不会被省略
+func (ctx *serializeContext) prunes(empty bool) bool {
+	if !ctx.opts.PruneEmpty || !empty {
+		return false
+	}
+	switch ctx.state.empty {
+	case emptyFiltered:
+		return true
+	case emptyExplicit:
+		return ctx.opts.PruneEmptyCollections
+	}
+	return false
+}
+
+// isEmptyJSON 判断编码结果是否为空对象或空数组
+func isEmptyJSON(b []byte) bool {
+	return string(b) == "{}" || string(b) == "[]"
+}
+
+// isEmptyResult 判断中间表示是否为空对象或空数组，包括PreserveTypes保留了原类型的切片和数组
+func isEmptyResult(v any) bool {
+	switch v := v.(type) {
+	case nil:
+		return false
+	case map[string]any:
+		return len(v) == 0
+	case []any:
+		return len(v) == 0
+	}
+	rv := reflect.ValueOf(v)
+	return (rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array) && rv.Len() == 0
+}
+
+// prunable 判断类型为t的字段是否可能因PruneEmpty被省略，供schema判断字段是否必需
+func prunable(t reflect.Type, opts *Options) bool {
+	if !opts.PruneEmpty {
+		return false
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		return t != timeType
+	case reflect.Map:
+		return true
+	case reflect.Slice:
+		return opts.PruneEmptyCollections
+	case reflect.Array:
+		return opts.PruneEmptyCollections && t.Len() == 0
+	}
+	return false
+}
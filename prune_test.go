@@ -0,0 +1,93 @@
+package jsongroup
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+type pruneSettings struct {
+	Theme string `json:"theme" groups:"admin"`
+	Lang  string `json:"lang,omitempty" groups:"public"`
+}
+
+type pruneProfile struct {
+	Settings pruneSettings `json:"settings" groups:"public"`
+	Bio      string        `json:"bio" groups:"admin"`
+}
+
+type pruneDoc struct {
+	Name     string                   `json:"name" groups:"public"`
+	Profile  pruneProfile             `json:"profile" groups:"public"`
+	Children []pruneSettings          `json:"children" groups:"public"`
+	ByKey    map[string]pruneSettings `json:"by_key" groups:"public"`
+	Tags     []string                 `json:"tags" groups:"public"`
+	Labels   map[string]string        `json:"labels" groups:"public"`
+	Empty    struct{}                 `json:"empty" groups:"public"`
+	Note     *string                  `json:"note" groups:"public"`
+}
+
+func newPruneDoc() *pruneDoc {
+	return &pruneDoc{
+		Name:     "n",
+		Profile:  pruneProfile{Settings: pruneSettings{Theme: "dark"}, Bio: "b"},
+		Children: []pruneSettings{{Theme: "a"}, {Theme: "b"}},
+		ByKey:    map[string]pruneSettings{"x": {Theme: "x"}, "y": {Lang: "de"}},
+		Tags:     []string{},
+		Labels:   map[string]string{},
+	}
+}
+
+// assertPruned 检查直接编码、MarshalToMap和EstimateSize的结果一致
+func assertPruned(t *testing.T, v any, opts *Options, want string, groups ...string) {
+	t.Helper()
+	direct := marshalString(t, v, opts, groups...)
+	assertJSONEqual(t, direct, want)
+
+	m, err := MarshalToMapWithOptions(v, opts, groups...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mapped, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertJSONEqual(t, string(mapped), want)
+
+	if n, err := EstimateSize(v, opts, groups...); err != nil || n != len(direct) {
+		t.Errorf("EstimateSize = %d, %v; actual %d bytes", n, err, len(direct))
+	}
+}
+
+func TestPruneEmpty(t *testing.T) {
+	doc := newPruneDoc()
+	assertPruned(t, doc, New(),
+		`{"name":"n","profile":{"settings":{}},"children":[{},{}],"by_key":{"x":{},"y":{"lang":"de"}},"tags":[],"labels":{},"empty":{}}`,
+		"public")
+
+	// 因过滤而为空的对象逐层省略，源值本身为空的集合照常输出；切片中为空的元素保持原位
+	assertPruned(t, doc, New().WithPruneEmpty(true),
+		`{"name":"n","children":[{},{}],"by_key":{"y":{"lang":"de"}},"tags":[],"labels":{},"empty":{}}`,
+		"public")
+
+	// PruneEmptyCollections只按源值的长度省略空集合，元素全部为空的切片不是空集合
+	assertPruned(t, doc, New().WithPruneEmpty(true).WithPruneEmptyCollections(true),
+		`{"name":"n","children":[{},{}],"by_key":{"y":{"lang":"de"}}}`,
+		"public")
+
+	// NullIfEmpty输出的null不是空集合，不会被省略，包含null的对象也不再为空
+	assertPruned(t, doc, New().WithPruneEmpty(true).WithNullIfEmpty(true),
+		`{"name":"n","profile":{"settings":{"lang":null}},"children":[{"lang":null},{"lang":null}],`+
+			`"by_key":{"x":{"lang":null},"y":{"lang":"de"}},"tags":null,"labels":null,"empty":{},"note":null}`,
+		"public")
+}
+
+func TestPruneEmptyRoot(t *testing.T) {
+	// 根值不会被省略
+	assertPruned(t, pruneSettings{Theme: "dark"}, New().WithPruneEmpty(true), `{}`, "public")
+	assertPruned(t, &pruneProfile{}, New().WithPruneEmpty(true).WithPruneEmptyCollections(true), `{}`, "public")
+
+	// PruneEmptyCollections需要同时启用PruneEmpty
+	if err := New().WithPruneEmptyCollections(true).Validate(); err == nil {
+		t.Error("PruneEmptyCollections without PruneEmpty should be invalid")
+	}
+}
@@ -0,0 +1,6 @@
+//go:build race
+
+package jsongroup
+
+// raceEnabled 竞态检测器会给内存访问插桩并产生额外分配，分配次数的测试在-race下跳过
+const raceEnabled = true
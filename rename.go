@@ -0,0 +1,171 @@
+package jsongroup
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// renameNode FieldRenames按路径拆分后的前缀树节点，子节点以原始的JSON名称为键
+// 每次序列化调用创建一次，随上下文向下传递：进入结构体字段时取对应的子节点，切片元素、map值和指针沿用当前节点
+type renameNode struct {
+	// path 从根值开始的原始JSON名称路径，用于错误信息
+	path string
+	// to 该路径上的字段输出时使用的键名，为空表示不重命名
+	to string
+	// encodedKey 预先编码的新键名，形如 "to": ，直接编码时原样写入
+	encodedKey []byte
+	children   map[string]*renameNode
+	// checked 已检查过重命名冲突的结构体类型及结果，并行处理的分块共享同一棵树
+	checked sync.Map
+}
+
+// newRenameTree 将FieldRenames转换为前缀树，没有重命名时返回nil
+func newRenameTree(renames map[string]string) *renameNode {
+	if len(renames) == 0 {
+		return nil
+	}
+	root := &renameNode{}
+	for path, to := range renames {
+		n := root
+		for _, name := range strings.Split(path, ".") {
+			c, ok := n.children[name]
+			if !ok {
+				c = &renameNode{path: joinSchemaPath(n.path, name)}
+				if n.children == nil {
+					n.children = make(map[string]*renameNode)
+				}
+				n.children[name] = c
+			}
+			n = c
+		}
+		n.to = to
+		n.encodedKey = encodeKey(to)
+	}
+	return root
+}
+
+// child 返回名为name的字段对应的子节点，n为nil或该字段不在任何重命名路径上时返回nil
+func (n *renameNode) child(name string) *renameNode {
+	if n == nil {
+		return nil
+	}
+	return n.children[name]
+}
+
+// fieldKey 返回字段在当前对象中输出的键名
+func (ctx *serializeContext) fieldKey(field fieldInfo) string {
+	if c := ctx.renames.child(field.JSONName); c != nil && c.to != "" {
+		return c.to
+	}
+	return field.JSONName
+}
+
+// encodedFieldKey 返回字段在当前对象中输出的预先编码的键名
+func (ctx *serializeContext) encodedFieldKey(field fieldInfo) []byte {
+	if c := ctx.renames.child(field.JSONName); c != nil && c.to != "" {
+		return c.encodedKey
+	}
+	return field.EncodedKey
+}
+
+// checkRenames 检查当前节点对结构体类型t的重命名是否与t的其他字段或另一个重命名的键名相同，结果按类型记录在节点中
+// 与分组无关，按t的所有字段检查，冲突时返回ErrTypeInvalidOptions错误
+func (ctx *serializeContext) checkRenames(t reflect.Type) error {
+	n := ctx.renames
+	if n == nil || len(n.children) == 0 {
+		return nil
+	}
+	if cached, ok := n.checked.Load(t); ok {
+		err, _ := cached.(error)
+		return err
+	}
+
+	var problems []string
+	if info, err := ctx.opts.fieldsInfo(t); err == nil {
+		owners := make(map[string]string, len(info.fields))
+		for _, field := range info.fields {
+			key := ctx.fieldKey(field)
+			if owner, taken := owners[key]; taken && owner != field.JSONName {
+				problems = append(problems, fmt.Sprintf("%s中的字段%s和%s都输出为%s", t, owner, field.JSONName, key))
+				continue
+			}
+			owners[key] = field.JSONName
+		}
+	}
+	var err error
+	if len(problems) > 0 {
+		e := InvalidOptionsError(problems)
+		e.Message = "FieldRenames的新名称与已有的键冲突: " + strings.Join(problems, "; ")
+		e.Path = ctx.path()
+		err = e
+	}
+	n.checked.Store(t, err)
+	return err
+}
+
+// checkRenamePaths 启用StrictFieldRenames时按根值v的类型检查FieldRenames的每个路径，路径中的字段不存在时返回ErrTypeInvalidOptions错误
+// 切片、数组、map和指针不占用路径；路径经过接口类型的字段时无法预先确定，不作检查
+func (ctx *serializeContext) checkRenamePaths(v any) error {
+	if !ctx.opts.StrictFieldRenames || ctx.renames == nil || v == nil {
+		return nil
+	}
+	var problems []string
+	ctx.collectRenamePaths(ctx.renames, reflect.TypeOf(v), &problems)
+	if len(problems) > 0 {
+		return InvalidOptionsError(problems)
+	}
+	return nil
+}
+
+// collectRenamePaths 递归检查节点n的子节点在类型t中是否有对应的字段
+func (ctx *serializeContext) collectRenamePaths(n *renameNode, t reflect.Type, problems *[]string) {
+	for {
+		switch t.Kind() {
+		case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+			t = t.Elem()
+			continue
+		}
+		break
+	}
+	if t.Kind() == reflect.Interface {
+		return
+	}
+	var fields []fieldInfo
+	if t.Kind() == reflect.Struct && t != timeType {
+		info, err := ctx.opts.fieldsInfo(t)
+		if err != nil {
+			// 解析错误在序列化时返回
+			return
+		}
+		fields = info.fields
+	}
+	for _, name := range slices.Sorted(maps.Keys(n.children)) {
+		c := n.children[name]
+		i := slices.IndexFunc(fields, func(f fieldInfo) bool { return f.JSONName == name })
+		if i < 0 {
+			*problems = append(*problems, fmt.Sprintf("FieldRenames的路径%s在%s中不存在", c.path, t))
+			continue
+		}
+		if len(c.children) > 0 {
+			ctx.collectRenamePaths(c, t.FieldByIndex(fields[i].Index).Type, problems)
+		}
+	}
+}
+
+// renameProblems 检查FieldRenames的格式：路径的每一段和新名称都不能为空
+func renameProblems(renames map[string]string) []string {
+	var problems []string
+	for _, path := range slices.Sorted(maps.Keys(renames)) {
+		if slices.Contains(strings.Split(path, "."), "") {
+			problems = append(problems, fmt.Sprintf("FieldRenames的路径%q无效", path))
+		}
+		if renames[path] == "" {
+			problems = append(problems, fmt.Sprintf("FieldRenames中%s的新名称不能为空", path))
+		}
+	}
+	return problems
+}
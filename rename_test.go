@@ -0,0 +1,172 @@
+package jsongroup
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type renameItem struct {
+	SKU string `json:"sku" groups:"partner"`
+	Qty int    `json:"qty" groups:"partner"`
+}
+
+type renameAddress struct {
+	Street string `json:"street" groups:"partner"`
+	City   string `json:"city" groups:"partner"`
+}
+
+type renameOrder struct {
+	ID      int                      `json:"id" groups:"partner"`
+	Address *renameAddress           `json:"address" groups:"partner"`
+	Items   []renameItem             `json:"items" groups:"partner"`
+	Extra   map[string]renameAddress `json:"extra" groups:"partner"`
+	Meta    any                      `json:"meta,omitempty" groups:"partner"`
+	Secret  string                   `json:"secret" groups:"admin"`
+}
+
+func newRenameOrder() *renameOrder {
+	return &renameOrder{
+		ID:      7,
+		Address: &renameAddress{Street: "Main", City: "Berlin"},
+		Items:   []renameItem{{SKU: "a", Qty: 1}, {SKU: "b", Qty: 2}},
+		Extra:   map[string]renameAddress{"home": {City: "Paris"}},
+	}
+}
+
+var orderRenames = map[string]string{
+	"id":           "order_id",
+	"address.city": "town",
+	"items.sku":    "product_code",
+	"extra.city":   "town",
+	"secret":       "hidden",
+}
+
+func TestFieldRenames(t *testing.T) {
+	order := newRenameOrder()
+	opts := New().WithFieldRenames(orderRenames)
+	want := `{"order_id":7,"address":{"street":"Main","town":"Berlin"},` +
+		`"items":[{"product_code":"a","qty":1},{"product_code":"b","qty":2}],` +
+		`"extra":{"home":{"street":"","town":"Paris"}}}`
+
+	// 切片元素、map值和指针不占用路径；未被请求的字段即使被重命名也不输出
+	direct := marshalString(t, order, opts, "partner")
+	assertJSONEqual(t, direct, want)
+
+	m, err := MarshalToMapWithOptions(order, opts, "partner")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mapped, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertJSONEqual(t, string(mapped), want)
+
+	if n, err := EstimateSize(order, opts, "partner"); err != nil || n != len(direct) {
+		t.Errorf("EstimateSize = %d, %v; actual %d bytes", n, err, len(direct))
+	}
+
+	// 路径不存在时默认忽略
+	loose := New().WithFieldRenames(map[string]string{"id": "order_id", "missing": "x", "items.nope": "y"})
+	assertJSONEqual(t, marshalString(t, []renameItem{{SKU: "a"}}, loose, "partner"), `[{"sku":"a","qty":0}]`)
+}
+
+func TestFieldRenamesConflict(t *testing.T) {
+	// 新名称与同一对象中的其他键相同时返回错误，与是否请求了该字段的分组无关
+	opts := New().WithFieldRenames(map[string]string{"id": "secret"})
+	for name, marshal := range map[string]func() error{
+		"direct":   func() error { _, err := MarshalByGroupsWithOptions(newRenameOrder(), opts, "partner"); return err },
+		"map":      func() error { _, err := MarshalToMapWithOptions(newRenameOrder(), opts, "partner"); return err },
+		"estimate": func() error { _, err := EstimateSize(newRenameOrder(), opts, "partner"); return err },
+	} {
+		if err := marshal(); !errors.Is(err, ErrInvalidOptions) {
+			t.Errorf("%s: err = %v, want ErrInvalidOptions", name, err)
+		}
+	}
+
+	// 路径格式无效时由Validate报告
+	bad := New().WithFieldRenames(map[string]string{"items..sku": "x", "id": ""})
+	if err := bad.Validate(); !errors.Is(err, ErrInvalidOptions) {
+		t.Errorf("Validate = %v, want ErrInvalidOptions", err)
+	}
+}
+
+func TestStrictFieldRenames(t *testing.T) {
+	order := newRenameOrder()
+	strict := New().WithFieldRenames(orderRenames).WithStrictFieldRenames(true)
+	if _, err := MarshalByGroupsWithOptions(order, strict, "partner"); err != nil {
+		t.Fatalf("existing paths: %v", err)
+	}
+
+	// 路径中的字段不存在时按根值的类型报告，每个路径都列出
+	unknown := New().WithStrictFieldRenames(true).
+		WithFieldRenames(map[string]string{"missing": "x", "items.nope": "y", "address.city": "town"})
+	for name, marshal := range map[string]func() error{
+		"direct": func() error { _, err := MarshalByGroupsWithOptions(order, unknown, "partner"); return err },
+		"map":    func() error { _, err := MarshalToMapWithOptions(order, unknown, "partner"); return err },
+	} {
+		err := marshal()
+		if !errors.Is(err, ErrInvalidOptions) {
+			t.Fatalf("%s: err = %v, want ErrInvalidOptions", name, err)
+		}
+		if msg := err.Error(); !strings.Contains(msg, "items.nope") || !strings.Contains(msg, "missing") || strings.Contains(msg, "address.city") {
+			t.Errorf("%s: err = %v, want both unknown paths and no existing one", name, err)
+		}
+	}
+
+	// 路径经过接口类型的字段时无法预先检查
+	viaAny := New().WithStrictFieldRenames(true).WithFieldRenames(map[string]string{"meta.anything": "x"})
+	if _, err := MarshalByGroupsWithOptions(order, viaAny, "partner"); err != nil {
+		t.Errorf("path through an interface field: %v", err)
+	}
+}
+
+func TestFieldRenamesMergeAndConfig(t *testing.T) {
+	order := newRenameOrder()
+	want := marshalString(t, order, New().WithFieldRenames(orderRenames).WithStrictFieldRenames(true), "partner")
+
+	// Merge带上覆盖选项中显式设置的重命名
+	merged := New().WithNullIfEmpty(false).Merge(New().WithFieldRenames(orderRenames).WithStrictFieldRenames(true))
+	if got := marshalString(t, order, merged, "partner"); got != want {
+		t.Errorf("merged options: got %s, want %s", got, want)
+	}
+	// 覆盖选项未设置重命名时保留基础选项的设置
+	kept := New().WithFieldRenames(orderRenames).Merge(New().WithNullIfEmpty(false))
+	if len(kept.FieldRenames) != len(orderRenames) {
+		t.Errorf("Merge dropped the base renames: %v", kept.FieldRenames)
+	}
+
+	// OptionsFromMap，包括经过JSON编码的配置
+	config := New().WithFieldRenames(orderRenames).WithStrictFieldRenames(true).ConfigMap()
+	data, err := json.Marshal(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded map[string]any
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	for name, m := range map[string]map[string]any{"map": config, "json": decoded} {
+		opts, err := OptionsFromMap(m)
+		if err != nil {
+			t.Fatalf("%s: OptionsFromMap: %v", name, err)
+		}
+		if !opts.StrictFieldRenames {
+			t.Errorf("%s: StrictFieldRenames not restored", name)
+		}
+		if got := marshalString(t, order, opts, "partner"); got != want {
+			t.Errorf("%s: got %s, want %s", name, got, want)
+		}
+	}
+
+	// 配置中的严格检查同样生效
+	strict, err := OptionsFromMap(map[string]any{"field_renames": map[string]any{"nope": "x"}, "strict_field_renames": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := MarshalByGroupsWithOptions(order, strict, "partner"); !errors.Is(err, ErrInvalidOptions) {
+		t.Errorf("strict renames from config: err = %v, want ErrInvalidOptions", err)
+	}
+}
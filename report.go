@@ -0,0 +1,33 @@
+package jsongroup
+
+import "bytes"
+
+// Report 记录一次序列化过程中被省略的内容
+type Report struct {
+	// Skipped 尽力模式下因出错而被省略的字段、切片元素和map值，按遇到的顺序排列
+	Skipped []SkippedField
+	// Truncated 被MaxStringLen截断的字符串和被MaxSliceLen截断的切片的路径，按遇到的顺序排列
+	Truncated []string
+}
+
+// SkippedField 尽力模式下被省略的单个值
+type SkippedField struct {
+	// Path 被省略的值的路径，根值为空字符串
+	Path string
+	// Err 导致省略的错误
+	Err error
+}
+
+// MarshalByGroupsWithReport 与MarshalByGroupsWithOptions相同，同时返回序列化报告
+// 配合WithBestEffort使用时，可以在序列化成功的同时得知哪些字段被省略
+func MarshalByGroupsWithReport(v any, opts *Options, groups ...string) ([]byte, *Report, error) {
+	e := getEncoder()
+	defer putEncoder(e)
+
+	report := &Report{}
+	partial, err := e.marshal(v, opts, groups, report)
+	if err != nil && !partial {
+		return nil, nil, err
+	}
+	return bytes.Clone(e.buf), report, err
+}
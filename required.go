@@ -0,0 +1,116 @@
+package jsongroup
+
+import (
+	"reflect"
+	"slices"
+	"strconv"
+)
+
+// RequiredTagKey 声明字段在哪些分组下必需的标签键，标签值为逗号分隔的分组，如`required:"admin,public"`
+const RequiredTagKey = "required"
+
+// ValidateRequired 检查已解码的文档m是否包含prototype类型在指定分组下要求的所有字段，缺少的字段通过一个ErrTypeMissingRequired错误一并返回
+// 字段的required标签中任一分组出现在groups中时该字段必需，键不存在或值为null均视为缺少；未指定分组时没有必需的字段。
+// 嵌套结构体的要求只在父对象存在时检查：字段类型为结构体（或其指针）且值为map[string]any时递归检查，
+// 结构体的切片、数组和map对应的[]any、map[string]any逐个元素检查，值的形式与类型不对应时不再向下检查。
+// 错误中的路径为JSON路径，如items[2].id；m不会被修改，prototype可以是值、指针或reflect.Type，使用默认选项中的标签键
+func ValidateRequired(m map[string]any, prototype any, groups ...string) error {
+	opts := defaults()
+	o := *opts
+	o.fillDefaults()
+
+	t, ok := prototype.(reflect.Type)
+	if !ok {
+		t = reflect.TypeOf(prototype)
+	}
+	if t == nil {
+		return UnsupportedTypeError("", "nil")
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || t == timeType {
+		return UnsupportedTypeError("", t.String())
+	}
+	if len(groups) == 0 {
+		return nil
+	}
+
+	c := &requiredChecker{opts: &o, groups: groups}
+	if err := c.checkStruct(m, t, ""); err != nil {
+		return err
+	}
+	if len(c.missing) > 0 {
+		return MissingRequiredError(c.missing)
+	}
+	return nil
+}
+
+// requiredChecker 保存一次ValidateRequired调用的选项、分组和缺少的字段
+type requiredChecker struct {
+	opts    *Options
+	groups  []string
+	missing []string
+}
+
+// checkStruct 按结构体类型t检查对象m，字段按声明顺序检查
+func (c *requiredChecker) checkStruct(m map[string]any, t reflect.Type, path string) error {
+	info, err := c.opts.fieldsInfo(t)
+	if err != nil {
+		return ReflectionError(path, err)
+	}
+	for _, field := range info.fields {
+		fieldPath := joinSchemaPath(path, field.JSONName)
+		value := m[field.JSONName]
+		if value == nil {
+			if slices.ContainsFunc(field.Required, func(g string) bool { return slices.Contains(c.groups, g) }) {
+				c.missing = append(c.missing, fieldPath)
+			}
+			continue
+		}
+		if err := c.checkValue(value, t.FieldByIndex(field.Index).Type, fieldPath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkValue 按字段类型ft进入嵌套的结构体和结构体集合
+func (c *requiredChecker) checkValue(value any, ft reflect.Type, path string) error {
+	for ft.Kind() == reflect.Pointer {
+		ft = ft.Elem()
+	}
+	switch ft.Kind() {
+	case reflect.Struct:
+		if m, ok := value.(map[string]any); ok && ft != timeType && ft != syncMapType {
+			return c.checkStruct(m, ft, path)
+		}
+	case reflect.Slice, reflect.Array:
+		items, ok := value.([]any)
+		if !ok || !hasStructElem(ft) {
+			return nil
+		}
+		for i, item := range items {
+			if item == nil {
+				continue
+			}
+			if err := c.checkValue(item, ft.Elem(), path+"["+strconv.Itoa(i)+"]"); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		entries, ok := value.(map[string]any)
+		if !ok || !hasStructElem(ft) {
+			return nil
+		}
+		for _, k := range sortedKeys(entries) {
+			if entries[k] == nil {
+				continue
+			}
+			if err := c.checkValue(entries[k], ft.Elem(), joinSchemaPath(path, k)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package jsongroup
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// panicky 实现GroupMarshaler并在序列化时panic
+type panicky struct{}
+
+func (panicky) AppendByGroups([]byte, *EncodeState, ...string) ([]byte, error) {
+	panic("boom")
+}
+
+type holdsPanicky struct {
+	Name  string  `json:"name" groups:"public"`
+	Inner panicky `json:"inner" groups:"public"`
+}
+
+// reflectionError 断言err是带指定路径的ErrTypeReflection错误
+func reflectionError(t *testing.T, err error, path string) {
+	t.Helper()
+	var jerr *Error
+	if !errors.As(err, &jerr) || jerr.Type != ErrTypeReflection {
+		t.Fatalf("got %v, want a reflection error", err)
+	}
+	if jerr.Path != path {
+		t.Errorf("Path = %q, want %q", jerr.Path, path)
+	}
+}
+
+func TestPanicsBecomeErrors(t *testing.T) {
+	_, err := MarshalByGroups(holdsPanicky{Name: "a"}, "public")
+	reflectionError(t, err, "Inner")
+
+	hook := func(path string, f FieldDescriptor, v reflect.Value) (any, bool, error) {
+		if f.Name == "City" {
+			panic("hook failed")
+		}
+		return nil, false, nil
+	}
+	u := User{ID: 1, Address: &Address{City: "X"}}
+	// 回调中的panic报告在最近的结构体、切片或map值的路径上
+	_, err = MarshalByGroupsWithOptions(u, New().WithFieldHook(hook), "public")
+	reflectionError(t, err, "Address")
+	_, err = MarshalToMapWithOptions(u, New().WithFieldHook(hook), "public")
+	reflectionError(t, err, "Address")
+
+	filter := func(path string, owner reflect.Value, f FieldDescriptor, v reflect.Value) bool {
+		panic(errors.New("filter failed"))
+	}
+	_, err = MarshalToMapWithOptions(u, New().WithValueFilter(filter), "public")
+	reflectionError(t, err, "")
+}
+
+func TestPanicFollowsErrorPolicy(t *testing.T) {
+	v := []holdsPanicky{{Name: "a"}}
+	data, err := MarshalByGroupsWithOptions(v, New().WithErrorPolicy(ErrorPolicyCollect), "public")
+	reflectionError(t, err, "[0].Inner")
+	assertJSONEqual(t, string(data), `[{"name":"a","inner":null}]`)
+
+	data, report, err := MarshalByGroupsWithReport(v, New().WithBestEffort(true), "public")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertJSONEqual(t, string(data), `[{"name":"a"}]`)
+	if len(report.Skipped) != 1 || report.Skipped[0].Path != "[0].Inner" {
+		t.Errorf("Skipped = %+v", report.Skipped)
+	}
+}
+
+type cycleData struct {
+	Name string     `json:"name" groups:"public"`
+	Self *cycleData `json:"self" groups:"public"`
+}
+
+type cycleHolder struct {
+	Data *cycleData `json:"data" groups:"public"`
+}
+
+func TestCircularReferencePaths(t *testing.T) {
+	d := &cycleData{Name: "d"}
+	d.Self = d
+	loop := &cycleData{Name: "a", Self: &cycleData{Name: "b"}}
+	loop.Self.Self = loop
+
+	tests := []struct {
+		name            string
+		v               any
+		path, firstSeen string
+	}{
+		{"root self reference", d, "Self", ""},
+		{"nested self reference", cycleHolder{Data: d}, "Data.Self", "Data"},
+		{"two-node loop", cycleHolder{Data: loop}, "Data.Self.Self", "Data"},
+	}
+	for _, tc := range tests {
+		marshalers := map[string]func() error{
+			"bytes": func() error { _, err := MarshalByGroups(tc.v, "public"); return err },
+			"map":   func() error { _, err := MarshalToMap(tc.v, "public"); return err },
+		}
+		for kind, marshal := range marshalers {
+			err := marshal()
+			var jerr *Error
+			if !errors.As(err, &jerr) || !errors.Is(err, ErrCircularReference) {
+				t.Fatalf("%s %s: got %v, want a circular reference error", tc.name, kind, err)
+			}
+			if jerr.Path != tc.path || jerr.FirstSeenPath != tc.firstSeen {
+				t.Errorf("%s %s: Path = %q, FirstSeenPath = %q; want %q, %q", tc.name, kind, jerr.Path, jerr.FirstSeenPath, tc.path, tc.firstSeen)
+			}
+			data, _ := jerr.MarshalJSON()
+			if tc.firstSeen != "" && !strings.Contains(string(data), `"first_seen_path":"`+tc.firstSeen+`"`) {
+				t.Errorf("%s %s: MarshalJSON = %s", tc.name, kind, data)
+			}
+			msg := err.Error()
+			if !strings.Contains(msg, tc.path) || tc.firstSeen != "" && !strings.Contains(msg, "'"+tc.firstSeen+"'") {
+				t.Errorf("%s %s: message %q does not name both paths", tc.name, kind, msg)
+			}
+		}
+	}
+}
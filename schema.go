@@ -0,0 +1,375 @@
+package jsongroup
+
+import (
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// schemaDraft 生成的JSON Schema声明的版本
+const schemaDraft = "http://json-schema.org/draft-07/schema#"
+
+// schemaBuilder 按分组为类型生成JSON Schema
+// 具名结构体类型放入定义集合并通过$ref引用，类型之间的循环引用因此可以终止
+type schemaBuilder struct {
+	opts     *Options
+	groups   []string
+	groupKey string
+	// refPrefix 引用定义时的前缀，如"#/$defs/"
+	refPrefix string
+	// defName 为具名结构体类型生成定义名称，名称冲突时由builder追加序号
+	defName func(t reflect.Type) string
+	// root 根类型，递归引用根类型时使用rootRef；为nil时所有具名结构体都生成定义
+	root    reflect.Type
+	rootRef string
+	// openAPI 按OpenAPI 3.0的方式表示可空值（nullable: true），否则使用JSON Schema的类型数组
+	openAPI bool
+	// inherited 当前是否位于启用CascadeToUntagged后被包含的带分组字段之下，同一类型在两种情况下包含的字段不同
+	inherited bool
+	// defs 已生成的定义，names记录类型对应的定义名称
+	defs  map[string]any
+	names map[schemaRef]string
+}
+
+// schemaRef 定义的查找键，继承了分组匹配的类型单独生成定义
+type schemaRef struct {
+	typ       reflect.Type
+	inherited bool
+}
+
+// newSchemaBuilder 创建使用$defs存放定义的schema生成器
+func newSchemaBuilder(opts *Options, groups []string) *schemaBuilder {
+	return &schemaBuilder{
+		opts:      opts,
+		groups:    groups,
+		groupKey:  filterGroupKey(groups, opts.DenyGroups),
+		refPrefix: "#/$defs/",
+		defName:   schemaTypeName,
+		rootRef:   "#",
+		defs:      make(map[string]any),
+		names:     make(map[schemaRef]string),
+	}
+}
+
+// GenerateSchema 按指定分组为v的类型生成JSON Schema（draft-07）
+// 属性名来自json标签，未声明omitempty/omitzero的非指针字段列入required，不属于分组的字段不出现在schema中；
+// 嵌套的具名结构体放在$defs中通过$ref引用，v可以是值、指针或reflect.Type，opts为nil时使用默认选项
+func GenerateSchema(v any, opts *Options, groups ...string) (map[string]any, error) {
+	if opts == nil {
+		opts = defaults()
+	}
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	groups, err := opts.resolveGroups(groups)
+	if err != nil {
+		return nil, err
+	}
+	// 复制选项，避免修改调用方的配置
+	o := *opts
+	o.fillDefaults()
+
+	t, ok := v.(reflect.Type)
+	if !ok {
+		t = reflect.TypeOf(v)
+	}
+	if t == nil {
+		return nil, UnsupportedTypeError("", "nil")
+	}
+
+	b := newSchemaBuilder(&o, groups)
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	b.root = t
+
+	schema, err := b.rootSchema(t)
+	if err != nil {
+		return nil, err
+	}
+	schema["$schema"] = schemaDraft
+	if len(b.defs) > 0 {
+		schema["$defs"] = b.defs
+	}
+	return schema, nil
+}
+
+// rootSchema 生成根类型的schema，结构体根类型直接内联
+func (b *schemaBuilder) rootSchema(t reflect.Type) (map[string]any, error) {
+	if t.Kind() == reflect.Struct && t != timeType {
+		return b.objectSchema(t, "")
+	}
+	return b.typeSchema(t, "")
+}
+
+// typeSchema 生成类型的schema，path为错误信息中使用的路径
+func (b *schemaBuilder) typeSchema(t reflect.Type, path string) (map[string]any, error) {
+	if schema, ok := b.errorSchema(t); ok {
+		return schema, nil
+	}
+	if schema, ok := binarySchema(t); ok {
+		return schema, nil
+	}
+	switch t.Kind() {
+	case reflect.String, reflect.Complex64, reflect.Complex128:
+		// 复数编码为字符串
+		return map[string]any{"type": "string"}, nil
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return map[string]any{"type": "integer"}, nil
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}, nil
+	case reflect.Interface:
+		// 接口可以是任意值
+		return map[string]any{}, nil
+	case reflect.Pointer:
+		return b.typeSchema(t.Elem(), path)
+	case reflect.Slice, reflect.Array:
+		items, err := b.typeSchema(t.Elem(), path+"[]")
+		if err != nil {
+			return nil, err
+		}
+		schema := map[string]any{"type": "array", "items": items}
+		if t.Kind() == reflect.Array {
+			schema["minItems"] = t.Len()
+			schema["maxItems"] = t.Len()
+		}
+		return schema, nil
+	case reflect.Func:
+		// iter.Seq等迭代器输出为数组
+		if !isSeq(t) {
+			return nil, UnsupportedTypeError(path, t.String())
+		}
+		items, err := b.typeSchema(t.In(0).In(0), path+"[]")
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"type": "array", "items": items}, nil
+	case reflect.Map:
+		values, err := b.typeSchema(t.Elem(), path+"[]")
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"type": "object", "additionalProperties": values}, nil
+	case reflect.Struct:
+		if t == timeType {
+			return map[string]any{"type": "string", "format": "date-time"}, nil
+		}
+		if t == syncMapType {
+			// 值的类型未知
+			return map[string]any{"type": "object"}, nil
+		}
+		if load, ok := atomicLoadMethod(t); ok && t.PkgPath() == "sync/atomic" {
+			return b.typeSchema(load.Type.Out(0), path)
+		}
+		return b.structRef(t, path)
+	default:
+		return nil, UnsupportedTypeError(path, t.String())
+	}
+}
+
+// structRef 返回结构体类型的schema，具名类型生成定义后返回$ref
+func (b *schemaBuilder) structRef(t reflect.Type, path string) (map[string]any, error) {
+	if b.root != nil && t == b.root && !b.inherited {
+		return map[string]any{"$ref": b.rootRef}, nil
+	}
+	if t.Name() == "" {
+		// 匿名结构体不会形成循环，直接内联
+		return b.objectSchema(t, path)
+	}
+	ref := schemaRef{typ: t, inherited: b.inherited}
+	if name, ok := b.names[ref]; ok {
+		return map[string]any{"$ref": b.refPrefix + name}, nil
+	}
+
+	// 先登记名称再生成定义，递归遇到同一类型时直接返回$ref
+	name := b.defName(t)
+	for i := 2; ; i++ {
+		if _, taken := b.defs[name]; !taken {
+			break
+		}
+		name = b.defName(t) + strconv.Itoa(i)
+	}
+	b.names[ref] = name
+	b.defs[name] = nil
+
+	schema, err := b.objectSchema(t, path)
+	if err != nil {
+		return nil, err
+	}
+	b.defs[name] = schema
+	return map[string]any{"$ref": b.refPrefix + name}, nil
+}
+
+// objectSchema 按分组过滤结构体字段，生成object类型的schema
+func (b *schemaBuilder) objectSchema(t reflect.Type, path string) (map[string]any, error) {
+	properties := make(map[string]any)
+	var required []string
+	if err := b.addFields(t, path, properties, &required); err != nil {
+		return nil, err
+	}
+
+	schema := map[string]any{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema, nil
+}
+
+// addFields 将结构体的字段加入properties，内嵌的匿名结构体字段合并到当前对象
+func (b *schemaBuilder) addFields(t reflect.Type, path string, properties map[string]any, required *[]string) error {
+	if err := checkGroupTags(b.opts, t, path); err != nil {
+		return err
+	}
+	set, err := getFilteredFields(t, b.opts, b.groups, b.groupKey, b.opts.GroupMode, b.inherited)
+	if err != nil {
+		return ReflectionError(path, err)
+	}
+
+	for _, field := range set.fields {
+		ft := t.FieldByIndex(field.Index).Type
+		fieldPath := joinSchemaPath(path, field.Name)
+
+		if field.Anonymous && ft.Kind() == reflect.Struct {
+			if err := b.addFields(ft, fieldPath, properties, required); err != nil {
+				return err
+			}
+			continue
+		}
+
+		inherited := b.inherited
+		if b.opts.CascadeToUntagged && len(field.Groups) > 0 {
+			b.inherited = true
+		}
+		schema, err := b.typeSchema(ft, fieldPath)
+		b.inherited = inherited
+		if err != nil {
+			return err
+		}
+		if b.nullable(ft) {
+			schema = b.nullableSchema(schema)
+		}
+
+		// 重名字段与序列化时一样由后者覆盖前者
+		if _, exists := properties[field.JSONName]; exists {
+			*required = slices.DeleteFunc(*required, func(name string) bool { return name == field.JSONName })
+		}
+		properties[field.JSONName] = schema
+		if b.required(field, ft) {
+			*required = append(*required, field.JSONName)
+		}
+	}
+	return nil
+}
+
+// required 判断字段是否总会出现在输出中
+// 启用PruneEmpty时结构体和map字段可能被省略；启用NullIfEmpty时其余字段都会输出（可能为null）；否则omitempty/omitzero字段、nil指针和nil接口会被省略，
+// 启用NullNilErrors时nil的error接口除外
+func (b *schemaBuilder) required(field fieldInfo, ft reflect.Type) bool {
+	// 启用PruneEmpty时嵌套对象可能因过滤为空而被省略，NullIfEmpty不会使其输出为null
+	if prunable(ft, b.opts) {
+		return false
+	}
+	if b.opts.NullIfEmpty {
+		return true
+	}
+	// 提升该字段的内嵌结构体可能整体被省略
+	if field.OmitEmpty || field.OmitZero || len(field.Embeds) > 0 {
+		return false
+	}
+	if b.errorInterface(ft) && b.opts.NullNilErrors {
+		return true
+	}
+	return ft.Kind() != reflect.Pointer && ft.Kind() != reflect.Interface
+}
+
+// nullable 判断字段值是否可能输出为null
+// 只有启用NullIfEmpty时空值才输出为null；结构体（time.Time除外）和非空数组不会为空，接口本身已允许任意值。
+// error接口的schema描述的是错误的输出形式，nil时的null需要单独允许，启用NullNilErrors时也是如此
+func (b *schemaBuilder) nullable(ft reflect.Type) bool {
+	if !b.opts.NullIfEmpty {
+		return b.errorInterface(ft) && b.opts.NullNilErrors
+	}
+	switch ft.Kind() {
+	case reflect.Interface:
+		return b.errorInterface(ft)
+	case reflect.Struct:
+		return ft == timeType
+	case reflect.Array:
+		return ft.Len() == 0
+	}
+	return true
+}
+
+// errorInterface 判断ft是否为按ErrorFormat输出的error接口类型
+func (b *schemaBuilder) errorInterface(ft reflect.Type) bool {
+	return ft.Kind() == reflect.Interface && b.opts.ErrorFormat != ErrorFormatReflect && implementsError(ft)
+}
+
+// nullableSchema 允许schema描述的值为null
+func (b *schemaBuilder) nullableSchema(schema map[string]any) map[string]any {
+	if b.openAPI {
+		// OpenAPI 3.0中$ref不能与其他关键字并列，需要包装在allOf中
+		if _, ok := schema["$ref"]; ok {
+			return map[string]any{"allOf": []any{schema}, "nullable": true}
+		}
+		schema["nullable"] = true
+		return schema
+	}
+	if typ, ok := schema["type"].(string); ok {
+		schema["type"] = []string{typ, "null"}
+		return schema
+	}
+	return map[string]any{"anyOf": []any{schema, map[string]any{"type": "null"}}}
+}
+
+// joinSchemaPath 拼接错误信息中使用的路径
+func joinSchemaPath(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
+
+// schemaTypeName 返回具名结构体类型的定义名称
+// 泛型实例化的类型名包含类型参数的完整包路径，如Page[github.com/x/model.User]，直接作为$ref的一部分时斜杠会被当作JSON指针的分隔符；
+// 类型参数因此去掉包路径后以下划线连接，指针记为Ptr，切片记为Slice，如Page_User、Page_Ptr_User、Page_Slice_User
+func schemaTypeName(t reflect.Type) string {
+	base, args, generic := strings.Cut(t.Name(), "[")
+	if !generic {
+		return base
+	}
+	var sb strings.Builder
+	sb.WriteString(base)
+	var word []rune
+	flush := func() {
+		if len(word) > 0 {
+			sb.WriteByte('_')
+			sb.WriteString(string(word))
+			word = word[:0]
+		}
+	}
+	for i, r := range args {
+		switch {
+		case r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r):
+			word = append(word, r)
+		case r == '.' || r == '/':
+			// 丢弃包路径和包名，只保留类型名
+			word = word[:0]
+		case r == '*':
+			flush()
+			sb.WriteString("_Ptr")
+		case r == '[' && strings.HasPrefix(args[i+1:], "]"):
+			flush()
+			sb.WriteString("_Slice")
+		default:
+			flush()
+		}
+	}
+	flush()
+	return sb.String()
+}
@@ -0,0 +1,159 @@
+package jsongroup
+
+import (
+	"reflect"
+)
+
+// isSeq 判断t是否为func(yield func(T) bool)形式的迭代器，如iter.Seq[T]
+// 迭代器按切片处理：产生的元素依次序列化为JSON数组，不需要调用方先收集到切片中
+func isSeq(t reflect.Type) bool {
+	return t.Kind() == reflect.Func && t.CanSeq()
+}
+
+// seqLimitReached 判断迭代器已输出的元素数是否达到MaxSliceLen，达到时记录当前路径
+// 迭代器无法预知长度，只有在第MaxSliceLen+1个元素出现时才确定发生了截断，此后不再取元素
+func (ctx *serializeContext) seqLimitReached(i int) bool {
+	if n := ctx.opts.MaxSliceLen; n > 0 && i == n {
+		ctx.state.truncated = append(ctx.state.truncated, ctx.path())
+		return true
+	}
+	return false
+}
+
+// seqToSlice 将迭代器产生的元素转换为切片，nil迭代器与nil切片的处理相同
+// 元素出错且按当前策略需要返回时立即停止迭代
+func seqToSlice(ctx *serializeContext, v reflect.Value, groups []string, mode GroupMode) (any, error) {
+	if v.IsNil() {
+		if ctx.opts.NullIfEmpty {
+			return nil, nil
+		}
+		ctx.endArray(0)
+		return []any{}, nil
+	}
+
+	result := []any{}
+	i := 0
+	for item := range v.Seq() {
+		if ctx.seqLimitReached(i) {
+			if ctx.opts.MarkTruncatedSlices {
+				// 剩余元素数未知，标记值为true
+				result = append(result, map[string]any{TruncatedSliceKey: true})
+			}
+			break
+		}
+		itemCtx := ctx.withIndex(i)
+		i++
+
+		itemInterface, err := nilIfSkipped(valueToMap(itemCtx, item, groups, mode))
+		if err != nil {
+			switch itemCtx.handleError(err) {
+			case errorFail:
+				return nil, err
+			case errorNull:
+				result = append(result, nil)
+			}
+			continue
+		}
+		result = append(result, itemInterface)
+	}
+	ctx.endArray(i)
+	return result, nil
+}
+
+// encodeSeq 编码迭代器产生的元素，规则与seqToSlice一致
+func (e *encoder) encodeSeq(ctx *serializeContext, v reflect.Value, groups []string, mode GroupMode) (bool, error) {
+	if v.IsNil() {
+		if ctx.opts.NullIfEmpty {
+			return false, nil
+		}
+		e.buf = append(e.buf, "[]"...)
+		ctx.endArray(0)
+		return true, nil
+	}
+
+	e.buf = append(e.buf, '[')
+	n, i := 0, 0
+	for item := range v.Seq() {
+		if ctx.seqLimitReached(i) {
+			if ctx.opts.MarkTruncatedSlices {
+				if n > 0 {
+					e.buf = append(e.buf, ',')
+				}
+				e.buf = append(e.buf, `{"`+TruncatedSliceKey+`":true}`...)
+			}
+			break
+		}
+		itemCtx := ctx.withIndex(i)
+		i++
+
+		mark := len(e.buf)
+		if n > 0 {
+			e.buf = append(e.buf, ',')
+		}
+		valueMark := len(e.buf)
+		ok, err := nilIfSkipped(e.encodeValue(itemCtx, item, groups, mode))
+		if err != nil {
+			switch itemCtx.handleError(err) {
+			case errorFail:
+				return false, err
+			case errorOmit:
+				e.buf = e.buf[:mark]
+				continue
+			}
+			e.buf = append(e.buf[:valueMark], "null"...)
+			ok = true
+		}
+		if !ok {
+			e.buf = append(e.buf, "null"...)
+		}
+		n++
+	}
+	e.buf = append(e.buf, ']')
+	ctx.endArray(i)
+	return true, nil
+}
+
+// estimateSeq 估算迭代器编码后的字节数，会实际调用迭代器
+func estimateSeq(ctx *serializeContext, v reflect.Value, groups []string, mode GroupMode) (int, bool, error) {
+	if v.IsNil() {
+		if ctx.opts.NullIfEmpty {
+			return 0, false, nil
+		}
+		ctx.endArray(0)
+		return len("[]"), true, nil
+	}
+
+	n := len("[]")
+	count, i := 0, 0
+	for item := range v.Seq() {
+		if ctx.seqLimitReached(i) {
+			if ctx.opts.MarkTruncatedSlices {
+				n += len(`{"`+TruncatedSliceKey+`":true}`) + min(count, 1)
+			}
+			break
+		}
+		itemCtx := ctx.withIndex(i)
+		i++
+
+		size, ok, err := nilIfSkipped3(estimateValue(itemCtx, item, groups, mode))
+		if err != nil {
+			switch itemCtx.handleError(err) {
+			case errorFail:
+				return 0, false, err
+			case errorOmit:
+				continue
+			}
+			size, ok = len("null"), true
+		}
+		if !ok {
+			size = len("null")
+		}
+		n += size
+		count++
+	}
+	if count > 1 {
+		n += count - 1
+	}
+	ctx.endArray(i)
+	return n, true, nil
+}
@@ -0,0 +1,72 @@
+package jsongroup
+
+import (
+	"io"
+	"sync"
+)
+
+// maxPooledBuffer 归还对象池时保留缓冲区的最大容量
+// 超过该值的缓冲区直接丢弃，避免对象池长期持有大块内存
+const maxPooledBuffer = 64 << 10
+
+// encoderPool 编码器对象池，复用输出缓冲区
+var encoderPool = sync.Pool{
+	New: func() any { return new(encoder) },
+}
+
+// getEncoder 从对象池获取缓冲区为空的编码器
+func getEncoder() *encoder {
+	e := encoderPool.Get().(*encoder)
+	e.buf = e.buf[:0]
+	return e
+}
+
+// putEncoder 将编码器归还对象池，之后不得再引用其缓冲区
+func putEncoder(e *encoder) {
+	if cap(e.buf) > maxPooledBuffer {
+		e.buf = nil
+	}
+	encoderPool.Put(e)
+}
+
+// MarshalByGroupsAppend 按分组序列化v，并将结果追加到dst后返回
+// 调用方可复用dst避免每次分配输出缓冲区；出错时返回原始的dst
+// ErrorPolicyCollect策略下记录了字段错误时，同时返回追加后的结果和汇总的错误
+func MarshalByGroupsAppend(dst []byte, v any, opts *Options, groups ...string) ([]byte, error) {
+	e := encoder{buf: dst}
+	partial, err := e.marshal(v, opts, groups, nil)
+	if err != nil && !partial {
+		return dst, err
+	}
+	return e.buf, err
+}
+
+// Encoder 将按分组序列化的JSON写入io.Writer，内部使用对象池中的缓冲区
+type Encoder struct {
+	w    io.Writer
+	opts *Options
+}
+
+// NewEncoder 创建写入w的编码器，opts为nil时使用默认选项
+func NewEncoder(w io.Writer, opts *Options) *Encoder {
+	if opts == nil {
+		opts = defaults()
+	}
+	return &Encoder{w: w, opts: opts}
+}
+
+// Encode 按指定分组序列化v并写入底层Writer
+// 序列化出错时不会写入任何内容；ErrorPolicyCollect策略下记录了字段错误时仍写入输出，并返回汇总的错误
+func (enc *Encoder) Encode(v any, groups ...string) error {
+	e := getEncoder()
+	defer putEncoder(e)
+
+	partial, err := e.marshal(v, enc.opts, groups, nil)
+	if err != nil && !partial {
+		return err
+	}
+	if _, werr := enc.w.Write(e.buf); werr != nil {
+		return werr
+	}
+	return err
+}
@@ -0,0 +1,31 @@
+package jsongroup
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// syncMapType sync.Map的类型，按map而不是按结构体处理
+var syncMapType = reflect.TypeFor[sync.Map]()
+
+// syncMapSnapshot 通过Range遍历sync.Map，返回键按mapToMap的规则转换为字符串后的map[string]any
+// 遍历期间的并发修改可能部分可见，结果只是尽力而为的快照；返回值按普通map序列化，值照常过滤和递归处理。
+// 与原子类型相同，只遍历可寻址的sync.Map；不可寻址的值（如map中的值）已经是非原子复制得到的副本，返回ErrTypeUnsupportedValue错误
+func (ctx *serializeContext) syncMapSnapshot(v reflect.Value) (reflect.Value, error) {
+	if !v.CanAddr() {
+		return reflect.Value{}, UnsupportedValueError(ctx.path(), v, "sync.Map的值不可寻址，无法安全地遍历，需通过指针传入")
+	}
+	m := v.Addr().Interface().(*sync.Map)
+
+	snapshot := make(map[string]any)
+	m.Range(func(k, val any) bool {
+		if k == nil {
+			snapshot[fmt.Sprint(k)] = val
+		} else {
+			snapshot[mapKeyString(reflect.ValueOf(k))] = val
+		}
+		return true
+	})
+	return reflect.ValueOf(snapshot), nil
+}
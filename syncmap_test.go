@@ -0,0 +1,101 @@
+package jsongroup
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+type syncMapHolder struct {
+	Users   sync.Map  `json:"users" groups:"public"`
+	Shared  *sync.Map `json:"shared" groups:"public"`
+	Private sync.Map  `json:"private" groups:"admin"`
+}
+
+func TestSyncMapFields(t *testing.T) {
+	h := &syncMapHolder{Shared: new(sync.Map)}
+	h.Users.Store(1, &User{ID: 1, Name: "Alice", Email: "alice@example.com"})
+	h.Users.Store("two", User{ID: 2, Name: "Bob"})
+	h.Shared.Store("k", "v")
+	h.Private.Store("secret", 1)
+
+	// sync.Map字段和*sync.Map字段都输出为对象，值按分组过滤
+	want := `{"users":{"1":{"id":1,"name":"Alice"},"two":{"id":2,"name":"Bob"}},"shared":{"k":"v"}}`
+	assertJSONEqual(t, marshalString(t, h, New(), "public"), want)
+	// 切片元素可寻址，同样遍历原值
+	assertJSONEqual(t, marshalString(t, []*syncMapHolder{h}, New(), "public"), "["+want+"]")
+	assertJSONEqual(t, marshalString(t, h, New(), "admin"), `{"private":{"secret":1}}`)
+
+	m, err := MarshalToMap(h, "public")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if shared, ok := m["shared"].(map[string]any); !ok || shared["k"] != "v" {
+		t.Errorf("MarshalToMap shared = %#v", m["shared"])
+	}
+	data := marshalString(t, h, New(), "public")
+	if n, err := EstimateSize(h, nil, "public"); err != nil || n != len(data) {
+		t.Errorf("EstimateSize = %d, %v; actual %d bytes", n, err, len(data))
+	}
+}
+
+func TestSyncMapEmpty(t *testing.T) {
+	var h syncMapHolder
+	// 空的sync.Map输出{}，NullIfEmpty时输出null；nil的*sync.Map与其他nil指针相同
+	assertJSONEqual(t, marshalString(t, &h, New(), "public"), `{"users":{}}`)
+	assertJSONEqual(t, marshalString(t, &h, New().WithNullIfEmpty(true), "public"), `{"users":null,"shared":null}`)
+}
+
+func TestSyncMapNotAddressable(t *testing.T) {
+	// map的值不可寻址，取出时已经被非原子地复制，不遍历副本而是返回错误
+	maps := map[string]sync.Map{"a": {}}
+	for name, marshal := range map[string]func() error{
+		"direct":   func() error { _, err := MarshalByGroups(maps); return err },
+		"map":      func() error { _, err := MarshalToValue(maps, nil); return err },
+		"estimate": func() error { _, err := EstimateSize(maps, nil); return err },
+	} {
+		var jerr *Error
+		if err := marshal(); !errors.As(err, &jerr) || jerr.Type != ErrTypeUnsupportedValue || jerr.Path != "a" {
+			t.Errorf("%s: err = %v, want unsupported value at a", name, err)
+		}
+	}
+
+	// 存放指针时照常遍历
+	ptrs := map[string]*sync.Map{"a": new(sync.Map)}
+	ptrs["a"].Store("k", 1)
+	assertJSONEqual(t, marshalString(t, ptrs, New()), `{"a":{"k":1}}`)
+}
+
+// TestSyncMapReadWhileWritten 序列化与写入同时进行，配合-race检查sync.Map是通过Range遍历的
+func TestSyncMapReadWhileWritten(t *testing.T) {
+	h := &syncMapHolder{Shared: new(sync.Map)}
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; ; i++ {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			h.Users.Store(strconv.Itoa(i%16), i)
+			h.Shared.Delete(strconv.Itoa(i % 4))
+			h.Shared.Store(strconv.Itoa(i%4), i)
+		}
+	}()
+
+	for range 200 {
+		if _, err := MarshalToMap(h, "public"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := MarshalByGroups(h, "public"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	close(done)
+	wg.Wait()
+}
@@ -0,0 +1,112 @@
+package jsongroup
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// aliasSource 包含各种可能被原样放入MarshalToMap结果的切片和map
+type aliasSource struct {
+	Tags     []string            `json:"tags"`
+	Scores   []int32             `json:"scores"`
+	Fixed    [2]string           `json:"fixed"`
+	Labels   map[string]string   `json:"labels"`
+	Index    map[string][]int    `json:"index"`
+	Raw      []byte              `json:"raw"`
+	Message  json.RawMessage     `json:"message"`
+	Extra    map[string]any      `json:"extra"`
+	Items    []any               `json:"items"`
+	Nested   [][]string          `json:"nested"`
+	Home     *Address            `json:"home"`
+	Previous []Address           `json:"previous"`
+	Seen     time.Time           `json:"seen"`
+	Groups   map[string][]string `json:"groups"`
+}
+
+func newAliasSource() *aliasSource {
+	return &aliasSource{
+		Tags:     []string{"a", "b"},
+		Scores:   []int32{1, 2},
+		Fixed:    [2]string{"x", "y"},
+		Labels:   map[string]string{"k": "v"},
+		Index:    map[string][]int{"n": {1, 2}},
+		Raw:      []byte("raw"),
+		Message:  json.RawMessage(`{"m":[1]}`),
+		Extra:    map[string]any{"list": []string{"e"}, "inner": map[string]any{"x": 1}},
+		Items:    []any{[]string{"i"}, map[string]int{"j": 1}},
+		Nested:   [][]string{{"n1"}, {"n2"}},
+		Home:     &Address{Street: "1 Main St", City: "Springfield"},
+		Previous: []Address{{City: "Shelbyville"}},
+		Seen:     time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Groups:   map[string][]string{"g": {"public"}},
+	}
+}
+
+// scribble 修改结果中所有map、切片和数组的内容，包括PreserveTypes保留了原类型的切片
+func scribble(v any) {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, e := range v {
+			scribble(e)
+			v[k] = "changed"
+		}
+		v["added"] = true
+	case []any:
+		for i, e := range v {
+			scribble(e)
+			v[i] = "changed"
+		}
+	default:
+		rv := reflect.ValueOf(v)
+		if rv.Kind() != reflect.Slice {
+			return
+		}
+		for i := range rv.Len() {
+			rv.Index(i).Set(reflect.Zero(rv.Type().Elem()))
+		}
+	}
+}
+
+// TestMarshalToMapDoesNotAliasSource 修改结果不影响源值，修改源值也不影响已返回的结果
+func TestMarshalToMapDoesNotAliasSource(t *testing.T) {
+	for _, opts := range []*Options{New(), New().WithPreserveTypes(true)} {
+		src := newAliasSource()
+		m, err := MarshalToMapWithOptions(src, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if opts.PreserveTypes {
+			// 基本类型的切片以原类型返回，但必须是副本
+			if _, ok := m["tags"].([]string); !ok {
+				t.Fatalf("tags = %#v, want []string", m["tags"])
+			}
+			if _, ok := m["scores"].([]int32); !ok {
+				t.Fatalf("scores = %#v, want []int32", m["scores"])
+			}
+		}
+
+		before, _ := json.Marshal(m)
+		scribble(m)
+		if want := newAliasSource(); !reflect.DeepEqual(src, want) {
+			t.Errorf("PreserveTypes=%v: mutating the result changed the source:\ngot  %+v\nwant %+v", opts.PreserveTypes, src, want)
+		}
+
+		// 反方向：修改源值中的切片和map后，重新得到的结果与修改前一致
+		src = newAliasSource()
+		m, err = MarshalToMapWithOptions(src, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		src.Tags[0], src.Scores[0], src.Raw[0], src.Message[2] = "z", 9, 'R', 'M'
+		src.Labels["k"], src.Index["n"][0], src.Nested[0][0] = "w", 9, "z"
+		src.Extra["list"].([]string)[0] = "z"
+		src.Extra["inner"].(map[string]any)["x"] = 2
+		src.Items[0].([]string)[0] = "z"
+		src.Home.City, src.Previous[0].City, src.Groups["g"][0] = "z", "z", "z"
+		if after, _ := json.Marshal(m); string(after) != string(before) {
+			t.Errorf("PreserveTypes=%v: mutating the source changed the result:\ngot  %s\nwant %s", opts.PreserveTypes, after, before)
+		}
+	}
+}
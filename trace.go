@@ -0,0 +1,86 @@
+package jsongroup
+
+import (
+	"context"
+	"log/slog"
+	"reflect"
+)
+
+// fieldDecision 字段过滤的结果，记录在跟踪日志的decision属性中，也作为OnFieldExcluded的reason
+type fieldDecision string
+
+const (
+	// decisionIncluded 字段被输出（包括启用NullIfEmpty时输出的null）
+	decisionIncluded fieldDecision = "included"
+	// decisionExcludedByGroup 字段不属于请求的分组
+	decisionExcludedByGroup fieldDecision = "excluded_by_group"
+	// decisionOmittedEmpty 字段因omitempty、omitzero或值为nil被省略
+	decisionOmittedEmpty fieldDecision = "omitted_empty"
+	// decisionNilSkipped 启用IgnoreNilPointers时跳过的nil指针字段
+	decisionNilSkipped fieldDecision = "nil_skipped"
+	// decisionExcludedByValue 字段被ValueFilter排除
+	decisionExcludedByValue fieldDecision = "excluded_by_value"
+)
+
+// traceKey 跟踪日志的去重键，同一类型的同一字段每种结果只记录一次
+type traceKey struct {
+	typ      reflect.Type
+	field    string
+	decision fieldDecision
+}
+
+// observing 判断是否需要记录字段的过滤结果，TraceLogger、MetricsHook、DeprecationHook和XRay均未设置时只有四次判断
+func (ctx *serializeContext) observing() bool {
+	return ctx.opts.TraceLogger != nil || ctx.opts.MetricsHook != nil || ctx.opts.DeprecationHook != nil || ctx.xray != nil
+}
+
+// emptyDecision 返回空值字段的过滤结果：启用NullIfEmpty时输出为null，否则被省略
+func emptyDecision(ctx *serializeContext) fieldDecision {
+	if ctx.opts.NullIfEmpty {
+		return decisionIncluded
+	}
+	return decisionOmittedEmpty
+}
+
+// recordField 记录结构体类型t中字段的过滤结果
+// 跟踪日志在同一次序列化中每种结果只记录第一次出现的路径，大切片的日志量因此与元素数无关；
+// 指标回调对每个未输出的字段都会调用
+func (ctx *serializeContext) recordField(t reflect.Type, field fieldInfo, groups []string, decision fieldDecision) {
+	if ctx.xray != nil {
+		ctx.recordXRay(field, groups, decision)
+	}
+	if decision == decisionIncluded {
+		ctx.reportDeprecated(t, field)
+	}
+	if h := ctx.opts.MetricsHook; h != nil && decision != decisionIncluded {
+		h.OnFieldExcluded(t.String(), field.JSONName, string(decision))
+	}
+	if ctx.opts.TraceLogger != nil && ctx.firstTrace(traceKey{typ: t, field: field.Name, decision: decision}) {
+		ctx.opts.TraceLogger.LogAttrs(context.Background(), slog.LevelDebug, "jsongroup: 字段过滤",
+			slog.String("path", ctx.withPath(field.Name).path()),
+			slog.String("type", t.String()),
+			slog.Any("groups", field.Groups),
+			slog.Any("requested", groups),
+			slog.String("decision", string(decision)),
+		)
+	}
+}
+
+// recordExcluded 记录结构体中不属于请求分组的字段
+func (ctx *serializeContext) recordExcluded(t reflect.Type, set *fieldSet, groups []string) {
+	for _, field := range set.excluded {
+		ctx.recordField(t, field, groups, decisionExcludedByGroup)
+	}
+}
+
+// firstTrace 登记去重键，第一次出现时返回true
+func (ctx *serializeContext) firstTrace(key traceKey) bool {
+	if _, seen := ctx.state.traced[key]; seen {
+		return false
+	}
+	if ctx.state.traced == nil {
+		ctx.state.traced = make(map[traceKey]struct{})
+	}
+	ctx.state.traced[key] = struct{}{}
+	return true
+}
@@ -0,0 +1,65 @@
+package jsongroup
+
+import (
+	"reflect"
+	"unicode/utf8"
+)
+
+// truncatedEnd 返回字符串保留前n个字符时的字节偏移，字符数不超过n或n不为正数时返回false
+// 按UTF-8字符计数，偏移总是落在字符边界上，不会拆开多字节序列
+func truncatedEnd(s string, n int) (int, bool) {
+	// 字节数不超过n时字符数必然不超过n
+	if n <= 0 || len(s) <= n {
+		return 0, false
+	}
+	i := 0
+	for range n {
+		_, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+		if i >= len(s) {
+			return 0, false
+		}
+	}
+	return i, true
+}
+
+// truncate 按MaxStringLen截断字符串并追加MaxStringMarker，截断时记录当前路径
+func (ctx *serializeContext) truncate(s string) string {
+	end, ok := truncatedEnd(s, ctx.opts.MaxStringLen)
+	if !ok {
+		return s
+	}
+	ctx.state.truncated = append(ctx.state.truncated, ctx.path())
+	return s[:end] + ctx.opts.MaxStringMarker
+}
+
+// TruncatedSliceKey 启用MarkTruncatedSlices时，追加在被截断切片末尾的标记对象中记录剩余元素数的键名
+// 迭代器无法得知剩余元素数，标记值为true
+const TruncatedSliceKey = "$truncated"
+
+// limitSlice 返回按MaxSliceLen限制后需要输出的元素数，发生截断时记录当前路径
+func (ctx *serializeContext) limitSlice(length int) int {
+	if n := ctx.opts.MaxSliceLen; n > 0 && length > n {
+		ctx.state.truncated = append(ctx.state.truncated, ctx.path())
+		return n
+	}
+	return length
+}
+
+// markTruncated 启用MarkTruncatedSlices且有remaining个元素未输出时，在结果末尾追加{"$truncated":remaining}
+func (ctx *serializeContext) markTruncated(result []any, remaining int) []any {
+	if remaining <= 0 || !ctx.opts.MarkTruncatedSlices {
+		return result
+	}
+	return append(result, map[string]any{TruncatedSliceKey: int64(remaining)})
+}
+
+// truncatesField 判断结构体字段的值是否需要按MaxStringLen截断
+// 未设置MaxStringLen时只有一次比较，基本类型字段仍走预编译的快速路径
+func (ctx *serializeContext) truncatesField(v reflect.Value) bool {
+	if ctx.opts.MaxStringLen <= 0 || v.Kind() != reflect.String {
+		return false
+	}
+	_, ok := truncatedEnd(v.String(), ctx.opts.MaxStringLen)
+	return ok
+}
@@ -0,0 +1,87 @@
+package jsongroup
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// typeOptionsRegistry RegisterTypeOptions注册的按类型选项
+// 注册时复制整个映射后原子替换，序列化过程中读取无需加锁；未注册任何类型时为nil，只需一次原子读取
+var typeOptionsRegistry atomic.Pointer[map[reflect.Type]*Options]
+
+// typeOptionsMu 串行化注册操作
+var typeOptionsMu sync.Mutex
+
+// typeOptionsKey 单次序列化内合并结果的缓存键：同一父选项下进入同一类型时复用合并后的选项
+type typeOptionsKey struct {
+	parent *Options
+	typ    reflect.Type
+}
+
+// RegisterTypeOptions 为结构体类型t注册默认选项，无论调用方传入什么选项，该类型的值都按注册的选项序列化
+// 序列化进入t类型的值时，opts中显式设置过的字段（与Merge的规则相同）叠加到调用方的选项上，只作用于该值及其子树；
+// 递归深度和循环引用检测沿用父级的状态。注册的选项优先于调用方的选项，嵌套的注册类型在父级叠加结果上再叠加自身的选项，
+// 即离值最近的注册优先。顶层包装、集合包装、Envelope、编码后端和指标回调只在根值处生效，在子树中叠加不起作用；
+// 以匿名字段内嵌的结构体合并到外层对象，按外层的选项处理。
+// 保存的是opts的副本，opts为nil时取消注册；注册的选项无效时panic，通常在init中调用，可以与序列化并发进行
+func RegisterTypeOptions(t reflect.Type, opts *Options) {
+	if opts != nil {
+		if err := opts.Validate(); err != nil {
+			panic(fmt.Sprintf("jsongroup: RegisterTypeOptions(%s): %v", t, err))
+		}
+		opts = opts.Clone()
+	}
+
+	typeOptionsMu.Lock()
+	defer typeOptionsMu.Unlock()
+
+	next := make(map[reflect.Type]*Options)
+	if cur := typeOptionsRegistry.Load(); cur != nil {
+		maps.Copy(next, *cur)
+	}
+	if opts == nil {
+		delete(next, t)
+	} else {
+		next[t] = opts
+	}
+	if len(next) == 0 {
+		typeOptionsRegistry.Store(nil)
+		return
+	}
+	typeOptionsRegistry.Store(&next)
+}
+
+// withTypeOptions 返回按t注册的选项叠加后的上下文，t未注册时返回ctx本身
+// 子上下文的路径与ctx相同，深度和状态继续共享
+func (ctx *serializeContext) withTypeOptions(t reflect.Type, groups []string) *serializeContext {
+	registry := typeOptionsRegistry.Load()
+	if registry == nil {
+		return ctx
+	}
+	registered, ok := (*registry)[t]
+	if !ok {
+		return ctx
+	}
+
+	key := typeOptionsKey{parent: ctx.opts, typ: t}
+	merged, ok := ctx.state.typeOpts[key]
+	if !ok {
+		merged = ctx.opts.Merge(registered)
+		merged.fillDefaults()
+		if ctx.state.typeOpts == nil {
+			ctx.state.typeOpts = make(map[typeOptionsKey]*Options)
+		}
+		ctx.state.typeOpts[key] = merged
+	}
+
+	child := ctx.withPath("")
+	child.opts = merged
+	if !slices.Equal(merged.DenyGroups, ctx.opts.DenyGroups) {
+		child.groupKey = filterGroupKey(groups, merged.DenyGroups)
+	}
+	return child
+}
@@ -0,0 +1,103 @@
+package jsongroup
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// 测试中共用的数据结构
+
+type Address struct {
+	Street string `json:"street" groups:"public,admin"`
+	City   string `json:"city" groups:"public,admin"`
+	Zip    string `json:"zip,omitempty" groups:"admin"`
+}
+
+type User struct {
+	ID       int      `json:"id" groups:"public,admin"`
+	Name     string   `json:"name" groups:"public,admin"`
+	Email    string   `json:"email" groups:"admin"`
+	Password string   `json:"password"`
+	Address  *Address `json:"address,omitempty" groups:"public,admin"`
+}
+
+type Social struct {
+	Platform string `json:"platform"`
+	Handle   string `json:"handle"`
+}
+
+type Profile struct {
+	Bio     string   `json:"bio" groups:"public"`
+	Website string   `json:"website" groups:"public"`
+	Socials []Social `json:"socials" groups:"public"`
+}
+
+type Stats struct {
+	Followers int     `json:"followers"`
+	Following int     `json:"following"`
+	Score     float64 `json:"score"`
+}
+
+type ComplexUser struct {
+	ID        int               `json:"id" groups:"public,admin,internal"`
+	Name      string            `json:"name" groups:"public,admin"`
+	Email     string            `json:"email" groups:"admin"`
+	Phone     string            `json:"phone,omitempty" groups:"admin"`
+	Active    bool              `json:"active" groups:"public,admin"`
+	CreatedAt time.Time         `json:"created_at" groups:"admin,internal"`
+	Tags      []string          `json:"tags" groups:"public"`
+	Labels    map[string]string `json:"labels" groups:"internal"`
+	Profile   Profile           `json:"profile" groups:"public,admin"`
+	Stats     Stats             `json:"stats" groups:"public,internal"`
+	Addresses []Address         `json:"addresses" groups:"admin"`
+	Notes     string            `json:"notes" groups:"internal"`
+}
+
+// newComplexUser 返回字段都已填充的ComplexUser
+func newComplexUser(id int) ComplexUser {
+	return ComplexUser{
+		ID:        id,
+		Name:      "Alice",
+		Email:     "alice@example.com",
+		Active:    true,
+		CreatedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Tags:      []string{"go", "json"},
+		Labels:    map[string]string{"tier": "gold"},
+		Profile: Profile{
+			Bio:     "hello",
+			Website: "https://example.com",
+			Socials: []Social{{Platform: "github", Handle: "alice"}, {Platform: "x", Handle: "@alice"}},
+		},
+		Stats:     Stats{Followers: 10, Following: 3, Score: 4.5},
+		Addresses: []Address{{Street: "1 Main St", City: "Springfield", Zip: "12345"}},
+		Notes:     "vip",
+	}
+}
+
+// marshalString 序列化v并在出错时终止测试
+func marshalString(t testing.TB, v any, opts *Options, groups ...string) string {
+	t.Helper()
+	data, err := MarshalByGroupsWithOptions(v, opts, groups...)
+	if err != nil {
+		t.Fatalf("MarshalByGroupsWithOptions: %v", err)
+	}
+	return string(data)
+}
+
+// assertJSONEqual 按JSON语义比较got和want，与键的顺序和空白无关
+func assertJSONEqual(t testing.TB, got, want string) {
+	t.Helper()
+	var g, w any
+	if err := json.Unmarshal([]byte(got), &g); err != nil {
+		t.Fatalf("invalid JSON %s: %v", got, err)
+	}
+	if err := json.Unmarshal([]byte(want), &w); err != nil {
+		t.Fatalf("invalid expected JSON %s: %v", want, err)
+	}
+	gb, _ := json.Marshal(g)
+	wb, _ := json.Marshal(w)
+	if string(gb) != string(wb) {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+}
@@ -0,0 +1,28 @@
+package jsongroup
+
+import (
+	"fmt"
+	"reflect"
+	"unicode/utf8"
+)
+
+// checkUTF8 启用InvalidUTF8PolicyError时检查字符串，包含无效的UTF-8字节时返回带当前路径的ErrTypeUnsupportedValue错误
+// what说明字符串所处的位置，如"字符串"或"map的键"
+func (ctx *serializeContext) checkUTF8(v reflect.Value, s, what string) error {
+	if ctx.opts.InvalidUTF8Policy != InvalidUTF8PolicyError || utf8.ValidString(s) {
+		return nil
+	}
+	return UnsupportedValueError(ctx.path(), v, fmt.Sprintf("%s第%d个字节处包含无效的UTF-8", what, invalidUTF8Offset(s)))
+}
+
+// invalidUTF8Offset 返回s中第一个无效UTF-8字节的位置，s有效时返回-1
+func invalidUTF8Offset(s string) int {
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			return i
+		}
+		i += size
+	}
+	return -1
+}
@@ -0,0 +1,17 @@
+package jsongroup
+
+import "reflect"
+
+// ValueFilter 按字段的值决定是否输出，对已通过分组过滤的结构体字段调用，返回false时省略该字段
+// path为字段的完整路径，owner为字段所在的结构体值，可用于依赖其他字段的判断；field与FieldHook收到的描述相同。
+// owner和value可能是可寻址的，回调只能读取，不得通过反射修改它们，否则会改变调用方的数据以及其余字段的输出
+type ValueFilter func(path string, owner reflect.Value, field FieldDescriptor, value reflect.Value) bool
+
+// keepsField 对字段调用ValueFilter，返回是否输出该字段；ctx为字段的上下文，parent为字段所在的结构体值
+// 在FieldHook之前调用，value为字段的原始值；合并到外层对象的内嵌结构体本身不调用，只对其中的字段调用
+func (ctx *serializeContext) keepsField(parent reflect.Value, field fieldInfo, v reflect.Value) bool {
+	if field.Anonymous && v.Kind() == reflect.Struct {
+		return true
+	}
+	return ctx.opts.ValueFilter(ctx.path(), parent, newFieldDescriptor(parent, field), v)
+}
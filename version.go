@@ -0,0 +1,136 @@
+package jsongroup
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// versionRegistry RegisterVersion注册的API版本：版本名 -> 版本声明
+// 与fieldGroupsRegistry相同，注册时复制整个映射后原子替换；未注册任何版本时为nil
+var versionRegistry atomic.Pointer[map[string]*versionSpec]
+
+// versionMu 串行化注册操作
+var versionMu sync.Mutex
+
+// versionSpec 单个API版本的声明，分组在查询时沿继承链计算，重新注册父版本后子版本随之变化
+type versionSpec struct {
+	parent  string
+	add     []string
+	remove  []string
+	inherit bool
+}
+
+// VersionOption RegisterVersion的选项
+type VersionOption func(*versionSpec)
+
+// InheritFrom 以已注册的版本parent的分组为起点
+func InheritFrom(parent string) VersionOption {
+	return func(s *versionSpec) { s.parent, s.inherit = parent, true }
+}
+
+// AddGroups 在继承的分组之后追加分组，多次调用时依次追加
+func AddGroups(groups ...string) VersionOption {
+	return func(s *versionSpec) { s.add = append(s.add, groups...) }
+}
+
+// RemoveGroups 从继承和追加的分组中移除分组，多次调用时取并集
+func RemoveGroups(groups ...string) VersionOption {
+	return func(s *versionSpec) { s.remove = append(s.remove, groups...) }
+}
+
+// RegisterVersion 注册API版本name对应的分组集合，适合把逐步累积的版本（v1 ⊂ v2 ⊂ v3）集中声明在一处
+// 版本的分组为继承的父版本的分组加上AddGroups追加的分组，再去掉RemoveGroups移除的分组，重复的分组只保留第一次出现的位置。
+// 父版本必须已经注册，重新注册已有的版本时替换之前的声明，继承它的版本随之变化；继承关系形成环时返回错误，注册不生效。
+// 通常在init中调用，可以与序列化并发进行
+func RegisterVersion(name string, opts ...VersionOption) error {
+	if name == "" {
+		return InvalidOptionsError([]string{"版本名不能为空"})
+	}
+	spec := &versionSpec{}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(spec)
+		}
+	}
+	spec.add = slices.Clone(spec.add)
+	spec.remove = slices.Clone(spec.remove)
+
+	versionMu.Lock()
+	defer versionMu.Unlock()
+
+	next := make(map[string]*versionSpec)
+	if cur := versionRegistry.Load(); cur != nil {
+		maps.Copy(next, *cur)
+	}
+	if spec.inherit {
+		if _, ok := next[spec.parent]; !ok {
+			return InvalidOptionsError([]string{fmt.Sprintf("版本%s继承的版本%s未注册", name, spec.parent)})
+		}
+		// 父版本已注册，其继承链上出现name时说明重新注册后会形成环
+		for p := spec.parent; ; {
+			if p == name {
+				return InvalidOptionsError([]string{fmt.Sprintf("版本%s的继承关系形成环", name)})
+			}
+			parent := next[p]
+			if !parent.inherit {
+				break
+			}
+			p = parent.parent
+		}
+	}
+	next[name] = spec
+	versionRegistry.Store(&next)
+	return nil
+}
+
+// GroupsForVersion 返回API版本对应的分组，每次调用返回新的切片
+// 版本未注册时返回ErrTypeUnknownVersion错误
+func GroupsForVersion(version string) ([]string, error) {
+	registry := versionRegistry.Load()
+	if registry == nil {
+		return nil, UnknownVersionError(version)
+	}
+	spec, ok := (*registry)[version]
+	if !ok {
+		return nil, UnknownVersionError(version)
+	}
+	return spec.groups(*registry), nil
+}
+
+// groups 沿继承链计算版本的分组，注册时已排除环和未注册的父版本
+func (s *versionSpec) groups(registry map[string]*versionSpec) []string {
+	var groups []string
+	if s.inherit {
+		groups = registry[s.parent].groups(registry)
+	}
+	for _, g := range s.add {
+		if !slices.Contains(groups, g) {
+			groups = append(groups, g)
+		}
+	}
+	return slices.DeleteFunc(groups, func(g string) bool { return slices.Contains(s.remove, g) })
+}
+
+// resolveGroups 返回一次调用实际请求的分组：设置了Version时在版本的分组之后追加调用时指定的分组，
+// 再按StrictDenyGroups检查被禁止的分组；版本未注册时返回ErrTypeUnknownVersion错误
+func (o *Options) resolveGroups(groups []string) ([]string, error) {
+	if o.Version != "" {
+		resolved, err := GroupsForVersion(o.Version)
+		if err != nil {
+			return nil, err
+		}
+		for _, g := range groups {
+			if !slices.Contains(resolved, g) {
+				resolved = append(resolved, g)
+			}
+		}
+		groups = resolved
+	}
+	if err := o.checkDeniedGroups(groups); err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
@@ -0,0 +1,69 @@
+package jsongroup
+
+import "slices"
+
+// DefaultXRayKey 启用XRay时附加在每个对象中的元数据键名，Options.XRayKey为空时使用
+const DefaultXRayKey = "__jsongroup"
+
+// xrayObject 单个结构体对象的过滤元数据，由recordField在与跟踪日志相同的判断点填入
+// 以匿名字段内嵌的结构体与外层对象共用同一份记录
+type xrayObject struct {
+	// 未输出的字段：json名称 -> 原因
+	excluded map[string]any
+	// 输出的字段：json名称 -> 匹配的分组，只在启用XRayMatchedGroups时记录
+	included map[string]any
+}
+
+// beginXRay 在编码结构体前为ctx准备元数据记录，ctx已持有记录（内嵌字段）时返回false，由外层对象负责输出
+func (ctx *serializeContext) beginXRay() bool {
+	if !ctx.opts.XRay || ctx.xray != nil {
+		return false
+	}
+	ctx.xray = &xrayObject{excluded: make(map[string]any)}
+	if ctx.opts.XRayMatchedGroups {
+		ctx.xray.included = make(map[string]any)
+	}
+	return true
+}
+
+// endXRay 取出ctx的元数据记录并转换为中间表示，同时清除记录
+// 没有未输出的字段时省略excluded键，元数据仍然输出，以表明该对象经过了检查
+func (ctx *serializeContext) endXRay() map[string]any {
+	x := ctx.xray
+	ctx.xray = nil
+	meta := make(map[string]any, 2)
+	if len(x.excluded) > 0 {
+		meta["excluded"] = x.excluded
+	}
+	if x.included != nil {
+		meta["included"] = x.included
+	}
+	return meta
+}
+
+// xrayKey 返回元数据的键名
+func (o *Options) xrayKey() string {
+	if o.XRayKey == "" {
+		return DefaultXRayKey
+	}
+	return o.XRayKey
+}
+
+// recordXRay 将字段的过滤结果记入当前对象的元数据
+func (ctx *serializeContext) recordXRay(field fieldInfo, groups []string, decision fieldDecision) {
+	if decision != decisionIncluded {
+		ctx.xray.excluded[field.JSONName] = string(decision)
+		return
+	}
+	if ctx.xray.included == nil {
+		return
+	}
+	// 匹配的分组为字段分组中被请求且未被禁止的部分
+	matched := []any{}
+	for _, g := range field.Groups {
+		if slices.Contains(groups, g) && !slices.Contains(ctx.opts.DenyGroups, g) {
+			matched = append(matched, g)
+		}
+	}
+	ctx.xray.included[field.JSONName] = matched
+}
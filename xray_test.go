@@ -0,0 +1,57 @@
+package jsongroup
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+type xrayInner struct {
+	City string `json:"city" groups:"public"`
+}
+
+// XRayBase 导出的内嵌类型，未导出的内嵌类型不参与序列化
+type XRayBase struct {
+	Created string `json:"created" groups:"admin"`
+}
+
+type xrayOuter struct {
+	XRayBase
+	Name  string      `json:"name" groups:"public"`
+	Email string      `json:"email" groups:"admin"`
+	Bio   string      `json:"bio,omitempty" groups:"public"`
+	Home  xrayInner   `json:"home" groups:"public"`
+	Rows  []xrayInner `json:"rows" groups:"public"`
+}
+
+// assertXRay 检查直接编码和MarshalToMap的结果一致
+func assertXRay(t *testing.T, v any, opts *Options, want string, groups ...string) {
+	t.Helper()
+	assertJSONEqual(t, marshalString(t, v, opts, groups...), want)
+	m, err := MarshalToMapWithOptions(v, opts, groups...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mapped, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertJSONEqual(t, string(mapped), want)
+}
+
+func TestXRay(t *testing.T) {
+	v := &xrayOuter{Name: "n", Email: "e", Home: xrayInner{City: "c"}, Rows: []xrayInner{{City: "r"}}}
+
+	// 没有未输出字段的对象省略excluded键，但仍附加元数据；内嵌结构体的字段记在外层对象中
+	assertXRay(t, v, New().WithXRay(true),
+		`{"name":"n","home":{"city":"c","__jsongroup":{}},"rows":[{"city":"r","__jsongroup":{}}],`+
+			`"__jsongroup":{"excluded":{"created":"excluded_by_group","email":"excluded_by_group","bio":"omitted_empty"}}}`,
+		"public")
+
+	assertXRay(t, v, New().WithXRay(true).WithXRayMatchedGroups(true).WithXRayKey("_x"),
+		`{"created":"","email":"e","_x":{"excluded":{"name":"excluded_by_group","bio":"excluded_by_group",`+
+			`"home":"excluded_by_group","rows":"excluded_by_group"},"included":{"created":["admin"],"email":["admin"]}}}`,
+		"admin")
+
+	assertXRay(t, &xrayInner{City: "c"}, New().WithXRay(true).WithXRayMatchedGroups(true),
+		`{"city":"c","__jsongroup":{"included":{"city":["public"]}}}`, "public")
+}
@@ -0,0 +1,256 @@
+// Package yaml 按分组将值序列化为YAML
+//
+// 过滤规则、NullIfEmpty、深度限制和循环引用检测与jsongroup完全一致：
+// 先按分组直接编码为JSON，再按原有顺序逐个读取JSON标记并输出YAML，
+// 因此字段保持声明顺序，数值保留原始字面量，不依赖任何第三方YAML库。
+package yaml
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/JieBaiYou/jsongroup"
+)
+
+// MarshalYAMLByGroups 按指定分组将v序列化为YAML
+// time.Time与JSON输出一样表示为RFC3339格式的字符串，含换行的字符串使用字面量块（|）输出；
+// ErrorPolicyCollect策略下记录了字段错误时，同时返回输出和汇总的错误
+func MarshalYAMLByGroups(v any, opts *jsongroup.Options, groups ...string) ([]byte, error) {
+	data, err := jsongroup.MarshalByGroupsWithOptions(v, opts, groups...)
+	if data == nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	root, decErr := readNode(dec)
+	if decErr != nil {
+		return nil, decErr
+	}
+
+	var buf bytes.Buffer
+	writeNode(&buf, root, 0, false)
+	return buf.Bytes(), err
+}
+
+// member 对象中的一个键值对
+type member struct {
+	key   string
+	value any
+}
+
+// object 保持键顺序的对象
+type object []member
+
+// readNode 读取一个完整的JSON值，对象读取为object，数组读取为[]any，数值保留为json.Number
+func readNode(dec *json.Decoder) (any, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		return tok, nil
+	}
+
+	switch delim {
+	case '{':
+		obj := object{}
+		for dec.More() {
+			keyTok, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := readNode(dec)
+			if err != nil {
+				return nil, err
+			}
+			obj = append(obj, member{key: keyTok.(string), value: value})
+		}
+		_, err = dec.Token()
+		return obj, err
+	default:
+		list := []any{}
+		for dec.More() {
+			value, err := readNode(dec)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		_, err = dec.Token()
+		return list, err
+	}
+}
+
+// writeNode 输出一个值并以换行结束
+// inline为true时当前行已写入"- "，值的第一行直接接在后面，其余行按indent缩进
+func writeNode(buf *bytes.Buffer, node any, indent int, inline bool) {
+	switch n := node.(type) {
+	case object:
+		if len(n) == 0 {
+			buf.WriteString("{}\n")
+			return
+		}
+		for i, m := range n {
+			if i > 0 || !inline {
+				writeIndent(buf, indent)
+			}
+			buf.WriteString(formatKey(m.key))
+			buf.WriteByte(':')
+			writeChild(buf, m.value, indent)
+		}
+	case []any:
+		if len(n) == 0 {
+			buf.WriteString("[]\n")
+			return
+		}
+		for i, item := range n {
+			if i > 0 || !inline {
+				writeIndent(buf, indent)
+			}
+			buf.WriteString("- ")
+			writeNode(buf, item, indent+2, true)
+		}
+	case string:
+		if isBlockCandidate(n) {
+			writeBlock(buf, n, indent)
+			return
+		}
+		buf.WriteString(formatString(n))
+		buf.WriteByte('\n')
+	default:
+		buf.WriteString(formatScalar(n))
+		buf.WriteByte('\n')
+	}
+}
+
+// writeChild 输出对象成员的值，冒号已写入
+func writeChild(buf *bytes.Buffer, value any, indent int) {
+	switch v := value.(type) {
+	case object:
+		if len(v) > 0 {
+			buf.WriteByte('\n')
+			writeNode(buf, v, indent+2, false)
+			return
+		}
+	case []any:
+		if len(v) > 0 {
+			buf.WriteByte('\n')
+			writeNode(buf, v, indent+2, false)
+			return
+		}
+	case string:
+		if isBlockCandidate(v) {
+			buf.WriteByte(' ')
+			writeBlock(buf, v, indent+2)
+			return
+		}
+	}
+	buf.WriteByte(' ')
+	writeNode(buf, value, indent+2, true)
+}
+
+// writeIndent 写入缩进空格
+func writeIndent(buf *bytes.Buffer, indent int) {
+	for range indent {
+		buf.WriteByte(' ')
+	}
+}
+
+// isBlockCandidate 判断字符串是否可以用字面量块输出
+// 只有含换行、首行不以空白开头且不含其他控制字符的字符串才使用字面量块，其余情况使用双引号
+func isBlockCandidate(s string) bool {
+	if !strings.Contains(s, "\n") {
+		return false
+	}
+	if s[0] == ' ' || s[0] == '\t' || s[0] == '\n' {
+		return false
+	}
+	for _, r := range s {
+		if r < 0x20 && r != '\n' && r != '\t' {
+			return false
+		}
+		if r == 0x7f || r == '\u0085' || r == '\u2028' || r == '\u2029' || r == '\ufeff' {
+			return false
+		}
+	}
+	return true
+}
+
+// writeBlock 以字面量块输出字符串，内容行缩进indent，结尾换行数决定保留方式（|-、|、|+）
+func writeBlock(buf *bytes.Buffer, s string, indent int) {
+	body := strings.TrimRight(s, "\n")
+	switch trailing := len(s) - len(body); {
+	case trailing == 0:
+		buf.WriteString("|-\n")
+	case trailing == 1:
+		buf.WriteString("|\n")
+	default:
+		buf.WriteString("|+\n")
+	}
+	for _, line := range strings.Split(body, "\n") {
+		if line != "" {
+			writeIndent(buf, indent)
+			buf.WriteString(line)
+		}
+		buf.WriteByte('\n')
+	}
+	// |+保留的多余换行以空行表示
+	for range len(s) - len(body) - 1 {
+		buf.WriteByte('\n')
+	}
+}
+
+// formatKey 格式化对象的键
+func formatKey(key string) string {
+	return formatString(key)
+}
+
+// formatString 格式化字符串标量，可能被解析为其他类型或含特殊字符的字符串使用双引号
+func formatString(s string) string {
+	if isPlainSafe(s) {
+		return s
+	}
+	return strconv.Quote(s)
+}
+
+// formatScalar 格式化数值、布尔值和null
+func formatScalar(v any) string {
+	switch v := v.(type) {
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	case nil:
+		return "null"
+	default:
+		return formatString(v.(string))
+	}
+}
+
+// reservedWords 不加引号时会被YAML解析为布尔值或null的单词
+var reservedWords = map[string]bool{
+	"y": true, "n": true, "yes": true, "no": true, "on": true, "off": true,
+	"true": true, "false": true, "null": true,
+}
+
+// isPlainSafe 判断字符串能否不加引号输出
+// 只允许以字母或下划线开头、由字母数字和少量标点组成的字符串，避免被解析为数值、时间或特殊值
+func isPlainSafe(s string) bool {
+	if s == "" || reservedWords[strings.ToLower(s)] || s[len(s)-1] == ' ' {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c == '_':
+		case i > 0 && (c >= '0' && c <= '9' || c == '-' || c == '.' || c == '/' || c == ' '):
+		default:
+			return false
+		}
+	}
+	return true
+}
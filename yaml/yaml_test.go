@@ -0,0 +1,248 @@
+package yaml
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/JieBaiYou/jsongroup"
+)
+
+type address struct {
+	Street string `json:"street" groups:"public"`
+	City   string `json:"city" groups:"public,admin"`
+	Zip    string `json:"zip,omitempty" groups:"admin"`
+}
+
+type account struct {
+	ID       int               `json:"id" groups:"public,admin"`
+	Name     string            `json:"name" groups:"public"`
+	Note     string            `json:"note" groups:"public"`
+	Tags     []string          `json:"tags" groups:"public"`
+	Matrix   [][]int           `json:"matrix" groups:"public"`
+	Address  *address          `json:"address" groups:"public,admin"`
+	History  []address         `json:"history" groups:"admin"`
+	Labels   map[string]string `json:"labels" groups:"public"`
+	Score    float64           `json:"score" groups:"public"`
+	Active   bool              `json:"active" groups:"public"`
+	Password string            `json:"password" groups:"internal"`
+}
+
+func newAccount() account {
+	return account{
+		ID:      1,
+		Name:    "yes",
+		Note:    "line one\n  indented\n\nlast\n\n",
+		Tags:    []string{"", "123", "a: b", "#comment", " lead", "null", "plain text", "tab\there", " "},
+		Matrix:  [][]int{{1, 2}, {}, {3}},
+		Address: &address{Street: "1 Main St", City: "Springfield", Zip: "12345"},
+		History: []address{{City: "Shelbyville"}, {City: "Ogdenville", Zip: "2"}},
+		Labels:  map[string]string{"tier": "gold", "key with space": "x", "0": "zero"},
+		Score:   -1.5e-7,
+		Active:  true,
+	}
+}
+
+// TestMarshalYAMLRoundTrip 解析输出的YAML，结果应与同样选项和分组下的JSON输出相同
+func TestMarshalYAMLRoundTrip(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		v      any
+		opts   *jsongroup.Options
+		groups []string
+	}{
+		{"public", newAccount(), jsongroup.New(), []string{"public"}},
+		{"admin", newAccount(), jsongroup.New(), []string{"admin"}},
+		{"no groups", newAccount(), jsongroup.New(), nil},
+		{"null if empty", account{}, jsongroup.New().WithNullIfEmpty(true), []string{"public", "admin"}},
+		{"top level key", []account{newAccount(), {}}, jsongroup.New().WithTopLevelKey("data"), []string{"public"}},
+		{"block strings", []string{"a\nb", "a\n", "a\n\n\n", "\nleading"}, nil, nil},
+		{"root scalar", "multi\nline", nil, nil},
+		{"root number", 42, nil, nil},
+		{"empty object", address{}, nil, []string{"none"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := MarshalYAMLByGroups(tc.v, tc.opts, tc.groups...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			jsonData, err := jsongroup.MarshalByGroupsWithOptions(tc.v, tc.opts, tc.groups...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			dec := json.NewDecoder(bytes.NewReader(jsonData))
+			dec.UseNumber()
+			var want any
+			if err := dec.Decode(&want); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := parseYAML(string(data))
+			if err != nil {
+				t.Fatalf("%v in\n%s", err, data)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("YAML decodes to\n%#v\nwant\n%#v\nYAML:\n%s", got, want, data)
+			}
+		})
+	}
+}
+
+// TestMarshalYAMLKeepsFieldOrder 字段按声明顺序输出
+func TestMarshalYAMLKeepsFieldOrder(t *testing.T) {
+	data, err := MarshalYAMLByGroups(address{Street: "s", City: "c", Zip: "z"}, nil, "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "city: c\nzip: z\n"; string(data) != want {
+		t.Errorf("got %q, want %q", data, want)
+	}
+}
+
+func TestMarshalYAMLErrors(t *testing.T) {
+	type holder struct {
+		Name string   `json:"name" groups:"public"`
+		Ch   chan int `json:"ch" groups:"public"`
+	}
+	v := holder{Name: "a", Ch: make(chan int)}
+	if _, err := MarshalYAMLByGroups(v, nil, "public"); !errors.Is(err, jsongroup.ErrUnsupportedType) {
+		t.Errorf("got %v, want ErrUnsupportedType", err)
+	}
+
+	// Collect策略下同时返回输出和错误
+	data, err := MarshalYAMLByGroups(v, jsongroup.New().WithErrorPolicy(jsongroup.ErrorPolicyCollect), "public")
+	if !errors.Is(err, jsongroup.ErrUnsupportedType) || string(data) != "name: a\nch: null\n" {
+		t.Errorf("got %q, %v", data, err)
+	}
+}
+
+// parseYAML 解析MarshalYAMLByGroups输出的YAML子集：块映射、块序列、双引号和普通标量、字面量块以及空的{}和[]
+// 对象解析为map[string]any，数值保留为json.Number，与json.Decoder.UseNumber的结果可直接比较
+func parseYAML(doc string) (any, error) {
+	if !strings.HasSuffix(doc, "\n") {
+		return nil, errors.New("document does not end with a newline")
+	}
+	p := &yamlParser{lines: strings.Split(strings.TrimSuffix(doc, "\n"), "\n")}
+	v, err := p.node(0)
+	if err == nil && p.i != len(p.lines) {
+		err = errors.New("unexpected content at line " + strconv.Itoa(p.i+1))
+	}
+	return v, err
+}
+
+type yamlParser struct {
+	lines []string
+	i     int
+}
+
+// node 解析从当前行开始、缩进为indent的值
+func (p *yamlParser) node(indent int) (any, error) {
+	line := p.lines[p.i][indent:]
+	if strings.HasPrefix(line, "- ") {
+		list := []any{}
+		for p.i < len(p.lines) && strings.HasPrefix(p.lines[p.i], strings.Repeat(" ", indent)+"- ") {
+			// 序列项的第一行按缩进indent+2的行处理
+			p.lines[p.i] = strings.Repeat(" ", indent+2) + p.lines[p.i][indent+2:]
+			item, err := p.node(indent + 2)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, item)
+		}
+		return list, nil
+	}
+
+	if _, rest, ok := splitKey(line); ok && rest != line {
+		obj := map[string]any{}
+		for p.i < len(p.lines) && len(p.lines[p.i]) > indent && p.lines[p.i][:indent] == strings.Repeat(" ", indent) && p.lines[p.i][indent] != ' ' {
+			key, rest, ok := splitKey(p.lines[p.i][indent:])
+			if !ok {
+				return nil, errors.New("expected a key at line " + strconv.Itoa(p.i+1))
+			}
+			var value any
+			var err error
+			if rest == "" {
+				p.i++
+				value, err = p.node(indent + 2)
+			} else {
+				value, err = p.scalar(strings.TrimPrefix(rest, " "), indent+2)
+			}
+			if err != nil {
+				return nil, err
+			}
+			obj[key] = value
+		}
+		return obj, nil
+	}
+	return p.scalar(line, indent)
+}
+
+// splitKey 拆分"key: value"，返回键和冒号之后的内容；不是键值对时ok为false
+func splitKey(line string) (key, rest string, ok bool) {
+	if strings.HasPrefix(line, `"`) {
+		quoted, err := strconv.QuotedPrefix(line)
+		if err != nil || !strings.HasPrefix(line[len(quoted):], ":") {
+			return "", line, false
+		}
+		key, _ = strconv.Unquote(quoted)
+		return key, line[len(quoted)+1:], true
+	}
+	i := strings.Index(line, ":")
+	if i < 0 || (i+1 < len(line) && line[i+1] != ' ') {
+		return "", line, false
+	}
+	return line[:i], line[i+1:], true
+}
+
+// scalar 解析当前行的标量，字面量块的内容行缩进为indent
+func (p *yamlParser) scalar(s string, indent int) (any, error) {
+	p.i++
+	switch s {
+	case "{}":
+		return map[string]any{}, nil
+	case "[]":
+		return []any{}, nil
+	case "null":
+		return nil, nil
+	case "true", "false":
+		return s == "true", nil
+	case "|", "|-", "|+":
+		return p.block(s, indent), nil
+	}
+	switch c := s[0]; {
+	case c == '"':
+		return strconv.Unquote(s)
+	case c == '-' || (c >= '0' && c <= '9'):
+		return json.Number(s), nil
+	}
+	return s, nil
+}
+
+// block 读取字面量块的内容行，header决定结尾换行的保留方式
+func (p *yamlParser) block(header string, indent int) string {
+	var lines []string
+	prefix := strings.Repeat(" ", indent)
+	for ; p.i < len(p.lines); p.i++ {
+		line := p.lines[p.i]
+		if line != "" && !strings.HasPrefix(line, prefix) {
+			break
+		}
+		lines = append(lines, strings.TrimPrefix(line, prefix))
+	}
+	blank := 0
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines, blank = lines[:len(lines)-1], blank+1
+	}
+	body := strings.Join(lines, "\n")
+	switch header {
+	case "|-":
+		return body
+	case "|":
+		return body + "\n"
+	}
+	return body + "\n" + strings.Repeat("\n", blank)
+}